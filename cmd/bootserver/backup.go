@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pddg/tiny-cluster/pkg/backup"
+	"github.com/pddg/tiny-cluster/pkg/infra"
+)
+
+func newBackupCommand() *cobra.Command {
+	var (
+		etcdOpts etcdOptions
+		fileName string
+		prefix   string
+	)
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Save all keys under the prefix into the archive file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			etcdClient, err := etcdOpts.newClient()
+			if err != nil {
+				return err
+			}
+			defer etcdClient.Close()
+			if len(prefix) == 0 {
+				prefix = etcdOpts.prefix
+			}
+			archive, err := infra.NewKeyspaceRepository(etcdClient).Snapshot(context.Background(), prefix)
+			if err != nil {
+				return err
+			}
+			if fileName == "-" {
+				return backup.Write(cmd.OutOrStdout(), archive)
+			}
+			f, err := os.Create(fileName)
+			if err != nil {
+				return err
+			}
+			if err := backup.Write(f, archive); err != nil {
+				f.Close()
+				return err
+			}
+			// The archive may be truncated if it can not be closed, such as on a full disk.
+			return f.Close()
+		},
+	}
+	etcdOpts.addFlags(backupCmd.Flags())
+	backupCmd.Flags().StringVarP(&fileName, "output", "o", "-", "Path to the archive file. '-' means stdout")
+	backupCmd.Flags().StringVar(&prefix, "prefix", "", "Prefix of the keys to be saved. --etcd-prefix is used if not given")
+	return backupCmd
+}
+
+func newRestoreCommand() *cobra.Command {
+	var (
+		etcdOpts   etcdOptions
+		fileName   string
+		prefix     string
+		onConflict string
+	)
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore the keys from the archive file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policy, err := backup.ParseConflictPolicy(onConflict)
+			if err != nil {
+				return err
+			}
+			var r io.Reader = os.Stdin
+			if fileName != "-" {
+				f, err := os.Open(fileName)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+			archive, err := backup.Read(r)
+			if err != nil {
+				return err
+			}
+			target := prefix
+			if len(target) == 0 {
+				target = archive.Prefix
+			}
+			etcdClient, err := etcdOpts.newClient()
+			if err != nil {
+				return err
+			}
+			defer etcdClient.Close()
+			result, err := infra.NewKeyspaceRepository(etcdClient).Restore(context.Background(), archive, target, policy)
+			if err != nil {
+				// The entries are written in several transactions, and the ones before the error are kept.
+				if result != nil && result.Written+result.Skipped != 0 {
+					return fmt.Errorf("restored partially: %d of %d entries were applied (%d written, %d skipped) before the error: %w",
+						result.Written+result.Skipped, len(archive.Entries), result.Written, result.Skipped, err)
+				}
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		},
+	}
+	etcdOpts.addFlags(restoreCmd.Flags())
+	restoreCmd.Flags().StringVarP(&fileName, "file", "f", "-", "Path to the archive file. '-' means stdin")
+	restoreCmd.Flags().StringVar(&prefix, "prefix", "", "Prefix to restore the keys into. The prefix of the archive is used if not given")
+	restoreCmd.Flags().StringVar(&onConflict, "on-conflict", string(backup.ConflictFail), "What to do if the key already exists (fail, skip or overwrite)")
+	return restoreCmd
+}
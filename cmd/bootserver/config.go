@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/config"
+)
+
+// annotationStrictConfig marks the commands which report the settings of the config file they do not know.
+// The others ignore them, so that the config file of the server can be shared with them.
+const annotationStrictConfig = "tiny-cluster/strict-config"
+
+// secretSettings are masked when the settings are shown.
+var secretSettings = map[string]bool{
+	"etcd-password": true,
+}
+
+// configPath returns the path to the config file given by --config or TC_CONFIG. It is empty if not given.
+func configPath(flags *pflag.FlagSet) string {
+	if flags.Changed("config") {
+		path, _ := flags.GetString("config")
+		return path
+	}
+	return os.Getenv(config.EnvName("config"))
+}
+
+// loadConfig sets the flags which are not given on the command line from the environment variables
+// and the config file at path. It returns the source of the value of each flag.
+func loadConfig(flags *pflag.FlagSet, path string, strict bool) (map[string]string, error) {
+	values := map[string]string{}
+	if len(path) != 0 {
+		var err error
+		values, err = config.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config.Load(flags, os.Environ(), values, strict)
+}
+
+// setting is the effective value of a flag of start shown by 'config validate'.
+type setting struct {
+	Name   string `json:"name"`
+	Env    string `json:"env"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func newConfigCommand() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the config of the server",
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Validate the config file and the environment variables of 'start', and show the effective settings",
+		Long: `Validate the config file and the environment variables of 'start', and show the effective settings.
+
+The config file is YAML whose keys are the flags of 'start', such as 'etcd-endpoints'.
+The nested keys are joined by '-', so that 'etcd: {endpoints: [...]}' is also 'etcd-endpoints'.
+Each flag can also be given by the environment variable TC_<FLAG>, such as TC_ETCD_ENDPOINTS.
+The flags on the command line take precedence over the environment variables,
+which take precedence over the config file.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var opts startOptions
+			flags := pflag.NewFlagSet("start", pflag.ContinueOnError)
+			opts.addFlags(flags)
+			sources, err := loadConfig(flags, configPath(cmd.Flags()), true)
+			if err != nil {
+				return err
+			}
+			if err := opts.validate(); err != nil {
+				return err
+			}
+			var settings []setting
+			for name, value := range config.Values(flags) {
+				if secretSettings[name] && len(value) != 0 {
+					value = "********"
+				}
+				settings = append(settings, setting{Name: name, Env: config.EnvName(name), Value: value, Source: sources[name]})
+			}
+			sort.Slice(settings, func(i, j int) bool {
+				return settings[i].Name < settings[j].Name
+			})
+			return encodeJSON(cmd.OutOrStdout(), settings)
+		},
+	})
+	return configCmd
+}
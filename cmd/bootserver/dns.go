@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/dns"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+// dnsOptions is the options to serve the names of the machines over DNS.
+type dnsOptions struct {
+	listen    string
+	domain    string
+	upstream  string
+	ttl       time.Duration
+	namespace string
+}
+
+func (o *dnsOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.listen, "dns-listen", "", "Address to serve the names of the machines over DNS on UDP and TCP. e.g. :53. Disabled if not given")
+	flags.StringVar(&o.domain, "cluster-domain", "cluster.internal", "Domain of the machines. Each machine is resolved as <name>.<cluster-domain>")
+	flags.StringVar(&o.upstream, "dns-upstream", "", "Address of the DNS server to resolve the names out of --cluster-domain. They are refused if not given")
+	flags.DurationVar(&o.ttl, "dns-ttl", time.Minute, "TTL of the DNS records of the machines")
+	flags.StringVar(&o.namespace, "dns-namespace", namespace.Default, "Namespace of the machines served over DNS")
+}
+
+// start serves DNS in the background and returns the function to stop it.
+// The errors of the listeners are sent to errCh.
+func (o *dnsOptions) start(machineUsecase usecase.MachineUsecase, errCh chan<- error) (func(), error) {
+	if err := namespace.Validate(o.namespace); err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenPacket("udp", o.listen)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", o.listen)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	records := dns.NewRecords(o.domain)
+	server := dns.NewServer(records, o.upstream, o.ttl)
+	ctx, cancel := context.WithCancel(namespace.NewContext(context.Background(), o.namespace))
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		watch.Sync(ctx, machineUsecase, records)
+	}()
+	go func() {
+		errCh <- server.ServeUDP(conn)
+	}()
+	go func() {
+		errCh <- server.ServeTCP(listener)
+	}()
+	log.Printf("Serving the machines in '%s' as %s on %s", o.namespace, records.Domain(), o.listen)
+	return func() {
+		conn.Close()
+		listener.Close()
+		cancel()
+		<-synced
+	}, nil
+}
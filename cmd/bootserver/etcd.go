@@ -0,0 +1,78 @@
+package main
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/infra"
+)
+
+// etcdOptions is the options to connect to etcd, which are shared among the commands.
+type etcdOptions struct {
+	endpoints      []string
+	prefix         string
+	timeout        int
+	requestTimeout int
+	caCert         string
+	cert           string
+	key            string
+	username       string
+	password       string
+}
+
+func (o *etcdOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&o.endpoints, "etcd-endpoints", []string{"http://127.0.0.1:2379"}, "Comma separated etcd endpoints")
+	flags.StringVar(&o.prefix, "etcd-prefix", infra.BasePrefix, "Prefix of all keys in etcd. Change it to share etcd with other installations")
+	flags.IntVar(&o.timeout, "etcd-timeout", 10, "Timeout in seconds to connect to etcd")
+	flags.IntVar(&o.requestTimeout, "etcd-request-timeout", 30, "Timeout in seconds of each request to etcd. 0 means no timeout")
+	flags.StringVar(&o.caCert, "etcd-cacert", "", "Path to the CA certificate to verify etcd")
+	flags.StringVar(&o.cert, "etcd-cert", "", "Path to the client certificate for etcd")
+	flags.StringVar(&o.key, "etcd-key", "", "Path to the key of the client certificate for etcd")
+	flags.StringVar(&o.username, "etcd-username", "", "Username to authenticate with etcd")
+	flags.StringVar(&o.password, "etcd-password", "", "Password to authenticate with etcd")
+}
+
+// newClient returns the etcd client. It must be closed by the caller.
+func (o *etcdOptions) newClient() (*infra.Client, error) {
+	return infra.NewClient(infra.Config{
+		Endpoints:      o.endpoints,
+		Prefix:         o.prefix,
+		DialTimeout:    time.Duration(o.timeout) * time.Second,
+		RequestTimeout: time.Duration(o.requestTimeout) * time.Second,
+		CACert:         o.caCert,
+		Cert:           o.cert,
+		Key:            o.key,
+		Username:       o.username,
+		Password:       o.password,
+	})
+}
+
+// embeddedEtcdOptions is the options to run etcd in the process.
+type embeddedEtcdOptions struct {
+	enabled        bool
+	name           string
+	dataDir        string
+	clientURL      string
+	peerURL        string
+	initialCluster string
+}
+
+func (o *embeddedEtcdOptions) addFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.enabled, "embedded-etcd", false, "Run etcd in the process instead of connecting to --etcd-endpoints")
+	flags.StringVar(&o.name, "embedded-etcd-name", defaultReplicaID(), "Member name of the embedded etcd. It must be unique in the cluster")
+	flags.StringVar(&o.dataDir, "data-dir", "/var/lib/tiny-cluster/etcd", "Directory to store the data of the embedded etcd")
+	flags.StringVar(&o.clientURL, "embedded-etcd-client-url", "http://127.0.0.1:2379", "URL of the embedded etcd to serve the clients")
+	flags.StringVar(&o.peerURL, "embedded-etcd-peer-url", "http://127.0.0.1:2380", "URL of the embedded etcd to communicate with the other members")
+	flags.StringVar(&o.initialCluster, "embedded-etcd-initial-cluster", "", "Comma separated 'name=peer-url' of all members to form a cluster among the replicas. A single member cluster is formed if not given")
+}
+
+func (o *embeddedEtcdOptions) start() (*infra.EmbeddedServer, error) {
+	return infra.StartEmbedded(infra.EmbeddedConfig{
+		Name:           o.name,
+		DataDir:        o.dataDir,
+		ClientURL:      o.clientURL,
+		PeerURL:        o.peerURL,
+		InitialCluster: o.initialCluster,
+	})
+}
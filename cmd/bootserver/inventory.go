@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/inventory"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+// inventoryFormat returns the format given by the flag, or detected from the file name.
+func inventoryFormat(formatName string, fileName string) (inventory.Format, error) {
+	if len(formatName) != 0 {
+		return inventory.ParseFormat(formatName)
+	}
+	if len(fileName) != 0 && fileName != "-" {
+		return inventory.FormatFromPath(fileName)
+	}
+	return inventory.FormatJSON, nil
+}
+
+// addNamespaceFlag adds the flag to specify the namespace of the machines.
+func addNamespaceFlag(flags *pflag.FlagSet, nsName *string) {
+	flags.StringVarP(nsName, "namespace", "n", namespace.Default, "Namespace of the machines")
+}
+
+func newImportCommand() *cobra.Command {
+	var (
+		storeOpts  storeOptions
+		nsName     string
+		fileName   string
+		formatName string
+		dryRun     bool
+	)
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Register or update machines from the inventory file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := namespace.Validate(nsName); err != nil {
+				return err
+			}
+			format, err := inventoryFormat(formatName, fileName)
+			if err != nil {
+				return err
+			}
+			var r io.Reader = os.Stdin
+			if fileName != "-" {
+				f, err := os.Open(fileName)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+			machines, err := inventory.Decode(r, format)
+			if err != nil {
+				return err
+			}
+			ctx := actor.NewContext(context.Background(), currentUser())
+			ctx = namespace.NewContext(ctx, nsName)
+			s, err := storeOpts.open()
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			machineUsecase := s.machineUsecase()
+			result, err := machineUsecase.ImportMachines(ctx, machines, dryRun)
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		},
+	}
+	storeOpts.addFlags(importCmd.Flags())
+	addNamespaceFlag(importCmd.Flags(), &nsName)
+	importCmd.Flags().StringVarP(&fileName, "file", "f", "-", "Path to the inventory file. '-' means stdin")
+	importCmd.Flags().StringVar(&formatName, "format", "", "Format of the inventory (csv, yaml or json). Detected from the file name if not given")
+	importCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show what would be changed")
+	return importCmd
+}
+
+func newExportCommand() *cobra.Command {
+	var (
+		storeOpts  storeOptions
+		nsName     string
+		fileName   string
+		formatName string
+	)
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write all machines into the inventory file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := namespace.Validate(nsName); err != nil {
+				return err
+			}
+			format, err := inventoryFormat(formatName, fileName)
+			if err != nil {
+				return err
+			}
+			s, err := storeOpts.open()
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			machineUsecase := s.machineUsecase()
+			machines, err := machineUsecase.GetAllMachines(namespace.NewContext(context.Background(), nsName))
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			if fileName != "-" {
+				f, err := os.Create(fileName)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			return inventory.Encode(w, format, machines)
+		},
+	}
+	storeOpts.addFlags(exportCmd.Flags())
+	addNamespaceFlag(exportCmd.Flags(), &nsName)
+	exportCmd.Flags().StringVarP(&fileName, "output", "o", "-", "Path to the inventory file. '-' means stdout")
+	exportCmd.Flags().StringVar(&formatName, "format", "", "Format of the inventory (csv, yaml or json). Detected from the file name if not given")
+	return exportCmd
+}
+
+func newMigrateMACsCommand() *cobra.Command {
+	var (
+		storeOpts storeOptions
+		nsName    string
+		dryRun    bool
+	)
+	migrateCmd := &cobra.Command{
+		Use:   "migrate-macs",
+		Short: "Re-key the machines stored by the MACs which are not normalized",
+		Long: `Re-key the machines stored by the MACs which are not normalized, such as "52-54-00-AB-CD-EF".
+
+The machines registered before the MACs were normalized can not be found by their MACs.
+Each of them is stored again by the normalized MAC such as "52:54:00:ab:cd:ef".
+The machine which is the same as the one stored by the normalized MAC is merged into it.
+The machines which share the normalized MAC but differ from each other are reported
+as the collisions and left as they are, so that they can be fixed by hand and migrated again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := namespace.Validate(nsName); err != nil {
+				return err
+			}
+			s, err := storeOpts.open()
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			ctx := actor.NewContext(context.Background(), currentUser())
+			ctx = namespace.NewContext(ctx, nsName)
+			result, err := s.machineUsecase().MigrateMACs(ctx, dryRun)
+			if err != nil {
+				return err
+			}
+			return encodeJSON(cmd.OutOrStdout(), result)
+		},
+	}
+	storeOpts.addFlags(migrateCmd.Flags())
+	addNamespaceFlag(migrateCmd.Flags(), &nsName)
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show what would be changed")
+	return migrateCmd
+}
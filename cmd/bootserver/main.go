@@ -7,6 +7,15 @@ import (
 func main() {
 	rootCmd := newRootComand()
 	rootCmd.AddCommand(newStartCommand())
+	rootCmd.AddCommand(newImportCommand())
+	rootCmd.AddCommand(newExportCommand())
+	rootCmd.AddCommand(newMigrateMACsCommand())
+	rootCmd.AddCommand(newRenderCommand())
+	rootCmd.AddCommand(newBackupCommand())
+	rootCmd.AddCommand(newRestoreCommand())
+	rootCmd.AddCommand(newTokensCommand())
+	rootCmd.AddCommand(newWebhooksCommand())
+	rootCmd.AddCommand(newConfigCommand())
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/render"
+)
+
+func newRenderCommand() *cobra.Command {
+	var (
+		storeOpts     storeOptions
+		nsName        string
+		fileName      string
+		opts          render.Options
+		watch         bool
+		reloadCommand string
+		delay         time.Duration
+	)
+	renderCmd := &cobra.Command{
+		Use:   "render FORMAT",
+		Short: "Write the configuration file of the DNS or DHCP server from the machines. FORMAT is hosts, bind, dnsmasq or dhcpd",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := namespace.Validate(nsName); err != nil {
+				return err
+			}
+			format, err := render.ParseFormat(args[0])
+			if err != nil {
+				return err
+			}
+			if watch && fileName == "-" {
+				return fmt.Errorf("--watch requires --output")
+			}
+			s, err := storeOpts.open()
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			machineUsecase := s.machineUsecase()
+			ctx := namespace.NewContext(context.Background(), nsName)
+			if watch {
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				go func() {
+					sigCh := make(chan os.Signal, 1)
+					signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+					sig := <-sigCh
+					log.Printf("Shutting down on %s", sig)
+					cancel()
+				}()
+				file := &render.File{
+					Path:          fileName,
+					Format:        format,
+					Options:       opts,
+					ReloadCommand: reloadCommand,
+					Delay:         delay,
+				}
+				return file.Watch(ctx, machineUsecase)
+			}
+			machines, err := machineUsecase.GetAllMachines(ctx)
+			if err != nil {
+				return err
+			}
+			opts.Serial = uint32(time.Now().Unix())
+			var buf bytes.Buffer
+			if err := render.Render(&buf, format, machines, opts); err != nil {
+				return err
+			}
+			if fileName == "-" {
+				_, err := buf.WriteTo(cmd.OutOrStdout())
+				return err
+			}
+			return render.WriteFile(fileName, buf.Bytes())
+		},
+	}
+	storeOpts.addFlags(renderCmd.Flags())
+	addNamespaceFlag(renderCmd.Flags(), &nsName)
+	renderCmd.Flags().StringVarP(&fileName, "output", "o", "-", "Path to the file, which is replaced atomically. '-' means stdout")
+	renderCmd.Flags().StringVar(&opts.Domain, "cluster-domain", "cluster.internal", "Domain of the machines. Each machine is named as <name>.<cluster-domain>")
+	renderCmd.Flags().DurationVar(&opts.TTL, "ttl", time.Minute, "TTL of the records in the zone file")
+	renderCmd.Flags().StringVar(&opts.NameServer, "name-server", "", "Name server of the zone file. ns.<cluster-domain> if not given")
+	renderCmd.Flags().BoolVar(&watch, "watch", false, "Keep rewriting the file whenever the machines are changed")
+	renderCmd.Flags().StringVar(&reloadCommand, "reload-command", "", "Shell command run after the file is rewritten in --watch. e.g. 'systemctl reload dnsmasq'")
+	renderCmd.Flags().DurationVar(&delay, "delay", time.Second, "Time to wait for the following changes before the file is rewritten in --watch")
+	return renderCmd
+}
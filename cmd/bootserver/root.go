@@ -1,15 +1,35 @@
 package main
 
 import (
+	"os/user"
+
 	"github.com/spf13/cobra"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
 )
 
 func newRootComand() *cobra.Command {
-	return &cobra.Command{
+	rootCmd := &cobra.Command{
 		Use:   "bootserver",
 		Short: "Run boot server",
+		// The flags which are not given on the command line are loaded from TC_* and the config file.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			_, err := loadConfig(cmd.Flags(), configPath(cmd.Flags()), cmd.Annotations[annotationStrictConfig] == "true")
+			return err
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return cmd.Help()
 		},
 	}
+	rootCmd.PersistentFlags().String("config", "", "Path to the YAML config file whose keys are the flags. Also given by TC_CONFIG")
+	return rootCmd
+}
+
+// currentUser returns the name of the user who runs the command, which is recorded as the actor.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return actor.Unknown
+	}
+	return u.Username
 }
@@ -1,40 +1,304 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"sort"
+	"syscall"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
 
+	"github.com/pddg/tiny-cluster/pkg/api"
 	"github.com/pddg/tiny-cluster/pkg/boot"
+	"github.com/pddg/tiny-cluster/pkg/config"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/gc"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+	"github.com/pddg/tiny-cluster/pkg/webhook"
 )
 
+// reloadableSettings are applied on SIGHUP. The others take effect after restart.
+var reloadableSettings = map[string]bool{
+	"advertise-url": true,
+	"tls-cert":      true,
+	"tls-key":       true,
+	"ui":            true,
+	"openapi":       true,
+}
+
+// startOptions is the settings of the server, which are also loaded from TC_* and the config file.
+type startOptions struct {
+	listenPort      int
+	grpcListenPort  int
+	bootFileDir     string
+	advertiseURL    string
+	replicaID       string
+	authEnabled     bool
+	uiEnabled       bool
+	openAPIEnabled  bool
+	webhooksEnabled bool
+	tls             tlsOptions
+	store           storeOptions
+	embedded        embeddedEtcdOptions
+	dns             dnsOptions
+}
+
+func (o *startOptions) addFlags(flags *pflag.FlagSet) {
+	flags.IntVarP(&o.listenPort, "port", "p", 8080, "Listen port number")
+	flags.IntVar(&o.grpcListenPort, "grpc-port", 9090, "Listen port number of gRPC API")
+	flags.StringVarP(&o.bootFileDir, "dist", "d", "/opt/bootserver", "Path to files to distribute")
+	flags.StringVar(&o.advertiseURL, "advertise-url", "http://tcboot:8080", "URL of this server which the machines fetch the boot files from")
+	flags.StringVar(&o.replicaID, "replica-id", defaultReplicaID(), "ID of this replica, which must be unique among the replicas sharing the datastore")
+	flags.BoolVar(&o.authEnabled, "auth", false, "Require the bearer tokens created by 'tokens create' for the API")
+	flags.BoolVar(&o.uiEnabled, "ui", true, "Serve the web dashboard at /ui")
+	flags.BoolVar(&o.openAPIEnabled, "openapi", true, "Serve the OpenAPI document at /openapi.json")
+	flags.BoolVar(&o.webhooksEnabled, "webhooks", true, "Deliver the events of the machines to the webhooks")
+	o.tls.addFlags(flags)
+	o.store.addFlags(flags)
+	o.embedded.addFlags(flags)
+	o.dns.addFlags(flags)
+}
+
+// validate reports all invalid settings by the names of the flags.
+func (o *startOptions) validate() error {
+	v := &tcErr.ValidationError{}
+	if _, err := os.Stat(o.bootFileDir); err != nil {
+		v.Add("dist", fmt.Sprintf("%s does not exist", o.bootFileDir))
+	}
+	if u, err := url.Parse(o.advertiseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		v.Add("advertise-url", "must be the URL of http or https")
+	}
+	switch o.store.store {
+	case storeEtcd, storeMemory:
+	case storeBolt:
+		if len(o.store.storeFile) == 0 {
+			v.Add("store-file", fmt.Sprintf("is required with --store=%s", storeBolt))
+		}
+	default:
+		v.Add("store", fmt.Sprintf("unknown store '%s'", o.store.store))
+	}
+	if o.authEnabled && o.store.store == storeMemory {
+		v.Add("auth", fmt.Sprintf("requires --store=%s or %s, where the tokens can be created", storeEtcd, storeBolt))
+	}
+	if o.embedded.enabled && o.store.store != storeEtcd {
+		v.Add("embedded-etcd", fmt.Sprintf("requires --store=%s", storeEtcd))
+	}
+	if o.tls.enabled() {
+		if len(o.tls.cert) == 0 || len(o.tls.key) == 0 {
+			v.Add("tls-cert", "must be given with --tls-key")
+		} else if _, err := o.tls.load(); err != nil {
+			v.Add("tls-cert", err.Error())
+		}
+	}
+	return v.Err()
+}
+
+// reloadStartOptions loads the settings again. The flags on the command line of cmd still take precedence.
+func reloadStartOptions(cmd *cobra.Command) (*startOptions, *pflag.FlagSet, error) {
+	opts := &startOptions{}
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	opts.addFlags(flags)
+	if err := config.CopyChanged(flags, cmd.Flags()); err != nil {
+		return nil, nil, err
+	}
+	if _, err := loadConfig(flags, configPath(cmd.Flags()), true); err != nil {
+		return nil, nil, err
+	}
+	return opts, flags, opts.validate()
+}
+
 func newStartCommand() *cobra.Command {
-	var (
-		listenPort  int
-		bootFileDir string
-	)
+	var opts startOptions
 	startCmd := &cobra.Command{
 		Use:   "start",
 		Short: "Start server",
+		Long: `Start server.
+
+Each flag can also be given by the environment variable TC_<FLAG>, such as TC_ETCD_ENDPOINTS,
+or by the key of the config file given by --config. Run 'config validate' to check them.
+On SIGHUP, the config file and the environment variables are loaded again,
+and the changes of advertise-url, tls-cert, tls-key, ui and openapi are applied without restart.`,
+		Annotations: map[string]string{annotationStrictConfig: "true"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if _, err := os.Stat(bootFileDir); err != nil {
-				log.Fatalf("%s does not exist.", bootFileDir)
+			if err := opts.validate(); err != nil {
+				return err
 			}
+			errCh := make(chan error, 5)
+			if opts.embedded.enabled {
+				etcdServer, err := opts.embedded.start()
+				if err != nil {
+					return err
+				}
+				defer etcdServer.Close()
+				opts.store.etcd.endpoints = etcdServer.Endpoints()
+				go func() {
+					errCh <- <-etcdServer.Err()
+				}()
+			}
+			s, err := opts.store.open()
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			machineUsecase := s.machineUsecase()
+
+			// The background tasks which must run on only one of the replicas are registered into the runner.
+			runner := leader.NewRunner(opts.replicaID, s.leaderElection)
+			if opts.webhooksEnabled {
+				runner.Register("webhooks", webhook.NewDispatcher(s.webhookUsecase(), machineUsecase).Run)
+			}
+			runner.Register("gc", gc.NewCollector(machineUsecase).Run)
+			runnerCtx, stopRunner := context.WithCancel(context.Background())
+			runnerDone := make(chan struct{})
+			go func() {
+				defer close(runnerDone)
+				if err := runner.Run(runnerCtx); err != nil {
+					log.Printf("Failed to resign the leadership: %v", err)
+				}
+			}()
+			defer func() {
+				stopRunner()
+				<-runnerDone
+			}()
+
+			// The boot files are always served without the tokens, since the machines being provisioned have none.
+			interceptors := []grpc.UnaryServerInterceptor{api.ErrorInterceptor, api.ActorInterceptor, api.NamespaceInterceptor}
+			var apiMiddlewares []echo.MiddlewareFunc
+			if opts.authEnabled {
+				tokenUsecase := s.tokenUsecase()
+				interceptors = append(interceptors, api.AuthInterceptor(tokenUsecase))
+				apiMiddlewares = append(apiMiddlewares, api.AuthMiddleware(tokenUsecase))
+			} else {
+				log.Print("The API is served without authentication. Anyone who can reach it can modify the machines")
+			}
+			grpcServer := grpc.NewServer(grpc.UnaryInterceptor(api.ChainUnaryInterceptors(interceptors...)))
+			api.RegisterMachineDatabaseServer(grpcServer, api.NewMachineDatabaseServer(machineUsecase))
+			listener, err := net.Listen("tcp", fmt.Sprintf(":%d", opts.grpcListenPort))
+			if err != nil {
+				return err
+			}
+			go func() {
+				errCh <- grpcServer.Serve(listener)
+			}()
+			defer grpcServer.Stop()
+
+			// DNS is served on every replica so that each node can use its local replica.
+			if len(opts.dns.listen) != 0 {
+				stopDNS, err := opts.dns.start(machineUsecase, errCh)
+				if err != nil {
+					return err
+				}
+				defer stopDNS()
+			}
+
 			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
 
 			e.Use(middleware.Logger())
 
-			e.GET("/default.ipxe", boot.IPXEScriptHandler)
-			e.Static("/boot", bootFileDir)
+			routes := &api.Routes{
+				MachineUsecase: machineUsecase,
+				Runner:         runner,
+				BootFileDir:    opts.bootFileDir,
+				IPXEScript:     boot.NewIPXEScript(opts.advertiseURL),
+				APIMiddlewares: apiMiddlewares,
+				UI:             api.NewToggle(opts.uiEnabled),
+				OpenAPI:        api.NewToggle(opts.openAPIEnabled),
+			}
+			routes.Register(e)
+
+			server := &http.Server{Addr: fmt.Sprintf(":%d", opts.listenPort)}
+			var cert *certificate
+			if opts.tls.enabled() {
+				c, err := opts.tls.load()
+				if err != nil {
+					return err
+				}
+				cert = &certificate{cert: c}
+				server.TLSConfig = &tls.Config{GetCertificate: cert.get}
+			}
+			go func() {
+				errCh <- e.StartServer(server)
+			}()
+
+			// reload applies the reloadable settings, and reports the others which require restart.
+			current := config.Values(cmd.Flags())
+			reload := func() error {
+				next, flags, err := reloadStartOptions(cmd)
+				if err != nil {
+					return err
+				}
+				if opts.tls.enabled() != next.tls.enabled() {
+					log.Print("Switching TLS on or off requires restart")
+				} else if next.tls.enabled() {
+					c, err := next.tls.load()
+					if err != nil {
+						return err
+					}
+					cert.set(c)
+				}
+				routes.IPXEScript.SetBaseURL(next.advertiseURL)
+				routes.UI.Set(next.uiEnabled)
+				routes.OpenAPI.Set(next.openAPIEnabled)
+				var restart []string
+				for name, value := range config.Values(flags) {
+					if current[name] == value {
+						continue
+					}
+					if reloadableSettings[name] {
+						current[name] = value
+					} else {
+						restart = append(restart, name)
+					}
+				}
+				if len(restart) != 0 {
+					sort.Strings(restart)
+					log.Printf("The changes of %v take effect after restart", restart)
+				}
+				return nil
+			}
 
-			return e.Start(fmt.Sprintf(":%d", listenPort))
+			// Return on the signals so that the leadership is resigned and taken over immediately.
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+			for {
+				select {
+				case err := <-errCh:
+					return err
+				case sig := <-sigCh:
+					if sig != syscall.SIGHUP {
+						log.Printf("Shutting down on %s", sig)
+						return nil
+					}
+					if err := reload(); err != nil {
+						log.Printf("Failed to reload the config. The current settings are kept: %v", err)
+						continue
+					}
+					log.Print("Reloaded the config")
+				}
+			}
 		},
 	}
-	startCmd.Flags().IntVarP(&listenPort, "port", "p", 8080, "Listen port number")
-	startCmd.Flags().StringVarP(&bootFileDir, "dist", "d", "/opt/bootserver", "Path to files to distribute")
+	opts.addFlags(startCmd.Flags())
 	return startCmd
 }
+
+// defaultReplicaID returns the host name, which is unique if a replica runs on each node.
+func defaultReplicaID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "default"
+	}
+	return hostname
+}
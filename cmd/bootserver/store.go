@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/boltdb"
+	"github.com/pddg/tiny-cluster/pkg/infra"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+const (
+	storeEtcd   = "etcd"
+	storeMemory = "memory"
+	storeBolt   = "bolt"
+)
+
+// electionName is the name of the election among the replicas of bootserver.
+const electionName = "bootserver"
+
+// storeOptions is the options to choose the datastore of the machines.
+// The machines can be moved between the datastores by 'export' and 'import'.
+type storeOptions struct {
+	store     string
+	storeFile string
+	etcd      etcdOptions
+}
+
+func (o *storeOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.store, "store", storeEtcd, "Datastore of the machines (etcd, memory or bolt)")
+	flags.StringVar(&o.storeFile, "store-file", "", "File to persist the machines. Optional JSON file with --store=memory, and required database file with --store=bolt")
+	o.etcd.addFlags(flags)
+}
+
+// store is the repositories on the datastore.
+type store struct {
+	machineRepo   repositories.MachineRepository
+	heartbeatRepo repositories.HeartbeatRepository
+	tokenRepo     repositories.TokenRepository
+	webhookRepo   repositories.WebhookRepository
+	// leaderElection is shared among the replicas only with etcd.
+	// The other datastores can not be shared, so that the only replica always leads.
+	leaderElection repositories.LeaderElection
+	release        func()
+}
+
+// open returns the repositories on the datastore. It must be released by the caller.
+func (o *storeOptions) open() (*store, error) {
+	switch o.store {
+	case storeEtcd:
+		etcdClient, err := o.etcd.newClient()
+		if err != nil {
+			return nil, err
+		}
+		return &store{
+			machineRepo:    infra.NewMachineRepository(etcdClient),
+			heartbeatRepo:  infra.NewHeartbeatRepository(etcdClient),
+			tokenRepo:      infra.NewTokenRepository(etcdClient),
+			webhookRepo:    infra.NewWebhookRepository(etcdClient),
+			leaderElection: infra.NewLeaderElection(etcdClient, electionName),
+			release:        func() { etcdClient.Close() },
+		}, nil
+	case storeMemory:
+		machineRepo, err := memory.NewMachineRepository(o.storeFile)
+		if err != nil {
+			return nil, err
+		}
+		return &store{
+			machineRepo:    machineRepo,
+			heartbeatRepo:  memory.NewHeartbeatRepository(),
+			tokenRepo:      memory.NewTokenRepository(),
+			webhookRepo:    memory.NewWebhookRepository(),
+			leaderElection: leader.NewLocalElection(),
+			release:        func() {},
+		}, nil
+	case storeBolt:
+		if len(o.storeFile) == 0 {
+			return nil, fmt.Errorf("--store-file is required with --store=%s", storeBolt)
+		}
+		db, err := boltdb.Open(o.storeFile)
+		if err != nil {
+			return nil, err
+		}
+		return &store{
+			machineRepo:    boltdb.NewMachineRepository(db),
+			heartbeatRepo:  boltdb.NewHeartbeatRepository(db),
+			tokenRepo:      boltdb.NewTokenRepository(db),
+			webhookRepo:    boltdb.NewWebhookRepository(db),
+			leaderElection: leader.NewLocalElection(),
+			release:        func() { db.Close() },
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown store '%s'", o.store)
+	}
+}
+
+// machineUsecase returns the usecase on the repositories of the datastore.
+func (s *store) machineUsecase() usecase.MachineUsecase {
+	return usecase.NewMachineUseCase(s.machineRepo, s.heartbeatRepo)
+}
+
+// tokenUsecase returns the usecase of the API tokens in the datastore.
+func (s *store) tokenUsecase() usecase.TokenUsecase {
+	return usecase.NewTokenUseCase(s.tokenRepo)
+}
+
+// webhookUsecase returns the usecase of the webhooks in the datastore.
+func (s *store) webhookUsecase() usecase.WebhookUsecase {
+	return usecase.NewWebhookUseCase(s.webhookRepo)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"crypto/tls"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// tlsOptions is the options to serve the HTTP API over TLS.
+type tlsOptions struct {
+	cert string
+	key  string
+}
+
+func (o *tlsOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.cert, "tls-cert", "", "Path to the certificate to serve the HTTP API over TLS. It is reloaded on SIGHUP")
+	flags.StringVar(&o.key, "tls-key", "", "Path to the key of --tls-cert")
+}
+
+func (o *tlsOptions) enabled() bool {
+	return len(o.cert) != 0 || len(o.key) != 0
+}
+
+func (o *tlsOptions) load() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(o.cert, o.key)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// certificate is the certificate of the server which can be replaced while serving.
+type certificate struct {
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+func (c *certificate) set(cert *tls.Certificate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = cert
+}
+
+// get is tls.Config.GetCertificate.
+func (c *certificate) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
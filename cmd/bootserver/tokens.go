@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// tokenView is the token shown to the user. The hash is never shown.
+type tokenView struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Role      string `json:"role"`
+	// Scope is the label selector of the machines which the token can access.
+	Scope map[string]string `json:"scope,omitempty"`
+	// MAC is the MAC address of the machine which the token is bound to.
+	MAC string `json:"mac,omitempty"`
+	// Token is the bearer token, which is shown only when created.
+	Token string `json:"token,omitempty"`
+}
+
+func newTokenView(token *models.Token) *tokenView {
+	return &tokenView{
+		ID:        token.ID,
+		Name:      token.Name,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+		Role:      auth.PrincipalFromToken(token).Role,
+		Scope:     token.Scope,
+		MAC:       token.MAC,
+	}
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// openSharedStore returns the store where what is managed, such as the tokens, can be shared with the server.
+func (o *storeOptions) openSharedStore(what string) (*store, error) {
+	if o.store == storeMemory {
+		return nil, fmt.Errorf("the %s can not be shared with the server on --store=%s", what, storeMemory)
+	}
+	return o.open()
+}
+
+func newTokensCommand() *cobra.Command {
+	tokensCmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Manage the API tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	tokensCmd.AddCommand(newTokensCreateCommand())
+	tokensCmd.AddCommand(newTokensListCommand())
+	tokensCmd.AddCommand(newTokensRevokeCommand())
+	return tokensCmd
+}
+
+func newTokensCreateCommand() *cobra.Command {
+	var (
+		storeOpts storeOptions
+		name      string
+		role      string
+		scope     map[string]string
+		ttl       time.Duration
+		mac       string
+	)
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the token. The bearer token is shown only once",
+		Long: `Create the token. The bearer token is shown only once.
+
+The token created with --mac is bound to the machine, which is given to tcagent at the provisioning.
+It can only read the machine, patch its spec and send its heartbeats.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(mac) == 0 && len(name) == 0 {
+				return fmt.Errorf("--name is required unless --mac is given")
+			}
+			if len(mac) != 0 && (cmd.Flags().Changed("role") || cmd.Flags().Changed("scope")) {
+				return fmt.Errorf("--role and --scope can not be given with --mac, which permits only the machine")
+			}
+			s, err := storeOpts.openSharedStore("tokens")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			tokenUsecase := s.tokenUsecase()
+			var (
+				token  *models.Token
+				bearer string
+			)
+			if len(mac) != 0 {
+				token, bearer, err = tokenUsecase.CreateMachineToken(context.Background(), name, mac, ttl)
+			} else {
+				token, bearer, err = tokenUsecase.CreateToken(context.Background(), name, role, scope, ttl)
+			}
+			if err != nil {
+				return err
+			}
+			view := newTokenView(token)
+			view.Token = bearer
+			return encodeJSON(cmd.OutOrStdout(), view)
+		},
+	}
+	storeOpts.addFlags(createCmd.Flags())
+	createCmd.Flags().StringVar(&name, "name", "", "Name of who uses the token, which is recorded as the actor. The MAC by default with --mac")
+	createCmd.Flags().StringVar(&role, "role", auth.RoleViewer, fmt.Sprintf("Role of the token. One of %s, %s and %s", auth.RoleViewer, auth.RoleOperator, auth.RoleAdmin))
+	createCmd.Flags().StringToStringVar(&scope, "scope", nil, "Labels of the machines which the token can access. e.g. env=staging. All machines if not specified")
+	createCmd.Flags().DurationVar(&ttl, "ttl", 0, "Time until the token expires. It never expires if 0")
+	createCmd.Flags().StringVar(&mac, "mac", "", "MAC address of the machine which the token is bound to, such as for tcagent")
+	return createCmd
+}
+
+func newTokensListCommand() *cobra.Command {
+	var storeOpts storeOptions
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show all tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := storeOpts.openSharedStore("tokens")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			tokens, err := s.tokenUsecase().GetTokens(context.Background())
+			if err != nil {
+				return err
+			}
+			views := make([]*tokenView, 0, len(tokens))
+			for _, token := range tokens {
+				views = append(views, newTokenView(token))
+			}
+			return encodeJSON(cmd.OutOrStdout(), views)
+		},
+	}
+	storeOpts.addFlags(listCmd.Flags())
+	return listCmd
+}
+
+func newTokensRevokeCommand() *cobra.Command {
+	var storeOpts storeOptions
+	revokeCmd := &cobra.Command{
+		Use:   "revoke ID...",
+		Short: "Revoke the tokens",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := storeOpts.openSharedStore("tokens")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			for _, id := range args {
+				if err := s.tokenUsecase().RevokeToken(context.Background(), id); err != nil {
+					return fmt.Errorf("failed to revoke '%s': %w", id, err)
+				}
+			}
+			return nil
+		},
+	}
+	storeOpts.addFlags(revokeCmd.Flags())
+	return revokeCmd
+}
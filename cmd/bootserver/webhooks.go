@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+// webhookView is the webhook shown to the user. The secret is shown only when created.
+type webhookView struct {
+	ID        string   `json:"id"`
+	Namespace string   `json:"namespace"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+	// Secret is the key to verify the signatures of the payloads.
+	Secret string `json:"secret,omitempty"`
+}
+
+func newWebhookView(webhook *models.Webhook) *webhookView {
+	return &webhookView{
+		ID:        webhook.ID,
+		Namespace: webhook.Namespace,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+func newWebhooksCommand() *cobra.Command {
+	webhooksCmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Manage the webhooks notified of the events of the machines",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+	webhooksCmd.AddCommand(newWebhooksCreateCommand())
+	webhooksCmd.AddCommand(newWebhooksListCommand())
+	webhooksCmd.AddCommand(newWebhooksDeleteCommand())
+	webhooksCmd.AddCommand(newWebhooksDeliveriesCommand())
+	return webhooksCmd
+}
+
+func newWebhooksCreateCommand() *cobra.Command {
+	var (
+		storeOpts storeOptions
+		nsName    string
+		url       string
+		events    []string
+	)
+	createCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create the webhook. The secret to verify the signatures is shown only once",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := namespace.Validate(nsName); err != nil {
+				return err
+			}
+			s, err := storeOpts.openSharedStore("webhooks")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			ctx := namespace.NewContext(context.Background(), nsName)
+			webhook, err := s.webhookUsecase().CreateWebhook(ctx, url, events)
+			if err != nil {
+				return err
+			}
+			view := newWebhookView(webhook)
+			view.Secret = webhook.Secret
+			return encodeJSON(cmd.OutOrStdout(), view)
+		},
+	}
+	storeOpts.addFlags(createCmd.Flags())
+	addNamespaceFlag(createCmd.Flags(), &nsName)
+	createCmd.Flags().StringVar(&url, "url", "", "HTTP or HTTPS URL which the events are posted to")
+	createCmd.Flags().StringSliceVar(&events, "event", nil, fmt.Sprintf("Event to be notified. One of %s. All events if not specified", strings.Join(models.WebhookEvents, ", ")))
+	createCmd.MarkFlagRequired("url")
+	return createCmd
+}
+
+func newWebhooksListCommand() *cobra.Command {
+	var storeOpts storeOptions
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show all webhooks",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := storeOpts.openSharedStore("webhooks")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			webhooks, err := s.webhookUsecase().GetWebhooks(context.Background())
+			if err != nil {
+				return err
+			}
+			views := make([]*webhookView, 0, len(webhooks))
+			for _, webhook := range webhooks {
+				views = append(views, newWebhookView(webhook))
+			}
+			return encodeJSON(cmd.OutOrStdout(), views)
+		},
+	}
+	storeOpts.addFlags(listCmd.Flags())
+	return listCmd
+}
+
+func newWebhooksDeleteCommand() *cobra.Command {
+	var storeOpts storeOptions
+	deleteCmd := &cobra.Command{
+		Use:   "delete ID...",
+		Short: "Delete the webhooks and their deliveries",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := storeOpts.openSharedStore("webhooks")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			for _, id := range args {
+				if err := s.webhookUsecase().DeleteWebhook(context.Background(), id); err != nil {
+					return fmt.Errorf("failed to delete '%s': %w", id, err)
+				}
+			}
+			return nil
+		},
+	}
+	storeOpts.addFlags(deleteCmd.Flags())
+	return deleteCmd
+}
+
+func newWebhooksDeliveriesCommand() *cobra.Command {
+	var storeOpts storeOptions
+	deliveriesCmd := &cobra.Command{
+		Use:   "deliveries ID",
+		Short: "Show the latest attempts to deliver the events to the webhook",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := storeOpts.openSharedStore("webhooks")
+			if err != nil {
+				return err
+			}
+			defer s.release()
+			deliveries, err := s.webhookUsecase().GetDeliveries(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			if deliveries == nil {
+				deliveries = []*models.WebhookDelivery{}
+			}
+			return encodeJSON(cmd.OutOrStdout(), deliveries)
+		},
+	}
+	storeOpts.addFlags(deliveriesCmd.Flags())
+	return deliveriesCmd
+}
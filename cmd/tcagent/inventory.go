@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+)
+
+func newInventoryCommand() *cobra.Command {
+	var collectorOpts collectorOptions
+	inventoryCmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "Show the hardware inventory of this machine without reporting it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			inv, err := collectorOpts.collector().Collect()
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(inv)
+		},
+	}
+	collectorOpts.addFlags(inventoryCmd.Flags())
+	return inventoryCmd
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"log"
+)
+
+func main() {
+	rootCmd := newRootComand()
+	rootCmd.AddCommand(newRunCommand())
+	rootCmd.AddCommand(newInventoryCommand())
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
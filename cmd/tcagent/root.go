@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/agent"
+)
+
+func newRootComand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tcagent",
+		Short: "Report the hardware inventory and the heartbeats of this machine",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+}
+
+// collectorOptions is the options to collect the hardware inventory.
+type collectorOptions struct {
+	root  string
+	iface string
+}
+
+func (o *collectorOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.root, "root", "/", "Directory where proc and sys are mounted")
+	flags.StringVar(&o.iface, "interface", "", "Network interface whose MAC identifies this machine. The first physical one is used if not given")
+}
+
+func (o *collectorOptions) collector() *agent.Collector {
+	return &agent.Collector{
+		Root:      o.root,
+		Interface: o.iface,
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/pddg/tiny-cluster/pkg/agent"
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+func newRunCommand() *cobra.Command {
+	var (
+		collectorOpts  collectorOptions
+		server         string
+		nsName         string
+		credentialFile string
+		caFile         string
+		interval       time.Duration
+		once           bool
+	)
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Report the inventory and the heartbeats to the server periodically",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := namespace.Validate(nsName); err != nil {
+				return err
+			}
+			if interval <= 0 || interval >= usecase.HeartbeatTTL {
+				return fmt.Errorf("--interval must be positive and shorter than %v", usecase.HeartbeatTTL)
+			}
+			secure := len(caFile) != 0
+			credential, err := agent.LoadCredential(credentialFile, secure)
+			if err != nil {
+				return err
+			}
+			opts := []grpc.DialOption{grpc.WithPerRPCCredentials(credential)}
+			if secure {
+				tlsCreds, err := credentials.NewClientTLSFromFile(caFile, "")
+				if err != nil {
+					return err
+				}
+				opts = append(opts, grpc.WithTransportCredentials(tlsCreds))
+			} else {
+				opts = append(opts, grpc.WithInsecure())
+			}
+			conn, err := grpc.Dial(server, opts...)
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+
+			hostname, _ := os.Hostname()
+			ctx := metadata.AppendToOutgoingContext(context.Background(),
+				api.ActorMetadataKey, "tcagent@"+hostname,
+				api.NamespaceMetadataKey, nsName,
+			)
+			a := agent.New(api.NewMachineDatabaseClient(conn), collectorOpts.collector(), interval)
+			if once {
+				_, err := a.Report(ctx)
+				return err
+			}
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+			go func() {
+				sig := <-signals
+				log.Printf("Received %v, shutting down", sig)
+				cancel()
+			}()
+			return a.Run(ctx)
+		},
+	}
+	collectorOpts.addFlags(runCmd.Flags())
+	runCmd.Flags().StringVar(&server, "server", "tcboot:9090", "Address of the gRPC API of bootserver")
+	runCmd.Flags().StringVarP(&nsName, "namespace", "n", namespace.Default, "Namespace where this machine is registered")
+	runCmd.Flags().StringVar(&credentialFile, "credential-file", "/etc/tcagent/credential", "Path to the credential issued to this machine at the provisioning by 'bootserver tokens create --mac'")
+	runCmd.Flags().StringVar(&caFile, "ca-file", "", "Path to the CA certificate of the server. The connection is not encrypted if not given")
+	runCmd.Flags().DurationVar(&interval, "interval", 30*time.Second, "Interval to report")
+	runCmd.Flags().BoolVar(&once, "once", false, "Report only once and exit")
+	return runCmd
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/cobra"
+)
+
+func newInventoryCommand() *cobra.Command {
+	var (
+		clientOpts clientOptions
+		list       bool
+		host       string
+	)
+	inventoryCmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "Show the Ansible dynamic inventory of the machines",
+		Long: `Show the Ansible dynamic inventory of the machines.
+The machines are grouped by the labels as <key>_<value> (e.g. rack_a1), by the deployment (deployed or undeployed)
+and by the liveness (reachable or unreachable). To use this as the inventory script, run it from an executable file
+such as:
+
+  #!/bin/sh
+  exec tcctl inventory --server http://tcboot:8080 "$@"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list && len(host) != 0 {
+				return fmt.Errorf("--list and --host can not be used together")
+			}
+			query := url.Values{}
+			if len(host) != 0 {
+				query.Set("host", host)
+			}
+			var inv json.RawMessage
+			if err := clientOpts.get("/inventory/ansible", query, &inv); err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(inv)
+		},
+	}
+	clientOpts.addFlags(inventoryCmd.Flags())
+	inventoryCmd.Flags().BoolVar(&list, "list", false, "Show all groups and hosts, which is the default")
+	inventoryCmd.Flags().StringVar(&host, "host", "", "Show only the variables of the host")
+	return inventoryCmd
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"log"
+)
+
+func main() {
+	rootCmd := newRootComand()
+	rootCmd.AddCommand(newInventoryCommand())
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+func newRootComand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tcctl",
+		Short: "Query the machines through the HTTP API of bootserver",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+}
+
+// clientOptions is the options to call the HTTP API.
+type clientOptions struct {
+	server    string
+	namespace string
+	tokenFile string
+}
+
+func (o *clientOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.server, "server", "http://tcboot:8080", "URL of the HTTP API of bootserver")
+	flags.StringVarP(&o.namespace, "namespace", "n", namespace.Default, "Namespace of the machines")
+	flags.StringVar(&o.tokenFile, "token-file", "", "Path to the file of the bearer token created by 'bootserver tokens create'. Required if the server runs with --auth")
+}
+
+// get calls the API at path under /api/v1 in the namespace, and decodes the JSON response into v.
+func (o *clientOptions) get(path string, query url.Values, v interface{}) error {
+	if err := namespace.Validate(o.namespace); err != nil {
+		return err
+	}
+	prefix := "/api/v1"
+	if o.namespace != namespace.Default {
+		prefix += "/namespaces/" + o.namespace
+	}
+	u := strings.TrimSuffix(o.server, "/") + prefix + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if len(o.tokenFile) != 0 {
+		content, err := ioutil.ReadFile(o.tokenFile)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(echo.HeaderAuthorization, api.BearerScheme+" "+strings.TrimSpace(string(content)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp.StatusCode, resp.Body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// responseError returns the error described by the problem details in the response.
+func responseError(status int, body io.Reader) error {
+	var problem api.Problem
+	if err := json.NewDecoder(body).Decode(&problem); err != nil || len(problem.Detail) == 0 {
+		return fmt.Errorf("the server responded %d %s", status, http.StatusText(status))
+	}
+	return fmt.Errorf("the server responded %d: %s", status, problem.Detail)
+}
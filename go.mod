@@ -3,7 +3,7 @@ module github.com/pddg/tiny-cluster
 go 1.15
 
 require (
-	github.com/coreos/etcd v3.3.25+incompatible // indirect
+	github.com/coreos/etcd v3.3.25+incompatible
 	github.com/coreos/go-systemd v0.0.0-20191104093116-d3cd4ed1dbcf // indirect
 	github.com/dustin/go-humanize v1.0.0 // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
@@ -15,20 +15,26 @@ require (
 	github.com/json-iterator/go v1.1.10 // indirect
 	github.com/labstack/echo/v4 v4.1.17
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
+	go.etcd.io/bbolt v1.3.5
 	go.etcd.io/etcd v3.3.25+incompatible
 	go.etcd.io/etcd/api/v3 v3.0.0-20201024185310-8fc5ef4a039c
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.16.0 // indirect
 	golang.org/x/lint v0.0.0-20200302205851-738671d3881b // indirect
-	golang.org/x/net v0.0.0-20201009032441-dbdefad45b89 // indirect
+	golang.org/x/net v0.0.0-20201009032441-dbdefad45b89
 	golang.org/x/sys v0.0.0-20201009025420-dfb3f7c4e634 // indirect
 	golang.org/x/time v0.0.0-20191024005414-555d28b269f0 // indirect
 	golang.org/x/tools v0.0.0-20200806022845-90696ccdc692 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
+	google.golang.org/grpc v1.29.1
 	google.golang.org/protobuf v1.25.0
 	honnef.co/go/tools v0.0.1-2020.1.4 // indirect
-	sigs.k8s.io/yaml v1.2.0 // indirect
-
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace google.golang.org/grpc => google.golang.org/grpc v1.26.0
+
+// etcd v3.3 embed imports bbolt by its old path, which has been renamed to go.etcd.io/bbolt.
+replace github.com/coreos/bbolt => go.etcd.io/bbolt v1.3.4
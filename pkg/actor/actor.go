@@ -0,0 +1,24 @@
+// Package actor carries the name of the one who operates TinyCluster through context.
+package actor
+
+import "context"
+
+// Unknown is the name of the actor used when it is not given.
+const Unknown = "unknown"
+
+type contextKey struct{}
+
+// NewContext returns a new context which carries the name of the actor.
+func NewContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the name of the actor stored in ctx.
+// This returns Unknown if the name has not been stored.
+func FromContext(ctx context.Context) string {
+	name, ok := ctx.Value(contextKey{}).(string)
+	if !ok || len(name) == 0 {
+		return Unknown
+	}
+	return name
+}
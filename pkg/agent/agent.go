@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/genproto/protobuf/field_mask"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/api/pb"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// Agent reports the inventory and the heartbeats of the machine to the MachineDatabase API.
+type Agent struct {
+	client    api.MachineDatabaseClient
+	collector *Collector
+	interval  time.Duration
+}
+
+// Report collects the inventory, updates the spec of the machine if it has changed, and sends the heartbeat.
+// The machine must have been registered in the database.
+func (a *Agent) Report(ctx context.Context) (*Inventory, error) {
+	inv, err := a.collector.Collect()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.GetMachines(ctx, &pb.GetMachinesRequest{
+		Queries: []*pb.GetMachinesRequest_QueryItem{{Key: "mac", Value: inv.MAC}},
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the machine %w", err)
+	}
+	if len(resp.GetMachines()) == 0 {
+		return nil, xerrors.Errorf("machine '%s' has not been registered %w", inv.MAC, tcErr.ErrNotFound)
+	}
+	if err := a.updateSpec(ctx, resp.GetMachines()[0], inv); err != nil {
+		return nil, err
+	}
+	heartbeat, err := a.client.Heartbeat(ctx, &pb.HeartbeatRequest{Mac: inv.MAC})
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to send the heartbeat %w", err)
+	}
+	if !heartbeat.GetSuccess() {
+		return nil, xerrors.Errorf("Failed to send the heartbeat: %s", heartbeat.GetMessage())
+	}
+	return inv, nil
+}
+
+// updateSpec patches the spec of the machine by the detected values which differ from the registered ones.
+func (a *Agent) updateSpec(ctx context.Context, registered *pb.Machine, inv *Inventory) error {
+	current := registered.GetSpec()
+	var paths []string
+	for _, field := range []struct {
+		path       string
+		detected   int
+		registered int32
+	}{
+		{"spec.core", inv.Spec.Core, current.GetCore()},
+		{"spec.memory", inv.Spec.Memory, current.GetMemory()},
+		{"spec.disk", inv.Spec.Disk, current.GetDisk()},
+	} {
+		// The value which could not be detected must not overwrite the registered one.
+		if field.detected != 0 && int32(field.detected) != field.registered {
+			paths = append(paths, field.path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	resp, err := a.client.PatchMachine(ctx, &pb.PatchMachineRequest{
+		Mac: inv.MAC,
+		Machine: &pb.Machine{
+			Spec: &pb.MachineSpec{
+				Core:   int32(inv.Spec.Core),
+				Memory: int32(inv.Spec.Memory),
+				Disk:   int32(inv.Spec.Disk),
+			},
+		},
+		UpdateMask: &field_mask.FieldMask{Paths: paths},
+	})
+	if err != nil {
+		return xerrors.Errorf("Failed to update the spec %w", err)
+	}
+	if !resp.GetSuccess() {
+		return xerrors.Errorf("Failed to update the spec: %s", resp.GetMessage())
+	}
+	log.Printf("Updated %v of %s", paths, inv.MAC)
+	return nil
+}
+
+// Run reports at the interval until the context is done.
+// The failures are logged and retried at the next interval, so that the agent survives the outage of the server.
+func (a *Agent) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := a.Report(ctx); err != nil {
+			log.Printf("Failed to report: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// New returns the agent which reports at the interval.
+// The interval must be shorter than usecase.HeartbeatTTL to keep the machine reachable.
+func New(client api.MachineDatabaseClient, collector *Collector, interval time.Duration) *Agent {
+	return &Agent{
+		client:    client,
+		collector: collector,
+		interval:  interval,
+	}
+}
@@ -0,0 +1,143 @@
+package agent_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/pddg/tiny-cluster/pkg/agent"
+	"github.com/pddg/tiny-cluster/pkg/api"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+// testServer is the MachineDatabase API on the memory, which records the credentials sent by the clients.
+type testServer struct {
+	usecase usecase.MachineUsecase
+
+	mu          sync.Mutex
+	credentials []string
+}
+
+func (s *testServer) recordCredential(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	s.credentials = append(s.credentials, md.Get(api.AuthorizationMetadataKey)...)
+	s.mu.Unlock()
+	return handler(ctx, req)
+}
+
+func startTestServer(t *testing.T) (*testServer, api.MachineDatabaseClient) {
+	t.Helper()
+	machineRepo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatalf("Failed to create the repository due to %v", err)
+	}
+	s := &testServer{usecase: usecase.NewMachineUseCase(machineRepo, memory.NewHeartbeatRepository())}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.recordCredential))
+	api.RegisterMachineDatabaseServer(grpcServer, api.NewMachineDatabaseServer(s.usecase))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen due to %v", err)
+	}
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	credentialFile := filepath.Join(t.TempDir(), "credential")
+	if err := ioutil.WriteFile(credentialFile, []byte("machine-token\n"), 0600); err != nil {
+		t.Fatalf("Failed to write the credential due to %v", err)
+	}
+	credential, err := agent.LoadCredential(credentialFile, false)
+	if err != nil {
+		t.Fatalf("Failed to load the credential due to %v", err)
+	}
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(), grpc.WithPerRPCCredentials(credential))
+	if err != nil {
+		t.Fatalf("Failed to dial due to %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, api.NewMachineDatabaseClient(conn)
+}
+
+func TestAgent_Report(t *testing.T) {
+	registered := &models.Machine{
+		Name:     "machine1",
+		MAC:      "52:54:00:00:00:01",
+		IPv4Addr: "192.168.0.2",
+		Spec:     models.MachineSpec{Core: 2, Memory: 2048, Disk: 64},
+	}
+	testCases := map[string]struct {
+		fixtures  []*models.Machine
+		expect    *models.Machine
+		expectErr error
+	}{
+		"update the spec": {
+			fixtures: []*models.Machine{registered},
+			expect: &models.Machine{
+				Name:     registered.Name,
+				MAC:      registered.MAC,
+				IPv4Addr: registered.IPv4Addr,
+				Spec:     serverSpec,
+				Liveness: models.LivenessReachable,
+			},
+		},
+		"not registered": {
+			fixtures:  nil,
+			expect:    nil,
+			expectErr: tcErr.ErrNotFound,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			server, client := startTestServer(t)
+			if _, err := server.usecase.ImportMachines(ctx, tc.fixtures, false); err != nil {
+				t.Fatalf("Failed to register the machines due to %v", err)
+			}
+			a := agent.New(client, &agent.Collector{Root: serverTree.create(t)}, time.Minute)
+			before := time.Now().Unix()
+			_, err := a.Report(ctx)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			actual, err := server.usecase.GetMachineByQuery(ctx, &usecase.MachineQuery{"mac": registered.MAC})
+			if err != nil {
+				t.Fatalf("Failed to get the machine due to %v", err)
+			}
+			if tc.expect == nil {
+				if len(actual) != 0 {
+					t.Errorf("Expect: no machines, Actual: %v", actual)
+				}
+				return
+			}
+			if len(actual) != 1 || actual[0].LastSeen < before {
+				t.Fatalf("The heartbeat must be recorded. Actual: %v", actual)
+			}
+			actual[0].LastSeen = 0
+			if !reflect.DeepEqual(actual[0], tc.expect) {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual[0])
+			}
+			for _, c := range server.credentials {
+				if c != "Bearer machine-token" {
+					t.Errorf("Expect: the credential of the machine, Actual: %s", c)
+				}
+			}
+			if len(server.credentials) == 0 {
+				t.Error("The credential must be sent")
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package agent
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+const (
+	// sectorSize is the unit of /sys/block/*/size, which is always 512 regardless of the device.
+	sectorSize = 512
+	// zeroMAC is the address of the interfaces which have no hardware address.
+	zeroMAC = "00:00:00:00:00:00"
+)
+
+// DMI is the identity of the hardware given by the firmware.
+// The fields are empty if the firmware does not provide them, or they are not readable.
+type DMI struct {
+	Vendor      string `json:"vendor,omitempty"`
+	Product     string `json:"product,omitempty"`
+	Serial      string `json:"serial,omitempty"`
+	UUID        string `json:"uuid,omitempty"`
+	BIOSVersion string `json:"bios_version,omitempty"`
+}
+
+// Inventory is the hardware inventory of the machine.
+type Inventory struct {
+	// MAC is the MAC address which identifies the machine in the database.
+	MAC string `json:"mac"`
+	// Spec is the spec collected from the machine. The field is 0 if it could not be detected.
+	Spec models.MachineSpec `json:"spec"`
+	DMI  DMI                `json:"dmi"`
+}
+
+// Collector collects the hardware inventory from procfs, sysfs and DMI.
+type Collector struct {
+	// Root is the directory where 'proc' and 'sys' are placed. It is "/" on the real machine,
+	// and a fake tree in tests.
+	Root string
+	// Interface is the network interface whose MAC identifies the machine.
+	// The first physical interface in the order of the names is used if empty.
+	Interface string
+}
+
+func (c *Collector) path(elem ...string) string {
+	return filepath.Join(append([]string{c.Root}, elem...)...)
+}
+
+func (c *Collector) readString(elem ...string) (string, error) {
+	content, err := ioutil.ReadFile(c.path(elem...))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Collect returns the inventory of the machine.
+func (c *Collector) Collect() (*Inventory, error) {
+	mac, err := c.mac()
+	if err != nil {
+		return nil, err
+	}
+	cores, err := c.cores()
+	if err != nil {
+		return nil, err
+	}
+	memory, err := c.memory()
+	if err != nil {
+		return nil, err
+	}
+	disk, err := c.disk()
+	if err != nil {
+		return nil, err
+	}
+	return &Inventory{
+		MAC: mac,
+		Spec: models.MachineSpec{
+			Core:   cores,
+			Memory: memory,
+			Disk:   disk,
+		},
+		DMI: c.dmi(),
+	}, nil
+}
+
+// cores returns the number of the logical processors in /proc/cpuinfo.
+func (c *Collector) cores() (int, error) {
+	f, err := os.Open(c.path("proc", "cpuinfo"))
+	if err != nil {
+		return 0, xerrors.Errorf("Failed to read cpuinfo %w", err)
+	}
+	defer f.Close()
+	cores := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := strings.SplitN(scanner.Text(), ":", 2)[0]; strings.TrimSpace(key) == "processor" {
+			cores++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, xerrors.Errorf("Failed to read cpuinfo %w", err)
+	}
+	return cores, nil
+}
+
+// memory returns MemTotal in /proc/meminfo in MiB.
+func (c *Collector) memory() (int, error) {
+	f, err := os.Open(c.path("proc", "meminfo"))
+	if err != nil {
+		return 0, xerrors.Errorf("Failed to read meminfo %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// e.g. "MemTotal:       16318480 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != "MemTotal:" || fields[2] != "kB" {
+			continue
+		}
+		kib, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, xerrors.Errorf("Invalid MemTotal '%s' %w", fields[1], err)
+		}
+		return int(kib / 1024), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, xerrors.Errorf("Failed to read meminfo %w", err)
+	}
+	return 0, xerrors.Errorf("MemTotal is not found in meminfo %w", tcErr.ErrNotFound)
+}
+
+// disk returns the total size of the physical disks in GiB.
+// The virtual devices such as loop and device-mapper, and the removable ones are not counted.
+func (c *Collector) disk() (int, error) {
+	devices, err := ioutil.ReadDir(c.path("sys", "block"))
+	if err != nil {
+		return 0, xerrors.Errorf("Failed to list the block devices %w", err)
+	}
+	var total int64
+	for _, d := range devices {
+		name := d.Name()
+		// Only the physical devices have the link to the device.
+		if _, err := os.Stat(c.path("sys", "block", name, "device")); err != nil {
+			continue
+		}
+		if removable, _ := c.readString("sys", "block", name, "removable"); removable == "1" {
+			continue
+		}
+		size, err := c.readString("sys", "block", name, "size")
+		if err != nil {
+			return 0, xerrors.Errorf("Failed to read the size of %s %w", name, err)
+		}
+		sectors, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, xerrors.Errorf("Invalid size of %s '%s' %w", name, size, err)
+		}
+		total += sectors * sectorSize
+	}
+	return int(total >> 30), nil
+}
+
+// mac returns the MAC address of the interface which identifies the machine.
+func (c *Collector) mac() (string, error) {
+	if len(c.Interface) != 0 {
+		address, err := c.readString("sys", "class", "net", c.Interface, "address")
+		if err != nil {
+			return "", xerrors.Errorf("Failed to read the address of %s %w", c.Interface, err)
+		}
+		return address, nil
+	}
+	interfaces, err := ioutil.ReadDir(c.path("sys", "class", "net"))
+	if err != nil {
+		return "", xerrors.Errorf("Failed to list the network interfaces %w", err)
+	}
+	names := make([]string, 0, len(interfaces))
+	for _, i := range interfaces {
+		names = append(names, i.Name())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Only the physical interfaces have the link to the device.
+		if _, err := os.Stat(c.path("sys", "class", "net", name, "device")); err != nil {
+			continue
+		}
+		address, err := c.readString("sys", "class", "net", name, "address")
+		if err != nil || len(address) == 0 || address == zeroMAC {
+			continue
+		}
+		return address, nil
+	}
+	return "", xerrors.Errorf("No physical network interface is found %w", tcErr.ErrNotFound)
+}
+
+// dmi returns the identity of the hardware in /sys/class/dmi/id.
+// Some of them are readable only by root, and virtual machines may not have them at all.
+func (c *Collector) dmi() DMI {
+	read := func(name string) string {
+		value, _ := c.readString("sys", "class", "dmi", "id", name)
+		return value
+	}
+	return DMI{
+		Vendor:      read("sys_vendor"),
+		Product:     read("product_name"),
+		Serial:      read("product_serial"),
+		UUID:        read("product_uuid"),
+		BIOSVersion: read("bios_version"),
+	}
+}
@@ -0,0 +1,159 @@
+package agent_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/agent"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// fakeTree is the files of procfs and sysfs by the path from the root.
+// An empty content makes a directory.
+type fakeTree map[string]string
+
+func (f fakeTree) create(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range f {
+		p := filepath.Join(root, name)
+		if len(content) == 0 {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatalf("Failed to create %s due to %v", name, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create %s due to %v", name, err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s due to %v", name, err)
+		}
+	}
+	return root
+}
+
+func (f fakeTree) with(files fakeTree) fakeTree {
+	merged := fakeTree{}
+	for k, v := range f {
+		merged[k] = v
+	}
+	for k, v := range files {
+		merged[k] = v
+	}
+	return merged
+}
+
+// serverTree is the machine with 4 cores, 2GiB memory, 128GiB disk and 2 interfaces.
+var serverTree = fakeTree{
+	"proc/cpuinfo": "processor\t: 0\nmodel name\t: Fake CPU\n\nprocessor\t: 1\n\nprocessor\t: 2\n\nprocessor\t: 3\n",
+	"proc/meminfo": "MemTotal:        2097152 kB\nMemFree:         1048576 kB\n",
+	// 64GiB + 64GiB disks
+	"sys/block/sda/device":        "",
+	"sys/block/sda/removable":     "0\n",
+	"sys/block/sda/size":          "134217728\n",
+	"sys/block/nvme0n1/device":    "",
+	"sys/block/nvme0n1/removable": "0\n",
+	"sys/block/nvme0n1/size":      "134217728\n",
+	// The virtual and the removable devices are not counted.
+	"sys/block/loop0/size":    "2097152\n",
+	"sys/block/sdb/device":    "",
+	"sys/block/sdb/removable": "1\n",
+	"sys/block/sdb/size":      "2097152\n",
+	// The loopback and the virtual interfaces are not used.
+	"sys/class/net/lo/address":      "00:00:00:00:00:00\n",
+	"sys/class/net/docker0/address": "02:42:00:00:00:01\n",
+	"sys/class/net/eth1/device":     "",
+	"sys/class/net/eth1/address":    "52:54:00:00:00:02\n",
+	"sys/class/net/eth0/device":     "",
+	"sys/class/net/eth0/address":    "52:54:00:00:00:01\n",
+	"sys/class/dmi/id/sys_vendor":   "Fake Vendor\n",
+	"sys/class/dmi/id/product_name": "Fake Server\n",
+	"sys/class/dmi/id/product_uuid": "00000000-0000-0000-0000-000000000001\n",
+	"sys/class/dmi/id/bios_version": "1.0.0\n",
+}
+
+var serverSpec = models.MachineSpec{Core: 4, Memory: 2048, Disk: 128}
+
+func TestCollector_Collect(t *testing.T) {
+	testCases := map[string]struct {
+		tree      fakeTree
+		iface     string
+		expect    *agent.Inventory
+		expectErr error
+	}{
+		"physical server": {
+			tree: serverTree,
+			expect: &agent.Inventory{
+				MAC:  "52:54:00:00:00:01",
+				Spec: serverSpec,
+				DMI: agent.DMI{
+					Vendor:      "Fake Vendor",
+					Product:     "Fake Server",
+					UUID:        "00000000-0000-0000-0000-000000000001",
+					BIOSVersion: "1.0.0",
+				},
+			},
+		},
+		"specified interface": {
+			tree:  serverTree,
+			iface: "eth1",
+			expect: &agent.Inventory{
+				MAC:  "52:54:00:00:00:02",
+				Spec: serverSpec,
+				DMI: agent.DMI{
+					Vendor:      "Fake Vendor",
+					Product:     "Fake Server",
+					UUID:        "00000000-0000-0000-0000-000000000001",
+					BIOSVersion: "1.0.0",
+				},
+			},
+		},
+		"no DMI": {
+			tree: fakeTree{
+				"proc/cpuinfo":               "processor\t: 0\n",
+				"proc/meminfo":               "MemTotal:        1048576 kB\n",
+				"sys/block":                  "",
+				"sys/class/net/ens3/device":  "",
+				"sys/class/net/ens3/address": "52:54:00:00:00:03\n",
+			},
+			expect: &agent.Inventory{
+				MAC:  "52:54:00:00:00:03",
+				Spec: models.MachineSpec{Core: 1, Memory: 1024, Disk: 0},
+			},
+		},
+		"no physical interface": {
+			tree: fakeTree{
+				"proc/cpuinfo":             "processor\t: 0\n",
+				"proc/meminfo":             "MemTotal:        1048576 kB\n",
+				"sys/block":                "",
+				"sys/class/net/lo/address": "00:00:00:00:00:00\n",
+			},
+			expectErr: tcErr.ErrNotFound,
+		},
+		"no MemTotal": {
+			tree:      serverTree.with(fakeTree{"proc/meminfo": "MemFree:         1048576 kB\n"}),
+			expectErr: tcErr.ErrNotFound,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			collector := &agent.Collector{Root: tc.tree.create(t), Interface: tc.iface}
+			actual, err := collector.Collect()
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// tokenCredentials sends the per-machine credential as a bearer token with each request.
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (c *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		api.AuthorizationMetadataKey: api.BearerScheme + " " + c.token,
+	}, nil
+}
+
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+// LoadCredential reads the credential issued to the machine at the provisioning.
+// If secure is true, the credential is never sent over the connection without TLS.
+func LoadCredential(path string, secure bool) (credentials.PerRPCCredentials, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to read the credential %w", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if len(token) == 0 {
+		return nil, xerrors.Errorf("The credential '%s' is empty %w", path, tcErr.ErrInvalidArgument)
+	}
+	return &tokenCredentials{token: token, secure: secure}, nil
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+// BearerScheme is the authentication scheme of the tokens in the Authorization header and metadata.
+const BearerScheme = "Bearer"
+
+// bearerToken returns the token in the value of the Authorization header.
+func bearerToken(value string) (string, bool) {
+	elems := strings.SplitN(strings.TrimSpace(value), " ", 2)
+	if len(elems) != 2 || !strings.EqualFold(elems[0], BearerScheme) {
+		return "", false
+	}
+	token := strings.TrimSpace(elems[1])
+	return token, len(token) != 0
+}
+
+// authenticate returns the context of the client who has the bearer token in the Authorization header.
+// The name of the token is recorded as the actor instead of the one the client claims.
+func authenticate(ctx context.Context, tokens usecase.TokenUsecase, authorization string) (context.Context, error) {
+	bearer, ok := bearerToken(authorization)
+	if !ok {
+		return nil, xerrors.Errorf("bearer token is required %w", tcErr.ErrAuthFailed)
+	}
+	token, err := tokens.Authenticate(ctx, bearer)
+	if err != nil {
+		return nil, err
+	}
+	ctx = auth.NewContext(ctx, auth.PrincipalFromToken(token))
+	return actor.NewContext(ctx, tokenActor(token)), nil
+}
+
+// tokenActor returns the actor of the operations by the token.
+func tokenActor(token *models.Token) string {
+	return "token:" + token.Name
+}
+
+// isAuthError returns true if err tells that the client could not be authenticated.
+func isAuthError(err error) bool {
+	return xerrors.Is(err, tcErr.ErrAuthFailed)
+}
+
+// AuthInterceptor rejects the requests without a valid bearer token in the metadata.
+// It must be placed after ActorInterceptor, since it overwrites the actor by the name of the token.
+func AuthInterceptor(tokens usecase.TokenUsecase) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var authorization string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(AuthorizationMetadataKey); len(values) != 0 {
+				authorization = values[0]
+			}
+		}
+		authCtx, err := authenticate(ctx, tokens, authorization)
+		if isAuthError(err) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err != nil {
+			return nil, StatusError(err)
+		}
+		return handler(authCtx, req)
+	}
+}
+
+// AuthMiddleware rejects the HTTP requests without a valid bearer token in the Authorization header.
+func AuthMiddleware(tokens usecase.TokenUsecase) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			authCtx, err := authenticate(req.Context(), tokens, req.Header.Get(echo.HeaderAuthorization))
+			if isAuthError(err) {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, BearerScheme)
+				return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+			}
+			if err != nil {
+				return httpError(err)
+			}
+			c.SetRequest(req.WithContext(authCtx))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase/mock"
+)
+
+// authTestCases is the values of Authorization and the results of the authentication.
+var authTestCases = map[string]struct {
+	authorization string
+	expectActor   string
+	expectCode    codes.Code
+	expectStatus  int
+}{
+	"valid token": {
+		authorization: "Bearer valid.secret",
+		expectActor:   "token:admin",
+		expectCode:    codes.OK,
+		expectStatus:  http.StatusOK,
+	},
+	"case insensitive scheme": {
+		authorization: "bearer valid.secret",
+		expectActor:   "token:admin",
+		expectCode:    codes.OK,
+		expectStatus:  http.StatusOK,
+	},
+	"invalid token": {
+		authorization: "Bearer invalid.secret",
+		expectCode:    codes.Unauthenticated,
+		expectStatus:  http.StatusUnauthorized,
+	},
+	"other scheme": {
+		authorization: "Basic YWRtaW46YWRtaW4=",
+		expectCode:    codes.Unauthenticated,
+		expectStatus:  http.StatusUnauthorized,
+	},
+	"no token": {
+		authorization: "",
+		expectCode:    codes.Unauthenticated,
+		expectStatus:  http.StatusUnauthorized,
+	},
+	"datastore is down": {
+		authorization: "Bearer unavailable.secret",
+		expectCode:    codes.Unavailable,
+		expectStatus:  http.StatusServiceUnavailable,
+	},
+	"datastore timed out": {
+		authorization: "Bearer timedout.secret",
+		expectCode:    codes.DeadlineExceeded,
+		expectStatus:  http.StatusGatewayTimeout,
+	},
+}
+
+func newTokenUsecaseMock(ctrl *gomock.Controller) *mock.MockTokenUsecase {
+	tokenMock := mock.NewMockTokenUsecase(ctrl)
+	tokenMock.EXPECT().Authenticate(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, bearer string) (*models.Token, error) {
+		switch bearer {
+		case "valid.secret":
+			return &models.Token{ID: "valid", Name: "admin"}, nil
+		case "unavailable.secret":
+			return nil, tcErr.ErrUnavailable
+		case "timedout.secret":
+			return nil, xerrors.Errorf("failed to get the token %w", tcErr.ErrTimedOut)
+		}
+		return nil, xerrors.Errorf("unknown token %w", tcErr.ErrAuthFailed)
+	}).AnyTimes()
+	return tokenMock
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	interceptor := api.AuthInterceptor(newTokenUsecaseMock(ctrl))
+	for name, tc := range authTestCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			ctx := actor.NewContext(context.Background(), "claimed")
+			if len(tc.authorization) != 0 {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(api.AuthorizationMetadataKey, tc.authorization))
+			}
+			var (
+				actual        string
+				authenticated bool
+			)
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				actual = actor.FromContext(ctx)
+				_, authenticated = auth.FromContext(ctx)
+				return nil, nil
+			}
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			if code := status.Code(err); code != tc.expectCode {
+				t.Fatalf("Expect: %v, Actual: %v", tc.expectCode, err)
+			}
+			if actual != tc.expectActor {
+				t.Errorf("Expect: %s, Actual: %s", tc.expectActor, actual)
+			}
+			if authenticated != (tc.expectCode == codes.OK) {
+				t.Errorf("The principal must be passed to the handler only if authenticated")
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	e := echo.New()
+	var actual string
+	e.GET("/", func(c echo.Context) error {
+		actual = actor.FromContext(c.Request().Context())
+		return c.NoContent(http.StatusOK)
+	}, api.AuthMiddleware(newTokenUsecaseMock(ctrl)))
+	for name, tc := range authTestCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if len(tc.authorization) != 0 {
+				req.Header.Set(echo.HeaderAuthorization, tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tc.expectStatus {
+				t.Fatalf("Expect: %d, Actual: %d %s", tc.expectStatus, rec.Code, rec.Body.String())
+			}
+			if actual != tc.expectActor {
+				t.Errorf("Expect: %s, Actual: %s", tc.expectActor, actual)
+			}
+			if challenge := rec.Header().Get(echo.HeaderWWWAuthenticate); (challenge == api.BearerScheme) != (rec.Code == http.StatusUnauthorized) {
+				t.Errorf("WWW-Authenticate must be set only if unauthorized. Actual: %s", challenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pddg/tiny-cluster/pkg/api/pb"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func machineFromPb(m *pb.Machine) *models.Machine {
+	if m == nil {
+		return &models.Machine{}
+	}
+	machine := &models.Machine{
+		MAC:          m.GetMac(),
+		Name:         m.GetName(),
+		IPv4Addr:     m.GetIpv4Addr(),
+		DeployedDate: m.GetDeployedDate(),
+		Labels:       m.GetLabels(),
+	}
+	if spec := m.GetSpec(); spec != nil {
+		machine.Spec = models.MachineSpec{
+			Core:   int(spec.GetCore()),
+			Memory: int(spec.GetMemory()),
+			Disk:   int(spec.GetDisk()),
+		}
+	}
+	return machine
+}
+
+func machineToPb(m *models.Machine) *pb.Machine {
+	return &pb.Machine{
+		Mac:          m.MAC,
+		Name:         m.Name,
+		Ipv4Addr:     m.IPv4Addr,
+		DeployedDate: m.DeployedDate,
+		Spec: &pb.MachineSpec{
+			Core:   int32(m.Spec.Core),
+			Memory: int32(m.Spec.Memory),
+			Disk:   int32(m.Spec.Disk),
+		},
+		LastSeen: m.LastSeen,
+		Liveness: m.Liveness,
+		Labels:   m.Labels,
+	}
+}
+
+func machinesToPb(machines []*models.Machine) []*pb.Machine {
+	pbMachines := make([]*pb.Machine, 0, len(machines))
+	for _, m := range machines {
+		pbMachines = append(pbMachines, machineToPb(m))
+	}
+	return pbMachines
+}
+
+func machineHistoriesToPb(histories []*models.MachineHistory) ([]*pb.MachineHistory, error) {
+	pbHistories := make([]*pb.MachineHistory, 0, len(histories))
+	for _, h := range histories {
+		pbHistory := &pb.MachineHistory{
+			Revision:  h.Revision,
+			Mac:       h.MAC,
+			Actor:     h.Actor,
+			Timestamp: h.Timestamp,
+			Operation: h.Operation,
+		}
+		if h.Machine != nil {
+			pbHistory.Machine = machineToPb(h.Machine)
+		}
+		for _, c := range h.Changes {
+			oldValue, err := json.Marshal(c.Old)
+			if err != nil {
+				return nil, err
+			}
+			newValue, err := json.Marshal(c.New)
+			if err != nil {
+				return nil, err
+			}
+			pbHistory.Changes = append(pbHistory.Changes, &pb.FieldChange{
+				Field:    c.Field,
+				OldValue: string(oldValue),
+				NewValue: string(newValue),
+			})
+		}
+		pbHistories = append(pbHistories, pbHistory)
+	}
+	return pbHistories, nil
+}
+
+// pbFieldNames maps the field names of pb.Machine which differ from models.Machine.
+var pbFieldNames = map[string]string{
+	"ipv4addr": "ipv4_addr",
+}
+
+// fieldPathsFromPb converts the paths of FieldMask for pb.Machine into ones for models.Machine.
+func fieldPathsFromPb(paths []string) []string {
+	converted := make([]string, 0, len(paths))
+	for _, p := range paths {
+		elems := strings.Split(p, ".")
+		for i, e := range elems {
+			if name, ok := pbFieldNames[e]; ok {
+				elems[i] = name
+			}
+		}
+		converted = append(converted, strings.Join(elems, "."))
+	}
+	return converted
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/xerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// ProblemContentType is the media type of the error responses of the HTTP API (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// internalErrorMessage is sent to the clients instead of the internal errors,
+// since their messages may reveal the details of the server such as the paths and the endpoints.
+const internalErrorMessage = "internal server error"
+
+// errorCodes is the gRPC code and the HTTP status of each error code of TinyCluster.
+var errorCodes = map[int]struct {
+	grpc codes.Code
+	http int
+}{
+	tcErr.CodeErrOther:            {codes.Internal, http.StatusInternalServerError},
+	tcErr.CodeErrNotFound:         {codes.NotFound, http.StatusNotFound},
+	tcErr.CodeErrTimedOut:         {codes.DeadlineExceeded, http.StatusGatewayTimeout},
+	tcErr.CodeErrAlreadyExists:    {codes.AlreadyExists, http.StatusConflict},
+	tcErr.CodeErrAuthFailed:       {codes.Unauthenticated, http.StatusUnauthorized},
+	tcErr.CodeErrPermissionDenied: {codes.PermissionDenied, http.StatusForbidden},
+	tcErr.CodeErrContextCanceled:  {codes.Canceled, http.StatusRequestTimeout},
+	tcErr.CodeErrInvalidArgument:  {codes.InvalidArgument, http.StatusBadRequest},
+	tcErr.CodeErrConflict:         {codes.Aborted, http.StatusConflict},
+	tcErr.CodeErrUnavailable:      {codes.Unavailable, http.StatusServiceUnavailable},
+}
+
+// StatusError converts err into the gRPC status error which has the code corresponding to err.
+// The invalid fields of ValidationError are attached as the BadRequest details.
+// The internal errors are logged and their messages are replaced with the generic one.
+// The errors which already have the status are returned as they are.
+func StatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, message := errorCodes[tcErr.CodeOf(err)].grpc, err.Error()
+	if code == codes.Internal || code == codes.Unknown {
+		log.Printf("Internal error: %v", err)
+		message = internalErrorMessage
+	}
+	st := status.New(code, message)
+	var invalid *tcErr.ValidationError
+	if xerrors.As(err, &invalid) {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range invalid.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		if detailed, detailErr := st.WithDetails(badRequest); detailErr == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+// ErrorInterceptor converts the errors returned by the handlers into the gRPC status errors,
+// so that the clients can tell what kind of error occurred.
+func ErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, StatusError(err)
+}
+
+// Problem is the error response of the HTTP API in the format of RFC 7807.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Op is the operation which failed.
+	Op string `json:"op,omitempty"`
+	// Kind is the kind of the resource which the error is about.
+	Kind string `json:"kind,omitempty"`
+	// ID identifies the resource which the error is about.
+	ID string `json:"id,omitempty"`
+	// InvalidParams is the fields which are invalid.
+	InvalidParams []tcErr.FieldViolation `json:"invalid-params,omitempty"`
+}
+
+// httpError converts the error into the one which has the appropriate status code.
+func httpError(err error) error {
+	return &echo.HTTPError{
+		Code:     errorCodes[tcErr.CodeOf(err)].http,
+		Message:  err.Error(),
+		Internal: err,
+	}
+}
+
+// newProblem returns the problem which describes err.
+// The detail of the internal error is replaced with the generic one, and err is logged by ProblemHandler instead.
+func newProblem(err error) *Problem {
+	code := errorCodes[tcErr.CodeOf(err)].http
+	detail := err.Error()
+	var httpErr *echo.HTTPError
+	if xerrors.As(err, &httpErr) {
+		code = httpErr.Code
+		detail = fmt.Sprint(httpErr.Message)
+	}
+	if code == http.StatusInternalServerError {
+		detail = internalErrorMessage
+	}
+	problem := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: detail,
+	}
+	var tcError *tcErr.TinyClusterError
+	if xerrors.As(err, &tcError) {
+		problem.Op = tcError.Op
+		problem.Kind = tcError.Kind
+		problem.ID = tcError.ID
+	}
+	var invalid *tcErr.ValidationError
+	if xerrors.As(err, &invalid) {
+		problem.InvalidParams = invalid.Violations
+	}
+	return problem
+}
+
+// ProblemHandler writes the errors returned by the HTTP handlers as problem+json.
+// This is intended to be set as echo.Echo.HTTPErrorHandler.
+func ProblemHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	problem := newProblem(err)
+	if problem.Status >= http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(problem.Status)
+	} else {
+		c.Response().Header().Set(echo.HeaderContentType, ProblemContentType)
+		err = c.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
@@ -0,0 +1,165 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/xerrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// errorTestCases is the errors and the codes they must be translated to.
+var errorTestCases = map[string]struct {
+	err          error
+	expectCode   codes.Code
+	expectStatus int
+}{
+	"not found": {
+		err:          tcErr.Wrap("PatchMachine", tcErr.KindMachine, "52:54:00:00:00:01", tcErr.ErrNotFound),
+		expectCode:   codes.NotFound,
+		expectStatus: http.StatusNotFound,
+	},
+	"unavailable": {
+		err:          xerrors.Errorf("Failed to connect to etcd %w", tcErr.ErrUnavailable),
+		expectCode:   codes.Unavailable,
+		expectStatus: http.StatusServiceUnavailable,
+	},
+	"permission denied": {
+		err:          xerrors.Errorf("out of the scope %w", tcErr.ErrPermissionDenied),
+		expectCode:   codes.PermissionDenied,
+		expectStatus: http.StatusForbidden,
+	},
+	"conflict": {
+		err:          tcErr.ErrConflict,
+		expectCode:   codes.Aborted,
+		expectStatus: http.StatusConflict,
+	},
+	"unknown": {
+		err:          xerrors.New("unknown"),
+		expectCode:   codes.Internal,
+		expectStatus: http.StatusInternalServerError,
+	},
+	"echo": {
+		err:          echo.ErrNotFound,
+		expectCode:   codes.Internal,
+		expectStatus: http.StatusNotFound,
+	},
+}
+
+func TestStatusError(t *testing.T) {
+	for name, tc := range errorTestCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := api.StatusError(tc.err)
+			if code := status.Code(err); code != tc.expectCode {
+				t.Errorf("Expect: %v, Actual: %v", tc.expectCode, code)
+			}
+		})
+	}
+	if api.StatusError(nil) != nil {
+		t.Error("nil must be returned as it is")
+	}
+	withStatus := status.Error(codes.InvalidArgument, "invalid namespace")
+	if api.StatusError(withStatus) != withStatus {
+		t.Error("The status must be returned as it is")
+	}
+}
+
+func TestProblemHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = api.ProblemHandler
+	for name, tc := range errorTestCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			e.HTTPErrorHandler(tc.err, e.NewContext(req, rec))
+			if rec.Code != tc.expectStatus {
+				t.Errorf("Expect: %d, Actual: %d", tc.expectStatus, rec.Code)
+			}
+			if contentType := rec.Header().Get(echo.HeaderContentType); contentType != api.ProblemContentType {
+				t.Errorf("Expect: %s, Actual: %s", api.ProblemContentType, contentType)
+			}
+			var problem api.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to decode the problem due to %v", err)
+			}
+			if problem.Status != tc.expectStatus || problem.Title != http.StatusText(tc.expectStatus) || len(problem.Detail) == 0 {
+				t.Errorf("Invalid problem. Actual: %#v", problem)
+			}
+			var tcError *tcErr.TinyClusterError
+			if xerrors.As(tc.err, &tcError) && problem.Kind != tcError.Kind {
+				t.Errorf("Expect: %s, Actual: %s", tcError.Kind, problem.Kind)
+			}
+		})
+	}
+}
+
+func TestInternalError(t *testing.T) {
+	err := tcErr.Wrap("GetMachines", tcErr.KindMachine, "", xerrors.New("open /var/lib/tiny-cluster/bolt.db: permission denied"))
+
+	if message := status.Convert(api.StatusError(err)).Message(); strings.Contains(message, "/var/lib") {
+		t.Errorf("The internal error must not be sent. Actual: %s", message)
+	}
+
+	e := echo.New()
+	e.Logger.SetOutput(ioutil.Discard)
+	for name, err := range map[string]error{"error": err, "echo": &echo.HTTPError{Code: http.StatusInternalServerError, Message: err.Error(), Internal: err}} {
+		rec := httptest.NewRecorder()
+		api.ProblemHandler(err, e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec))
+		var problem api.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Failed to decode the problem due to %v", err)
+		}
+		if rec.Code != http.StatusInternalServerError || strings.Contains(problem.Detail, "/var/lib") {
+			t.Errorf("The internal error must not be written by %s. Actual: %d %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	invalid := &tcErr.ValidationError{}
+	invalid.Add("mac", "'mac1' is not a 48-bit MAC address")
+	invalid.Add("spec.core", "must be positive but 0")
+	err := xerrors.Errorf("failed to register %w", invalid)
+
+	st := status.Convert(api.StatusError(err))
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("Expect: %v, Actual: %v", codes.InvalidArgument, st.Code())
+	}
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range badRequest.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	if !reflect.DeepEqual(fields, []string{"mac", "spec.core"}) {
+		t.Errorf("The invalid fields must be attached. Actual: %v", fields)
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	api.ProblemHandler(err, e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec))
+	var problem api.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode the problem due to %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || !reflect.DeepEqual(problem.InvalidParams, invalid.Violations) {
+		t.Errorf("The invalid fields must be written. Actual: %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+const (
+	// ActorMetadataKey is the key of gRPC metadata to tell the server who sends the request.
+	ActorMetadataKey = "tc-actor"
+	// NamespaceMetadataKey is the key of gRPC metadata to specify the namespace of the request.
+	NamespaceMetadataKey = "tc-namespace"
+	// AuthorizationMetadataKey is the key of gRPC metadata which has the bearer token.
+	AuthorizationMetadataKey = "authorization"
+)
+
+// ActorInterceptor stores the actor of the request into the context.
+// The actor is taken from the metadata if given, otherwise the address of the peer is used.
+func ActorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	name := actor.Unknown
+	if p, ok := peer.FromContext(ctx); ok {
+		name = p.Addr.String()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(ActorMetadataKey); len(values) != 0 && len(values[0]) != 0 {
+			name = values[0]
+		}
+	}
+	return handler(actor.NewContext(ctx, name), req)
+}
+
+// NamespaceInterceptor stores the namespace of the request into the context.
+// The namespace is taken from the metadata if given, otherwise the default namespace is used.
+func NamespaceInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	name := namespace.Default
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(NamespaceMetadataKey); len(values) != 0 && len(values[0]) != 0 {
+			name = values[0]
+		}
+	}
+	if err := namespace.Validate(name); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return handler(namespace.NewContext(ctx, name), req)
+}
+
+// ChainUnaryInterceptors returns the interceptor which runs the interceptors in the order.
+// grpc.ChainUnaryInterceptor is not available in the version of gRPC this module depends on.
+func ChainUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		chained := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], chained
+			chained = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return interceptor(ctx, req, info, next)
+			}
+		}
+		return chained(ctx, req)
+	}
+}
@@ -0,0 +1,81 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+func TestNamespaceInterceptor(t *testing.T) {
+	testCases := map[string]struct {
+		md        metadata.MD
+		expect    string
+		expectErr codes.Code
+	}{
+		"default namespace without metadata": {
+			md:        nil,
+			expect:    namespace.Default,
+			expectErr: codes.OK,
+		},
+		"namespace from metadata": {
+			md:        metadata.Pairs(api.NamespaceMetadataKey, "lab"),
+			expect:    "lab",
+			expectErr: codes.OK,
+		},
+		"invalid namespace": {
+			md:        metadata.Pairs(api.NamespaceMetadataKey, "Invalid_Name"),
+			expectErr: codes.InvalidArgument,
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tc.md)
+			}
+			var actual string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				actual = namespace.FromContext(ctx)
+				return nil, nil
+			}
+			_, err := api.NamespaceInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			if code := status.Code(err); code != tc.expectErr {
+				t.Fatalf("Expect: %v, Actual: %v", tc.expectErr, err)
+			}
+			if actual != tc.expect {
+				t.Errorf("Expect: %s, Actual: %s", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestChainUnaryInterceptors(t *testing.T) {
+	md := metadata.Pairs(api.ActorMetadataKey, "alice", api.NamespaceMetadataKey, "lab")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	var actualActor, actualNamespace string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		actualActor = actor.FromContext(ctx)
+		actualNamespace = namespace.FromContext(ctx)
+		return req, nil
+	}
+	interceptor := api.ChainUnaryInterceptors(api.ActorInterceptor, api.NamespaceInterceptor)
+	resp, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{}, handler)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp != "request" {
+		t.Errorf("Expect: request, Actual: %v", resp)
+	}
+	if actualActor != "alice" || actualNamespace != "lab" {
+		t.Errorf("Expect: alice in lab, Actual: %s in %s", actualActor, actualNamespace)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/api/pb"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+type machineDatabaseServerImpl struct {
+	usecase usecase.MachineUsecase
+}
+
+func (s *machineDatabaseServerImpl) GetMachines(ctx context.Context, req *pb.GetMachinesRequest) (*pb.GetMachinesResponse, error) {
+	query := usecase.MachineQuery{}
+	for _, q := range req.GetQueries() {
+		query[q.GetKey()] = q.GetValue()
+	}
+	machines, err := s.usecase.GetMachineByQuery(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetMachinesResponse{
+		Machines: machinesToPb(machines),
+	}, nil
+}
+
+func (s *machineDatabaseServerImpl) RegisterOrUpdateMachine(ctx context.Context, req *pb.RegisterOrUpdateMachineRequest) (*pb.RegisterOrUpdateMachineResponse, error) {
+	err := s.usecase.RegisterOrUpdateMachine(ctx, machineFromPb(req.GetMachine()))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.RegisterOrUpdateMachineResponse{Success: true}, nil
+}
+
+func (s *machineDatabaseServerImpl) DeleteMachine(ctx context.Context, req *pb.DeleteMachineRequest) (*pb.DeleteMachineResponse, error) {
+	err := s.usecase.DeleteMachine(ctx, machineFromPb(req.GetMachine()))
+	if err != nil {
+		// Deleting the machine which does not exist is not an error if forced.
+		if req.GetForce() && xerrors.Is(err, tcErr.ErrNotFound) {
+			return &pb.DeleteMachineResponse{Success: true}, nil
+		}
+		return nil, err
+	}
+	return &pb.DeleteMachineResponse{Success: true}, nil
+}
+
+func (s *machineDatabaseServerImpl) PatchMachine(ctx context.Context, req *pb.PatchMachineRequest) (*pb.PatchMachineResponse, error) {
+	paths := fieldPathsFromPb(req.GetUpdateMask().GetPaths())
+	machine, err := s.usecase.PatchMachine(ctx, req.GetMac(), machineFromPb(req.GetMachine()), paths)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PatchMachineResponse{
+		Success: true,
+		Machine: machineToPb(machine),
+	}, nil
+}
+
+func (s *machineDatabaseServerImpl) GetMachineHistory(ctx context.Context, req *pb.GetMachineHistoryRequest) (*pb.GetMachineHistoryResponse, error) {
+	histories, err := s.usecase.GetMachineHistory(ctx, req.GetMac())
+	if err != nil {
+		return nil, err
+	}
+	pbHistories, err := machineHistoriesToPb(histories)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetMachineHistoryResponse{
+		Histories: pbHistories,
+	}, nil
+}
+
+func (s *machineDatabaseServerImpl) RevertMachine(ctx context.Context, req *pb.RevertMachineRequest) (*pb.RevertMachineResponse, error) {
+	machine, err := s.usecase.RevertMachine(ctx, req.GetMac(), req.GetRevision())
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.RevertMachineResponse{Success: true}
+	if machine != nil {
+		resp.Machine = machineToPb(machine)
+	}
+	return resp, nil
+}
+
+func (s *machineDatabaseServerImpl) Heartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	if err := s.usecase.Heartbeat(ctx, req.GetMac()); err != nil {
+		return nil, err
+	}
+	return &pb.HeartbeatResponse{Success: true}, nil
+}
+
+// NewMachineDatabaseServer returns the implementation of MachineDatabase service.
+func NewMachineDatabaseServer(machineUsecase usecase.MachineUsecase) MachineDatabaseServer {
+	return &machineDatabaseServerImpl{
+		usecase: machineUsecase,
+	}
+}
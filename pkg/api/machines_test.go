@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"golang.org/x/xerrors"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/api/pb"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase/mock"
+)
+
+func Test_machineDatabaseServerImpl_PatchMachine(t *testing.T) {
+	sampleErr := xerrors.Errorf("Sample error")
+	patched := &models.Machine{
+		MAC:      "mac1",
+		Name:     "machine1",
+		IPv4Addr: "192.168.0.3",
+		Spec: models.MachineSpec{
+			Core:   4,
+			Memory: 2048,
+			Disk:   128,
+		},
+	}
+	testCases := map[string]struct {
+		paths       []string
+		expectPaths []string
+		errFixture  error
+		expect      *pb.PatchMachineResponse
+		expectErr   error
+	}{
+		"patch normally": {
+			paths:       []string{"ipv4addr", "spec.core"},
+			expectPaths: []string{"ipv4_addr", "spec.core"},
+			errFixture:  nil,
+			expect: &pb.PatchMachineResponse{
+				Success: true,
+				Machine: &pb.Machine{
+					Mac:      "mac1",
+					Name:     "machine1",
+					Ipv4Addr: "192.168.0.3",
+					Spec: &pb.MachineSpec{
+						Core:   4,
+						Memory: 2048,
+						Disk:   128,
+					},
+				},
+			},
+			expectErr: nil,
+		},
+		"not found": {
+			paths:       []string{"name"},
+			expectPaths: []string{"name"},
+			errFixture:  tcErr.ErrNotFound,
+			expect:      nil,
+			expectErr:   tcErr.ErrNotFound,
+		},
+		"unexpected error": {
+			paths:       []string{"name"},
+			expectPaths: []string{"name"},
+			errFixture:  sampleErr,
+			expect:      nil,
+			expectErr:   sampleErr,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			var machine *models.Machine
+			if tc.errFixture == nil {
+				machine = patched
+			}
+			usecaseMock.EXPECT().
+				PatchMachine(ctx, "mac1", gomock.Any(), tc.expectPaths).
+				Return(machine, tc.errFixture)
+			server := api.NewMachineDatabaseServer(usecaseMock)
+			actual, err := server.PatchMachine(ctx, &pb.PatchMachineRequest{
+				Mac:        "mac1",
+				Machine:    &pb.Machine{Ipv4Addr: "192.168.0.3"},
+				UpdateMask: &field_mask.FieldMask{Paths: tc.paths},
+			})
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+				return
+			}
+			if !proto.Equal(actual, tc.expect) {
+				t.Errorf("Invalid response. Expected: %v, Actual: %v", tc.expect, actual)
+			}
+		})
+	}
+}
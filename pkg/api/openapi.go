@@ -0,0 +1,589 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/inventory"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/render"
+)
+
+const (
+	// openAPIVersion is the version of the OpenAPI specification which the document follows.
+	openAPIVersion = "3.0.3"
+	// apiVersion is the version of the HTTP API.
+	apiVersion = "v1"
+	// restPrefix is the path of the REST API in the default namespace.
+	restPrefix = "/api/v1"
+	// namespacedRESTPrefix is the path of the REST API in the other namespaces.
+	namespacedRESTPrefix = restPrefix + "/namespaces/:namespace"
+	// bearerScheme is the name of the security scheme of the tokens.
+	bearerScheme = "bearerAuth"
+)
+
+// schema is a Schema Object of OpenAPI.
+type schema map[string]interface{}
+
+var (
+	stringSchema  = schema{"type": "string"}
+	integerSchema = schema{"type": "integer"}
+	booleanSchema = schema{"type": "boolean"}
+	binarySchema  = schema{"type": "string", "format": "binary"}
+)
+
+// enumSchema returns the schema of the string which is one of values.
+func enumSchema(values ...string) schema {
+	return schema{"type": "string", "enum": values}
+}
+
+// parameter is a query parameter of the operation. The path parameters are taken from the path.
+type parameter struct {
+	name        string
+	description string
+	schema      schema
+	required    bool
+}
+
+// content is the body of a request or a response.
+type content struct {
+	// mediaTypes is the media types which the body can be in.
+	mediaTypes []string
+	// model is the value whose type is the schema of the body, or nil if schema is given.
+	model  interface{}
+	schema schema
+}
+
+// jsonContent returns the JSON body whose schema is the type of model.
+func jsonContent(model interface{}) *content {
+	return &content{mediaTypes: []string{echo.MIMEApplicationJSON}, model: model}
+}
+
+// operation is an operation of the HTTP API.
+type operation struct {
+	method string
+	// path is the path of the route in the form of echo, such as "/machines/:mac".
+	path        string
+	id          string
+	tag         string
+	summary     string
+	description string
+	query       []parameter
+	// request is the body of the request, or nil if it has none.
+	request *content
+	// status is the status code of the successful response.
+	status int
+	// response is the body of the successful response, or nil if it has none.
+	response *content
+	// noContent is true if the operation may also succeed with 204 No Content.
+	noContent bool
+	// secured is true if the operation requires the token when the authentication is enabled.
+	secured bool
+	// root is true if the operation of the REST API is also served at the root in the default namespace,
+	// which is the path the other tools such as Ansible are pointed at.
+	root bool
+}
+
+// machinePatch is the body of PATCH /machines/:mac, whose fields are all optional.
+type machinePatch struct {
+	Name         string            `json:"name,omitempty"`
+	IPv4Addr     string            `json:"ipv4_addr,omitempty"`
+	DeployedDate int64             `json:"deployed_date,omitempty"`
+	Spec         *machineSpecPatch `json:"spec,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+// machineSpecPatch is the spec in machinePatch, whose fields are updated one by one.
+type machineSpecPatch struct {
+	Core   int `json:"core,omitempty"`
+	Memory int `json:"memory,omitempty"`
+	Disk   int `json:"disk,omitempty"`
+}
+
+// inventoryMediaTypes is the media types of the inventory formats.
+var inventoryMediaTypes = []string{
+	inventory.FormatJSON.ContentType(),
+	inventory.FormatCSV.ContentType(),
+	inventory.FormatYAML.ContentType(),
+}
+
+// rootOperations is the operations served at the root.
+var rootOperations = []*operation{
+	{
+		method:   http.MethodGet,
+		path:     "/default.ipxe",
+		id:       "getIPXEScript",
+		tag:      "boot",
+		summary:  "Get the iPXE script to boot the machines",
+		status:   http.StatusOK,
+		response: &content{mediaTypes: []string{echo.MIMETextPlainCharsetUTF8}, schema: stringSchema},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/boot/*",
+		id:       "getBootFile",
+		tag:      "boot",
+		summary:  "Get the file to boot the machines, such as the kernel and the initrd",
+		status:   http.StatusOK,
+		response: &content{mediaTypes: []string{"application/octet-stream"}, schema: binarySchema},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/status",
+		id:       "getStatus",
+		tag:      "status",
+		summary:  "Get the leader election seen from the replica",
+		status:   http.StatusOK,
+		response: jsonContent(leader.Status{}),
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/ui",
+		id:      "redirectToUI",
+		tag:     "ui",
+		summary: "Redirect to the web dashboard",
+		status:  http.StatusMovedPermanently,
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/ui/",
+		id:       "getUI",
+		tag:      "ui",
+		summary:  "Get the web dashboard",
+		status:   http.StatusOK,
+		response: &content{mediaTypes: []string{echo.MIMETextHTMLCharsetUTF8}, schema: stringSchema},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/ui/:file",
+		id:       "getUIFile",
+		tag:      "ui",
+		summary:  "Get the file of the web dashboard",
+		status:   http.StatusOK,
+		response: &content{mediaTypes: []string{"application/javascript", "text/css"}, schema: stringSchema},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/openapi.json",
+		id:       "getOpenAPI",
+		tag:      "status",
+		summary:  "Get this document",
+		status:   http.StatusOK,
+		response: &content{mediaTypes: []string{echo.MIMEApplicationJSON}, schema: schema{"type": "object"}},
+	},
+}
+
+// restOperations is the operations of the REST API, which are served under both restPrefix and namespacedRESTPrefix,
+// and also at the root if root is true.
+var restOperations = []*operation{
+	{
+		method:  http.MethodGet,
+		path:    "/inventory",
+		id:      "exportInventory",
+		tag:     "inventory",
+		summary: "Export all machines",
+		query: []parameter{
+			{name: "format", description: "Format of the inventory", schema: enumSchema("json", "csv", "yaml")},
+		},
+		status:   http.StatusOK,
+		response: &content{mediaTypes: inventoryMediaTypes, model: []*models.Machine{}},
+	},
+	{
+		method:      http.MethodPost,
+		path:        "/inventory",
+		id:          "importInventory",
+		tag:         "inventory",
+		summary:     "Register or update the machines",
+		description: "Either all machines are written or nothing is written. The format is taken from the Content-Type unless 'format' is given.",
+		query: []parameter{
+			{name: "format", description: "Format of the inventory", schema: enumSchema("json", "csv", "yaml")},
+			{name: "dry_run", description: "Only return what would be changed", schema: booleanSchema},
+		},
+		request:  &content{mediaTypes: inventoryMediaTypes, model: []*models.Machine{}},
+		status:   http.StatusOK,
+		response: jsonContent(models.ImportResult{}),
+	},
+	{
+		method:      http.MethodGet,
+		path:        "/inventory/ansible",
+		id:          "getAnsibleInventory",
+		tag:         "integrations",
+		summary:     "Get the Ansible dynamic inventory",
+		description: "The variables of the host are returned instead if 'host' is given.",
+		query: []parameter{
+			{name: "host", description: "Name of the machine", schema: stringSchema},
+		},
+		status:   http.StatusOK,
+		response: jsonContent(inventory.AnsibleInventory{}),
+		root:     true,
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/machines",
+		id:      "getMachines",
+		tag:     "machines",
+		summary: "Find the machines",
+		description: "All machines are returned if no condition is given, otherwise the machines matched with all of them unless 'and' is false. " +
+			"The labels are matched by 'label.<key>' parameters such as 'label.env=prod'.",
+		query: []parameter{
+			{name: "mac", description: "MAC address in any form", schema: stringSchema},
+			{name: "name", description: "Name", schema: stringSchema},
+			{name: "ipv4", description: "IPv4 address", schema: stringSchema},
+			{name: "liveness", description: "Liveness of the heartbeats", schema: enumSchema(models.LivenessReachable, models.LivenessUnreachable, models.LivenessUnknown)},
+			{name: "last_seen_before", description: "UNIX time which the last heartbeat is before", schema: integerSchema},
+			{name: "last_seen_after", description: "UNIX time which the last heartbeat is after", schema: integerSchema},
+			{name: "and", description: "Match all conditions if true, otherwise any of them", schema: booleanSchema},
+		},
+		status:   http.StatusOK,
+		response: jsonContent([]*models.Machine{}),
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/machines/:mac",
+		id:       "getMachine",
+		tag:      "machines",
+		summary:  "Get the machine",
+		status:   http.StatusOK,
+		response: jsonContent(models.Machine{}),
+	},
+	{
+		method:      http.MethodPut,
+		path:        "/machines/:mac",
+		id:          "putMachine",
+		tag:         "machines",
+		summary:     "Register or update the machine",
+		description: "The MAC in the body can be omitted, and must be the same as the one in the path if given.",
+		request:     jsonContent(models.Machine{}),
+		status:      http.StatusOK,
+		response:    jsonContent(models.Machine{}),
+	},
+	{
+		method:      http.MethodPatch,
+		path:        "/machines/:mac",
+		id:          "patchMachine",
+		tag:         "machines",
+		summary:     "Update the fields of the machine",
+		description: "Only the fields in the body are updated. The fields of 'spec' are updated one by one, and the others are replaced as a whole.",
+		request:     &content{mediaTypes: []string{echo.MIMEApplicationJSON, "application/merge-patch+json"}, model: machinePatch{}},
+		status:      http.StatusOK,
+		response:    jsonContent(models.Machine{}),
+	},
+	{
+		method:  http.MethodDelete,
+		path:    "/machines/:mac",
+		id:      "deleteMachine",
+		tag:     "machines",
+		summary: "Delete the machine",
+		status:  http.StatusNoContent,
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/machines/:mac/history",
+		id:       "getMachineHistory",
+		tag:      "machines",
+		summary:  "Get the changes of the machine",
+		status:   http.StatusOK,
+		response: jsonContent([]*models.MachineHistory{}),
+	},
+	{
+		method:      http.MethodPost,
+		path:        "/machines/:mac/revert",
+		id:          "revertMachine",
+		tag:         "machines",
+		summary:     "Restore the machine to the state just after the revision",
+		description: "No content is returned if the machine had been deleted at the revision.",
+		query: []parameter{
+			{name: "revision", description: "Revision of the history", schema: integerSchema, required: true},
+		},
+		status:    http.StatusOK,
+		response:  jsonContent(models.Machine{}),
+		noContent: true,
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/machines/:mac/reprovision",
+		id:       "reprovisionMachine",
+		tag:      "machines",
+		summary:  "Clear the deployed date of the machine to provision it again",
+		status:   http.StatusOK,
+		response: jsonContent(models.Machine{}),
+	},
+	{
+		method:  http.MethodPost,
+		path:    "/machines/:mac/heartbeat",
+		id:      "heartbeat",
+		tag:     "machines",
+		summary: "Record the check-in of the machine",
+		status:  http.StatusNoContent,
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/render/:format",
+		id:      "renderConfig",
+		tag:     "integrations",
+		summary: "Render the configuration file of the DNS or DHCP server",
+		query: []parameter{
+			{name: "domain", description: "Domain of the machines, which is required by bind", schema: stringSchema},
+			{name: "ttl", description: "TTL of the records such as 60s", schema: stringSchema},
+			{name: "name_server", description: "Name server of the zone", schema: stringSchema},
+		},
+		status:   http.StatusOK,
+		response: &content{mediaTypes: []string{echo.MIMETextPlainCharsetUTF8}, schema: stringSchema},
+	},
+	{
+		method:      http.MethodGet,
+		path:        "/sd/prometheus",
+		id:          "getPrometheusTargets",
+		tag:         "integrations",
+		summary:     "Get the targets of Prometheus HTTP service discovery",
+		description: "Each 'port' is a port such as 9100, or a label selector and a port such as 'role=db:9187'.",
+		query: []parameter{
+			{name: "port", description: "Ports of the targets", schema: schema{"type": "array", "items": stringSchema}},
+		},
+		status:   http.StatusOK,
+		response: jsonContent([]*inventory.PrometheusTargetGroup{}),
+		root:     true,
+	},
+}
+
+// pathParamSchemas is the schemas of the path parameters by their names. The others are strings.
+var pathParamSchemas = map[string]schema{
+	"format": enumSchema(string(render.FormatHosts), string(render.FormatBIND), string(render.FormatDnsmasq), string(render.FormatDhcpd)),
+}
+
+// openAPIBuilder builds the document and collects the schemas of the bodies.
+type openAPIBuilder struct {
+	schemas map[string]schema
+}
+
+// schemaOf returns the schema of the JSON encoding of t. The structs are referred from the components.
+func (b *openAPIBuilder) schemaOf(t reflect.Type) schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaOf(t.Elem())
+	case reflect.String:
+		return stringSchema
+	case reflect.Bool:
+		return booleanSchema
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return integerSchema
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		// The unexported types describing only the API are named as the exported ones.
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		ref := schema{"$ref": "#/components/schemas/" + name}
+		if _, ok := b.schemas[name]; ok {
+			return ref
+		}
+		s := schema{"type": "object"}
+		// Register it before the fields for the recursive types.
+		b.schemas[name] = s
+		properties := schema{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if len(field.PkgPath) != 0 || tag == "-" {
+				continue
+			}
+			options := strings.Split(tag, ",")
+			name := options[0]
+			if len(name) == 0 {
+				name = field.Name
+			}
+			properties[name] = b.schemaOf(field.Type)
+			if !strings.Contains(tag, ",omitempty") {
+				required = append(required, name)
+			}
+		}
+		s["properties"] = properties
+		if len(required) != 0 {
+			s["required"] = required
+		}
+		return ref
+	default:
+		// Any value such as interface{}.
+		return schema{}
+	}
+}
+
+// contentOf returns the content object of the body.
+func (b *openAPIBuilder) contentOf(c *content) schema {
+	s := c.schema
+	if c.model != nil {
+		s = b.schemaOf(reflect.TypeOf(c.model))
+	}
+	mediaTypes := schema{}
+	for _, mediaType := range c.mediaTypes {
+		mediaTypes[mediaType] = schema{"schema": s}
+	}
+	return mediaTypes
+}
+
+// openAPIPath converts the path of echo into the one of OpenAPI and returns it with the names of its parameters.
+// The wildcard is named 'path'.
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		name := ""
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name = strings.TrimPrefix(segment, ":")
+		case segment == "*":
+			name = "path"
+		default:
+			continue
+		}
+		segments[i] = "{" + name + "}"
+		params = append(params, name)
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationOf returns the operation object of op.
+func (b *openAPIBuilder) operationOf(op *operation, pathParams []string) schema {
+	var params []schema
+	for _, name := range pathParams {
+		s, ok := pathParamSchemas[name]
+		if !ok {
+			s = stringSchema
+		}
+		params = append(params, schema{"name": name, "in": "path", "required": true, "schema": s})
+	}
+	for _, p := range op.query {
+		params = append(params, schema{"name": p.name, "in": "query", "required": p.required, "description": p.description, "schema": p.schema})
+	}
+	success := schema{"description": http.StatusText(op.status)}
+	if op.response != nil {
+		success["content"] = b.contentOf(op.response)
+	}
+	responses := schema{
+		strconv.Itoa(op.status): success,
+		"default": schema{
+			"description": "Error",
+			"content":     schema{ProblemContentType: schema{"schema": b.schemaOf(reflect.TypeOf(Problem{}))}},
+		},
+	}
+	if op.noContent {
+		responses[strconv.Itoa(http.StatusNoContent)] = schema{"description": http.StatusText(http.StatusNoContent)}
+	}
+	o := schema{
+		"operationId": op.id,
+		"tags":        []string{op.tag},
+		"summary":     op.summary,
+		"responses":   responses,
+	}
+	if len(op.description) != 0 {
+		o["description"] = op.description
+	}
+	if len(params) != 0 {
+		o["parameters"] = params
+	}
+	if op.request != nil {
+		o["requestBody"] = schema{"required": true, "content": b.contentOf(op.request)}
+	}
+	if op.secured {
+		o["security"] = []schema{{bearerScheme: []string{}}}
+	}
+	return o
+}
+
+// operations returns the operations of the HTTP API with the prefixes of the paths, in the form of echo.
+// The REST API is served in both of the default namespace and the namespace given by the path.
+func operations() []*operation {
+	ops := append([]*operation{}, rootOperations...)
+	for _, prefix := range []string{restPrefix, namespacedRESTPrefix} {
+		for _, op := range restOperations {
+			copied := *op
+			copied.path = prefix + op.path
+			copied.secured = true
+			if prefix == namespacedRESTPrefix {
+				copied.id += "InNamespace"
+			}
+			ops = append(ops, &copied)
+		}
+	}
+	for _, op := range restOperations {
+		if op.root {
+			copied := *op
+			copied.secured = true
+			copied.id += "AtRoot"
+			ops = append(ops, &copied)
+		}
+	}
+	return ops
+}
+
+// openAPIDocument returns the OpenAPI document of the HTTP API.
+func openAPIDocument() schema {
+	b := &openAPIBuilder{schemas: map[string]schema{}}
+	paths := schema{}
+	for _, op := range operations() {
+		path, params := openAPIPath(op.path)
+		item, ok := paths[path].(schema)
+		if !ok {
+			item = schema{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = b.operationOf(op, params)
+	}
+	tags := map[string]struct{}{}
+	for _, op := range operations() {
+		tags[op.tag] = struct{}{}
+	}
+	tagObjects := make([]schema, 0, len(tags))
+	for tag := range tags {
+		tagObjects = append(tagObjects, schema{"name": tag})
+	}
+	sort.Slice(tagObjects, func(i, j int) bool {
+		return tagObjects[i]["name"].(string) < tagObjects[j]["name"].(string)
+	})
+	return schema{
+		"openapi": openAPIVersion,
+		"info": schema{
+			"title":       "tiny-cluster bootserver",
+			"description": "The API to boot and manage the machines. The REST API requires the bearer token when bootserver runs with the authentication.",
+			"version":     apiVersion,
+		},
+		"tags":  tagObjects,
+		"paths": paths,
+		"components": schema{
+			"schemas": b.schemas,
+			"securitySchemes": schema{
+				bearerScheme: schema{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+	openAPIErr  error
+)
+
+// OpenAPIHandler writes the OpenAPI 3 document of the routes registered by Routes.
+func OpenAPIHandler(c echo.Context) error {
+	openAPIOnce.Do(func() {
+		openAPIJSON, openAPIErr = json.Marshal(openAPIDocument())
+	})
+	if openAPIErr != nil {
+		return openAPIErr
+	}
+	return c.JSONBlob(http.StatusOK, openAPIJSON)
+}
@@ -0,0 +1,144 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+	"github.com/pddg/tiny-cluster/pkg/usecase/mock"
+)
+
+// openAPIDocument is the part of the OpenAPI document checked by the tests.
+type openAPIDocument struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+// routePathParam matches the parameters in the paths of echo.
+var routePathParam = regexp.MustCompile(`:([^/]+)`)
+
+// schemaRef matches the references to the schemas.
+var schemaRef = regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`)
+
+// newRoutes returns the server which has the routes registered as bootserver does with the authentication.
+func newRoutes(t *testing.T) *echo.Echo {
+	ctrl := gomock.NewController(t)
+	e := echo.New()
+	e.HTTPErrorHandler = api.ProblemHandler
+	routes := &api.Routes{
+		MachineUsecase: mock.NewMockMachineUsecase(ctrl),
+		Runner:         leader.NewRunner("replica1", leader.NewLocalElection()),
+		BootFileDir:    t.TempDir(),
+		APIMiddlewares: []echo.MiddlewareFunc{api.AuthMiddleware(mock.NewMockTokenUsecase(ctrl))},
+	}
+	routes.Register(e)
+	return e
+}
+
+func getOpenAPIDocument(t *testing.T, e *echo.Echo) (*openAPIDocument, []byte) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expect: %d, Actual: %d %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	doc := &openAPIDocument{}
+	if err := json.Unmarshal(rec.Body.Bytes(), doc); err != nil {
+		t.Fatalf("Failed to decode the document: %v", err)
+	}
+	return doc, rec.Body.Bytes()
+}
+
+// TestOpenAPIHandler_routes fails if a route is missing from the document or the document has an unknown route.
+func TestOpenAPIHandler_routes(t *testing.T) {
+	e := newRoutes(t)
+	doc, _ := getOpenAPIDocument(t, e)
+
+	// The groups with the middlewares have the routes of NotFoundHandler to run them on any path.
+	notFound := runtime.FuncForPC(reflect.ValueOf(echo.NotFoundHandler).Pointer()).Name()
+	routes := map[string]bool{}
+	for _, r := range e.Routes() {
+		if r.Name == notFound {
+			continue
+		}
+		path := routePathParam.ReplaceAllString(r.Path, "{$1}")
+		path = strings.Replace(path, "*", "{path}", 1)
+		routes[r.Method+" "+path] = true
+	}
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var undocumented, unknown []string
+	for route := range routes {
+		if !documented[route] {
+			undocumented = append(undocumented, route)
+		}
+	}
+	for route := range documented {
+		if !routes[route] {
+			unknown = append(unknown, route)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unknown)
+	if len(undocumented) != 0 {
+		t.Errorf("The routes are not in the OpenAPI document: %v", undocumented)
+	}
+	if len(unknown) != 0 {
+		t.Errorf("The OpenAPI document has the routes which are not served: %v", unknown)
+	}
+}
+
+func TestOpenAPIHandler_document(t *testing.T) {
+	doc, body := getOpenAPIDocument(t, newRoutes(t))
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("Expect OpenAPI 3, Actual: %s", doc.OpenAPI)
+	}
+	for _, match := range schemaRef.FindAllSubmatch(body, -1) {
+		if _, ok := doc.Components.Schemas[string(match[1])]; !ok {
+			t.Errorf("Schema %s is referred but not defined", match[1])
+		}
+	}
+	ids := map[string]string{}
+	for path, item := range doc.Paths {
+		for method, raw := range item {
+			var op struct {
+				OperationID string                     `json:"operationId"`
+				Responses   map[string]json.RawMessage `json:"responses"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				t.Fatalf("Failed to decode %s %s: %v", method, path, err)
+			}
+			if other, ok := ids[op.OperationID]; ok || len(op.OperationID) == 0 {
+				t.Errorf("The operation ID of %s %s must be unique. Also used by: %s", method, path, other)
+			}
+			ids[op.OperationID] = method + " " + path
+			if len(op.Responses) < 2 {
+				t.Errorf("%s %s must have the successful and the error responses", method, path)
+			}
+		}
+	}
+	for _, name := range []string{"Machine", "MachineHistory", "ImportResult", "Problem"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Schema %s must be defined", name)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/inventory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/render"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+// RESTHandler serves the HTTP API to manipulate the machine data.
+type RESTHandler struct {
+	machineUsecase usecase.MachineUsecase
+}
+
+// Register registers the routes of the API into the group.
+// The routes are served in the default namespace, and in the other ones under '/namespaces/:namespace'.
+func (h *RESTHandler) Register(g *echo.Group) {
+	h.register(g)
+	h.register(g.Group("/namespaces/:namespace", namespaceMiddleware))
+}
+
+// RegisterRoot registers the routes which are also served at the root in the default namespace,
+// so that the other tools such as Ansible and Prometheus can be pointed at the short paths.
+func (h *RESTHandler) RegisterRoot(e *echo.Echo, middlewares ...echo.MiddlewareFunc) {
+	e.GET("/inventory/ansible", h.AnsibleInventory, middlewares...)
+	e.GET("/sd/prometheus", h.PrometheusTargets, middlewares...)
+}
+
+func (h *RESTHandler) register(g *echo.Group) {
+	g.GET("/inventory", h.ExportInventory)
+	g.POST("/inventory", h.ImportInventory)
+	g.GET("/inventory/ansible", h.AnsibleInventory)
+	g.GET("/machines", h.GetMachines)
+	g.GET("/machines/:mac", h.GetMachine)
+	g.PUT("/machines/:mac", h.PutMachine)
+	g.PATCH("/machines/:mac", h.PatchMachine)
+	g.DELETE("/machines/:mac", h.DeleteMachine)
+	g.GET("/machines/:mac/history", h.GetMachineHistory)
+	g.POST("/machines/:mac/revert", h.RevertMachine)
+	g.POST("/machines/:mac/reprovision", h.ReprovisionMachine)
+	g.POST("/machines/:mac/heartbeat", h.Heartbeat)
+	g.GET("/render/:format", h.Render)
+	g.GET("/sd/prometheus", h.PrometheusTargets)
+}
+
+// namespaceMiddleware stores the namespace given by the path parameter into the context of the request.
+func namespaceMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name := c.Param("namespace")
+		if err := namespace.Validate(name); err != nil {
+			return httpError(err)
+		}
+		req := c.Request()
+		c.SetRequest(req.WithContext(namespace.NewContext(req.Context(), name)))
+		return next(c)
+	}
+}
+
+// inventoryFormat returns the format specified by the query parameter or the Content-Type.
+func inventoryFormat(c echo.Context) (inventory.Format, error) {
+	if name := c.QueryParam("format"); len(name) != 0 {
+		return inventory.ParseFormat(name)
+	}
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	for _, f := range []inventory.Format{inventory.FormatCSV, inventory.FormatYAML} {
+		if strings.HasPrefix(contentType, f.ContentType()) {
+			return f, nil
+		}
+	}
+	return inventory.FormatJSON, nil
+}
+
+// ExportInventory writes all machines in the format given by 'format' query parameter.
+func (h *RESTHandler) ExportInventory(c echo.Context) error {
+	format := inventory.FormatJSON
+	if name := c.QueryParam("format"); len(name) != 0 {
+		var err error
+		if format, err = inventory.ParseFormat(name); err != nil {
+			return httpError(err)
+		}
+	}
+	machines, err := h.machineUsecase.GetAllMachines(c.Request().Context())
+	if err != nil {
+		return httpError(err)
+	}
+	var buf bytes.Buffer
+	if err := inventory.Encode(&buf, format, machines); err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// ImportInventory registers or updates the machines in the request body.
+// If 'dry_run' query parameter is true, nothing is written and only the differences are returned.
+func (h *RESTHandler) ImportInventory(c echo.Context) error {
+	format, err := inventoryFormat(c)
+	if err != nil {
+		return httpError(err)
+	}
+	dryRun := false
+	if value := c.QueryParam("dry_run"); len(value) != 0 {
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "dry_run must be a boolean")
+		}
+	}
+	machines, err := inventory.Decode(c.Request().Body, format)
+	if err != nil {
+		return httpError(err)
+	}
+	result, err := h.machineUsecase.ImportMachines(c.Request().Context(), machines, dryRun)
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// AnsibleInventory writes the Ansible dynamic inventory of all machines.
+// If 'host' query parameter is given, only the variables of the machine whose name is matched with it are written.
+func (h *RESTHandler) AnsibleInventory(c echo.Context) error {
+	ctx := c.Request().Context()
+	if name := c.QueryParam("host"); len(name) != 0 {
+		machine, err := h.machineUsecase.GetMachineByName(ctx, name)
+		if err != nil {
+			return httpError(err)
+		}
+		if machine == nil {
+			return httpError(tcErr.Wrap("AnsibleInventory", tcErr.KindMachine, name, tcErr.ErrNotFound))
+		}
+		return c.JSON(http.StatusOK, inventory.AnsibleHostVarsOf(machine))
+	}
+	machines, err := h.machineUsecase.GetAllMachinesWithLiveness(ctx)
+	if err != nil {
+		return httpError(err)
+	}
+	inv, err := inventory.Ansible(machines)
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, inv)
+}
+
+// PrometheusTargets writes the targets of Prometheus HTTP service discovery on the deployed machines.
+// The ports are given by 'port' query parameters such as "9100" or "role=db:9187", which are parsed by
+// inventory.ParsePortRule, so that each scrape job can have its own URL.
+func (h *RESTHandler) PrometheusTargets(c echo.Context) error {
+	var rules []*inventory.PortRule
+	for _, value := range c.QueryParams()["port"] {
+		rule, err := inventory.ParsePortRule(value)
+		if err != nil {
+			return httpError(err)
+		}
+		rules = append(rules, rule)
+	}
+	machines, err := h.machineUsecase.GetAllMachines(c.Request().Context())
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, inventory.Prometheus(machines, rules))
+}
+
+// GetMachines writes the machines with their liveness.
+// The query parameters are the conditions of usecase.MachineQuery such as 'name' and 'label.env',
+// and all machines are written if none is given.
+func (h *RESTHandler) GetMachines(c echo.Context) error {
+	ctx := c.Request().Context()
+	query := usecase.MachineQuery{}
+	for key, values := range c.QueryParams() {
+		query[key] = values[0]
+	}
+	var machines []*models.Machine
+	var err error
+	if len(query) == 0 {
+		machines, err = h.machineUsecase.GetAllMachinesWithLiveness(ctx)
+	} else {
+		machines, err = h.machineUsecase.GetMachineByQuery(ctx, &query)
+	}
+	if err != nil {
+		return httpError(err)
+	}
+	if machines == nil {
+		machines = []*models.Machine{}
+	}
+	return c.JSON(http.StatusOK, machines)
+}
+
+// findMachine returns the machine whose MAC is given by the path parameter with its liveness.
+func (h *RESTHandler) findMachine(c echo.Context) (*models.Machine, error) {
+	mac := c.Param("mac")
+	machines, err := h.machineUsecase.GetMachineByQuery(c.Request().Context(), &usecase.MachineQuery{"mac": mac})
+	if err != nil {
+		return nil, err
+	}
+	if len(machines) == 0 {
+		return nil, tcErr.Wrap("GetMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+	}
+	return machines[0], nil
+}
+
+// GetMachine writes the machine whose MAC is given by the path parameter.
+func (h *RESTHandler) GetMachine(c echo.Context) error {
+	machine, err := h.findMachine(c)
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, machine)
+}
+
+// PutMachine registers or updates the machine in the request body with the MAC given by the path parameter.
+func (h *RESTHandler) PutMachine(c echo.Context) error {
+	machine := &models.Machine{}
+	if err := json.NewDecoder(c.Request().Body).Decode(machine); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "the body must be a machine in JSON")
+	}
+	mac, err := usecase.NormalizeMAC(c.Param("mac"))
+	if err != nil {
+		return httpError(err)
+	}
+	if len(machine.MAC) != 0 {
+		if bodyMAC, err := usecase.NormalizeMAC(machine.MAC); err != nil || bodyMAC != mac {
+			return echo.NewHTTPError(http.StatusBadRequest, "the MAC in the body must be the same as the one in the path")
+		}
+	}
+	machine.MAC = mac
+	if err := h.machineUsecase.RegisterOrUpdateMachine(c.Request().Context(), machine); err != nil {
+		return httpError(err)
+	}
+	return h.GetMachine(c)
+}
+
+// PatchMachine updates the fields of the machine given in the request body, like JSON merge patch.
+// The fields of "spec" are updated one by one, and the other fields are replaced as a whole.
+func (h *RESTHandler) PatchMachine(c echo.Context) error {
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	machine := &models.Machine{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "the body must be a JSON object of the fields to update")
+	}
+	if err := json.Unmarshal(body, machine); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var paths []string
+	for field, value := range fields {
+		var specFields map[string]json.RawMessage
+		if field != "spec" || json.Unmarshal(value, &specFields) != nil {
+			paths = append(paths, field)
+			continue
+		}
+		for specField := range specFields {
+			paths = append(paths, field+"."+specField)
+		}
+	}
+	sort.Strings(paths)
+	patched, err := h.machineUsecase.PatchMachine(c.Request().Context(), c.Param("mac"), machine, paths)
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, patched)
+}
+
+// DeleteMachine deletes the machine whose MAC is given by the path parameter.
+func (h *RESTHandler) DeleteMachine(c echo.Context) error {
+	if err := h.machineUsecase.DeleteMachine(c.Request().Context(), &models.Machine{MAC: c.Param("mac")}); err != nil {
+		return httpError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMachineHistory writes the changes of the machine whose MAC is given by the path parameter.
+func (h *RESTHandler) GetMachineHistory(c echo.Context) error {
+	histories, err := h.machineUsecase.GetMachineHistory(c.Request().Context(), c.Param("mac"))
+	if err != nil {
+		return httpError(err)
+	}
+	if histories == nil {
+		histories = []*models.MachineHistory{}
+	}
+	return c.JSON(http.StatusOK, histories)
+}
+
+// RevertMachine restores the machine whose MAC is given by the path parameter to the state just after
+// the revision given by 'revision' query parameter. This writes no content if the machine is deleted by it.
+func (h *RESTHandler) RevertMachine(c echo.Context) error {
+	revision, err := strconv.ParseInt(c.QueryParam("revision"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "revision must be an integer")
+	}
+	machine, err := h.machineUsecase.RevertMachine(c.Request().Context(), c.Param("mac"), revision)
+	if err != nil {
+		return httpError(err)
+	}
+	if machine == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	return c.JSON(http.StatusOK, machine)
+}
+
+// ReprovisionMachine clears the deployed date of the machine whose MAC is given by the path parameter,
+// so that it is treated as undeployed, e.g. in the undeployed group of the Ansible inventory, until it is deployed again.
+func (h *RESTHandler) ReprovisionMachine(c echo.Context) error {
+	machine, err := h.machineUsecase.PatchMachine(c.Request().Context(), c.Param("mac"), &models.Machine{}, []string{"deployed_date"})
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, machine)
+}
+
+// Heartbeat records the check-in of the machine given by the path parameter.
+// The machines are expected to call this periodically to be kept reachable.
+func (h *RESTHandler) Heartbeat(c echo.Context) error {
+	if err := h.machineUsecase.Heartbeat(c.Request().Context(), c.Param("mac")); err != nil {
+		return httpError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Render writes the configuration file of the DNS or DHCP server given by the path parameter, such as /etc/hosts.
+// The domain, the TTL and the name server are given by 'domain', 'ttl' and 'name_server' query parameters.
+func (h *RESTHandler) Render(c echo.Context) error {
+	format, err := render.ParseFormat(c.Param("format"))
+	if err != nil {
+		return httpError(err)
+	}
+	opts := render.Options{
+		Domain:     c.QueryParam("domain"),
+		TTL:        time.Minute,
+		NameServer: c.QueryParam("name_server"),
+		Serial:     uint32(time.Now().Unix()),
+	}
+	if value := c.QueryParam("ttl"); len(value) != 0 {
+		opts.TTL, err = time.ParseDuration(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "ttl must be a duration such as 60s")
+		}
+	}
+	machines, err := h.machineUsecase.GetAllMachines(c.Request().Context())
+	if err != nil {
+		return httpError(err)
+	}
+	var buf bytes.Buffer
+	if err := render.Render(&buf, format, machines, opts); err != nil {
+		return httpError(err)
+	}
+	return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, buf.Bytes())
+}
+
+// NewRESTHandler returns the handler of the HTTP API.
+func NewRESTHandler(machineUsecase usecase.MachineUsecase) *RESTHandler {
+	return &RESTHandler{
+		machineUsecase: machineUsecase,
+	}
+}
@@ -0,0 +1,356 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+	"github.com/pddg/tiny-cluster/pkg/usecase/mock"
+)
+
+func TestRESTHandler_Heartbeat(t *testing.T) {
+	testCases := map[string]struct {
+		errFixture error
+		expect     int
+	}{
+		"registered machine": {
+			errFixture: nil,
+			expect:     http.StatusNoContent,
+		},
+		"unknown machine": {
+			errFixture: xerrors.Errorf("machine 'mac1' has not been registered %w", tcErr.ErrNotFound),
+			expect:     http.StatusNotFound,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			usecaseMock.EXPECT().Heartbeat(gomock.Any(), "mac1").Return(tc.errFixture)
+			e := echo.New()
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/machines/mac1/heartbeat", nil))
+			if rec.Code != tc.expect {
+				t.Errorf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_Render(t *testing.T) {
+	machines := []*models.Machine{{Name: "machine1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1"}}
+	testCases := map[string]struct {
+		path   string
+		expect int
+		body   string
+	}{
+		"hosts": {
+			path:   "/render/hosts?domain=cluster.internal",
+			expect: http.StatusOK,
+			body:   "192.168.1.1\tmachine1.cluster.internal\tmachine1\n",
+		},
+		"bind": {
+			path:   "/render/bind?domain=cluster.internal&ttl=5m",
+			expect: http.StatusOK,
+			body:   "$TTL 300\n",
+		},
+		"bind without domain": {
+			path:   "/render/bind",
+			expect: http.StatusBadRequest,
+		},
+		"unknown format": {
+			path:   "/render/unbound",
+			expect: http.StatusBadRequest,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			usecaseMock.EXPECT().GetAllMachines(gomock.Any()).Return(machines, nil).AnyTimes()
+			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.expect {
+				t.Fatalf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tc.body) {
+				t.Errorf("Expect to contain: %s, Actual: %s", tc.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_AnsibleInventory(t *testing.T) {
+	machine := &models.Machine{Name: "machine1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1", Labels: map[string]string{"rack": "a1"}}
+	testCases := map[string]struct {
+		path   string
+		expect int
+		body   string
+	}{
+		"list": {
+			path:   "/inventory/ansible",
+			expect: http.StatusOK,
+			body:   `"rack_a1":{"hosts":["machine1"]}`,
+		},
+		"host": {
+			path:   "/inventory/ansible?host=machine1",
+			expect: http.StatusOK,
+			body:   `"ansible_host":"192.168.1.1"`,
+		},
+		"unknown host": {
+			path:   "/inventory/ansible?host=unknown",
+			expect: http.StatusNotFound,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			usecaseMock.EXPECT().GetAllMachinesWithLiveness(gomock.Any()).Return([]*models.Machine{machine}, nil).AnyTimes()
+			usecaseMock.EXPECT().GetMachineByName(gomock.Any(), "machine1").Return(machine, nil).AnyTimes()
+			usecaseMock.EXPECT().GetMachineByName(gomock.Any(), "unknown").Return(nil, nil).AnyTimes()
+			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.expect {
+				t.Fatalf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tc.body) {
+				t.Errorf("Expect to contain: %s, Actual: %s", tc.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_PrometheusTargets(t *testing.T) {
+	machine := &models.Machine{Name: "db1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1", DeployedDate: 1, Labels: map[string]string{"role": "db"}}
+	testCases := map[string]struct {
+		path   string
+		expect int
+		body   string
+	}{
+		"default port": {
+			path:   "/sd/prometheus",
+			expect: http.StatusOK,
+			body:   `"targets":["192.168.1.1:9100"]`,
+		},
+		"ports by selector": {
+			path:   "/sd/prometheus?port=role%3Dweb:80&port=role%3Ddb:9187",
+			expect: http.StatusOK,
+			body:   `"targets":["192.168.1.1:9187"]`,
+		},
+		"invalid port": {
+			path:   "/sd/prometheus?port=http",
+			expect: http.StatusBadRequest,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			usecaseMock.EXPECT().GetAllMachines(gomock.Any()).Return([]*models.Machine{machine}, nil).AnyTimes()
+			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.expect {
+				t.Fatalf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tc.body) {
+				t.Errorf("Expect to contain: %s, Actual: %s", tc.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_GetMachines(t *testing.T) {
+	machine := &models.Machine{Name: "machine1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1", Labels: map[string]string{"env": "prod"}}
+	testCases := map[string]struct {
+		path        string
+		expectQuery usecase.MachineQuery
+		expect      int
+		body        string
+	}{
+		"all": {
+			path:   "/machines",
+			expect: http.StatusOK,
+			body:   `"name":"machine1"`,
+		},
+		"query": {
+			path:        "/machines?label.env=prod&liveness=reachable",
+			expectQuery: usecase.MachineQuery{"label.env": "prod", "liveness": "reachable"},
+			expect:      http.StatusOK,
+			body:        `"name":"machine1"`,
+		},
+		"no match": {
+			path:        "/machines?name=machine2",
+			expectQuery: usecase.MachineQuery{"name": "machine2"},
+			expect:      http.StatusOK,
+			body:        `[]`,
+		},
+		"single": {
+			path:        "/machines/52-54-00-00-00-01",
+			expectQuery: usecase.MachineQuery{"mac": "52-54-00-00-00-01"},
+			expect:      http.StatusOK,
+			body:        `"name":"machine1"`,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			if tc.expectQuery == nil {
+				usecaseMock.EXPECT().GetAllMachinesWithLiveness(gomock.Any()).Return([]*models.Machine{machine}, nil)
+			} else {
+				usecaseMock.EXPECT().GetMachineByQuery(gomock.Any(), &tc.expectQuery).DoAndReturn(func(ctx context.Context, query *usecase.MachineQuery) ([]*models.Machine, error) {
+					if (*query)["name"] != "machine2" {
+						return []*models.Machine{machine}, nil
+					}
+					return nil, nil
+				})
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.expect {
+				t.Fatalf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tc.body) {
+				t.Errorf("Expect to contain: %s, Actual: %s", tc.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_PatchMachine(t *testing.T) {
+	testCases := map[string]struct {
+		body        string
+		expectPaths []string
+		expect      int
+	}{
+		"fields": {
+			body:        `{"name":"machine2","labels":{"env":"prod"}}`,
+			expectPaths: []string{"labels", "name"},
+			expect:      http.StatusOK,
+		},
+		"spec fields": {
+			body:        `{"spec":{"core":8,"disk":512}}`,
+			expectPaths: []string{"spec.core", "spec.disk"},
+			expect:      http.StatusOK,
+		},
+		"not an object": {
+			body:   `["name"]`,
+			expect: http.StatusBadRequest,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			if tc.expectPaths != nil {
+				usecaseMock.EXPECT().PatchMachine(gomock.Any(), "52:54:00:00:00:01", gomock.Any(), tc.expectPaths).Return(&models.Machine{MAC: "52:54:00:00:00:01"}, nil)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/machines/52:54:00:00:00:01", strings.NewReader(tc.body)))
+			if rec.Code != tc.expect {
+				t.Errorf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_PutMachine(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		register bool
+		expect   int
+	}{
+		"without MAC": {
+			body:     `{"name":"machine1","ipv4_addr":"192.168.1.1"}`,
+			register: true,
+			expect:   http.StatusOK,
+		},
+		"same MAC in another form": {
+			body:     `{"mac":"52-54-00-00-00-01","name":"machine1"}`,
+			register: true,
+			expect:   http.StatusOK,
+		},
+		"different MAC": {
+			body:   `{"mac":"52:54:00:00:00:02","name":"machine1"}`,
+			expect: http.StatusBadRequest,
+		},
+	}
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			usecaseMock := mock.NewMockMachineUsecase(ctrl)
+			if tc.register {
+				usecaseMock.EXPECT().RegisterOrUpdateMachine(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, machine *models.Machine) error {
+					if machine.MAC != "52:54:00:00:00:01" {
+						t.Errorf("The MAC must be taken from the path. Actual: %s", machine.MAC)
+					}
+					return nil
+				})
+				usecaseMock.EXPECT().GetMachineByQuery(gomock.Any(), gomock.Any()).Return([]*models.Machine{{MAC: "52:54:00:00:00:01"}}, nil)
+			}
+			e := echo.New()
+			e.HTTPErrorHandler = api.ProblemHandler
+			api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/machines/52:54:00:00:00:01", strings.NewReader(tc.body)))
+			if rec.Code != tc.expect {
+				t.Errorf("Expect: %d, Actual: %d %s", tc.expect, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRESTHandler_ReprovisionMachine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	usecaseMock := mock.NewMockMachineUsecase(ctrl)
+	usecaseMock.EXPECT().PatchMachine(gomock.Any(), "52:54:00:00:00:01", &models.Machine{}, []string{"deployed_date"}).Return(&models.Machine{MAC: "52:54:00:00:00:01"}, nil)
+	e := echo.New()
+	e.HTTPErrorHandler = api.ProblemHandler
+	api.NewRESTHandler(usecaseMock).Register(e.Group(""))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/machines/52:54:00:00:00:01/reprovision", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expect: %d, Actual: %d %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
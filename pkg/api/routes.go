@@ -0,0 +1,83 @@
+package api
+
+import (
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/boot"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+	"github.com/pddg/tiny-cluster/pkg/ui"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+// Routes is the routes of the HTTP server of bootserver, which are described by the OpenAPI document.
+type Routes struct {
+	// MachineUsecase serves the REST API.
+	MachineUsecase usecase.MachineUsecase
+	// Runner is the election of this replica shown by the status.
+	Runner *leader.Runner
+	// BootFileDir is the directory of the files to boot the machines, which are served under '/boot'.
+	BootFileDir string
+	// IPXEScript is served at '/default.ipxe'. The script for http://tcboot:8080 is served if nil.
+	IPXEScript *boot.IPXEScript
+	// APIMiddlewares is applied only to the REST API including its routes at the root, such as AuthMiddleware.
+	// The boot files are always served without the tokens, since the machines being provisioned have none.
+	APIMiddlewares []echo.MiddlewareFunc
+	// UI and OpenAPI switch the dashboard and the OpenAPI document. They are served if nil.
+	UI      *Toggle
+	OpenAPI *Toggle
+}
+
+// Register registers all routes into e.
+func (r *Routes) Register(e *echo.Echo) {
+	script := r.IPXEScript
+	if script == nil {
+		script = boot.NewIPXEScript("http://tcboot:8080")
+	}
+	e.GET("/default.ipxe", script.Handler)
+	e.Static("/boot", r.BootFileDir)
+	rest := NewRESTHandler(r.MachineUsecase)
+	rest.Register(e.Group("/api/v1", r.APIMiddlewares...))
+	rest.RegisterRoot(e, r.APIMiddlewares...)
+	NewStatusHandler(r.Runner).Register(e.Group(""))
+	ui.Register(e.Group("/ui", r.UI.Middleware))
+	e.GET("/openapi.json", OpenAPIHandler, r.OpenAPI.Middleware)
+}
+
+// Toggle switches the routes on and off while serving. The routes are always registered,
+// so that they are described by the OpenAPI document, and respond Not Found while switched off.
+type Toggle struct {
+	disabled int32
+}
+
+// NewToggle returns the toggle switched on if enabled.
+func NewToggle(enabled bool) *Toggle {
+	t := &Toggle{}
+	t.Set(enabled)
+	return t
+}
+
+// Set switches the routes on if enabled, otherwise off.
+func (t *Toggle) Set(enabled bool) {
+	var disabled int32
+	if !enabled {
+		disabled = 1
+	}
+	atomic.StoreInt32(&t.disabled, disabled)
+}
+
+// Enabled returns whether the routes are switched on. The nil toggle is always on.
+func (t *Toggle) Enabled() bool {
+	return t == nil || atomic.LoadInt32(&t.disabled) == 0
+}
+
+// Middleware responds Not Found while the toggle is switched off.
+func (t *Toggle) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !t.Enabled() {
+			return echo.ErrNotFound
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase/mock"
+)
+
+func TestRoutes_root(t *testing.T) {
+	machines := []*models.Machine{{Name: "machine1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1", DeployedDate: 1}}
+	ctrl := gomock.NewController(t)
+	usecaseMock := mock.NewMockMachineUsecase(ctrl)
+	usecaseMock.EXPECT().GetAllMachinesWithLiveness(gomock.Any()).Return(machines, nil)
+	usecaseMock.EXPECT().GetAllMachines(gomock.Any()).Return(machines, nil)
+	e := echo.New()
+	e.HTTPErrorHandler = api.ProblemHandler
+	routes := &api.Routes{
+		MachineUsecase: usecaseMock,
+		Runner:         leader.NewRunner("replica1", leader.NewLocalElection()),
+		BootFileDir:    t.TempDir(),
+		APIMiddlewares: []echo.MiddlewareFunc{api.AuthMiddleware(mock.NewMockTokenUsecase(ctrl))},
+	}
+	routes.Register(e)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	expected := map[string]string{
+		"/inventory/ansible": `"ansible_host":"192.168.1.1"`,
+		"/sd/prometheus":     `"targets":["192.168.1.1:9100"]`,
+	}
+
+	// The routes at the root are authenticated as the REST API.
+	for path := range expected {
+		if rec := get(path); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: Expect: %d, Actual: %d %s", path, http.StatusUnauthorized, rec.Code, rec.Body.String())
+		}
+	}
+	routes.APIMiddlewares = nil
+	e = echo.New()
+	routes.Register(e)
+	for path, body := range expected {
+		rec := get(path)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: Expect: %d, Actual: %d %s", path, http.StatusOK, rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), body) {
+			t.Errorf("%s: Expect to contain: %s, Actual: %s", path, body, rec.Body.String())
+		}
+	}
+}
+
+func TestToggle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	e := echo.New()
+	e.HTTPErrorHandler = api.ProblemHandler
+	routes := &api.Routes{
+		MachineUsecase: mock.NewMockMachineUsecase(ctrl),
+		Runner:         leader.NewRunner("replica1", leader.NewLocalElection()),
+		BootFileDir:    t.TempDir(),
+		UI:             api.NewToggle(false),
+		OpenAPI:        api.NewToggle(true),
+	}
+	routes.Register(e)
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if code := get("/ui/"); code != http.StatusNotFound {
+		t.Errorf("Expect: %d, Actual: %d", http.StatusNotFound, code)
+	}
+	if code := get("/openapi.json"); code != http.StatusOK {
+		t.Errorf("Expect: %d, Actual: %d", http.StatusOK, code)
+	}
+	routes.UI.Set(true)
+	routes.OpenAPI.Set(false)
+	if code := get("/ui/"); code != http.StatusOK {
+		t.Errorf("Expect: %d, Actual: %d", http.StatusOK, code)
+	}
+	if code := get("/openapi.json"); code != http.StatusNotFound {
+		t.Errorf("Expect: %d, Actual: %d", http.StatusNotFound, code)
+	}
+}
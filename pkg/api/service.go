@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/pddg/tiny-cluster/pkg/api/pb"
+)
+
+// MachineDatabaseServer is the server API for MachineDatabase service.
+//
+// protoc-gen-go does not generate the codes for gRPC services, and
+// the one generated by protoc-gen-go-grpc requires newer grpc-go than etcd client supports.
+// So the service is described manually according to proto/mdb.proto.
+type MachineDatabaseServer interface {
+	GetMachines(context.Context, *pb.GetMachinesRequest) (*pb.GetMachinesResponse, error)
+	RegisterOrUpdateMachine(context.Context, *pb.RegisterOrUpdateMachineRequest) (*pb.RegisterOrUpdateMachineResponse, error)
+	DeleteMachine(context.Context, *pb.DeleteMachineRequest) (*pb.DeleteMachineResponse, error)
+	PatchMachine(context.Context, *pb.PatchMachineRequest) (*pb.PatchMachineResponse, error)
+	GetMachineHistory(context.Context, *pb.GetMachineHistoryRequest) (*pb.GetMachineHistoryResponse, error)
+	RevertMachine(context.Context, *pb.RevertMachineRequest) (*pb.RevertMachineResponse, error)
+	Heartbeat(context.Context, *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error)
+}
+
+const machineDatabaseServiceName = "tiny_cluster.mdb.MachineDatabase"
+
+// MachineDatabaseClient is the client API for MachineDatabase service.
+type MachineDatabaseClient interface {
+	GetMachines(ctx context.Context, in *pb.GetMachinesRequest, opts ...grpc.CallOption) (*pb.GetMachinesResponse, error)
+	RegisterOrUpdateMachine(ctx context.Context, in *pb.RegisterOrUpdateMachineRequest, opts ...grpc.CallOption) (*pb.RegisterOrUpdateMachineResponse, error)
+	DeleteMachine(ctx context.Context, in *pb.DeleteMachineRequest, opts ...grpc.CallOption) (*pb.DeleteMachineResponse, error)
+	PatchMachine(ctx context.Context, in *pb.PatchMachineRequest, opts ...grpc.CallOption) (*pb.PatchMachineResponse, error)
+	GetMachineHistory(ctx context.Context, in *pb.GetMachineHistoryRequest, opts ...grpc.CallOption) (*pb.GetMachineHistoryResponse, error)
+	RevertMachine(ctx context.Context, in *pb.RevertMachineRequest, opts ...grpc.CallOption) (*pb.RevertMachineResponse, error)
+	Heartbeat(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error)
+}
+
+type machineDatabaseClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewMachineDatabaseClient returns the client of MachineDatabase service on the connection.
+func NewMachineDatabaseClient(cc *grpc.ClientConn) MachineDatabaseClient {
+	return &machineDatabaseClient{cc: cc}
+}
+
+func (c *machineDatabaseClient) GetMachines(ctx context.Context, in *pb.GetMachinesRequest, opts ...grpc.CallOption) (*pb.GetMachinesResponse, error) {
+	out := new(pb.GetMachinesResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/GetMachines", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *machineDatabaseClient) RegisterOrUpdateMachine(ctx context.Context, in *pb.RegisterOrUpdateMachineRequest, opts ...grpc.CallOption) (*pb.RegisterOrUpdateMachineResponse, error) {
+	out := new(pb.RegisterOrUpdateMachineResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/RegisterOrUpdateMachine", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *machineDatabaseClient) DeleteMachine(ctx context.Context, in *pb.DeleteMachineRequest, opts ...grpc.CallOption) (*pb.DeleteMachineResponse, error) {
+	out := new(pb.DeleteMachineResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/DeleteMachine", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *machineDatabaseClient) PatchMachine(ctx context.Context, in *pb.PatchMachineRequest, opts ...grpc.CallOption) (*pb.PatchMachineResponse, error) {
+	out := new(pb.PatchMachineResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/PatchMachine", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *machineDatabaseClient) GetMachineHistory(ctx context.Context, in *pb.GetMachineHistoryRequest, opts ...grpc.CallOption) (*pb.GetMachineHistoryResponse, error) {
+	out := new(pb.GetMachineHistoryResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/GetMachineHistory", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *machineDatabaseClient) RevertMachine(ctx context.Context, in *pb.RevertMachineRequest, opts ...grpc.CallOption) (*pb.RevertMachineResponse, error) {
+	out := new(pb.RevertMachineResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/RevertMachine", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *machineDatabaseClient) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error) {
+	out := new(pb.HeartbeatResponse)
+	if err := c.cc.Invoke(ctx, "/"+machineDatabaseServiceName+"/Heartbeat", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterMachineDatabaseServer registers the implementation of MachineDatabase service to the gRPC server.
+func RegisterMachineDatabaseServer(s *grpc.Server, srv MachineDatabaseServer) {
+	s.RegisterService(&machineDatabaseServiceDesc, srv)
+}
+
+var machineDatabaseServiceDesc = grpc.ServiceDesc{
+	ServiceName: machineDatabaseServiceName,
+	HandlerType: (*MachineDatabaseServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMachines",
+			Handler:    getMachinesHandler,
+		},
+		{
+			MethodName: "RegisterOrUpdateMachine",
+			Handler:    registerOrUpdateMachineHandler,
+		},
+		{
+			MethodName: "DeleteMachine",
+			Handler:    deleteMachineHandler,
+		},
+		{
+			MethodName: "PatchMachine",
+			Handler:    patchMachineHandler,
+		},
+		{
+			MethodName: "GetMachineHistory",
+			Handler:    getMachineHistoryHandler,
+		},
+		{
+			MethodName: "RevertMachine",
+			Handler:    revertMachineHandler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    heartbeatHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "mdb.proto",
+}
+
+func getMachinesHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.GetMachinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).GetMachines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/GetMachines",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).GetMachines(ctx, req.(*pb.GetMachinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func registerOrUpdateMachineHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.RegisterOrUpdateMachineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).RegisterOrUpdateMachine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/RegisterOrUpdateMachine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).RegisterOrUpdateMachine(ctx, req.(*pb.RegisterOrUpdateMachineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func deleteMachineHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.DeleteMachineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).DeleteMachine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/DeleteMachine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).DeleteMachine(ctx, req.(*pb.DeleteMachineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func patchMachineHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.PatchMachineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).PatchMachine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/PatchMachine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).PatchMachine(ctx, req.(*pb.PatchMachineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func getMachineHistoryHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.GetMachineHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).GetMachineHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/GetMachineHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).GetMachineHistory(ctx, req.(*pb.GetMachineHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func revertMachineHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.RevertMachineRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).RevertMachine(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/RevertMachine",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).RevertMachine(ctx, req.(*pb.RevertMachineRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func heartbeatHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(pb.HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MachineDatabaseServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + machineDatabaseServiceName + "/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MachineDatabaseServer).Heartbeat(ctx, req.(*pb.HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/leader"
+)
+
+// StatusHandler serves the state of the replica, such as which replica leads.
+type StatusHandler struct {
+	runner *leader.Runner
+}
+
+// Register registers the routes of the status into the group.
+func (h *StatusHandler) Register(g *echo.Group) {
+	g.GET("/status", h.GetStatus)
+}
+
+// GetStatus writes the state of the leader election.
+func (h *StatusHandler) GetStatus(c echo.Context) error {
+	status, err := h.runner.Status(c.Request().Context())
+	if err != nil {
+		return httpError(err)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// NewStatusHandler returns the handler of the status of the replica.
+func NewStatusHandler(runner *leader.Runner) *StatusHandler {
+	return &StatusHandler{
+		runner: runner,
+	}
+}
@@ -0,0 +1,39 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/api"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+)
+
+func TestStatusHandler_GetStatus(t *testing.T) {
+	election := leader.NewLocalElection()
+	runner := leader.NewRunner("replica1", election)
+	runner.Register("gc", func(ctx context.Context) error { return nil })
+	if _, err := election.Campaign(context.Background(), "replica2"); err != nil {
+		t.Fatalf("Failed to campaign due to %v", err)
+	}
+	e := echo.New()
+	api.NewStatusHandler(runner).Register(e.Group(""))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expect: %d, Actual: %d %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	actual := new(leader.Status)
+	if err := json.Unmarshal(rec.Body.Bytes(), actual); err != nil {
+		t.Fatalf("Failed to decode the status due to %v", err)
+	}
+	expect := &leader.Status{ID: "replica1", Leader: "replica2", Tasks: []string{"gc"}}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
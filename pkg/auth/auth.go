@@ -0,0 +1,177 @@
+// Package auth carries the principal authenticated by the API token through context,
+// and decides which operations the principal is permitted.
+package auth
+
+import (
+	"context"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+const (
+	// RoleViewer can read the machines.
+	RoleViewer = "viewer"
+	// RoleOperator can also register, update and import the machines, and send their heartbeats.
+	RoleOperator = "operator"
+	// RoleAdmin can also delete the machines.
+	RoleAdmin = "admin"
+	// RoleMachine is the role of the tokens bound to a machine, such as the one of tcagent.
+	// It can only read the machine, patch its spec and send its heartbeats.
+	// It is given only to the tokens bound to the machines, and never by the name.
+	RoleMachine = "machine"
+)
+
+// roleLevels orders the roles. A role has all permissions of the lower ones.
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Operation is the kind of the operations on the machines.
+type Operation string
+
+const (
+	// OperationRead reads the machines and their histories.
+	OperationRead Operation = "read"
+	// OperationWrite registers, updates, patches, reverts and imports the machines.
+	OperationWrite Operation = "write"
+	// OperationPatchSpec patches only the spec of the machines, which is detected by the machines themselves.
+	OperationPatchSpec Operation = "patch_spec"
+	// OperationHeartbeat records the check-ins of the machines.
+	OperationHeartbeat Operation = "heartbeat"
+	// OperationDelete deletes the machines.
+	OperationDelete Operation = "delete"
+)
+
+// requiredRoles is the least role required for each operation.
+var requiredRoles = map[Operation]string{
+	OperationRead:      RoleViewer,
+	OperationWrite:     RoleOperator,
+	OperationPatchSpec: RoleOperator,
+	OperationHeartbeat: RoleOperator,
+	OperationDelete:    RoleAdmin,
+}
+
+// machineOperations is the operations permitted to RoleMachine on its own machine.
+var machineOperations = map[Operation]bool{
+	OperationRead:      true,
+	OperationPatchSpec: true,
+	OperationHeartbeat: true,
+}
+
+// ValidateRole returns ErrInvalidArgument if role is unknown.
+func ValidateRole(role string) error {
+	if _, ok := roleLevels[role]; !ok {
+		return xerrors.Errorf("role must be one of %s, %s and %s but '%s' %w", RoleViewer, RoleOperator, RoleAdmin, role, tcErr.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Principal is the client authenticated by the token.
+type Principal struct {
+	// Name is the name of the token.
+	Name string
+	// Role is one of RoleViewer, RoleOperator and RoleAdmin.
+	Role string
+	// Scope is the label selector of the machines the principal can access.
+	// The principal can access all machines if it is empty.
+	Scope map[string]string
+	// MAC is the MAC address of the only machine which the principal of RoleMachine can access.
+	MAC string
+}
+
+// PrincipalFromToken returns the principal who has the token.
+// The tokens created before the roles were introduced have no role, and are treated as RoleAdmin.
+// The tokens bound to the machines are always RoleMachine whatever their roles are.
+func PrincipalFromToken(token *models.Token) *Principal {
+	if len(token.MAC) != 0 {
+		return &Principal{
+			Name: token.Name,
+			Role: RoleMachine,
+			MAC:  token.MAC,
+		}
+	}
+	role := token.Role
+	if len(role) == 0 {
+		role = RoleAdmin
+	}
+	return &Principal{
+		Name:  token.Name,
+		Role:  role,
+		Scope: token.Scope,
+	}
+}
+
+// Permits returns true if the role of the principal is enough for op.
+func (p *Principal) Permits(op Operation) bool {
+	if p.Role == RoleMachine {
+		return len(p.MAC) != 0 && machineOperations[op]
+	}
+	return roleLevels[p.Role] >= roleLevels[requiredRoles[op]]
+}
+
+// InScope returns true if the machine has all labels in the scope.
+// Only its own machine is in the scope of RoleMachine.
+func (p *Principal) InScope(machine *models.Machine) bool {
+	if p.Role == RoleMachine {
+		return len(p.MAC) != 0 && machine.MAC == p.MAC
+	}
+	return models.MatchLabels(p.Scope, machine.Labels)
+}
+
+// Scoped returns true if the principal can access only some of the machines.
+func (p *Principal) Scoped() bool {
+	return len(p.Scope) != 0 || p.Role == RoleMachine
+}
+
+type contextKey struct{}
+
+// NewContext returns a new context which carries the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx.
+// This returns false if the request has not been authenticated, such as when the authentication is disabled
+// or the datastore is accessed directly from CLI.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Authorize returns ErrPermissionDenied if the principal in ctx is not permitted op on the machines.
+// The nil machines, which do not exist, are ignored. Any operation is permitted without the principal.
+func Authorize(ctx context.Context, op Operation, machines ...*models.Machine) error {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if !p.Permits(op) {
+		return xerrors.Errorf("%s can not %s the machines as %s %w", p.Name, op, p.Role, tcErr.ErrPermissionDenied)
+	}
+	for _, machine := range machines {
+		if machine != nil && !p.InScope(machine) {
+			return xerrors.Errorf("machine '%s' is out of the scope of %s %w", machine.MAC, p.Name, tcErr.ErrPermissionDenied)
+		}
+	}
+	return nil
+}
+
+// Filter returns only the machines in the scope of the principal in ctx.
+func Filter(ctx context.Context, machines []*models.Machine) []*models.Machine {
+	p, ok := FromContext(ctx)
+	if !ok || !p.Scoped() {
+		return machines
+	}
+	var filtered []*models.Machine
+	for _, machine := range machines {
+		if p.InScope(machine) {
+			filtered = append(filtered, machine)
+		}
+	}
+	return filtered
+}
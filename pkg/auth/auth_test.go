@@ -0,0 +1,168 @@
+package auth_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+var (
+	staging = &models.Machine{MAC: "52:54:00:00:00:01", Labels: map[string]string{"env": "staging", "rack": "a"}}
+	prod    = &models.Machine{MAC: "52:54:00:00:00:02", Labels: map[string]string{"env": "prod"}}
+)
+
+func TestAuthorize(t *testing.T) {
+	testCases := map[string]struct {
+		principal *auth.Principal
+		op        auth.Operation
+		machines  []*models.Machine
+		expectErr error
+	}{
+		"no principal": {
+			principal: nil,
+			op:        auth.OperationDelete,
+			machines:  []*models.Machine{prod},
+			expectErr: nil,
+		},
+		"viewer reads": {
+			principal: &auth.Principal{Name: "viewer", Role: auth.RoleViewer},
+			op:        auth.OperationRead,
+			machines:  []*models.Machine{prod},
+			expectErr: nil,
+		},
+		"viewer writes": {
+			principal: &auth.Principal{Name: "viewer", Role: auth.RoleViewer},
+			op:        auth.OperationWrite,
+			machines:  []*models.Machine{staging},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator writes in scope": {
+			principal: &auth.Principal{Name: "operator", Role: auth.RoleOperator, Scope: map[string]string{"env": "staging"}},
+			op:        auth.OperationWrite,
+			machines:  []*models.Machine{staging, nil},
+			expectErr: nil,
+		},
+		"operator writes out of scope": {
+			principal: &auth.Principal{Name: "operator", Role: auth.RoleOperator, Scope: map[string]string{"env": "staging"}},
+			op:        auth.OperationWrite,
+			machines:  []*models.Machine{staging, prod},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator deletes": {
+			principal: &auth.Principal{Name: "operator", Role: auth.RoleOperator},
+			op:        auth.OperationDelete,
+			machines:  []*models.Machine{staging},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"admin deletes": {
+			principal: &auth.Principal{Name: "admin", Role: auth.RoleAdmin},
+			op:        auth.OperationDelete,
+			machines:  []*models.Machine{prod},
+			expectErr: nil,
+		},
+		"machine reads itself": {
+			principal: &auth.Principal{Name: "machine", Role: auth.RoleMachine, MAC: staging.MAC},
+			op:        auth.OperationRead,
+			machines:  []*models.Machine{staging},
+			expectErr: nil,
+		},
+		"machine patches its spec": {
+			principal: &auth.Principal{Name: "machine", Role: auth.RoleMachine, MAC: staging.MAC},
+			op:        auth.OperationPatchSpec,
+			machines:  []*models.Machine{staging},
+			expectErr: nil,
+		},
+		"machine patches the spec of another machine": {
+			principal: &auth.Principal{Name: "machine", Role: auth.RoleMachine, MAC: staging.MAC},
+			op:        auth.OperationPatchSpec,
+			machines:  []*models.Machine{prod},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"machine writes itself": {
+			principal: &auth.Principal{Name: "machine", Role: auth.RoleMachine, MAC: staging.MAC},
+			op:        auth.OperationWrite,
+			machines:  []*models.Machine{staging},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"unknown role": {
+			principal: &auth.Principal{Name: "unknown", Role: "root"},
+			op:        auth.OperationRead,
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			if tc.principal != nil {
+				ctx = auth.NewContext(ctx, tc.principal)
+			}
+			err := auth.Authorize(ctx, tc.op, tc.machines...)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	machines := []*models.Machine{staging, prod}
+	testCases := map[string]struct {
+		principal *auth.Principal
+		expect    []*models.Machine
+	}{
+		"no principal": {
+			principal: nil,
+			expect:    machines,
+		},
+		"no scope": {
+			principal: &auth.Principal{Name: "viewer", Role: auth.RoleViewer},
+			expect:    machines,
+		},
+		"scoped": {
+			principal: &auth.Principal{Name: "viewer", Role: auth.RoleViewer, Scope: map[string]string{"env": "staging"}},
+			expect:    []*models.Machine{staging},
+		},
+		"nothing in scope": {
+			principal: &auth.Principal{Name: "viewer", Role: auth.RoleViewer, Scope: map[string]string{"env": "dev"}},
+			expect:    nil,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			if tc.principal != nil {
+				ctx = auth.NewContext(ctx, tc.principal)
+			}
+			actual := auth.Filter(ctx, machines)
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Expect: %v, Actual: %v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestPrincipalFromToken(t *testing.T) {
+	legacy := auth.PrincipalFromToken(&models.Token{Name: "legacy"})
+	if legacy.Role != auth.RoleAdmin {
+		t.Errorf("The tokens without the role must be admin. Actual: %s", legacy.Role)
+	}
+	viewer := auth.PrincipalFromToken(&models.Token{Name: "viewer", Role: auth.RoleViewer})
+	if viewer.Role != auth.RoleViewer {
+		t.Errorf("Expect: %s, Actual: %s", auth.RoleViewer, viewer.Role)
+	}
+	// The role of the token bound to a machine is ignored.
+	machine := auth.PrincipalFromToken(&models.Token{Name: "machine", Role: auth.RoleAdmin, Scope: map[string]string{"env": "staging"}, MAC: staging.MAC})
+	if machine.Role != auth.RoleMachine || machine.MAC != staging.MAC || len(machine.Scope) != 0 {
+		t.Errorf("The token bound to the machine must be %s. Actual: %v", auth.RoleMachine, machine)
+	}
+}
@@ -0,0 +1,119 @@
+// Package backup defines the archive format to save and restore the keyspace of TinyCluster.
+package backup
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// Version is the version of the archive format written by this package.
+const Version = 1
+
+// Entry is a key-value pair in the archive.
+type Entry struct {
+	// Key is relative to the prefix of the archive.
+	Key string `json:"key"`
+	// Value is the raw value. It is encoded in base64 in the file.
+	Value []byte `json:"value"`
+}
+
+// Archive is a snapshot of all key-value pairs under the prefix.
+type Archive struct {
+	// Version is the version of the archive format.
+	Version int `json:"version"`
+	// Prefix is the prefix of the keys when the snapshot was taken.
+	Prefix string `json:"prefix"`
+	// Revision is the revision of the datastore when the snapshot was taken.
+	Revision int64 `json:"revision"`
+	// CreatedAt is a UNIX time when the snapshot was taken.
+	CreatedAt int64 `json:"created_at"`
+	// Checksum is the SHA-256 hash of the entries, written in hex.
+	Checksum string `json:"checksum"`
+	// Entries is the list of the key-value pairs.
+	Entries []Entry `json:"entries"`
+}
+
+// ConflictPolicy decides what to do if the key to be restored already exists.
+type ConflictPolicy string
+
+const (
+	// ConflictFail aborts the restore before writing anything.
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictSkip keeps the existing value.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing value.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// ParseConflictPolicy returns the ConflictPolicy whose name is matched with the given one.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(name)); policy {
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+		return policy, nil
+	default:
+		return "", xerrors.Errorf("unknown conflict policy '%s' %w", name, tcErr.ErrInvalidArgument)
+	}
+}
+
+// RestoreResult is the result of the restore.
+type RestoreResult struct {
+	// Written is the number of the keys written.
+	Written int `json:"written"`
+	// Skipped is the number of the keys skipped because they already existed.
+	Skipped int `json:"skipped"`
+}
+
+func checksum(entries []Entry) (string, error) {
+	hash := sha256.New()
+	if err := json.NewEncoder(hash).Encode(entries); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Write writes the archive into w with the checksum of its entries.
+func Write(w io.Writer, archive *Archive) error {
+	sum, err := checksum(archive.Entries)
+	if err != nil {
+		return err
+	}
+	archive.Version = Version
+	archive.Checksum = sum
+	gw := gzip.NewWriter(w)
+	if err := json.NewEncoder(gw).Encode(archive); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// Read reads the archive from r and verifies its version and checksum.
+func Read(r io.Reader) (*Archive, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, xerrors.Errorf("not a backup archive: %v %w", err, tcErr.ErrInvalidArgument)
+	}
+	defer gr.Close()
+	archive := new(Archive)
+	if err := json.NewDecoder(gr).Decode(archive); err != nil {
+		return nil, xerrors.Errorf("malformed backup archive: %v %w", err, tcErr.ErrInvalidArgument)
+	}
+	if archive.Version != Version {
+		return nil, xerrors.Errorf("unsupported archive version %d %w", archive.Version, tcErr.ErrInvalidArgument)
+	}
+	sum, err := checksum(archive.Entries)
+	if err != nil {
+		return nil, err
+	}
+	if sum != archive.Checksum {
+		return nil, xerrors.Errorf("checksum mismatch, the archive may be corrupted %w", tcErr.ErrInvalidArgument)
+	}
+	return archive, nil
+}
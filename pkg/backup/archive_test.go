@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+func writeRawArchive(t *testing.T, archive *Archive) *bytes.Buffer {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gw).Encode(archive); err != nil {
+		t.Fatalf("Failed to encode the archive due to %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("Failed to close the writer due to %v", err)
+	}
+	return &buf
+}
+
+func TestWriteRead(t *testing.T) {
+	archive := &Archive{
+		Prefix:    "/tiny-cluster",
+		Revision:  10,
+		CreatedAt: 1600000000,
+		Entries: []Entry{
+			{Key: "machines/v1/mac1", Value: []byte(`{"mac":"mac1"}`)},
+			{Key: "machines/v1/mac2", Value: []byte{0x00, 0xff}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, archive); err != nil {
+		t.Fatalf("Failed to write due to %v", err)
+	}
+	actual, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Failed to read due to %v", err)
+	}
+	if !reflect.DeepEqual(actual, archive) {
+		t.Errorf("Expect: %#v, Actual: %#v", archive, actual)
+	}
+}
+
+func TestRead(t *testing.T) {
+	valid := &Archive{
+		Entries: []Entry{{Key: "a", Value: []byte("1")}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, valid); err != nil {
+		t.Fatalf("Failed to write due to %v", err)
+	}
+	tampered := *valid
+	tampered.Entries = []Entry{{Key: "a", Value: []byte("2")}}
+	unsupported := *valid
+	unsupported.Version = Version + 1
+	cases := map[string]struct {
+		input *bytes.Buffer
+	}{
+		"not gzip": {
+			input: bytes.NewBufferString("plain text"),
+		},
+		"tampered": {
+			input: writeRawArchive(t, &tampered),
+		},
+		"unsupported version": {
+			input: writeRawArchive(t, &unsupported),
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			_, err := Read(tc.input)
+			if !xerrors.Is(err, tcErr.ErrInvalidArgument) {
+				t.Errorf("Expect: %v, Actual: %v", tcErr.ErrInvalidArgument, err)
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, name := range []string{"fail", "SKIP", "overwrite"} {
+		if _, err := ParseConflictPolicy(name); err != nil {
+			t.Errorf("'%s' must be accepted but %v", name, err)
+		}
+	}
+	if _, err := ParseConflictPolicy("merge"); err == nil {
+		t.Error("Unknown policy must be rejected")
+	}
+}
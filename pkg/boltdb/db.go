@@ -0,0 +1,110 @@
+// Package boltdb implements the repositories on the embedded bbolt database.
+// It is intended for the single node deployment which does not run etcd.
+package boltdb
+
+import (
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+// openTimeout is the time to wait for the lock of the database file held by other processes.
+const openTimeout = time.Second
+
+var (
+	// metaBucket holds the revision of the database.
+	metaBucket = []byte("meta")
+	// machineBucket holds the machines by MAC.
+	machineBucket = []byte("machines/v1")
+	// machineHistoryBucket holds a nested bucket of the histories for each machine.
+	machineHistoryBucket = []byte("history/machines/v1")
+	// heartbeatBucket holds the last check-ins of the machines by MAC.
+	heartbeatBucket = []byte("heartbeats/v1")
+	// tokenBucket holds the API tokens by ID, which are shared among the namespaces.
+	tokenBucket = []byte("tokens/v1")
+	// webhookBucket holds the webhooks of all namespaces by ID.
+	webhookBucket = []byte("webhooks/v1")
+	// webhookDeliveryBucket holds a nested bucket of the deliveries for each webhook.
+	webhookDeliveryBucket = []byte("history/webhooks/v1")
+	// namespaceBucket holds a nested bucket for each namespace other than the default one,
+	// which has its own machineBucket and machineHistoryBucket.
+	// The default namespace uses the top level buckets.
+	namespaceBucket = []byte("clusters")
+)
+
+// bucketHolder is *bolt.Tx or *bolt.Bucket.
+type bucketHolder interface {
+	Bucket(name []byte) *bolt.Bucket
+	CreateBucketIfNotExists(name []byte) (*bolt.Bucket, error)
+}
+
+// namespaceRoot returns what holds the buckets of the namespace, creating it if create is true.
+// It returns false if the namespace has no data and create is false.
+func namespaceRoot(tx *bolt.Tx, ns string, create bool) (bucketHolder, bool, error) {
+	if ns == namespace.Default {
+		return tx, true, nil
+	}
+	namespaces := tx.Bucket(namespaceBucket)
+	if !create {
+		root := namespaces.Bucket([]byte(ns))
+		return root, root != nil, nil
+	}
+	root, err := namespaces.CreateBucketIfNotExists([]byte(ns))
+	if err != nil {
+		return nil, false, err
+	}
+	for _, name := range [][]byte{machineBucket, machineHistoryBucket, heartbeatBucket} {
+		if _, err := root.CreateBucketIfNotExists(name); err != nil {
+			return nil, false, err
+		}
+	}
+	return root, true, nil
+}
+
+// DB is the database file shared among the repositories.
+type DB struct {
+	db *bolt.DB
+	// mu serializes the writes and the notifications of them,
+	// so that the watchers receive the events in the order of the revisions.
+	mu            sync.Mutex
+	machineEvents *watch.Hub
+}
+
+// Open opens the database file, creating it if it does not exist.
+// The file can not be opened by other processes until closed.
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, xerrors.Errorf("'%s' is locked by another process %w", path, tcErr.ErrUnavailable)
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to open '%s': %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{metaBucket, machineBucket, machineHistoryBucket, heartbeatBucket, tokenBucket, webhookBucket, webhookDeliveryBucket, namespaceBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, xerrors.Errorf("Failed to initialize '%s': %w", path, err)
+	}
+	return &DB{
+		db:            db,
+		machineEvents: watch.NewHub(),
+	}, nil
+}
+
+// Close closes the database file.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
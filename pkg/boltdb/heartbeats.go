@@ -0,0 +1,128 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// beat is the last check-in of a machine stored in heartbeatBucket.
+type beat struct {
+	LastSeen  int64 `json:"last_seen"`
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+type heartbeatRepoImpl struct {
+	*DB
+}
+
+func (r *heartbeatRepoImpl) Heartbeat(ctx context.Context, mac string, ttl time.Duration) error {
+	now := time.Now()
+	valueByte, err := json.Marshal(&beat{
+		LastSeen:  now.Unix(),
+		ExpiresAt: now.Add(ttl).UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		root, _, err := namespaceRoot(tx, namespace.FromContext(ctx), true)
+		if err != nil {
+			return err
+		}
+		return root.Bucket(heartbeatBucket).Put([]byte(mac), valueByte)
+	})
+}
+
+func (r *heartbeatRepoImpl) GetHeartbeats(ctx context.Context) ([]*models.Heartbeat, error) {
+	now := time.Now().UnixNano()
+	var heartbeats []*models.Heartbeat
+	err := r.db.View(func(tx *bolt.Tx) error {
+		root, ok, err := namespaceRoot(tx, namespace.FromContext(ctx), false)
+		if err != nil || !ok {
+			return err
+		}
+		bucket := root.Bucket(heartbeatBucket)
+		if bucket == nil {
+			// The namespace was created before the heartbeats were introduced.
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			b := new(beat)
+			if err := json.Unmarshal(v, b); err != nil {
+				return err
+			}
+			heartbeats = append(heartbeats, &models.Heartbeat{
+				MAC:       string(k),
+				LastSeen:  b.LastSeen,
+				Reachable: now < b.ExpiresAt,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return heartbeats, nil
+}
+
+func (r *heartbeatRepoImpl) DeleteHeartbeats(ctx context.Context, macs []string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		root, ok, err := namespaceRoot(tx, namespace.FromContext(ctx), false)
+		if err != nil || !ok {
+			return err
+		}
+		bucket := root.Bucket(heartbeatBucket)
+		if bucket == nil {
+			return nil
+		}
+		for _, mac := range macs {
+			if err := bucket.Delete([]byte(mac)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *heartbeatRepoImpl) GetHeartbeatNamespaces(ctx context.Context) ([]string, error) {
+	var namespaces []string
+	err := r.db.View(func(tx *bolt.Tx) error {
+		if hasHeartbeats(tx) {
+			namespaces = append(namespaces, namespace.Default)
+		}
+		return tx.Bucket(namespaceBucket).ForEach(func(name, _ []byte) error {
+			if root := tx.Bucket(namespaceBucket).Bucket(name); root != nil && hasHeartbeats(root) {
+				namespaces = append(namespaces, string(name))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return namespaces, nil
+}
+
+// hasHeartbeats returns true if any machine has checked in the namespace whose buckets are held by root.
+func hasHeartbeats(root bucketHolder) bool {
+	bucket := root.Bucket(heartbeatBucket)
+	if bucket == nil {
+		return false
+	}
+	key, _ := bucket.Cursor().First()
+	return key != nil
+}
+
+// NewHeartbeatRepository returns the repository which keeps the heartbeats in the database file.
+func NewHeartbeatRepository(db *DB) repo.HeartbeatRepository {
+	return &heartbeatRepoImpl{
+		DB: db,
+	}
+}
@@ -0,0 +1,35 @@
+package boltdb_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/boltdb"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func TestHeartbeatRepository_deletedMachine(t *testing.T) {
+	db := openTemp(t)
+	ctx := context.Background()
+	machine := &models.Machine{MAC: "heartbeat-mac1", Name: "heartbeat1"}
+	machines := boltdb.NewMachineRepository(db)
+	heartbeats := boltdb.NewHeartbeatRepository(db)
+	if err := machines.RegisterMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	if err := heartbeats.Heartbeat(ctx, machine.MAC, time.Minute); err != nil {
+		t.Fatalf("Failed to record the heartbeat due to %v", err)
+	}
+	if err := machines.DeleteMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to delete the machine due to %v", err)
+	}
+	// The check-ins are deleted together with the machine.
+	actual, err := heartbeats.GetHeartbeats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the heartbeats due to %v", err)
+	}
+	if len(actual) != 0 {
+		t.Errorf("Expect: no heartbeats, Actual: %v", actual)
+	}
+}
@@ -0,0 +1,347 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+type machineRepoImpl struct {
+	*DB
+}
+
+// revisionKey returns the key of the history, which keeps the histories sorted by revision.
+func revisionKey(revision int64) []byte {
+	return []byte(fmt.Sprintf("%020d", revision))
+}
+
+// machineTxn is a write transaction which records the changes of the machines.
+type machineTxn struct {
+	ctx context.Context
+	tx  *bolt.Tx
+	// ns is the namespace of the changes and root holds its buckets.
+	ns   string
+	root bucketHolder
+	// revision is allocated at the first change in the transaction.
+	revision int64
+	events   []*models.MachineEvent
+}
+
+func (t *machineTxn) getMachine(mac string) (*models.Machine, error) {
+	return getMachine(t.root, mac)
+}
+
+// putMachine writes the machine and its history. after is nil to delete the machine.
+func (t *machineTxn) putMachine(mac string, operation string, before *models.Machine, after *models.Machine) error {
+	if t.revision == 0 {
+		revision, err := t.tx.Bucket(metaBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		t.revision = int64(revision)
+	}
+	event := &models.MachineEvent{
+		Type:      models.EventPut,
+		Namespace: t.ns,
+		Revision:  t.revision,
+		Machine:   after,
+	}
+	machines := t.root.Bucket(machineBucket)
+	if after == nil {
+		if err := machines.Delete([]byte(mac)); err != nil {
+			return err
+		}
+		// The check-ins of the deleted machine would be left forever otherwise.
+		if beats := t.root.Bucket(heartbeatBucket); beats != nil {
+			if err := beats.Delete([]byte(mac)); err != nil {
+				return err
+			}
+		}
+		event.Type = models.EventDelete
+		event.Machine = before
+	} else {
+		valueByte, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		if err := machines.Put([]byte(mac), valueByte); err != nil {
+			return err
+		}
+	}
+	histories, err := t.root.Bucket(machineHistoryBucket).CreateBucketIfNotExists([]byte(mac))
+	if err != nil {
+		return err
+	}
+	history := &models.MachineHistory{
+		Revision:  t.revision,
+		MAC:       mac,
+		Actor:     actor.FromContext(t.ctx),
+		Timestamp: time.Now().Unix(),
+		Operation: operation,
+		Machine:   after,
+		Changes:   models.DiffMachines(before, after),
+	}
+	valueByte, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	if err := histories.Put(revisionKey(t.revision), valueByte); err != nil {
+		return err
+	}
+	t.events = append(t.events, event)
+	return nil
+}
+
+// update runs fn in a write transaction in the namespace of ctx and notifies the changes after committed.
+func (r *machineRepoImpl) update(ctx context.Context, fn func(t *machineTxn) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := &machineTxn{
+		ctx: ctx,
+		ns:  namespace.FromContext(ctx),
+	}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		root, _, err := namespaceRoot(tx, t.ns, true)
+		if err != nil {
+			return err
+		}
+		t.tx = tx
+		t.root = root
+		return fn(t)
+	})
+	if err != nil {
+		return err
+	}
+	r.machineEvents.Publish(t.events...)
+	return nil
+}
+
+// view runs fn in a read transaction with the buckets of the namespace of ctx.
+// fn is not called if the namespace has no data.
+func (r *machineRepoImpl) view(ctx context.Context, fn func(root bucketHolder) error) error {
+	return r.db.View(func(tx *bolt.Tx) error {
+		root, ok, err := namespaceRoot(tx, namespace.FromContext(ctx), false)
+		if err != nil || !ok {
+			return err
+		}
+		return fn(root)
+	})
+}
+
+func getMachine(root bucketHolder, mac string) (*models.Machine, error) {
+	valueByte := root.Bucket(machineBucket).Get([]byte(mac))
+	if valueByte == nil {
+		return nil, nil
+	}
+	machine := new(models.Machine)
+	if err := json.Unmarshal(valueByte, machine); err != nil {
+		return nil, err
+	}
+	return machine, nil
+}
+
+func getMachineHistory(root bucketHolder, mac string, revision int64) (*models.MachineHistory, error) {
+	histories := root.Bucket(machineHistoryBucket).Bucket([]byte(mac))
+	if histories == nil {
+		return nil, tcErr.ErrNotFound
+	}
+	valueByte := histories.Get(revisionKey(revision))
+	if valueByte == nil {
+		return nil, tcErr.ErrNotFound
+	}
+	history := new(models.MachineHistory)
+	if err := json.Unmarshal(valueByte, history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *machineRepoImpl) GetMachines(ctx context.Context) ([]*models.Machine, error) {
+	var machines []*models.Machine
+	err := r.view(ctx, func(root bucketHolder) error {
+		// Keys are iterated in the byte order like etcd.
+		return root.Bucket(machineBucket).ForEach(func(_, v []byte) error {
+			machine := new(models.Machine)
+			if err := json.Unmarshal(v, machine); err != nil {
+				return err
+			}
+			machines = append(machines, machine)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return machines, nil
+}
+
+func (r *machineRepoImpl) GetMachine(ctx context.Context, mac string) (*models.Machine, error) {
+	var machine *models.Machine
+	err := r.view(ctx, func(root bucketHolder) error {
+		var err error
+		machine, err = getMachine(root, mac)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if machine == nil {
+		return nil, tcErr.Wrap("GetMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+	}
+	return machine, nil
+}
+
+// mutate applies the mutation to the machine whose MAC is mac and appends the history of it atomically.
+// current is nil if the machine does not exist, and returning nil deletes the machine.
+func (r *machineRepoImpl) mutate(ctx context.Context, mac string, operation string, mutation func(t *machineTxn, current *models.Machine) (*models.Machine, error)) (*models.Machine, error) {
+	var next *models.Machine
+	err := r.update(ctx, func(t *machineTxn) error {
+		current, err := t.getMachine(mac)
+		if err != nil {
+			return err
+		}
+		var before *models.Machine
+		if current != nil {
+			copied := *current
+			before = &copied
+		}
+		next, err = mutation(t, current)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(before, next) {
+			return nil
+		}
+		return t.putMachine(mac, operation, before, next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (r *machineRepoImpl) RegisterMachine(ctx context.Context, machine *models.Machine) error {
+	_, err := r.mutate(ctx, machine.MAC, models.OperationRegister, func(_ *machineTxn, current *models.Machine) (*models.Machine, error) {
+		if current != nil {
+			return nil, tcErr.Wrap("RegisterMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrAlreadyExists)
+		}
+		registered := *machine
+		return &registered, nil
+	})
+	return err
+}
+
+func (r *machineRepoImpl) UpdateMachine(ctx context.Context, machine *models.Machine) error {
+	_, err := r.mutate(ctx, machine.MAC, models.OperationUpdate, func(_ *machineTxn, current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("UpdateMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrNotFound)
+		}
+		updated := *machine
+		return &updated, nil
+	})
+	return err
+}
+
+func (r *machineRepoImpl) PatchMachine(ctx context.Context, mac string, patch repo.MachinePatchFunc) (*models.Machine, error) {
+	return r.mutate(ctx, mac, models.OperationUpdate, func(_ *machineTxn, current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("PatchMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+		}
+		machine := *current
+		if err := patch(&machine); err != nil {
+			return nil, err
+		}
+		if machine.MAC != current.MAC {
+			// MAC is the key. It can not be changed by patch.
+			return nil, tcErr.Wrap("PatchMachine", tcErr.KindMachine, mac, tcErr.ErrInvalidArgument)
+		}
+		return &machine, nil
+	})
+}
+
+func (r *machineRepoImpl) DeleteMachine(ctx context.Context, machine *models.Machine) error {
+	_, err := r.mutate(ctx, machine.MAC, models.OperationDelete, func(_ *machineTxn, current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("DeleteMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrNotFound)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (r *machineRepoImpl) GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error) {
+	var histories []*models.MachineHistory
+	err := r.view(ctx, func(root bucketHolder) error {
+		bucket := root.Bucket(machineHistoryBucket).Bucket([]byte(mac))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, v []byte) error {
+			history := new(models.MachineHistory)
+			if err := json.Unmarshal(v, history); err != nil {
+				return err
+			}
+			histories = append(histories, history)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func (r *machineRepoImpl) RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error) {
+	return r.mutate(ctx, mac, models.OperationRevert, func(t *machineTxn, _ *models.Machine) (*models.Machine, error) {
+		history, err := getMachineHistory(t.root, mac, revision)
+		if err != nil {
+			return nil, err
+		}
+		return history.Machine, nil
+	})
+}
+
+func (r *machineRepoImpl) ImportMachines(ctx context.Context, machines []*models.Machine) error {
+	// All machines are written in a transaction, so nothing is written on failure.
+	return r.update(ctx, func(t *machineTxn) error {
+		for _, machine := range machines {
+			current, err := t.getMachine(machine.MAC)
+			if err != nil {
+				return err
+			}
+			if reflect.DeepEqual(current, machine) {
+				continue
+			}
+			operation := models.OperationUpdate
+			if current == nil {
+				operation = models.OperationRegister
+			}
+			imported := *machine
+			if err := t.putMachine(machine.MAC, operation, current, &imported); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *machineRepoImpl) WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error) {
+	return r.machineEvents.Subscribe(ctx), nil
+}
+
+// NewMachineRepository returns the repository which keeps the machines in the database file.
+func NewMachineRepository(db *DB) repo.MachineRepository {
+	return &machineRepoImpl{
+		DB: db,
+	}
+}
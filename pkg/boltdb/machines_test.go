@@ -0,0 +1,49 @@
+package boltdb_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/boltdb"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func TestMachineRepository_reopen(t *testing.T) {
+	path := filepath.Join(tempDir(t), "tc.db")
+	ctx := context.Background()
+	machine := &models.Machine{MAC: "mac1", Name: "machine1"}
+
+	db := open(t, path)
+	if err := boltdb.NewMachineRepository(db).RegisterMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	if _, err := boltdb.Open(path); err == nil {
+		t.Fatal("The database must be locked while opened")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close the database due to %v", err)
+	}
+
+	db = open(t, path)
+	defer db.Close()
+	r := boltdb.NewMachineRepository(db)
+	machines, err := r.GetMachines(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the machines due to %v", err)
+	}
+	if !reflect.DeepEqual(machines, []*models.Machine{machine}) {
+		t.Errorf("Expect: %v, Actual: %v", []*models.Machine{machine}, machines)
+	}
+	if err := r.DeleteMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to delete the machine due to %v", err)
+	}
+	histories, err := r.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get the histories due to %v", err)
+	}
+	if len(histories) != 2 || histories[1].Revision <= histories[0].Revision {
+		t.Errorf("Revisions must continue after reopened. Actual: %v", histories)
+	}
+}
@@ -0,0 +1,68 @@
+package boltdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/boltdb"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/repositories/repotest"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tc-boltdb")
+	if err != nil {
+		t.Fatalf("Failed to create the directory due to %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func open(t *testing.T, path string) *boltdb.DB {
+	t.Helper()
+	db, err := boltdb.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open the database due to %v", err)
+	}
+	return db
+}
+
+// openTemp opens the new database which is closed and removed when t finishes.
+func openTemp(t *testing.T) *boltdb.DB {
+	t.Helper()
+	db := open(t, filepath.Join(tempDir(t), "tc.db"))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestRepositories runs the conformance suites of the repositories against the ones backed by bolt.
+func TestRepositories(t *testing.T) {
+	suites := map[string]func(t *testing.T){
+		"machines": func(t *testing.T) {
+			repotest.TestMachineRepository(t, func(t *testing.T) repo.MachineRepository {
+				return boltdb.NewMachineRepository(openTemp(t))
+			})
+		},
+		"heartbeats": func(t *testing.T) {
+			repotest.TestHeartbeatRepository(t, func(t *testing.T) repo.HeartbeatRepository {
+				return boltdb.NewHeartbeatRepository(openTemp(t))
+			})
+		},
+		"tokens": func(t *testing.T) {
+			repotest.TestTokenRepository(t, func(t *testing.T) repo.TokenRepository {
+				return boltdb.NewTokenRepository(openTemp(t))
+			})
+		},
+		"webhooks": func(t *testing.T) {
+			repotest.TestWebhookRepository(t, func(t *testing.T) repo.WebhookRepository {
+				return boltdb.NewWebhookRepository(openTemp(t))
+			})
+		},
+	}
+	for name, suite := range suites {
+		t.Run(name, suite)
+	}
+}
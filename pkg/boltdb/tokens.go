@@ -0,0 +1,80 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+type tokenRepoImpl struct {
+	*DB
+}
+
+func (r *tokenRepoImpl) CreateToken(ctx context.Context, token *models.Token) error {
+	valueByte, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokenBucket)
+		if bucket.Get([]byte(token.ID)) != nil {
+			return tcErr.Wrap("CreateToken", tcErr.KindToken, token.ID, tcErr.ErrAlreadyExists)
+		}
+		return bucket.Put([]byte(token.ID), valueByte)
+	})
+}
+
+func (r *tokenRepoImpl) GetToken(ctx context.Context, id string) (*models.Token, error) {
+	token := new(models.Token)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		valueByte := tx.Bucket(tokenBucket).Get([]byte(id))
+		if valueByte == nil {
+			return tcErr.Wrap("GetToken", tcErr.KindToken, id, tcErr.ErrNotFound)
+		}
+		return json.Unmarshal(valueByte, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *tokenRepoImpl) GetTokens(ctx context.Context) ([]*models.Token, error) {
+	var tokens []*models.Token
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokenBucket).ForEach(func(k, v []byte) error {
+			token := new(models.Token)
+			if err := json.Unmarshal(v, token); err != nil {
+				return err
+			}
+			tokens = append(tokens, token)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *tokenRepoImpl) DeleteToken(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(tokenBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return tcErr.Wrap("DeleteToken", tcErr.KindToken, id, tcErr.ErrNotFound)
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// NewTokenRepository returns the repository which stores the tokens in the database file.
+func NewTokenRepository(db *DB) repo.TokenRepository {
+	return &tokenRepoImpl{
+		DB: db,
+	}
+}
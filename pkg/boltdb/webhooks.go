@@ -0,0 +1,132 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+type webhookRepoImpl struct {
+	*DB
+}
+
+func (r *webhookRepoImpl) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	valueByte, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookBucket)
+		if bucket.Get([]byte(webhook.ID)) != nil {
+			return tcErr.Wrap("CreateWebhook", tcErr.KindWebhook, webhook.ID, tcErr.ErrAlreadyExists)
+		}
+		return bucket.Put([]byte(webhook.ID), valueByte)
+	})
+}
+
+func (r *webhookRepoImpl) GetWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(webhookBucket).ForEach(func(k, v []byte) error {
+			webhook := new(models.Webhook)
+			if err := json.Unmarshal(v, webhook); err != nil {
+				return err
+			}
+			webhooks = append(webhooks, webhook)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepoImpl) DeleteWebhook(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(webhookBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return tcErr.Wrap("DeleteWebhook", tcErr.KindWebhook, id, tcErr.ErrNotFound)
+		}
+		if err := bucket.Delete([]byte(id)); err != nil {
+			return err
+		}
+		deliveries := tx.Bucket(webhookDeliveryBucket)
+		if deliveries.Bucket([]byte(id)) == nil {
+			return nil
+		}
+		return deliveries.DeleteBucket([]byte(id))
+	})
+}
+
+func (r *webhookRepoImpl) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	valueByte, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(webhookBucket).Get([]byte(delivery.WebhookID)) == nil {
+			return tcErr.Wrap("AddDelivery", tcErr.KindWebhook, delivery.WebhookID, tcErr.ErrNotFound)
+		}
+		bucket, err := tx.Bucket(webhookDeliveryBucket).CreateBucketIfNotExists([]byte(delivery.WebhookID))
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(delivery.ID), valueByte); err != nil {
+			return err
+		}
+		count := 0
+		if err := bucket.ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		}); err != nil {
+			return err
+		}
+		// The oldest deliveries come first in the order of the keys.
+		cursor := bucket.Cursor()
+		for k, _ := cursor.First(); k != nil && count > repo.MaxWebhookDeliveries; k, _ = cursor.First() {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+			count--
+		}
+		return nil
+	})
+}
+
+func (r *webhookRepoImpl) GetDeliveries(ctx context.Context, id string) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := r.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(webhookBucket).Get([]byte(id)) == nil {
+			return tcErr.Wrap("GetDeliveries", tcErr.KindWebhook, id, tcErr.ErrNotFound)
+		}
+		bucket := tx.Bucket(webhookDeliveryBucket).Bucket([]byte(id))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			delivery := new(models.WebhookDelivery)
+			if err := json.Unmarshal(v, delivery); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// NewWebhookRepository returns the repository which stores the webhooks in the database file.
+func NewWebhookRepository(db *DB) repo.WebhookRepository {
+	return &webhookRepoImpl{
+		DB: db,
+	}
+}
@@ -1,17 +1,45 @@
 package boot
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/labstack/echo/v4"
 )
 
-func IPXEScriptHandler(c echo.Context) error {
-	return c.String(http.StatusOK, `#!ipxe
-set ubuntu http://tcboot:8080/boot/dists/ubuntu/20.04/
+// IPXEScript serves the iPXE script which boots the machines from the files served by bootserver.
+// The URL of bootserver can be changed while serving.
+type IPXEScript struct {
+	mu      sync.RWMutex
+	baseURL string
+}
+
+// NewIPXEScript returns the script which fetches the boot files under baseURL, such as http://tcboot:8080.
+func NewIPXEScript(baseURL string) *IPXEScript {
+	s := &IPXEScript{}
+	s.SetBaseURL(baseURL)
+	return s
+}
+
+// SetBaseURL changes the URL of bootserver advertised to the machines.
+func (s *IPXEScript) SetBaseURL(baseURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// Handler serves the script.
+func (s *IPXEScript) Handler(c echo.Context) error {
+	s.mu.RLock()
+	baseURL := s.baseURL
+	s.mu.RUnlock()
+	return c.String(http.StatusOK, fmt.Sprintf(`#!ipxe
+set ubuntu %s/boot/dists/ubuntu/20.04/
 initrd ${ubuntu}/initrd
 kernel ${ubuntu}/vmlinuz
 imgargs vmlinuz initrd=initrd boot=casper ip=dhcp url=${ubuntu}/20.04.1-live-server-amd64.iso debian-installer/language=en
 boot
-`)
+`, baseURL))
 }
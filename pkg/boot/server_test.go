@@ -0,0 +1,32 @@
+package boot_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/boot"
+)
+
+func TestIPXEScript_Handler(t *testing.T) {
+	script := boot.NewIPXEScript("http://tcboot:8080/")
+	get := func() string {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/default.ipxe", nil), rec)
+		if err := script.Handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Body.String()
+	}
+	if expect, actual := "set ubuntu http://tcboot:8080/boot/dists/ubuntu/20.04/\n", get(); !strings.Contains(actual, expect) {
+		t.Errorf("Expect: %s, Actual: %s", expect, actual)
+	}
+	script.SetBaseURL("https://boot.example.com")
+	if expect, actual := "set ubuntu https://boot.example.com/boot/dists/ubuntu/20.04/\n", get(); !strings.Contains(actual, expect) {
+		t.Errorf("Expect: %s, Actual: %s", expect, actual)
+	}
+}
@@ -0,0 +1,214 @@
+// Package config loads the settings of the commands from the config file and the environment variables.
+//
+// Each setting is a flag of the command. The config file is YAML whose keys are the names of the flags,
+// and the keys of the nested maps are joined by '-', so that "etcd: {endpoints: [...]}" sets --etcd-endpoints.
+// The environment variable of a flag is its name in upper case prefixed by EnvPrefix with '_' instead of '-',
+// such as TC_ETCD_ENDPOINTS. The flags given on the command line take precedence over the environment variables,
+// which take precedence over the config file, and the defaults of the flags are used for the rest.
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+	"sigs.k8s.io/yaml"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// EnvPrefix is the prefix of the environment variables of the flags.
+const EnvPrefix = "TC_"
+
+// Sources of the values of the flags, from the highest precedence.
+const (
+	// SourceFlag indicates that the value is given on the command line.
+	SourceFlag = "flag"
+	// SourceEnv indicates that the value is given by the environment variable.
+	SourceEnv = "env"
+	// SourceFile indicates that the value is given by the config file.
+	SourceFile = "file"
+	// SourceDefault indicates that the value is the default of the flag.
+	SourceDefault = "default"
+)
+
+// EnvName returns the environment variable of the flag, such as TC_ETCD_ENDPOINTS for etcd-endpoints.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// ReadFile returns the values in the config file at path by the names of the flags.
+func ReadFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := Parse(data)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Parse returns the values in the YAML config by the names of the flags.
+// The lists are joined by ',' as given to the slice flags.
+func Parse(data []byte) (map[string]string, error) {
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, xerrors.Errorf("the config is not a map of YAML %w", tcErr.ErrInvalidArgument)
+	}
+	values := map[string]string{}
+	v := &tcErr.ValidationError{}
+	flatten("", tree, values, v)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// flatten stores the values in tree into values with the keys joined by '-'.
+func flatten(prefix string, tree map[string]interface{}, values map[string]string, v *tcErr.ValidationError) {
+	for key, value := range tree {
+		name := key
+		if len(prefix) != 0 {
+			name = prefix + "-" + key
+		}
+		switch value := value.(type) {
+		case nil:
+			// An empty value leaves the default as it is.
+		case map[string]interface{}:
+			flatten(name, value, values, v)
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				if s, ok := scalar(item); ok {
+					items = append(items, s)
+				}
+			}
+			if len(items) != len(value) {
+				v.Add(name, "must be a list of scalars")
+				continue
+			}
+			values[name] = joinList(items)
+		default:
+			s, ok := scalar(value)
+			if !ok {
+				v.Add(name, "must be a scalar, a list or a map")
+				continue
+			}
+			values[name] = s
+		}
+	}
+}
+
+// scalar returns the string representation of the scalar decoded from the config.
+func scalar(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, true
+	case bool:
+		return strconv.FormatBool(value), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// joinList joins items in the form of the slice flags, which are parsed as a line of CSV.
+func joinList(items []string) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(items)
+	w.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// valueString returns the value of the flag in the form which can be given to Set.
+func valueString(flag *pflag.Flag) string {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		return joinList(slice.GetSlice())
+	}
+	return flag.Value.String()
+}
+
+// Load sets the flags which are not given on the command line from the environment variables,
+// and then from values of the config file. It returns the source of the value of each flag.
+// The flags set by Load are left unchanged as pflag reports, so that only the command line is copied by CopyChanged.
+// If strict is true, the values of the config file which no flag is named are reported as the errors,
+// otherwise they are ignored so that a config file can be shared among the commands.
+func Load(flags *pflag.FlagSet, environ []string, values map[string]string, strict bool) (map[string]string, error) {
+	env := map[string]string{}
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 && strings.HasPrefix(kv, EnvPrefix) {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	sources := map[string]string{}
+	v := &tcErr.ValidationError{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			sources[flag.Name] = SourceFlag
+			return
+		}
+		value, source := "", SourceDefault
+		if envValue, ok := env[EnvName(flag.Name)]; ok {
+			value, source = envValue, SourceEnv
+		} else if fileValue, ok := values[flag.Name]; ok {
+			value, source = fileValue, SourceFile
+		}
+		sources[flag.Name] = source
+		if source == SourceDefault {
+			return
+		}
+		if err := flags.Set(flag.Name, value); err != nil {
+			field := flag.Name
+			if source == SourceEnv {
+				field = EnvName(flag.Name)
+			}
+			v.Add(field, fmt.Sprintf("invalid value '%s': %v", value, err))
+		}
+		flag.Changed = false
+	})
+	if strict {
+		var unknown []string
+		for name := range values {
+			if flags.Lookup(name) == nil {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			v.Add(name, "unknown setting")
+		}
+	}
+	return sources, v.Err()
+}
+
+// CopyChanged sets the flags of dst which are given on the command line of src, so that they take precedence again
+// when the settings are loaded into dst.
+func CopyChanged(dst, src *pflag.FlagSet) error {
+	var err error
+	// Visit can not be used, since it visits the flags set by Load as well.
+	src.VisitAll(func(flag *pflag.Flag) {
+		if err == nil && flag.Changed && dst.Lookup(flag.Name) != nil {
+			err = dst.Set(flag.Name, valueString(flag))
+		}
+	})
+	return err
+}
+
+// Values returns the current values of the flags in the form which can be given to the flags.
+func Values(flags *pflag.FlagSet) map[string]string {
+	values := map[string]string{}
+	flags.VisitAll(func(flag *pflag.Flag) {
+		values[flag.Name] = valueString(flag)
+	})
+	return values
+}
@@ -0,0 +1,164 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/config"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		data      string
+		expect    map[string]string
+		expectErr error
+	}{
+		"flat": {
+			data:   "port: 8080\nstore: bolt\nauth: true\n",
+			expect: map[string]string{"port": "8080", "store": "bolt", "auth": "true"},
+		},
+		"nested": {
+			data: "etcd:\n  endpoints:\n    - https://etcd1:2379\n    - https://etcd2:2379\n  prefix: /tc\ndns:\n  ttl: 5m\n",
+			expect: map[string]string{
+				"etcd-endpoints": "https://etcd1:2379,https://etcd2:2379",
+				"etcd-prefix":    "/tc",
+				"dns-ttl":        "5m",
+			},
+		},
+		"empty value": {
+			data:   "dns-upstream:\n",
+			expect: map[string]string{},
+		},
+		"list of maps": {
+			data:      "etcd-endpoints:\n  - host: etcd1\n",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"not a map": {
+			data:      "- port\n",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			values, err := config.Parse([]byte(tc.data))
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if tc.expectErr == nil && !reflect.DeepEqual(values, tc.expect) {
+				t.Errorf("Expect: %v, Actual: %v", tc.expect, values)
+			}
+		})
+	}
+}
+
+// testFlags is the flags of a command.
+type testFlags struct {
+	port      int
+	endpoints []string
+	ttl       time.Duration
+	advertise string
+}
+
+func newTestFlags() (*pflag.FlagSet, *testFlags) {
+	f := &testFlags{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.IntVar(&f.port, "port", 8080, "")
+	flags.StringSliceVar(&f.endpoints, "etcd-endpoints", []string{"http://127.0.0.1:2379"}, "")
+	flags.DurationVar(&f.ttl, "dns-ttl", time.Minute, "")
+	flags.StringVar(&f.advertise, "advertise-url", "http://tcboot:8080", "")
+	return flags, f
+}
+
+func TestLoad(t *testing.T) {
+	testCases := map[string]struct {
+		args          []string
+		environ       []string
+		values        map[string]string
+		strict        bool
+		expect        testFlags
+		expectSources map[string]string
+		expectErr     error
+	}{
+		"defaults": {
+			expect: testFlags{port: 8080, endpoints: []string{"http://127.0.0.1:2379"}, ttl: time.Minute, advertise: "http://tcboot:8080"},
+			expectSources: map[string]string{
+				"port": config.SourceDefault, "etcd-endpoints": config.SourceDefault, "dns-ttl": config.SourceDefault, "advertise-url": config.SourceDefault,
+			},
+		},
+		"precedence": {
+			args:    []string{"--port=80"},
+			environ: []string{"TC_PORT=8000", "TC_ETCD_ENDPOINTS=http://etcd1:2379,http://etcd2:2379", "HOME=/root"},
+			values:  map[string]string{"port": "8888", "etcd-endpoints": "http://etcd3:2379", "dns-ttl": "5m"},
+			expect:  testFlags{port: 80, endpoints: []string{"http://etcd1:2379", "http://etcd2:2379"}, ttl: 5 * time.Minute, advertise: "http://tcboot:8080"},
+			expectSources: map[string]string{
+				"port": config.SourceFlag, "etcd-endpoints": config.SourceEnv, "dns-ttl": config.SourceFile, "advertise-url": config.SourceDefault,
+			},
+		},
+		"invalid env": {
+			environ:   []string{"TC_DNS_TTL=forever"},
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"unknown setting ignored": {
+			values: map[string]string{"grpc-port": "9090"},
+			expect: testFlags{port: 8080, endpoints: []string{"http://127.0.0.1:2379"}, ttl: time.Minute, advertise: "http://tcboot:8080"},
+		},
+		"unknown setting in strict": {
+			values:    map[string]string{"grpc-port": "9090"},
+			strict:    true,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			flags, f := newTestFlags()
+			if err := flags.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+			sources, err := config.Load(flags, tc.environ, tc.values, tc.strict)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if tc.expectErr != nil {
+				return
+			}
+			if !reflect.DeepEqual(*f, tc.expect) {
+				t.Errorf("Expect: %v, Actual: %v", tc.expect, *f)
+			}
+			if tc.expectSources != nil && !reflect.DeepEqual(sources, tc.expectSources) {
+				t.Errorf("Expect: %v, Actual: %v", tc.expectSources, sources)
+			}
+		})
+	}
+}
+
+func TestCopyChanged(t *testing.T) {
+	src, _ := newTestFlags()
+	if err := src.Parse([]string{"--port=80", "--etcd-endpoints=http://etcd1:2379,http://etcd2:2379"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Load(src, []string{"TC_DNS_TTL=5m"}, map[string]string{"advertise-url": "http://boot:8080"}, true); err != nil {
+		t.Fatal(err)
+	}
+	dst, f := newTestFlags()
+	if err := config.CopyChanged(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	// The command line takes precedence over the environment variables again,
+	// and the others are loaded again from the latest environment variables and config file.
+	if _, err := config.Load(dst, []string{"TC_PORT=8000", "TC_ADVERTISE_URL=https://boot.example.com"}, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	expect := testFlags{port: 80, endpoints: []string{"http://etcd1:2379", "http://etcd2:2379"}, ttl: time.Minute, advertise: "https://boot.example.com"}
+	if !reflect.DeepEqual(*f, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, *f)
+	}
+}
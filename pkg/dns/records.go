@@ -0,0 +1,122 @@
+// Package dns serves the names of the machines as an authoritative DNS server of the cluster domain.
+package dns
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// Records is the A and PTR records of the machines, which are updated by the changes of the machines.
+type Records struct {
+	domain string
+
+	mu       sync.RWMutex
+	machines map[string]*models.Machine
+	// byName maps the FQDN of each machine to its IPv4 address.
+	byName map[string]net.IP
+	// byAddr maps the IPv4 address of each machine to its FQDN.
+	byAddr map[string]string
+	// serial is the serial number of the zone, which is incremented on each change.
+	serial uint32
+}
+
+// NewRecords returns the records of the machines under domain such as "cluster.internal".
+func NewRecords(domain string) *Records {
+	return &Records{
+		domain:   fqdn(domain),
+		machines: map[string]*models.Machine{},
+		byName:   map[string]net.IP{},
+		byAddr:   map[string]string{},
+	}
+}
+
+// fqdn returns the lower case fully qualified domain name which ends with '.'.
+func fqdn(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// Domain returns the fully qualified cluster domain.
+func (r *Records) Domain() string {
+	return r.domain
+}
+
+// Name returns the FQDN of the machine.
+func (r *Records) Name(machine *models.Machine) string {
+	return fqdn(machine.Name) + r.domain
+}
+
+// Replace replaces all records by the ones of the machines.
+func (r *Records) Replace(machines []*models.Machine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.machines = make(map[string]*models.Machine, len(machines))
+	for _, machine := range machines {
+		r.machines[machine.MAC] = machine
+	}
+	r.rebuild()
+}
+
+// Apply updates the records by the change of the machine.
+func (r *Records) Apply(event *models.MachineEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch event.Type {
+	case models.EventPut:
+		r.machines[event.Machine.MAC] = event.Machine
+	case models.EventDelete:
+		delete(r.machines, event.Machine.MAC)
+	}
+	r.rebuild()
+}
+
+// rebuild builds the indexes of the records from the machines. r.mu must be held.
+func (r *Records) rebuild() {
+	r.byName = make(map[string]net.IP, len(r.machines))
+	r.byAddr = make(map[string]string, len(r.machines))
+	for _, machine := range r.machines {
+		ip := net.ParseIP(machine.IPv4Addr).To4()
+		if len(machine.Name) == 0 || ip == nil {
+			continue
+		}
+		name := r.Name(machine)
+		r.byName[name] = ip
+		r.byAddr[ip.String()] = name
+	}
+	r.serial++
+}
+
+// LookupName returns the IPv4 address of the machine whose FQDN is name.
+func (r *Records) LookupName(name string) (net.IP, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ip, ok := r.byName[fqdn(name)]
+	return ip, ok
+}
+
+// LookupAddr returns the FQDN of the machine whose IPv4 address is ip.
+func (r *Records) LookupAddr(ip net.IP) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.byAddr[ip.String()]
+	return name, ok
+}
+
+// InDomain returns true if name is the cluster domain or under it.
+func (r *Records) InDomain(name string) bool {
+	name = fqdn(name)
+	return name == r.domain || strings.HasSuffix(name, "."+r.domain)
+}
+
+// Serial returns the serial number of the zone.
+func (r *Records) Serial() uint32 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.serial
+}
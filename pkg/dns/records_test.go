@@ -0,0 +1,83 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/dns"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+var spec = models.MachineSpec{Core: 2, Memory: 1024, Disk: 64}
+
+var machineFixtures = []*models.Machine{
+	{Name: "machine1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1", Spec: spec},
+	{Name: "machine2", MAC: "52:54:00:00:00:02", IPv4Addr: "192.168.1.2", Spec: spec},
+	// This has no record since its address has not been assigned.
+	{Name: "machine3", MAC: "52:54:00:00:00:03", Spec: spec},
+}
+
+func TestRecords(t *testing.T) {
+	records := dns.NewRecords("Cluster.Internal")
+	records.Replace(machineFixtures)
+	serial := records.Serial()
+
+	renamed := *machineFixtures[0]
+	renamed.Name = "renamed"
+	records.Apply(&models.MachineEvent{Type: models.EventPut, Machine: &renamed})
+	records.Apply(&models.MachineEvent{Type: models.EventDelete, Machine: machineFixtures[1]})
+	if records.Serial() != serial+2 {
+		t.Errorf("Expect: %d, Actual: %d", serial+2, records.Serial())
+	}
+
+	cases := map[string]struct {
+		name string
+		ip   string
+	}{
+		"renamed":        {name: "renamed.cluster.internal.", ip: "192.168.1.1"},
+		"case":           {name: "RENAMED.cluster.internal", ip: "192.168.1.1"},
+		"old name":       {name: "machine1.cluster.internal."},
+		"deleted":        {name: "machine2.cluster.internal."},
+		"no address":     {name: "machine3.cluster.internal."},
+		"another domain": {name: "renamed.example.com."},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ip, ok := records.LookupName(tc.name)
+			if len(tc.ip) == 0 {
+				if ok {
+					t.Errorf("Expect no record, Actual: %s", ip)
+				}
+				return
+			}
+			if !ok || !ip.Equal(net.ParseIP(tc.ip)) {
+				t.Errorf("Expect: %s, Actual: %s", tc.ip, ip)
+			}
+			name, ok := records.LookupAddr(ip)
+			if !ok || name != "renamed.cluster.internal." {
+				t.Errorf("Expect: renamed.cluster.internal., Actual: %s", name)
+			}
+		})
+	}
+	if _, ok := records.LookupAddr(net.ParseIP("192.168.1.2")); ok {
+		t.Error("The address of the deleted machine must not be found")
+	}
+}
+
+func TestRecords_InDomain(t *testing.T) {
+	records := dns.NewRecords("cluster.internal")
+	cases := map[string]bool{
+		"cluster.internal.":          true,
+		"machine1.Cluster.Internal.": true,
+		"machine1.cluster.internal":  true,
+		"badcluster.internal.":       false,
+		"example.com.":               false,
+	}
+	for name, expected := range cases {
+		if actual := records.InDomain(name); actual != expected {
+			t.Errorf("%s: Expect: %t, Actual: %t", name, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// maxMessageSize is the largest DNS message which can be sent over TCP.
+	maxMessageSize = 65535
+	// forwardTimeout is the time to wait for the response of the upstream.
+	forwardTimeout = 5 * time.Second
+	// tcpIdleTimeout is the time to keep the idle TCP connection of the client.
+	tcpIdleTimeout = 10 * time.Second
+	// reverseDomain is the domain of the PTR records of the IPv4 addresses.
+	reverseDomain = ".in-addr.arpa."
+)
+
+// Server answers the A and PTR records of the machines and forwards the other queries to the upstream.
+type Server struct {
+	records  *Records
+	upstream string
+	ttl      uint32
+}
+
+// NewServer returns the server which answers from records with ttl.
+// The queries of the names out of the cluster domain are forwarded to upstream such as "192.168.1.1:53".
+// They are refused if upstream is empty.
+func NewServer(records *Records, upstream string, ttl time.Duration) *Server {
+	if len(upstream) != 0 {
+		if _, _, err := net.SplitHostPort(upstream); err != nil {
+			upstream = net.JoinHostPort(upstream, "53")
+		}
+	}
+	return &Server{
+		records:  records,
+		upstream: upstream,
+		ttl:      uint32(ttl / time.Second),
+	}
+}
+
+// ServeUDP answers the queries received by conn until it is closed.
+func (s *Server) ServeUDP(conn net.PacketConn) error {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		query := make([]byte, n)
+		copy(query, buf[:n])
+		go func() {
+			if resp := s.handle("udp", query); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+// ServeTCP answers the queries received over the connections accepted by listener until it is closed.
+func (s *Server) ServeTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+		resp := s.handle("tcp", query)
+		if resp == nil {
+			return
+		}
+		if err := writeTCPMessage(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// readTCPMessage reads the message prefixed with its length.
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// writeTCPMessage writes the message prefixed with its length.
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	copy(buf[2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+// handle returns the response to the query received over network.
+// This returns nil if the query is malformed and should be dropped.
+func (s *Server) handle(network string, query []byte) []byte {
+	var req dnsmessage.Message
+	if err := req.Unpack(query); err != nil || req.Header.Response {
+		return nil
+	}
+	resp := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 req.Header.ID,
+			Response:           true,
+			OpCode:             req.Header.OpCode,
+			RecursionDesired:   req.Header.RecursionDesired,
+			RecursionAvailable: len(s.upstream) != 0,
+		},
+		Questions: req.Questions,
+	}
+	if req.Header.OpCode != 0 || len(req.Questions) != 1 {
+		resp.Header.RCode = dnsmessage.RCodeFormatError
+		return pack(&resp)
+	}
+	q := req.Questions[0]
+	name := strings.ToLower(q.Name.String())
+	switch {
+	case q.Class != dnsmessage.ClassINET && q.Class != dnsmessage.ClassANY:
+		// Only the internet class is served by the upstream as well.
+	case s.records.InDomain(name):
+		s.answerName(&resp, q, name)
+		return pack(&resp)
+	case strings.HasSuffix(name, reverseDomain):
+		if s.answerAddr(&resp, q, name) {
+			return pack(&resp)
+		}
+	}
+	if len(s.upstream) == 0 {
+		resp.Header.RCode = dnsmessage.RCodeRefused
+		return pack(&resp)
+	}
+	forwarded, err := s.forward(network, query)
+	if err != nil {
+		log.Printf("Failed to forward the query of %s to %s: %v", name, s.upstream, err)
+		resp.Header.RCode = dnsmessage.RCodeServerFailure
+		return pack(&resp)
+	}
+	return forwarded
+}
+
+// answerName answers the query of the name in the cluster domain authoritatively.
+func (s *Server) answerName(resp *dnsmessage.Message, q dnsmessage.Question, name string) {
+	resp.Header.Authoritative = true
+	header := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: s.ttl}
+	if name == s.records.Domain() {
+		if q.Type == dnsmessage.TypeSOA || q.Type == dnsmessage.TypeALL {
+			resp.Answers = append(resp.Answers, s.soa())
+			return
+		}
+		resp.Authorities = append(resp.Authorities, s.soa())
+		return
+	}
+	ip, ok := s.records.LookupName(name)
+	if !ok {
+		resp.Header.RCode = dnsmessage.RCodeNameError
+		resp.Authorities = append(resp.Authorities, s.soa())
+		return
+	}
+	if q.Type != dnsmessage.TypeA && q.Type != dnsmessage.TypeALL {
+		// The name exists but has no record of the type, e.g. AAAA.
+		resp.Authorities = append(resp.Authorities, s.soa())
+		return
+	}
+	var a dnsmessage.AResource
+	copy(a.A[:], ip)
+	header.Type = dnsmessage.TypeA
+	resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: header, Body: &a})
+}
+
+// answerAddr answers the PTR query of the address of the machine.
+// This returns false if the address is not of any machine.
+func (s *Server) answerAddr(resp *dnsmessage.Message, q dnsmessage.Question, name string) bool {
+	ip := parseReverseName(name)
+	if ip == nil {
+		return false
+	}
+	fqdn, ok := s.records.LookupAddr(ip)
+	if !ok {
+		return false
+	}
+	resp.Header.Authoritative = true
+	if q.Type != dnsmessage.TypePTR && q.Type != dnsmessage.TypeALL {
+		return true
+	}
+	ptr, err := dnsmessage.NewName(fqdn)
+	if err != nil {
+		return false
+	}
+	resp.Answers = append(resp.Answers, dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET, TTL: s.ttl},
+		Body:   &dnsmessage.PTRResource{PTR: ptr},
+	})
+	return true
+}
+
+// parseReverseName returns the IPv4 address of the name such as "4.3.2.1.in-addr.arpa.".
+func parseReverseName(name string) net.IP {
+	labels := strings.Split(strings.TrimSuffix(name, reverseDomain), ".")
+	if len(labels) != net.IPv4len {
+		return nil
+	}
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return net.ParseIP(strings.Join(labels, ".")).To4()
+}
+
+// soa returns the SOA record of the cluster domain.
+func (s *Server) soa() dnsmessage.Resource {
+	domain := dnsmessage.MustNewName(s.records.Domain())
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: domain, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: s.ttl},
+		Body: &dnsmessage.SOAResource{
+			NS:      domain,
+			MBox:    dnsmessage.MustNewName("hostmaster." + s.records.Domain()),
+			Serial:  s.records.Serial(),
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			MinTTL:  s.ttl,
+		},
+	}
+}
+
+// forward sends the query to the upstream over network and returns its response as is.
+func (s *Server) forward(network string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, s.upstream, forwardTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(forwardTimeout))
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+func pack(msg *dnsmessage.Message) []byte {
+	buf, err := msg.Pack()
+	if err != nil {
+		log.Printf("Failed to pack the DNS response: %v", err)
+		return nil
+	}
+	return buf
+}
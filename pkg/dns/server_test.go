@@ -0,0 +1,166 @@
+package dns_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/pddg/tiny-cluster/pkg/dns"
+)
+
+// startUpstream starts the upstream which answers every A query with 10.0.0.1.
+func startUpstream(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if err := msg.Unpack(buf[:n]); err != nil {
+				continue
+			}
+			msg.Header.Response = true
+			msg.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}},
+			}}
+			resp, _ := msg.Pack()
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// startServer starts the server over UDP and TCP on the same port and returns its address.
+func startServer(t *testing.T, upstream string) string {
+	records := dns.NewRecords("cluster.internal")
+	records.Replace(machineFixtures)
+	server := dns.NewServer(records, upstream, time.Minute)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	listener, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go server.ServeUDP(conn)
+	go server.ServeTCP(listener)
+	return conn.LocalAddr().String()
+}
+
+func query(t *testing.T, network, addr, name string, qtype dnsmessage.Type) *dnsmessage.Message {
+	req := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	buf, err := req.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if network == "tcp" {
+		buf = append([]byte{byte(len(buf) >> 8), byte(len(buf))}, buf...)
+	}
+	if _, err := conn.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	resp := make([]byte, 65535)
+	n, err := conn.Read(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network == "tcp" {
+		resp = resp[2:]
+		n -= 2
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.ID != req.Header.ID {
+		t.Fatalf("Expect: %d, Actual: %d", req.Header.ID, msg.Header.ID)
+	}
+	return &msg
+}
+
+func TestServer(t *testing.T) {
+	addr := startServer(t, startUpstream(t))
+	cases := map[string]struct {
+		network string
+		name    string
+		qtype   dnsmessage.Type
+		rcode   dnsmessage.RCode
+		answer  string
+		soa     bool
+	}{
+		"A":                {network: "udp", name: "machine1.cluster.internal.", qtype: dnsmessage.TypeA, answer: "192.168.1.1"},
+		"A over TCP":       {network: "tcp", name: "machine2.cluster.internal.", qtype: dnsmessage.TypeA, answer: "192.168.1.2"},
+		"case insensitive": {network: "udp", name: "MACHINE1.cluster.internal.", qtype: dnsmessage.TypeA, answer: "192.168.1.1"},
+		"AAAA":             {network: "udp", name: "machine1.cluster.internal.", qtype: dnsmessage.TypeAAAA, soa: true},
+		"PTR":              {network: "udp", name: "1.1.168.192.in-addr.arpa.", qtype: dnsmessage.TypePTR, answer: "machine1.cluster.internal."},
+		"unknown":          {network: "udp", name: "unknown.cluster.internal.", qtype: dnsmessage.TypeA, rcode: dnsmessage.RCodeNameError, soa: true},
+		"SOA":              {network: "udp", name: "cluster.internal.", qtype: dnsmessage.TypeSOA},
+		"forwarded":        {network: "udp", name: "example.com.", qtype: dnsmessage.TypeA, answer: "10.0.0.1"},
+		"unknown PTR":      {network: "udp", name: "1.0.0.10.in-addr.arpa.", qtype: dnsmessage.TypePTR, answer: "10.0.0.1"},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			msg := query(t, tc.network, addr, tc.name, tc.qtype)
+			if msg.Header.RCode != tc.rcode {
+				t.Fatalf("Expect: %s, Actual: %s", tc.rcode, msg.Header.RCode)
+			}
+			if tc.soa {
+				if len(msg.Answers) != 0 || len(msg.Authorities) != 1 || msg.Authorities[0].Header.Type != dnsmessage.TypeSOA {
+					t.Errorf("Expect only SOA in the authorities, Actual: %v", msg)
+				}
+				return
+			}
+			if len(msg.Answers) != 1 {
+				t.Fatalf("Expect an answer, Actual: %v", msg.Answers)
+			}
+			var actual string
+			switch body := msg.Answers[0].Body.(type) {
+			case *dnsmessage.AResource:
+				actual = net.IP(body.A[:]).String()
+			case *dnsmessage.PTRResource:
+				actual = body.PTR.String()
+			case *dnsmessage.SOAResource:
+				actual = ""
+			}
+			if actual != tc.answer {
+				t.Errorf("Expect: %s, Actual: %s", tc.answer, actual)
+			}
+		})
+	}
+}
+
+func TestServer_noUpstream(t *testing.T) {
+	addr := startServer(t, "")
+	msg := query(t, "udp", addr, "example.com.", dnsmessage.TypeA)
+	if msg.Header.RCode != dnsmessage.RCodeRefused {
+		t.Errorf("Expect: %s, Actual: %s", dnsmessage.RCodeRefused, msg.Header.RCode)
+	}
+	msg = query(t, "udp", addr, "machine1.cluster.internal.", dnsmessage.TypeA)
+	if len(msg.Answers) != 1 || !msg.Header.Authoritative {
+		t.Errorf("Expect an authoritative answer, Actual: %v", msg)
+	}
+}
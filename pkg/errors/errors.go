@@ -1,6 +1,11 @@
 package errors
 
-import "golang.org/x/xerrors"
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/xerrors"
+)
 
 const (
 	// CodeErrOther is the error code for ErrOther.
@@ -13,10 +18,16 @@ const (
 	CodeErrAlreadyExists
 	// CodeErrAuthFailed is the error code for ErrAuthFailed.
 	CodeErrAuthFailed
-	// CodeErrPermissionDenied is the error code for ErrErrPermissionDenied.
+	// CodeErrPermissionDenied is the error code for ErrPermissionDenied.
 	CodeErrPermissionDenied
 	// CodeErrContextCanceled is the error code for ErrContextCanceled.
 	CodeErrContextCanceled
+	// CodeErrInvalidArgument is the error code for ErrInvalidArgument.
+	CodeErrInvalidArgument
+	// CodeErrConflict is the error code for ErrConflict.
+	CodeErrConflict
+	// CodeErrUnavailable is the error code for ErrUnavailable.
+	CodeErrUnavailable
 )
 
 var (
@@ -38,6 +49,23 @@ var (
 
 	// ErrContextCanceled will be occured when the context was canceled.
 	ErrContextCanceled = newError(CodeErrContextCanceled, "Context canceled")
+
+	// ErrInvalidArgument indicates that the given argument can not be accepted.
+	ErrInvalidArgument = newError(CodeErrInvalidArgument, "invalid argument")
+	// ErrConflict indicates that the item was modified by others during the operation.
+	ErrConflict = newError(CodeErrConflict, "the item was modified concurrently")
+	// ErrUnavailable indicates that the datastore can not be reached for now.
+	ErrUnavailable = newError(CodeErrUnavailable, "the datastore is unavailable")
+)
+
+// Kinds of the resources which the errors are about.
+const (
+	// KindMachine is the kind of the machines.
+	KindMachine = "machine"
+	// KindToken is the kind of the API tokens.
+	KindToken = "token"
+	// KindWebhook is the kind of the webhooks.
+	KindWebhook = "webhook"
 )
 
 func newError(code int, message string) error {
@@ -48,16 +76,83 @@ func newError(code int, message string) error {
 }
 
 // TinyClusterError is a base error type for TinyCluster.
+// The errors such as ErrNotFound only have the code, and the errors returned by Wrap also tell
+// which operation failed on which resource. Use errors.Is to test the code of the error,
+// and errors.As to take the details.
 type TinyClusterError struct {
+	// Op is the operation which failed, such as "GetMachines".
+	Op string
+	// Kind is the kind of the resource, such as KindMachine.
+	Kind string
+	// ID identifies the resource, such as the MAC of the machine.
+	ID string
+	// Err is the cause of the error.
+	Err error
+
 	code    int
 	message string
 }
 
-func (e TinyClusterError) Is(err error) bool {
-	var tmpErr *TinyClusterError
-	return xerrors.As(err, tmpErr) && tmpErr.code == e.code
+// Wrap returns the error of op on the resource of kind identified by id, which is caused by err.
+// The error has the code of err if err is an error of TinyCluster, otherwise CodeErrOther.
+func Wrap(op, kind, id string, err error) error {
+	return &TinyClusterError{
+		Op:   op,
+		Kind: kind,
+		ID:   id,
+		Err:  err,
+		code: CodeOf(err),
+	}
 }
 
-func (e TinyClusterError) Error() string {
-	return e.message
+// CodeOf returns the code of the first error of TinyCluster in the chain of err.
+// The cancellation and the deadline of the context are also translated to their codes.
+func CodeOf(err error) int {
+	var tcError *TinyClusterError
+	switch {
+	case xerrors.As(err, &tcError):
+		return tcError.code
+	case xerrors.Is(err, context.Canceled):
+		return CodeErrContextCanceled
+	case xerrors.Is(err, context.DeadlineExceeded):
+		return CodeErrTimedOut
+	}
+	return CodeErrOther
+}
+
+// Code returns the code of the error such as CodeErrNotFound.
+func (e *TinyClusterError) Code() int {
+	return e.code
+}
+
+// Is returns true if target is an error of TinyCluster with the same code.
+// This lets errors.Is(err, ErrNotFound) match the errors returned by Wrap.
+func (e *TinyClusterError) Is(target error) bool {
+	t, ok := target.(*TinyClusterError)
+	return ok && t.code == e.code
+}
+
+// Unwrap returns the cause of the error.
+func (e *TinyClusterError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TinyClusterError) Error() string {
+	if e.Err == nil {
+		return e.message
+	}
+	var b strings.Builder
+	if len(e.Op) != 0 {
+		b.WriteString(e.Op)
+		b.WriteString(": ")
+	}
+	if len(e.Kind) != 0 {
+		b.WriteString(e.Kind)
+		if len(e.ID) != 0 {
+			b.WriteString(" '" + e.ID + "'")
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
 }
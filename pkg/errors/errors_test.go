@@ -0,0 +1,110 @@
+package errors_test
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+func TestTinyClusterError_Is(t *testing.T) {
+	testCases := map[string]struct {
+		err    error
+		target error
+		expect bool
+	}{
+		"same error": {
+			err:    tcErr.ErrNotFound,
+			target: tcErr.ErrNotFound,
+			expect: true,
+		},
+		"wrapped by xerrors": {
+			err:    xerrors.Errorf("machine is missing %w", tcErr.ErrNotFound),
+			target: tcErr.ErrNotFound,
+			expect: true,
+		},
+		"wrapped with the resource": {
+			err:    tcErr.Wrap("GetToken", tcErr.KindToken, "id", tcErr.ErrNotFound),
+			target: tcErr.ErrNotFound,
+			expect: true,
+		},
+		"wrapped twice": {
+			err:    xerrors.Errorf("failed %w", tcErr.Wrap("GetToken", tcErr.KindToken, "id", tcErr.ErrUnavailable)),
+			target: tcErr.ErrUnavailable,
+			expect: true,
+		},
+		"other code": {
+			err:    tcErr.Wrap("GetToken", tcErr.KindToken, "id", tcErr.ErrUnavailable),
+			target: tcErr.ErrNotFound,
+			expect: false,
+		},
+		"cause is kept": {
+			err:    tcErr.Wrap("GetToken", tcErr.KindToken, "id", context.Canceled),
+			target: context.Canceled,
+			expect: true,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			if actual := xerrors.Is(tc.err, tc.target); actual != tc.expect {
+				t.Errorf("Expect: %v, Actual: %v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	testCases := map[string]struct {
+		err    error
+		expect int
+	}{
+		"sentinel": {
+			err:    tcErr.ErrConflict,
+			expect: tcErr.CodeErrConflict,
+		},
+		"wrapped": {
+			err:    tcErr.Wrap("DeleteMachine", tcErr.KindMachine, "mac", tcErr.ErrNotFound),
+			expect: tcErr.CodeErrNotFound,
+		},
+		"canceled": {
+			err:    xerrors.Errorf("failed %w", context.Canceled),
+			expect: tcErr.CodeErrContextCanceled,
+		},
+		"deadline": {
+			err:    context.DeadlineExceeded,
+			expect: tcErr.CodeErrTimedOut,
+		},
+		"unknown": {
+			err:    xerrors.New("unknown"),
+			expect: tcErr.CodeErrOther,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			if actual := tcErr.CodeOf(tc.err); actual != tc.expect {
+				t.Errorf("Expect: %d, Actual: %d", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestTinyClusterError_As(t *testing.T) {
+	err := xerrors.Errorf("failed %w", tcErr.Wrap("PatchMachine", tcErr.KindMachine, "52:54:00:00:00:01", tcErr.ErrNotFound))
+	var tcError *tcErr.TinyClusterError
+	if !xerrors.As(err, &tcError) {
+		t.Fatalf("The error must be a TinyClusterError. Actual: %#v", err)
+	}
+	if tcError.Op != "PatchMachine" || tcError.Kind != tcErr.KindMachine || tcError.ID != "52:54:00:00:00:01" {
+		t.Errorf("The details must be kept. Actual: %#v", tcError)
+	}
+	expect := "PatchMachine: machine '52:54:00:00:00:01': the item does not exist"
+	if tcError.Error() != expect {
+		t.Errorf("Expect: %s, Actual: %s", expect, tcError.Error())
+	}
+}
@@ -0,0 +1,51 @@
+package errors
+
+import "strings"
+
+// FieldViolation tells why the value of the field is invalid.
+type FieldViolation struct {
+	// Field is the path of the field such as "spec.core".
+	Field string `json:"field"`
+	// Description tells why the value is invalid.
+	Description string `json:"description"`
+}
+
+// ValidationError is the error of the invalid fields. It has the code of ErrInvalidArgument.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// Add appends the violation of the field.
+func (e *ValidationError) Add(field, description string) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Description: description})
+}
+
+// Prefix returns the error whose fields are prefixed by prefix, such as "machines[0]".
+func (e *ValidationError) Prefix(prefix string) *ValidationError {
+	prefixed := &ValidationError{}
+	for _, v := range e.Violations {
+		prefixed.Add(prefix+"."+v.Field, v.Description)
+	}
+	return prefixed
+}
+
+// Err returns e if it has any violations, otherwise nil.
+func (e *ValidationError) Err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Field+": "+v.Description)
+	}
+	return ErrInvalidArgument.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns ErrInvalidArgument, so that errors.Is(err, ErrInvalidArgument) matches the validation errors.
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidArgument
+}
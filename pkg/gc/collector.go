@@ -0,0 +1,50 @@
+// Package gc deletes the data left behind by the deleted machines.
+package gc
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+// DefaultInterval is the default interval to collect the garbage.
+const DefaultInterval = 10 * time.Minute
+
+// Collector prunes the check-ins of the deleted machines in all namespaces periodically.
+// It should run on only one replica, such as a task of leader.Runner.
+type Collector struct {
+	// Interval is the interval to collect the garbage.
+	Interval time.Duration
+
+	machines usecase.MachineUsecase
+}
+
+// NewCollector returns the collector with the default settings.
+func NewCollector(machines usecase.MachineUsecase) *Collector {
+	return &Collector{
+		Interval: DefaultInterval,
+		machines: machines,
+	}
+}
+
+// Run collects the garbage at once and then at each Interval until ctx is done. It implements leader.Task.
+func (c *Collector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		pruned, err := c.machines.PruneHeartbeats(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to prune the heartbeats: %v", err)
+		}
+		if pruned != 0 {
+			log.Printf("Pruned the heartbeats of %d deleted machines", pruned)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
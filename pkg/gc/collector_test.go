@@ -0,0 +1,43 @@
+package gc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/gc"
+	"github.com/pddg/tiny-cluster/pkg/usecase/mock"
+)
+
+func TestCollector_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	usecaseMock := mock.NewMockMachineUsecase(ctrl)
+	// The collector keeps running after the failure, and stops when ctx is done.
+	gomock.InOrder(
+		usecaseMock.EXPECT().PruneHeartbeats(gomock.Any()).Return(0, xerrors.New("unavailable")),
+		usecaseMock.EXPECT().PruneHeartbeats(gomock.Any()).Return(1, nil),
+		usecaseMock.EXPECT().PruneHeartbeats(gomock.Any()).DoAndReturn(func(context.Context) (int, error) {
+			cancel()
+			return 0, nil
+		}),
+	)
+	collector := gc.NewCollector(usecaseMock)
+	collector.Interval = 10 * time.Millisecond
+	done := make(chan error)
+	go func() {
+		done <- collector.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("The collector must stop when ctx is done")
+	}
+}
@@ -0,0 +1,204 @@
+package infra
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// enableAuth creates 'root' and read only 'reader' users, and enables the authentication.
+func enableAuth(ctx context.Context, t *testing.T, endpoints []string) {
+	t.Helper()
+	client, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to connect to etcd due to %v", err)
+	}
+	defer client.Close()
+	steps := []func() error{
+		func() error { _, err := client.UserAdd(ctx, "root", "rootpw"); return err },
+		func() error { _, err := client.UserGrantRole(ctx, "root", "root"); return err },
+		func() error { _, err := client.UserAdd(ctx, "reader", "readerpw"); return err },
+		func() error { _, err := client.RoleAdd(ctx, "reader"); return err },
+		func() error {
+			_, err := client.RoleGrantPermission(ctx, "reader", BasePrefix, clientv3.GetPrefixRangeEnd(BasePrefix), clientv3.PermissionType(clientv3.PermRead))
+			return err
+		},
+		func() error { _, err := client.UserGrantRole(ctx, "reader", "reader"); return err },
+		func() error { _, err := client.AuthEnable(ctx); return err },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("Failed to set up the authentication due to %v", err)
+		}
+	}
+}
+
+func TestClient_auth(t *testing.T) {
+	ctx := context.Background()
+	server := startTestEmbedded(t)
+	enableAuth(ctx, t, server.Endpoints())
+	cases := map[string]struct {
+		username  string
+		password  string
+		getErr    error
+		createErr error
+	}{
+		"wrong password": {
+			username:  "root",
+			password:  "wrong",
+			getErr:    tcErr.ErrAuthFailed,
+			createErr: tcErr.ErrAuthFailed,
+		},
+		"read only": {
+			username:  "reader",
+			password:  "readerpw",
+			createErr: tcErr.ErrPermissionDenied,
+		},
+		"root": {
+			username: "root",
+			password: "rootpw",
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			client := newTestEtcdClient(t, Config{
+				Endpoints:      server.Endpoints(),
+				RequestTimeout: 10 * time.Second,
+				Username:       tc.username,
+				Password:       tc.password,
+			})
+			defer client.Close()
+			r := NewMachineRepository(client)
+			_, err := r.GetMachines(ctx)
+			assertTinyClusterError(t, err, tc.getErr)
+			// Each case uses its own machine since the order of the cases is random.
+			err = r.RegisterMachine(ctx, &models.Machine{MAC: name})
+			assertTinyClusterError(t, err, tc.createErr)
+		})
+	}
+}
+
+func assertTinyClusterError(t *testing.T, actual error, expect error) {
+	t.Helper()
+	if expect == nil {
+		if actual != nil {
+			t.Errorf("Unexpected error %v", actual)
+		}
+		return
+	}
+	if !xerrors.Is(actual, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
+
+// writeTestCert writes a self-signed certificate and its key into dir.
+func writeTestCert(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate the key due to %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tiny-cluster-test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create the certificate due to %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal the key due to %v", err)
+	}
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	for path, block := range map[string]*pem.Block{
+		certPath: {Type: "CERTIFICATE", Bytes: certDER},
+		keyPath:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("Failed to write '%s' due to %v", path, err)
+		}
+	}
+	return certPath, keyPath
+}
+
+func TestConfig_tlsConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc-tls")
+	if err != nil {
+		t.Fatalf("Failed to create the directory due to %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certPath, keyPath := writeTestCert(t, dir)
+	cases := map[string]struct {
+		config  Config
+		useTLS  bool
+		numCert int
+		err     bool
+	}{
+		"plain": {
+			config: Config{Endpoints: []string{"http://127.0.0.1:2379"}},
+		},
+		"https endpoint": {
+			config: Config{Endpoints: []string{"https://127.0.0.1:2379"}},
+			useTLS: true,
+		},
+		"mutual TLS": {
+			config:  Config{CACert: certPath, Cert: certPath, Key: keyPath},
+			useTLS:  true,
+			numCert: 1,
+		},
+		"missing key": {
+			config: Config{Cert: certPath},
+			err:    true,
+		},
+		"CA is not a certificate": {
+			config: Config{CACert: keyPath},
+			err:    true,
+		},
+		"CA does not exist": {
+			config: Config{CACert: filepath.Join(dir, "not-exist.pem")},
+			err:    true,
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			actual, err := tc.config.tlsConfig()
+			if tc.err {
+				assertTinyClusterError(t, err, tcErr.ErrInvalidArgument)
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if (actual != nil) != tc.useTLS {
+				t.Fatalf("Expect TLS: %v, Actual: %v", tc.useTLS, actual)
+			}
+			if actual != nil && len(actual.Certificates) != tc.numCert {
+				t.Errorf("Expect: %d certificates, Actual: %d", tc.numCert, len(actual.Certificates))
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package infra
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+const (
+	// defaultDialTimeout is used if Config.DialTimeout is not set.
+	defaultDialTimeout = 10 * time.Second
+	// minReconnectBackoff is the wait after the first failure to connect to etcd.
+	minReconnectBackoff = 500 * time.Millisecond
+	// maxReconnectBackoff is the upper limit of the wait between the attempts to connect to etcd.
+	maxReconnectBackoff = 30 * time.Second
+	// keepAliveTime is the interval to ping etcd to detect the broken connection.
+	keepAliveTime = 30 * time.Second
+	// keepAliveTimeout is the time to wait for the response of the ping.
+	keepAliveTimeout = 10 * time.Second
+)
+
+// Config is the configuration to connect to etcd.
+type Config struct {
+	// Endpoints is the list of the URLs of etcd.
+	Endpoints []string
+	// Prefix is the prefix of all keys. BasePrefix is used if it is empty.
+	Prefix string
+	// DialTimeout is the timeout to establish the connection.
+	// defaultDialTimeout is used if it is 0.
+	DialTimeout time.Duration
+	// RequestTimeout is the timeout of each operation of the repositories.
+	// No timeout is set if it is 0.
+	RequestTimeout time.Duration
+
+	// CACert is the path to the CA certificate to verify etcd.
+	// The system certificate pool is used if it is empty.
+	CACert string
+	// Cert and Key are the paths to the client certificate and its key for mutual TLS.
+	Cert string
+	Key  string
+	// Username and Password are used to authenticate with etcd if Username is not empty.
+	Username string
+	Password string
+}
+
+// useTLS returns true if any of the TLS options is given or any endpoint requires TLS.
+func (c *Config) useTLS() bool {
+	if len(c.CACert) != 0 || len(c.Cert) != 0 || len(c.Key) != 0 {
+		return true
+	}
+	for _, ep := range c.Endpoints {
+		if strings.HasPrefix(ep, "https://") {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsConfig returns the TLS configuration, or nil if TLS is not used.
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	if !c.useTLS() {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if len(c.CACert) != 0 {
+		pemByte, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to read the CA certificate: %v %w", err, tcErr.ErrInvalidArgument)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemByte) {
+			return nil, xerrors.Errorf("no certificates in '%s' %w", c.CACert, tcErr.ErrInvalidArgument)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(c.Cert) != 0 || len(c.Key) != 0 {
+		if len(c.Cert) == 0 || len(c.Key) == 0 {
+			return nil, xerrors.Errorf("both the client certificate and its key are required %w", tcErr.ErrInvalidArgument)
+		}
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, xerrors.Errorf("Failed to load the client certificate: %v %w", err, tcErr.ErrInvalidArgument)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Client is the connection to etcd shared among the repositories.
+// The connection is established at the first use. If it fails,
+// the next attempt is delayed with an exponential backoff.
+// Once connected, the underlying gRPC connection reconnects by itself.
+type Client struct {
+	config    Config
+	tlsConfig *tls.Config
+	// dialing is held while connecting to etcd so that only one connection is made.
+	dialing chan struct{}
+
+	mu       sync.Mutex
+	client   *clientv3.Client
+	closed   bool
+	failures int
+	retryAt  time.Time
+	// lastErr is the error of the last attempt to connect, which is returned until retryAt.
+	lastErr error
+}
+
+// NewClient returns the client to connect to etcd with the config.
+// It does not connect to etcd until used, but the TLS options are validated here.
+func NewClient(config Config) (*Client, error) {
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultDialTimeout
+	}
+	if len(config.Prefix) == 0 {
+		config.Prefix = BasePrefix
+	}
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		config:    config,
+		tlsConfig: tlsConfig,
+		dialing:   make(chan struct{}, 1),
+	}, nil
+}
+
+// withTimeout returns the context which is canceled after the request timeout.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.config.RequestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.config.RequestTimeout)
+}
+
+// current returns the established connection, or nil if there is not.
+func (c *Client) current() (*clientv3.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, xerrors.Errorf("the etcd client has been closed %w", tcErr.ErrUnavailable)
+	}
+	return c.client, nil
+}
+
+// get returns the connection to etcd, connecting to it if necessary.
+func (c *Client) get(ctx context.Context) (*clientv3.Client, error) {
+	if client, err := c.current(); client != nil || err != nil {
+		return client, err
+	}
+	select {
+	case c.dialing <- struct{}{}:
+		defer func() { <-c.dialing }()
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+	// Others may have connected while waiting.
+	if client, err := c.current(); client != nil || err != nil {
+		return client, err
+	}
+	c.mu.Lock()
+	retryAt, lastErr := c.retryAt, c.lastErr
+	c.mu.Unlock()
+	if wait := time.Until(retryAt); wait > 0 {
+		return nil, xerrors.Errorf("retry after %s: %w", wait.Round(time.Millisecond), lastErr)
+	}
+	// The dial is abandoned when ctx is done, but the established connection outlives ctx.
+	dialCtx, cancelDial := context.WithCancel(context.Background())
+	dialed := make(chan struct{})
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		select {
+		case <-ctx.Done():
+			cancelDial()
+		case <-dialed:
+		}
+	}()
+	client, err := clientv3.New(clientv3.Config{
+		Context:              dialCtx,
+		Endpoints:            c.config.Endpoints,
+		DialTimeout:          c.config.DialTimeout,
+		DialKeepAliveTime:    keepAliveTime,
+		DialKeepAliveTimeout: keepAliveTimeout,
+		TLS:                  c.tlsConfig,
+		Username:             c.config.Username,
+		Password:             c.config.Password,
+		// Wait for the connection to be established so that the failure is detected here.
+		DialOptions: []grpc.DialOption{grpc.WithBlock()},
+	})
+	close(dialed)
+	<-watcherDone
+	if dialCtx.Err() != nil {
+		// The failure is caused by the caller, not by etcd, so that it does not delay the next dial.
+		if err == nil {
+			client.Close()
+		}
+		return nil, contextError(ctx)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		backoff := minReconnectBackoff << uint(c.failures)
+		if backoff > maxReconnectBackoff || backoff <= 0 {
+			backoff = maxReconnectBackoff
+		} else {
+			c.failures++
+		}
+		c.retryAt = time.Now().Add(backoff)
+		c.lastErr = dialError(err)
+		return nil, c.lastErr
+	}
+	if c.closed {
+		client.Close()
+		return nil, xerrors.Errorf("the etcd client has been closed %w", tcErr.ErrUnavailable)
+	}
+	c.client = client
+	c.failures = 0
+	c.retryAt = time.Time{}
+	c.lastErr = nil
+	return client, nil
+}
+
+// contextError returns the error of TinyCluster for ctx which is done.
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.Canceled {
+		return tcErr.ErrContextCanceled
+	}
+	return tcErr.ErrTimedOut
+}
+
+// dialError converts the error on connecting to etcd.
+// The rejected credentials are distinguished from the unreachable etcd.
+func dialError(err error) error {
+	if authErr := authError(err); authErr != nil {
+		return authErr
+	}
+	return xerrors.Errorf("Failed to connect to etcd (%v) %w", err, tcErr.ErrUnavailable)
+}
+
+// Close closes the connection to etcd. The client can not be used after closed.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
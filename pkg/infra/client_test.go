@@ -0,0 +1,108 @@
+package infra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+func isUnavailable(err error) bool {
+	return xerrors.Is(err, tcErr.ErrUnavailable)
+}
+
+func TestClient_get(t *testing.T) {
+	ctx := context.Background()
+	c := getTestEtcdClient(t)
+	defer c.Close()
+	dialCtx, cancel := context.WithCancel(ctx)
+	first, err := c.get(dialCtx)
+	if err != nil {
+		t.Fatalf("Failed to connect due to %v", err)
+	}
+	// The connection outlives the context of the request which has established it.
+	cancel()
+	if _, err := first.Get(ctx, BasePrefix); err != nil {
+		t.Errorf("The connection must not be closed with the context. Actual: %v", err)
+	}
+	second, err := c.get(ctx)
+	if err != nil {
+		t.Fatalf("Failed to connect due to %v", err)
+	}
+	if first != second {
+		t.Error("The connection must be shared")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Failed to close due to %v", err)
+	}
+	if _, err := c.get(ctx); !isUnavailable(err) {
+		t.Errorf("Closed client must not be used. Actual: %v", err)
+	}
+}
+
+func TestClient_getBackoff(t *testing.T) {
+	ctx := context.Background()
+	// Nothing listens on the port.
+	c := newTestEtcdClient(t, Config{
+		Endpoints:   []string{"http://127.0.0.1:1"},
+		DialTimeout: 100 * time.Millisecond,
+	})
+	defer c.Close()
+	if _, err := c.get(ctx); !isUnavailable(err) {
+		t.Fatalf("Expect: %v, Actual: %v", tcErr.ErrUnavailable, err)
+	}
+	start := time.Now()
+	if _, err := c.get(ctx); !isUnavailable(err) {
+		t.Fatalf("Expect: %v, Actual: %v", tcErr.ErrUnavailable, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("Must not dial again during the backoff. Elapsed: %s", elapsed)
+	}
+	if c.failures != 1 {
+		t.Errorf("Invalid number of failures. Expect: 1, Actual: %d", c.failures)
+	}
+}
+
+func TestClient_getCanceled(t *testing.T) {
+	// Nothing listens on the port.
+	c := newTestEtcdClient(t, Config{
+		Endpoints:   []string{"http://127.0.0.1:1"},
+		DialTimeout: time.Minute,
+	})
+	defer c.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := c.get(ctx); !xerrors.Is(err, tcErr.ErrContextCanceled) {
+		t.Fatalf("Expect: %v, Actual: %v", tcErr.ErrContextCanceled, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 10*time.Second {
+		t.Errorf("Must stop dialing when the context is canceled. Elapsed: %s", elapsed)
+	}
+	if c.failures != 0 {
+		t.Errorf("The cancellation must not be counted as the failure. Actual: %d", c.failures)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.get(ctx); !xerrors.Is(err, tcErr.ErrTimedOut) {
+		t.Errorf("Expect: %v, Actual: %v", tcErr.ErrTimedOut, err)
+	}
+}
+
+func TestClient_withTimeout(t *testing.T) {
+	c := newTestEtcdClient(t, Config{RequestTimeout: time.Second})
+	ctx, cancel := c.withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("The deadline must be set")
+	}
+	c = newTestEtcdClient(t, Config{})
+	ctx, cancel = c.withTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("The deadline must not be set")
+	}
+}
@@ -0,0 +1,106 @@
+package infra
+
+import (
+	"context"
+	"path"
+	"sync"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+const (
+	// electionKeyPath is the path under the prefix where the candidates of the elections are placed.
+	electionKeyPath = "election"
+	// electionTTL is the seconds until the leadership expires after the leader stops.
+	electionTTL = 10
+)
+
+type electionRepoImpl struct {
+	*baseRepoImpl
+	name string
+
+	mu       sync.Mutex
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+// electionPrefix returns the prefix of the keys of the candidates.
+// The candidate which has the oldest key is the leader.
+func (e *electionRepoImpl) electionPrefix() string {
+	return path.Join(e.client.config.Prefix, electionKeyPath, e.name)
+}
+
+func (e *electionRepoImpl) Campaign(ctx context.Context, id string) (<-chan struct{}, error) {
+	client, err := e.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The lease of the session is kept alive until ctx is done or Resign is called.
+	session, err := concurrency.NewSession(client, concurrency.WithTTL(electionTTL), concurrency.WithContext(ctx))
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to create the session %w:", etcdError(err))
+	}
+	election := concurrency.NewElection(session, e.electionPrefix())
+	if err := election.Campaign(ctx, id); err != nil {
+		session.Close()
+		if ctx.Err() != nil {
+			return nil, tcErr.ErrTimedOut
+		}
+		return nil, xerrors.Errorf("Failed to campaign %w:", etcdError(err))
+	}
+	e.mu.Lock()
+	e.session = session
+	e.election = election
+	e.mu.Unlock()
+	return session.Done(), nil
+}
+
+func (e *electionRepoImpl) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	session, election := e.session, e.election
+	e.session, e.election = nil, nil
+	e.mu.Unlock()
+	if session == nil {
+		return nil
+	}
+	defer session.Close()
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	if err := election.Resign(ctx); err != nil {
+		return xerrors.Errorf("Failed to resign %w:", etcdError(err))
+	}
+	return nil
+}
+
+func (e *electionRepoImpl) Leader(ctx context.Context) (string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	client, err := e.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Same as concurrency.Election.Leader, which requires a session even for reading.
+	resp, err := client.Get(ctx, e.electionPrefix()+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", xerrors.Errorf("Failed to get the leader %w:", etcdError(err))
+	}
+	if len(resp.Kvs) == 0 {
+		return "", tcErr.ErrNotFound
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// NewLeaderElection returns the election among the replicas which use the same name.
+func NewLeaderElection(client *Client, name string) repo.LeaderElection {
+	return &electionRepoImpl{
+		baseRepoImpl: &baseRepoImpl{
+			client: client,
+		},
+		name: name,
+	}
+}
@@ -0,0 +1,82 @@
+package infra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+func Test_electionRepoImpl(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client1 := getTestEtcdClient(t)
+	defer client1.Close()
+	client2 := getTestEtcdClient(t)
+	defer client2.Close()
+	name := "test-" + t.Name()
+	replica1 := NewLeaderElection(client1, name)
+	replica2 := NewLeaderElection(client2, name)
+
+	if _, err := replica1.Leader(ctx); !xerrors.Is(err, tcErr.ErrNotFound) {
+		t.Fatalf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+	}
+	if _, err := replica1.Campaign(ctx, "replica1"); err != nil {
+		t.Fatalf("Failed to campaign due to %v", err)
+	}
+	type result struct {
+		lost <-chan struct{}
+		err  error
+	}
+	elected := make(chan result, 1)
+	go func() {
+		lost, err := replica2.Campaign(ctx, "replica2")
+		elected <- result{lost, err}
+	}()
+	select {
+	case r := <-elected:
+		t.Fatalf("replica2 must not be elected while replica1 leads. Actual: %v", r.err)
+	case <-time.After(500 * time.Millisecond):
+	}
+	for name, r := range map[string]repo.LeaderElection{"replica1": replica1, "replica2": replica2} {
+		if actual, err := r.Leader(ctx); err != nil || actual != "replica1" {
+			t.Errorf("Expect: replica1 seen from %s, Actual: %s (%v)", name, actual, err)
+		}
+	}
+
+	if err := replica1.Resign(ctx); err != nil {
+		t.Fatalf("Failed to resign due to %v", err)
+	}
+	var r result
+	select {
+	case r = <-elected:
+		if r.err != nil {
+			t.Fatalf("Failed to campaign due to %v", r.err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("replica2 must be elected after replica1 resigned")
+	}
+	if actual, err := replica1.Leader(ctx); err != nil || actual != "replica2" {
+		t.Errorf("Expect: replica2, Actual: %s (%v)", actual, err)
+	}
+
+	// The leadership is lost when the lease expires.
+	raw := getTestClient(t)
+	defer raw.Close()
+	lease := replica2.(*electionRepoImpl).session.Lease()
+	if _, err := raw.Revoke(ctx, lease); err != nil {
+		t.Fatalf("Failed to revoke the lease due to %v", err)
+	}
+	select {
+	case <-r.lost:
+	case <-time.After(10 * time.Second):
+		t.Error("The leadership must be lost after the lease expired")
+	}
+	if _, err := replica1.Leader(ctx); !xerrors.Is(err, tcErr.ErrNotFound) {
+		t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+	}
+}
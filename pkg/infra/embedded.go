@@ -0,0 +1,105 @@
+package infra
+
+import (
+	"net/url"
+	"time"
+
+	"go.etcd.io/etcd/embed"
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// defaultStartTimeout is used if EmbeddedConfig.StartTimeout is not set.
+const defaultStartTimeout = time.Minute
+
+// EmbeddedConfig is the configuration of the etcd server run in the process.
+type EmbeddedConfig struct {
+	// Name is the name of the member. It must be unique in the cluster.
+	Name string
+	// DataDir is the directory to store the data.
+	DataDir string
+	// ClientURL is the URL to serve the clients.
+	ClientURL string
+	// PeerURL is the URL to communicate with the other members.
+	PeerURL string
+	// InitialCluster is the comma separated list of 'name=PeerURL' of all members
+	// to form the cluster at the first start. A single member cluster is formed if it is empty.
+	InitialCluster string
+	// StartTimeout is the time to wait for the server to be ready.
+	// defaultStartTimeout is used if it is 0.
+	StartTimeout time.Duration
+}
+
+// EmbeddedServer is the etcd server running in the process.
+type EmbeddedServer struct {
+	etcd *embed.Etcd
+}
+
+func parseURLs(rawURL string) ([]url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, xerrors.Errorf("malformed URL '%s': %v %w", rawURL, err, tcErr.ErrInvalidArgument)
+	}
+	return []url.URL{*u}, nil
+}
+
+// StartEmbedded starts the etcd server and waits for it to be ready.
+func StartEmbedded(config EmbeddedConfig) (*EmbeddedServer, error) {
+	cfg := embed.NewConfig()
+	cfg.Name = config.Name
+	cfg.Dir = config.DataDir
+	clientURLs, err := parseURLs(config.ClientURL)
+	if err != nil {
+		return nil, err
+	}
+	peerURLs, err := parseURLs(config.PeerURL)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LCUrls, cfg.ACUrls = clientURLs, clientURLs
+	cfg.LPUrls, cfg.APUrls = peerURLs, peerURLs
+	cfg.InitialCluster = config.InitialCluster
+	if len(cfg.InitialCluster) == 0 {
+		cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+	}
+	cfg.InitialClusterToken = "tiny-cluster"
+	if config.StartTimeout <= 0 {
+		config.StartTimeout = defaultStartTimeout
+	}
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to start etcd: %w", err)
+	}
+	select {
+	case <-e.Server.ReadyNotify():
+		return &EmbeddedServer{etcd: e}, nil
+	case err := <-e.Err():
+		e.Close()
+		return nil, xerrors.Errorf("Failed to start etcd: %w", err)
+	case <-time.After(config.StartTimeout):
+		// The other members may not have been started yet.
+		e.Server.Stop()
+		e.Close()
+		return nil, xerrors.Errorf("etcd did not get ready in %s %w", config.StartTimeout, tcErr.ErrTimedOut)
+	}
+}
+
+// Endpoints returns the URLs to connect to the server.
+func (s *EmbeddedServer) Endpoints() []string {
+	var endpoints []string
+	for _, u := range s.etcd.Config().ACUrls {
+		endpoints = append(endpoints, u.String())
+	}
+	return endpoints
+}
+
+// Err returns the channel to receive the fatal error of the server.
+func (s *EmbeddedServer) Err() <-chan error {
+	return s.etcd.Err()
+}
+
+// Close stops the server gracefully.
+func (s *EmbeddedServer) Close() {
+	s.etcd.Close()
+}
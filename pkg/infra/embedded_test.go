@@ -0,0 +1,94 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func getFreeURL(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to get a free port due to %v", err)
+	}
+	defer l.Close()
+	return fmt.Sprintf("http://%s", l.Addr().String())
+}
+
+// startTestEmbedded starts the etcd server which is stopped at the end of the test.
+func startTestEmbedded(t *testing.T) *EmbeddedServer {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tc-embedded")
+	if err != nil {
+		t.Fatalf("Failed to create the directory due to %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	server, err := StartEmbedded(EmbeddedConfig{
+		Name:         "test",
+		DataDir:      dir,
+		ClientURL:    getFreeURL(t),
+		PeerURL:      getFreeURL(t),
+		StartTimeout: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to start etcd due to %v", err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStartEmbedded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc-embedded")
+	if err != nil {
+		t.Fatalf("Failed to create the directory due to %v", err)
+	}
+	defer os.RemoveAll(dir)
+	config := EmbeddedConfig{
+		Name:         "test",
+		DataDir:      dir,
+		ClientURL:    getFreeURL(t),
+		PeerURL:      getFreeURL(t),
+		StartTimeout: 30 * time.Second,
+	}
+	ctx := context.Background()
+	machine := &models.Machine{MAC: "mac1", Name: "machine1"}
+
+	server, err := StartEmbedded(config)
+	if err != nil {
+		t.Fatalf("Failed to start etcd due to %v", err)
+	}
+	if !reflect.DeepEqual(server.Endpoints(), []string{config.ClientURL}) {
+		t.Errorf("Expect: %v, Actual: %v", []string{config.ClientURL}, server.Endpoints())
+	}
+	client := newTestEtcdClient(t, Config{Endpoints: server.Endpoints(), RequestTimeout: 10 * time.Second})
+	err = NewMachineRepository(client).RegisterMachine(ctx, machine)
+	client.Close()
+	server.Close()
+	if err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+
+	// The data is kept in the directory.
+	server, err = StartEmbedded(config)
+	if err != nil {
+		t.Fatalf("Failed to restart etcd due to %v", err)
+	}
+	defer server.Close()
+	client = newTestEtcdClient(t, Config{Endpoints: server.Endpoints(), RequestTimeout: 10 * time.Second})
+	defer client.Close()
+	machines, err := NewMachineRepository(client).GetMachines(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the machines due to %v", err)
+	}
+	if !reflect.DeepEqual(machines, []*models.Machine{machine}) {
+		t.Errorf("Expect: %v, Actual: %v", []*models.Machine{machine}, machines)
+	}
+}
@@ -0,0 +1,187 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+const (
+	// heartbeatKeyPath is the path under the prefix of the namespace where the last check-ins are placed.
+	heartbeatKeyPath = "heartbeats/v1"
+	// aliveKeyPath is the path under the prefix of the namespace where the keys attached to
+	// the leases of the check-ins are placed. The key disappears when the lease expires.
+	aliveKeyPath = "alive/v1"
+)
+
+// heartbeatValue is the value of the key under heartbeatKeyPath.
+type heartbeatValue struct {
+	LastSeen int64 `json:"last_seen"`
+}
+
+// deleteHeartbeatOps returns the operations to delete the check-ins of the machine whose MAC is mac
+// in the namespace whose key prefix is prefix, which are deleted together with the machine.
+func deleteHeartbeatOps(prefix string, mac string) []clientv3.Op {
+	return []clientv3.Op{
+		clientv3.OpDelete(path.Join(prefix, heartbeatKeyPath, mac)),
+		clientv3.OpDelete(path.Join(prefix, aliveKeyPath, mac)),
+	}
+}
+
+type heartbeatRepoImpl struct {
+	*baseRepoImpl
+}
+
+func (h *heartbeatRepoImpl) Heartbeat(ctx context.Context, mac string, ttl time.Duration) error {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	client, err := h.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	valueByte, err := json.Marshal(&heartbeatValue{LastSeen: time.Now().Unix()})
+	if err != nil {
+		return err
+	}
+	heartbeatKey := path.Join(h.keyPrefix(ctx), heartbeatKeyPath, mac)
+	aliveKey := path.Join(h.keyPrefix(ctx), aliveKeyPath, mac)
+	resp, err := client.Get(ctx, aliveKey)
+	if err != nil {
+		return xerrors.Errorf("Failed to get the value of '%s' %w:", aliveKey, etcdError(err))
+	}
+	var leaseID clientv3.LeaseID
+	if len(resp.Kvs) != 0 && resp.Kvs[0].Lease != 0 {
+		leaseID = clientv3.LeaseID(resp.Kvs[0].Lease)
+		// The lease may expire at any time. A new lease is granted in that case.
+		if _, err := client.KeepAliveOnce(ctx, leaseID); err != nil {
+			leaseID = 0
+		}
+	}
+	for {
+		if leaseID == 0 {
+			seconds := int64((ttl + time.Second - 1) / time.Second)
+			granted, err := client.Grant(ctx, seconds)
+			if err != nil {
+				return xerrors.Errorf("Failed to grant the lease %w:", etcdError(err))
+			}
+			leaseID = granted.ID
+		}
+		_, err := client.Txn(ctx).Then(
+			clientv3.OpPut(heartbeatKey, string(valueByte)),
+			clientv3.OpPut(aliveKey, "", clientv3.WithLease(leaseID)),
+		).Commit()
+		if rpctypes.Error(err) == rpctypes.ErrLeaseNotFound {
+			// The lease expired after kept alive.
+			leaseID = 0
+			continue
+		}
+		if err != nil {
+			return xerrors.Errorf("etcd client operation error %w:", etcdError(err))
+		}
+		return nil
+	}
+}
+
+func (h *heartbeatRepoImpl) GetHeartbeats(ctx context.Context) ([]*models.Heartbeat, error) {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	client, err := h.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	heartbeatPrefix := path.Join(h.keyPrefix(ctx), heartbeatKeyPath) + "/"
+	alivePrefix := path.Join(h.keyPrefix(ctx), aliveKeyPath) + "/"
+	resp, err := client.Txn(ctx).Then(
+		clientv3.OpGet(heartbeatPrefix, clientv3.WithPrefix()),
+		clientv3.OpGet(alivePrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly()),
+	).Commit()
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the heartbeats %w:", etcdError(err))
+	}
+	alive := map[string]bool{}
+	for _, kv := range resp.Responses[1].GetResponseRange().Kvs {
+		alive[path.Base(string(kv.Key))] = true
+	}
+	var heartbeats []*models.Heartbeat
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		value := new(heartbeatValue)
+		if err := json.Unmarshal(kv.Value, value); err != nil {
+			return nil, err
+		}
+		mac := path.Base(string(kv.Key))
+		heartbeats = append(heartbeats, &models.Heartbeat{
+			MAC:       mac,
+			LastSeen:  value.LastSeen,
+			Reachable: alive[mac],
+		})
+	}
+	return heartbeats, nil
+}
+
+func (h *heartbeatRepoImpl) DeleteHeartbeats(ctx context.Context, macs []string) error {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	client, err := h.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mac := range macs {
+		if _, err := client.Txn(ctx).Then(deleteHeartbeatOps(h.keyPrefix(ctx), mac)...).Commit(); err != nil {
+			return xerrors.Errorf("Failed to delete the heartbeat of '%s' %w:", mac, etcdError(err))
+		}
+	}
+	return nil
+}
+
+func (h *heartbeatRepoImpl) GetHeartbeatNamespaces(ctx context.Context) ([]string, error) {
+	ctx, cancel := h.withTimeout(ctx)
+	defer cancel()
+	client, err := h.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defaultPrefix := path.Join(h.client.config.Prefix, heartbeatKeyPath) + "/"
+	namespacesPrefix := path.Join(h.client.config.Prefix, namespacesKeyPath) + "/"
+	resp, err := client.Txn(ctx).Then(
+		clientv3.OpGet(defaultPrefix, clientv3.WithPrefix(), clientv3.WithCountOnly()),
+		// The namespaces are not recorded anywhere, so that they are found by the keys.
+		clientv3.OpGet(namespacesPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly()),
+	).Commit()
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the namespaces %w:", etcdError(err))
+	}
+	var namespaces []string
+	if resp.Responses[0].GetResponseRange().Count != 0 {
+		namespaces = append(namespaces, namespace.Default)
+	}
+	seen := map[string]bool{}
+	for _, kv := range resp.Responses[1].GetResponseRange().Kvs {
+		// The key is "<namespace>/heartbeats/v1/<mac>" under namespacesPrefix.
+		elems := strings.SplitN(strings.TrimPrefix(string(kv.Key), namespacesPrefix), "/", 2)
+		if len(elems) != 2 || !strings.HasPrefix(elems[1], heartbeatKeyPath+"/") || seen[elems[0]] {
+			continue
+		}
+		seen[elems[0]] = true
+		namespaces = append(namespaces, elems[0])
+	}
+	return namespaces, nil
+}
+
+// NewHeartbeatRepository returns the repository which keeps each machine reachable by an etcd lease.
+func NewHeartbeatRepository(client *Client) repo.HeartbeatRepository {
+	return &heartbeatRepoImpl{
+		baseRepoImpl: &baseRepoImpl{
+			client: client,
+		},
+	}
+}
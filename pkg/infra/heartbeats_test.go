@@ -0,0 +1,75 @@
+package infra
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/repositories/repotest"
+)
+
+func TestHeartbeatRepository_conformance(t *testing.T) {
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	client := getTestClient(t)
+	clean := func() {
+		ctx := context.Background()
+		for _, keyPath := range []string{heartbeatKeyPath, aliveKeyPath, namespacesKeyPath} {
+			if _, err := client.Delete(ctx, path.Join(BasePrefix, keyPath)+"/", clientv3.WithPrefix()); err != nil {
+				t.Fatalf("Failed to clean the keys due to %v", err)
+			}
+		}
+	}
+	repotest.TestHeartbeatRepository(t, func(t *testing.T) repo.HeartbeatRepository {
+		clean()
+		t.Cleanup(clean)
+		return NewHeartbeatRepository(etcdClient)
+	})
+}
+
+func TestHeartbeatRepository_deletedMachine(t *testing.T) {
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	client := getTestClient(t)
+	ctx := context.Background()
+	machine := &models.Machine{MAC: "heartbeat-mac1", Name: "heartbeat1"}
+	keys := []string{
+		path.Join(testMachinePrefix, machine.MAC),
+		path.Join(testMachineHistoryPrefix, machine.MAC) + "/",
+		path.Join(BasePrefix, heartbeatKeyPath, machine.MAC),
+		path.Join(BasePrefix, aliveKeyPath, machine.MAC),
+	}
+	defer func() {
+		for _, key := range keys {
+			if _, err := client.Delete(ctx, key, clientv3.WithPrefix()); err != nil {
+				t.Errorf("Failed to clean the keys due to %v", err)
+			}
+		}
+	}()
+	machines := NewMachineRepository(etcdClient)
+	heartbeats := NewHeartbeatRepository(etcdClient)
+	if err := machines.RegisterMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	if err := heartbeats.Heartbeat(ctx, machine.MAC, time.Minute); err != nil {
+		t.Fatalf("Failed to record the heartbeat due to %v", err)
+	}
+	if err := machines.DeleteMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to delete the machine due to %v", err)
+	}
+	// The check-ins are deleted together with the machine.
+	for _, key := range keys[2:] {
+		resp, err := client.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Failed to get the key due to %v", err)
+		}
+		if resp.Count != 0 {
+			t.Errorf("Expect '%s' to be deleted, Actual: %v", key, resp.Kvs)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// machineHistoryKeyPath is the path under the prefix of the namespace where the histories are placed.
+const machineHistoryKeyPath = "history/machines/v1"
+
+// machineHistoryPrefix returns the prefix of the history keys of the machine in the namespace of ctx.
+// The trailing slash prevents matching with other machines whose MAC starts with the same string.
+func (m *machineRepoImpl) machineHistoryPrefix(ctx context.Context, mac string) string {
+	return path.Join(m.keyPrefix(ctx), machineHistoryKeyPath, mac) + "/"
+}
+
+// lastMachineHistoryOp returns the operation to read the latest history of the machine.
+func (m *machineRepoImpl) lastMachineHistoryOp(ctx context.Context, mac string) clientv3.Op {
+	return clientv3.OpGet(m.machineHistoryPrefix(ctx, mac), clientv3.WithLastKey()...)
+}
+
+// historyRevision returns the revision of the history, which is the last element of its key.
+// The revision is taken from the key rather than the CreateRevision, so that it is kept by backup and restore.
+func historyRevision(key []byte) (int64, error) {
+	revision, err := strconv.ParseInt(path.Base(string(key)), 10, 64)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid key of the history '%s': %w", key, err)
+	}
+	return revision, nil
+}
+
+// nextHistoryRevision returns the revision of the history appended by the mutation of the machine
+// which was read at readRev, the revision of the store, with its latest history in last.
+// It is the revision which the transaction gets unless other keys are written meanwhile,
+// but greater than the latest history, which may have been restored from the store of larger revisions.
+func nextHistoryRevision(readRev int64, last []*mvccpb.KeyValue) (int64, error) {
+	revision := readRev
+	if len(last) != 0 {
+		lastRev, err := historyRevision(last[0].Key)
+		if err != nil {
+			return 0, err
+		}
+		if lastRev > revision {
+			revision = lastRev
+		}
+	}
+	return revision + 1, nil
+}
+
+// machineHistoryUnchanged returns the comparison which fails if any history of the machine has been written after readRev.
+// The transaction appending the history must include it, so that the histories are never appended out of order.
+func (m *machineRepoImpl) machineHistoryUnchanged(ctx context.Context, mac string, readRev int64) clientv3.Cmp {
+	return clientv3.Compare(clientv3.ModRevision(m.machineHistoryPrefix(ctx, mac)), "<", readRev+1).WithPrefix()
+}
+
+// newMachineHistoryOp returns the operation to append the history of the mutation from before to after.
+// The operation must be executed in the same transaction with the mutation and machineHistoryUnchanged.
+// revision is given by nextHistoryRevision, and the histories are sorted by it.
+func (m *machineRepoImpl) newMachineHistoryOp(ctx context.Context, mac string, revision int64, operation string, before *models.Machine, after *models.Machine) (clientv3.Op, error) {
+	history := &models.MachineHistory{
+		MAC:       mac,
+		Actor:     actor.FromContext(ctx),
+		Timestamp: time.Now().Unix(),
+		Operation: operation,
+		Machine:   after,
+		Changes:   models.DiffMachines(before, after),
+	}
+	valueByte, err := json.Marshal(history)
+	if err != nil {
+		return clientv3.Op{}, err
+	}
+	// The zero padded revision keeps the histories sorted by key.
+	key := m.machineHistoryPrefix(ctx, mac) + fmt.Sprintf("%020d", revision)
+	return clientv3.OpPut(key, string(valueByte)), nil
+}
+
+func (m *machineRepoImpl) GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error) {
+	var histories []*models.MachineHistory
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := m.machineHistoryPrefix(ctx, mac)
+	resp, err := client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the values whose key starts with '%s' %w:", prefix, etcdError(err))
+	}
+	for _, kv := range resp.Kvs {
+		history := new(models.MachineHistory)
+		if err := json.Unmarshal(kv.Value, history); err != nil {
+			return nil, err
+		}
+		if history.Revision, err = historyRevision(kv.Key); err != nil {
+			return nil, err
+		}
+		histories = append(histories, history)
+	}
+	return histories, nil
+}
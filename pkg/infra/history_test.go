@@ -0,0 +1,297 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	"github.com/pddg/tiny-cluster/pkg/backup"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func cleanMachineHistory(ctx context.Context, t *testing.T, client *clientv3.Client) {
+	t.Helper()
+	if _, err := client.Delete(ctx, testMachineHistoryPrefix, clientv3.WithPrefix()); err != nil {
+		t.Errorf("Failed to delete histories due to %v", err)
+	}
+}
+
+func Test_machineRepoImpl_GetMachineHistory(t *testing.T) {
+	ctx := actor.NewContext(context.Background(), "tester")
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
+	client := getTestClient(t)
+	machine := *machineFixtures.toSlice()[1]
+	updated := machine
+	updated.IPv4Addr = "192.168.1.3"
+	cleanMachineHistory(ctx, t, client)
+	defer func() {
+		tearDownTest(ctx, t, client, &machineFixtureImpl{&machine})
+		cleanMachineHistory(ctx, t, client)
+	}()
+
+	if err := r.RegisterMachine(ctx, &machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	if err := r.UpdateMachine(ctx, &updated); err != nil {
+		t.Fatalf("Failed to update the machine due to %v", err)
+	}
+	// No-op update must not be recorded.
+	if err := r.UpdateMachine(ctx, &updated); err != nil {
+		t.Fatalf("Failed to update the machine due to %v", err)
+	}
+	if err := r.DeleteMachine(ctx, &updated); err != nil {
+		t.Fatalf("Failed to delete the machine due to %v", err)
+	}
+
+	histories, err := r.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get histories due to %v", err)
+	}
+	expectOperations := []string{models.OperationRegister, models.OperationUpdate, models.OperationDelete}
+	var actualOperations []string
+	for _, h := range histories {
+		actualOperations = append(actualOperations, h.Operation)
+		if h.Actor != "tester" {
+			t.Errorf("Invalid actor. Expect: %s, Actual: %s", "tester", h.Actor)
+		}
+	}
+	if !reflect.DeepEqual(actualOperations, expectOperations) {
+		t.Fatalf("Invalid operations. Expect: %v, Actual: %v", expectOperations, actualOperations)
+	}
+	expectChanges := []models.FieldChange{{Field: "ipv4_addr", Old: machine.IPv4Addr, New: updated.IPv4Addr}}
+	if !reflect.DeepEqual(histories[1].Changes, expectChanges) {
+		t.Errorf("Invalid changes. Expect: %#v, Actual: %#v", expectChanges, histories[1].Changes)
+	}
+	if histories[2].Machine != nil {
+		t.Errorf("Deleted machine must not be recorded. Actual: %#v", histories[2].Machine)
+	}
+	for i := 1; i < len(histories); i++ {
+		if histories[i-1].Revision >= histories[i].Revision {
+			t.Errorf("Revisions are not increasing: %d, %d", histories[i-1].Revision, histories[i].Revision)
+		}
+	}
+}
+
+func Test_machineRepoImpl_RevertMachine(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
+	client := getTestClient(t)
+	machine := *machineFixtures.toSlice()[1]
+	updated := machine
+	updated.IPv4Addr = "192.168.1.3"
+	cleanMachineHistory(ctx, t, client)
+	defer func() {
+		tearDownTest(ctx, t, client, &machineFixtureImpl{&machine})
+		cleanMachineHistory(ctx, t, client)
+	}()
+
+	if err := r.RegisterMachine(ctx, &machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	if err := r.UpdateMachine(ctx, &updated); err != nil {
+		t.Fatalf("Failed to update the machine due to %v", err)
+	}
+	histories, err := r.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get histories due to %v", err)
+	}
+
+	testCases := []struct {
+		name      string
+		revision  int64
+		expect    *models.Machine
+		expectErr error
+	}{
+		{
+			name:      "revert to registered",
+			revision:  histories[0].Revision,
+			expect:    &machine,
+			expectErr: nil,
+		},
+		{
+			name:      "revert to updated",
+			revision:  histories[1].Revision,
+			expect:    &updated,
+			expectErr: nil,
+		},
+		{
+			name:      "revision does not exist",
+			revision:  histories[1].Revision + 100,
+			expect:    nil,
+			expectErr: tcErr.ErrNotFound,
+		},
+	}
+	// Run in order because each case depends on the state by the previous one.
+	for _, tc := range testCases {
+		actual, err := r.RevertMachine(ctx, machine.MAC, tc.revision)
+		if !xerrors.Is(err, tc.expectErr) {
+			t.Errorf("%s: Invalid error. Expect: %v, Actual: %v", tc.name, tc.expectErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expect) {
+			t.Errorf("%s: Invalid response. Expect: %#v, Actual: %#v", tc.name, tc.expect, actual)
+		}
+	}
+	histories, err = r.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get histories due to %v", err)
+	}
+	if last := histories[len(histories)-1]; last.Operation != models.OperationRevert {
+		t.Errorf("Revert must be recorded. Actual: %s", last.Operation)
+	}
+}
+
+func Test_machineRepoImpl_historyKeys(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient).(*machineRepoImpl)
+	client := getTestClient(t)
+	machine := *machineFixtures.toSlice()[1]
+	updated := machine
+	updated.IPv4Addr = "192.168.1.3"
+	otherKey := path.Join(BasePrefix, "history-keys-test")
+	cleanMachineHistory(ctx, t, client)
+	defer func() {
+		tearDownTest(ctx, t, client, &machineFixtureImpl{&machine})
+		cleanMachineHistory(ctx, t, client)
+		if _, err := client.Delete(ctx, otherKey); err != nil {
+			t.Errorf("Failed to delete %s due to %v", otherKey, err)
+		}
+	}()
+
+	if err := r.RegisterMachine(ctx, &machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	// Another key is written between the read and the transaction of the update.
+	repoClient, err := r.getClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.mutate(ctx, repoClient, machine.MAC, models.OperationUpdate, func(current *models.Machine) (*models.Machine, error) {
+		if _, err := client.Put(ctx, otherKey, "written"); err != nil {
+			return nil, err
+		}
+		return &updated, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to update the machine due to %v", err)
+	}
+	if err := r.DeleteMachine(ctx, &updated); err != nil {
+		t.Fatalf("Failed to delete the machine due to %v", err)
+	}
+
+	resp, err := client.Get(ctx, testMachineHistoryPrefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Kvs) != 3 {
+		t.Fatalf("Expect: 3 histories, Actual: %d", len(resp.Kvs))
+	}
+	// The keys are the revisions unless the others are written meanwhile, and sorted in the order of them anyway.
+	for i, kv := range resp.Kvs {
+		key := path.Base(string(kv.Key))
+		if i != 1 && key != fmt.Sprintf("%020d", kv.CreateRevision) {
+			t.Errorf("Invalid key of the revision %d: %s", kv.CreateRevision, key)
+		}
+		if i != 0 && resp.Kvs[i-1].CreateRevision >= kv.CreateRevision {
+			t.Errorf("Revisions are not sorted by key: %d, %d", resp.Kvs[i-1].CreateRevision, kv.CreateRevision)
+		}
+	}
+	if key := path.Base(string(resp.Kvs[1].Key)); key != fmt.Sprintf("%020d", resp.Kvs[1].CreateRevision-1) {
+		t.Errorf("Expect: the revision before the one of the transaction, Actual: %s (revision %d)", key, resp.Kvs[1].CreateRevision)
+	}
+}
+
+func Test_machineRepoImpl_restoredHistories(t *testing.T) {
+	ctx := actor.NewContext(context.Background(), "tester")
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
+	keyspace := NewKeyspaceRepository(etcdClient)
+	client := getTestClient(t)
+	machine := *machineFixtures.toSlice()[1]
+	updated := machine
+	updated.IPv4Addr = "192.168.1.3"
+	cleanMachineHistory(ctx, t, client)
+	defer func() {
+		tearDownTest(ctx, t, client, &machineFixtureImpl{&machine})
+		cleanMachineHistory(ctx, t, client)
+	}()
+
+	if err := r.RegisterMachine(ctx, &machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+	if err := r.UpdateMachine(ctx, &updated); err != nil {
+		t.Fatalf("Failed to update the machine due to %v", err)
+	}
+	archive, err := keyspace.Snapshot(ctx, BasePrefix)
+	if err != nil {
+		t.Fatalf("Failed to take a snapshot due to %v", err)
+	}
+	// The histories are restored from the store whose revisions are larger than this one,
+	// and all of them are written in a transaction.
+	const revisionOffset = 1000000000
+	var entries []backup.Entry
+	for _, entry := range archive.Entries {
+		switch {
+		case entry.Key == path.Join(machineKeyPath, machine.MAC):
+			entries = append(entries, entry)
+		case strings.HasPrefix(entry.Key, path.Join(machineHistoryKeyPath, machine.MAC)+"/"):
+			revision, err := historyRevision([]byte(entry.Key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry.Key = path.Join(path.Dir(entry.Key), fmt.Sprintf("%020d", revision+revisionOffset))
+			entries = append(entries, entry)
+		}
+	}
+	archive.Entries = entries
+	tearDownTest(ctx, t, client, &machineFixtureImpl{&machine})
+	cleanMachineHistory(ctx, t, client)
+	if _, err := keyspace.Restore(ctx, archive, BasePrefix, backup.ConflictFail); err != nil {
+		t.Fatalf("Failed to restore due to %v", err)
+	}
+
+	restored, err := r.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get histories due to %v", err)
+	}
+	if len(restored) != 2 || restored[0].Revision <= revisionOffset || restored[0].Revision >= restored[1].Revision {
+		t.Fatalf("The revisions must be restored as they were. Actual: %d histories, %v", len(restored), restored)
+	}
+	reverted, err := r.RevertMachine(ctx, machine.MAC, restored[0].Revision)
+	if err != nil {
+		t.Fatalf("Failed to revert the machine due to %v", err)
+	}
+	if !reflect.DeepEqual(reverted, &machine) {
+		t.Errorf("Invalid response. Expect: %#v, Actual: %#v", &machine, reverted)
+	}
+	histories, err := r.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get histories due to %v", err)
+	}
+	// The new history follows the restored ones.
+	if len(histories) != 3 || histories[2].Operation != models.OperationRevert || histories[2].Revision <= restored[1].Revision {
+		t.Fatalf("The revert must be the latest history. Actual: %v", histories)
+	}
+	reverted, err = r.RevertMachine(ctx, machine.MAC, restored[1].Revision)
+	if err != nil {
+		t.Fatalf("Failed to revert the machine due to %v", err)
+	}
+	if !reflect.DeepEqual(reverted, &updated) {
+		t.Errorf("Invalid response. Expect: %#v, Actual: %#v", &updated, reverted)
+	}
+}
@@ -2,50 +2,75 @@ package infra
 
 import (
 	"context"
-	"time"
+	"path"
 
-	"go.etcd.io/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
 	"golang.org/x/xerrors"
 
 	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
 )
 
+// BasePrefix is the default prefix of all keys written by TinyCluster.
 const BasePrefix = "/tiny-cluster"
 
+// namespacesKeyPath is the path under the prefix where the namespaces except the default one are placed.
+const namespacesKeyPath = "clusters"
+
 type baseRepoImpl struct {
-	config *clientv3.Config
+	client *Client
 }
 
-func (r *baseRepoImpl) newClient(ctx context.Context) (*clientv3.Client, error) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-	errCh := make(chan error, 1)
-	clientCh := make(chan *clientv3.Client)
-	go func() {
-		client, err := clientv3.New(*r.config)
-		if err != nil {
-			errCh <- xerrors.Errorf("Failed to create etcd client %w:", err)
-		} else {
-			clientCh <- client
-		}
-		close(clientCh)
-		close(errCh)
-	}()
-	select {
-	case <-timeoutCtx.Done():
-		return nil, tcErr.ErrTimedOut
-	case err := <-errCh:
-		return nil, xerrors.Errorf("Could not create etcd client %w:", err)
-	case client := <-clientCh:
-		return client, nil
+// keyPrefix returns the prefix of the keys in the namespace of ctx.
+// The default namespace is placed at the root of the prefix
+// to keep the keys written before the namespaces were introduced.
+func (r *baseRepoImpl) keyPrefix(ctx context.Context) string {
+	ns := namespace.FromContext(ctx)
+	if ns == namespace.Default {
+		return r.client.config.Prefix
+	}
+	return path.Join(r.client.config.Prefix, namespacesKeyPath, ns)
+}
+
+// withTimeout returns the context which is canceled after the request timeout of the client.
+func (r *baseRepoImpl) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return r.client.withTimeout(ctx)
+}
+
+// getClient returns the connection to etcd shared among the repositories.
+// It must not be closed by the caller.
+func (r *baseRepoImpl) getClient(ctx context.Context) (*clientv3.Client, error) {
+	return r.client.get(ctx)
+}
+
+// authError returns the error of TinyCluster if etcd rejected the credentials, otherwise nil.
+func authError(err error) error {
+	switch rpctypes.Error(err) {
+	case rpctypes.ErrAuthFailed, rpctypes.ErrInvalidAuthToken, rpctypes.ErrUserEmpty:
+		return xerrors.Errorf("%v %w", err, tcErr.ErrAuthFailed)
+	case rpctypes.ErrPermissionDenied:
+		return xerrors.Errorf("%v %w", err, tcErr.ErrPermissionDenied)
+	}
+	return nil
+}
+
+// etcdError converts the error returned by etcd into the one of TinyCluster if it is known.
+func etcdError(err error) error {
+	if authErr := authError(err); authErr != nil {
+		return authErr
+	}
+	if err == context.DeadlineExceeded {
+		return tcErr.ErrTimedOut
 	}
+	return err
 }
 
 func doGetWithRev(ctx context.Context, client *clientv3.Client, key string, opts ...clientv3.OpOption) ([]byte, int64, error) {
 	var value []byte
 	resp, err := client.Get(ctx, key, opts...)
 	if err != nil {
-		return value, 0, xerrors.Errorf("Failed to get the key ('%s') %w:", key, err)
+		return value, 0, xerrors.Errorf("Failed to get the key ('%s') %w:", key, etcdError(err))
 	}
 	if resp.Count == 0 {
 		return value, 0, tcErr.ErrNotFound
@@ -64,7 +89,7 @@ func doGetAll(ctx context.Context, client *clientv3.Client, key string, opts ...
 	opts = append(opts, clientv3.WithPrefix())
 	resp, err := client.Get(ctx, key, opts...)
 	if err != nil {
-		return values, xerrors.Errorf("Failed to get the values whose key starts with '%s' %w:", key, err)
+		return values, xerrors.Errorf("Failed to get the values whose key starts with '%s' %w:", key, etcdError(err))
 	}
 	for _, kv := range resp.Kvs {
 		if kv.Version == 0 {
@@ -75,15 +100,17 @@ func doGetAll(ctx context.Context, client *clientv3.Client, key string, opts ...
 	return values, nil
 }
 
-func doCreate(ctx context.Context, client *clientv3.Client, key string, value string) error {
+// doCreate puts the value only if the key does not exist.
+// ops are executed in the same transaction if the value is put.
+func doCreate(ctx context.Context, client *clientv3.Client, key string, value string, ops ...clientv3.Op) error {
 	doesNotExist := clientv3.Compare(clientv3.Version(key), "=", 0)
 	create := clientv3.OpPut(key, value)
 	createResp, err := client.Txn(ctx).
 		If(doesNotExist).
-		Then(create).
+		Then(append([]clientv3.Op{create}, ops...)...).
 		Commit()
 	if err != nil {
-		return xerrors.Errorf("etcd client operation error %w:", err)
+		return xerrors.Errorf("etcd client operation error %w:", etcdError(err))
 	}
 	if !createResp.Succeeded {
 		return tcErr.ErrAlreadyExists
@@ -102,7 +129,7 @@ RETRY:
 		Then(updateItem).
 		Commit()
 	if err != nil {
-		return xerrors.Errorf("etcd client operation error %w:", err)
+		return xerrors.Errorf("etcd client operation error %w:", etcdError(err))
 	}
 	if !updateResp.Succeeded {
 		return tcErr.ErrNotFound
@@ -120,6 +147,40 @@ RETRY:
 	return nil
 }
 
+// doCompareAndSwap puts the value only if the key has not been modified since rev.
+// rev must be 0 to create the key which does not exist.
+// It returns false if the key has been modified by others.
+// cmps must also hold to put the value, and ops are executed in the same transaction if the value is put.
+func doCompareAndSwap(ctx context.Context, client *clientv3.Client, rev int64, key string, value string, cmps []clientv3.Cmp, ops ...clientv3.Op) (bool, error) {
+	return doCompare(ctx, client, rev, key, cmps, append([]clientv3.Op{clientv3.OpPut(key, value)}, ops...))
+}
+
+// doCompareAndDelete deletes the key only if the key has not been modified since rev.
+// It returns false if the key has been modified by others.
+// cmps must also hold to delete the key, and ops are executed in the same transaction if the key is deleted.
+func doCompareAndDelete(ctx context.Context, client *clientv3.Client, rev int64, key string, cmps []clientv3.Cmp, ops ...clientv3.Op) (bool, error) {
+	return doCompare(ctx, client, rev, key, cmps, append([]clientv3.Op{clientv3.OpDelete(key)}, ops...))
+}
+
+func doCompare(ctx context.Context, client *clientv3.Client, rev int64, key string, cmps []clientv3.Cmp, ops []clientv3.Op) (bool, error) {
+	isNotUpdated := clientv3.Compare(clientv3.ModRevision(key), "=", rev)
+	resp, err := client.Txn(ctx).
+		If(append([]clientv3.Cmp{isNotUpdated}, cmps...)...).
+		Then(ops...).
+		Else(clientv3.OpGet(key, clientv3.WithCountOnly())).
+		Commit()
+	if err != nil {
+		return false, xerrors.Errorf("etcd client operation error %w:", etcdError(err))
+	}
+	if resp.Succeeded {
+		return true, nil
+	}
+	if resp.Responses[0].GetResponseRange().Count == 0 {
+		return false, tcErr.ErrNotFound
+	}
+	return false, nil
+}
+
 func doDelete(ctx context.Context, client *clientv3.Client, key string) error {
 	exists := clientv3.Compare(clientv3.Version(key), ">", 0)
 	deleteItem := clientv3.OpDelete(key)
@@ -128,7 +189,7 @@ func doDelete(ctx context.Context, client *clientv3.Client, key string) error {
 		Then(deleteItem).
 		Commit()
 	if err != nil {
-		return xerrors.Errorf("etcd client operation error %w:", err)
+		return xerrors.Errorf("etcd client operation error %w:", etcdError(err))
 	}
 	if !deleteResp.Succeeded {
 		return tcErr.ErrNotFound
@@ -3,13 +3,14 @@ package infra
 import (
 	"context"
 	"os"
+	"path"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
 	"time"
 
-	"go.etcd.io/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3"
 
 	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
 )
@@ -21,6 +22,13 @@ type testFixture interface {
 
 const testEtcdEndpointsKey = "TC_ETCD_ENDPOINTS"
 
+var (
+	// testMachinePrefix is the prefix of the machines in the default namespace.
+	testMachinePrefix = path.Join(BasePrefix, machineKeyPath)
+	// testMachineHistoryPrefix is the prefix of the histories in the default namespace.
+	testMachineHistoryPrefix = path.Join(BasePrefix, machineHistoryKeyPath)
+)
+
 func getTestEndpoints(t *testing.T) []string {
 	t.Helper()
 	urlsStr := os.Getenv(testEtcdEndpointsKey)
@@ -40,6 +48,26 @@ func getTestClient(t *testing.T) *clientv3.Client {
 	return client
 }
 
+// newTestEtcdClient returns the client with the config, which must be valid.
+func newTestEtcdClient(t *testing.T, config Config) *Client {
+	t.Helper()
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("Failed to create the client due to %v", err)
+	}
+	return client
+}
+
+// getTestEtcdClient returns the client shared among the repositories under the test.
+func getTestEtcdClient(t *testing.T) *Client {
+	t.Helper()
+	return newTestEtcdClient(t, Config{
+		Endpoints:      getTestEndpoints(t),
+		DialTimeout:    10 * time.Second,
+		RequestTimeout: 10 * time.Second,
+	})
+}
+
 func setUpTest(ctx context.Context, t *testing.T, client *clientv3.Client, fixtures testFixture) {
 	t.Helper()
 	if fixtures == nil {
@@ -340,3 +368,66 @@ func Test_doDelete(t *testing.T) {
 		})
 	}
 }
+
+func Test_doCompareAndSwap(t *testing.T) {
+	testCases := map[string]struct {
+		fixtures      *testFixtureImpl
+		key           string
+		delBeforeSwap bool
+		putBeforeSwap bool
+		expected      bool
+		expectedErr   error
+	}{
+		"swap successfully": {
+			fixtures:    &testFixtureImpl{"key": "value"},
+			key:         "key",
+			expected:    true,
+			expectedErr: nil,
+		},
+		"modified before swap": {
+			fixtures:      &testFixtureImpl{"key": "value"},
+			key:           "key",
+			putBeforeSwap: true,
+			expected:      false,
+			expectedErr:   nil,
+		},
+		"delete before swap": {
+			fixtures:      &testFixtureImpl{"key": "value"},
+			key:           "key",
+			delBeforeSwap: true,
+			expected:      false,
+			expectedErr:   tcErr.ErrNotFound,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		ctx := context.Background()
+		t.Run(tn, func(t *testing.T) {
+			client := getTestClient(t)
+			setUpTest(ctx, t, client, tc.fixtures)
+			defer tearDownTest(ctx, t, client, tc.fixtures)
+			resp, err := client.Get(ctx, tc.key)
+			if err != nil {
+				t.Errorf("Failed to get the value due to %v", err)
+			}
+			rev := resp.Kvs[0].ModRevision
+			if tc.delBeforeSwap {
+				if _, err := client.Delete(ctx, tc.key); err != nil {
+					t.Errorf("Failed to delete the key due to %v", err)
+				}
+			}
+			if tc.putBeforeSwap {
+				if _, err := client.Put(ctx, tc.key, "modified"); err != nil {
+					t.Errorf("Failed to update the key due to %v", err)
+				}
+			}
+			actual, err := doCompareAndSwap(ctx, client, rev, tc.key, "swapped", nil)
+			if err != tc.expectedErr {
+				t.Errorf("Error type is invalid. Expected: %v, Actual: %v", tc.expectedErr, err)
+			}
+			if actual != tc.expected {
+				t.Errorf("Result is invalid. Expected: %v, Actual: %v", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+// importChunkSize is the number of machines written in a transaction.
+// Each machine takes two operations (itself and its history) and two comparisons (of them),
+// and etcd accepts 128 operations and 128 comparisons in a transaction by default.
+const importChunkSize = 50
+
+type importEntry struct {
+	key    string
+	before *models.Machine
+	after  *models.Machine
+	// rev is the ModRevision of the key before the import, or 0 if the key did not exist.
+	rev int64
+	// readRev is the revision of the store when before was read.
+	readRev int64
+	// historyRev is the revision of the history of the import.
+	historyRev int64
+	// committedRev is the revision of the transaction which wrote after.
+	committedRev int64
+}
+
+func (m *machineRepoImpl) ImportMachines(ctx context.Context, machines []*models.Machine) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	// All machines and the keys of their histories are read at a revision by the ranged gets,
+	// rather than one by one.
+	machinePrefix := m.machinePrefix(ctx) + "/"
+	historyPrefix := path.Join(m.keyPrefix(ctx), machineHistoryKeyPath) + "/"
+	resp, err := client.Txn(ctx).Then(
+		clientv3.OpGet(machinePrefix, clientv3.WithPrefix()),
+		clientv3.OpGet(historyPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly()),
+	).Commit()
+	if err != nil {
+		return xerrors.Errorf("Failed to get the machines %w:", etcdError(err))
+	}
+	readRev := resp.Header.Revision
+	stored := map[string]*mvccpb.KeyValue{}
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		stored[string(kv.Key)] = kv
+	}
+	// The keys are sorted, so that the latest history of each machine is the last one of it.
+	lastHistories := map[string][]*mvccpb.KeyValue{}
+	for _, kv := range resp.Responses[1].GetResponseRange().Kvs {
+		mac := path.Dir(strings.TrimPrefix(string(kv.Key), historyPrefix))
+		lastHistories[mac] = []*mvccpb.KeyValue{kv}
+	}
+	var entries []*importEntry
+	for _, machine := range machines {
+		entry := &importEntry{
+			key:     path.Join(m.machinePrefix(ctx), machine.MAC),
+			after:   machine,
+			readRev: readRev,
+		}
+		if kv, ok := stored[entry.key]; ok {
+			entry.before = new(models.Machine)
+			if err := json.Unmarshal(kv.Value, entry.before); err != nil {
+				return err
+			}
+			entry.rev = kv.ModRevision
+		}
+		if entry.historyRev, err = nextHistoryRevision(readRev, lastHistories[machine.MAC]); err != nil {
+			return err
+		}
+		if reflect.DeepEqual(entry.before, entry.after) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	var committed []*importEntry
+	for start := 0; start < len(entries); start += importChunkSize {
+		end := start + importChunkSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		chunk := entries[start:end]
+		revision, err := m.commitImportChunk(ctx, client, chunk)
+		if err != nil {
+			if rollbackErr := m.rollbackImport(ctx, client, committed); rollbackErr != nil {
+				return xerrors.Errorf("failed to rollback the import (%v) after the error %w", rollbackErr, err)
+			}
+			return err
+		}
+		for _, entry := range chunk {
+			entry.committedRev = revision
+		}
+		committed = append(committed, chunk...)
+	}
+	return nil
+}
+
+// commitImportChunk writes the machines in a transaction and returns the revision of it.
+func (m *machineRepoImpl) commitImportChunk(ctx context.Context, client *clientv3.Client, chunk []*importEntry) (int64, error) {
+	var (
+		cmps []clientv3.Cmp
+		ops  []clientv3.Op
+	)
+	for _, entry := range chunk {
+		valueByte, err := json.Marshal(entry.after)
+		if err != nil {
+			return 0, err
+		}
+		operation := models.OperationUpdate
+		if entry.before == nil {
+			operation = models.OperationRegister
+		}
+		historyOp, err := m.newMachineHistoryOp(ctx, entry.after.MAC, entry.historyRev, operation, entry.before, entry.after)
+		if err != nil {
+			return 0, err
+		}
+		cmps = append(cmps,
+			clientv3.Compare(clientv3.ModRevision(entry.key), "=", entry.rev),
+			m.machineHistoryUnchanged(ctx, entry.after.MAC, entry.readRev),
+		)
+		ops = append(ops, clientv3.OpPut(entry.key, string(valueByte)), historyOp)
+	}
+	resp, err := client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return 0, xerrors.Errorf("etcd client operation error %w:", etcdError(err))
+	}
+	if !resp.Succeeded {
+		return 0, tcErr.ErrConflict
+	}
+	return resp.Header.Revision, nil
+}
+
+// rollbackImport restores the machines written by the import.
+// The machines modified by others after the import are left as they are.
+func (m *machineRepoImpl) rollbackImport(importCtx context.Context, client *clientv3.Client, committed []*importEntry) error {
+	// The context of the import may have been canceled, but the rollback must be done.
+	detached := actor.NewContext(context.Background(), actor.FromContext(importCtx))
+	detached = namespace.NewContext(detached, namespace.FromContext(importCtx))
+	ctx, cancel := m.withTimeout(detached)
+	defer cancel()
+	var failed []string
+	for _, entry := range committed {
+		// The history of the import is the latest one unless the machine has been modified after the import.
+		historyRev := entry.committedRev
+		if entry.historyRev > historyRev {
+			historyRev = entry.historyRev
+		}
+		historyOp, err := m.newMachineHistoryOp(ctx, entry.after.MAC, historyRev+1, models.OperationRevert, entry.after, entry.before)
+		if err != nil {
+			return err
+		}
+		historyUnchanged := []clientv3.Cmp{m.machineHistoryUnchanged(ctx, entry.after.MAC, entry.committedRev)}
+		var succeeded bool
+		if entry.before == nil {
+			succeeded, err = doCompareAndDelete(ctx, client, entry.committedRev, entry.key, historyUnchanged, historyOp)
+		} else {
+			valueByte, marshalErr := json.Marshal(entry.before)
+			if marshalErr != nil {
+				return marshalErr
+			}
+			succeeded, err = doCompareAndSwap(ctx, client, entry.committedRev, entry.key, string(valueByte), historyUnchanged, historyOp)
+		}
+		if err != nil || !succeeded {
+			failed = append(failed, entry.after.MAC)
+		}
+	}
+	if len(failed) != 0 {
+		return xerrors.Errorf("machines (%s) could not be restored %w", strings.Join(failed, ", "), tcErr.ErrConflict)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func Test_machineRepoImpl_ImportMachines(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
+	client := getTestClient(t)
+	// More machines than importChunkSize to be written in several transactions.
+	var imported machineFixtureImpl
+	for i := 0; i < importChunkSize+10; i++ {
+		imported = append(imported, &models.Machine{
+			MAC:  fmt.Sprintf("import-mac%03d", i),
+			Name: fmt.Sprintf("import%03d", i),
+		})
+	}
+	updated := *machineFixtures.toSlice()[1]
+	updated.Name = "updated"
+	imported = append(imported, &updated)
+	setUpTest(ctx, t, client, machineFixtures)
+	defer func() {
+		tearDownTest(ctx, t, client, machineFixtures)
+		tearDownTest(ctx, t, client, &imported)
+		cleanMachineHistory(ctx, t, client)
+	}()
+	// The history restored from the store of the larger revisions.
+	const restoredRev = int64(1e12)
+	restored := fmt.Sprintf("%s/%s/%020d", testMachineHistoryPrefix, updated.MAC, restoredRev)
+	if _, err := client.Put(ctx, restored, `{"operation":"register"}`); err != nil {
+		t.Fatalf("Failed to put the history due to %v", err)
+	}
+
+	if err := r.ImportMachines(ctx, imported.toSlice()); err != nil {
+		t.Fatalf("Failed to import due to %v", err)
+	}
+	actual, err := r.GetMachines(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get machines due to %v", err)
+	}
+	expect := append([]*models.Machine{machineFixtures.toSlice()[0]}, imported.toSlice()...)
+	sort.Slice(actual, func(i, j int) bool { return actual[i].MAC < actual[j].MAC })
+	sort.Slice(expect, func(i, j int) bool { return expect[i].MAC < expect[j].MAC })
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Invalid machines. Expect: %d machines, Actual: %d machines", len(expect), len(actual))
+	}
+	histories, err := r.GetMachineHistory(ctx, updated.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get histories due to %v", err)
+	}
+	if len(histories) != 2 || histories[1].Operation != models.OperationUpdate || histories[1].Revision <= restoredRev {
+		t.Errorf("The update must be recorded after the restored history. Actual: %#v", histories)
+	}
+}
+
+func Test_machineRepoImpl_rollbackImport(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient).(*machineRepoImpl)
+	client := getTestClient(t)
+	original := machineFixtures.toSlice()[1]
+	updated := *original
+	updated.Name = "updated"
+	created := &models.Machine{MAC: "import-new", Name: "new"}
+	setUpTest(ctx, t, client, &machineFixtureImpl{original})
+	defer func() {
+		tearDownTest(ctx, t, client, &machineFixtureImpl{original, created})
+		cleanMachineHistory(ctx, t, client)
+	}()
+
+	resp, err := client.Get(ctx, testMachinePrefix+"/"+original.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get the value due to %v", err)
+	}
+	entries := []*importEntry{
+		{key: testMachinePrefix + "/" + original.MAC, before: original, after: &updated, rev: resp.Kvs[0].ModRevision},
+		{key: testMachinePrefix + "/" + created.MAC, before: nil, after: created, rev: 0},
+	}
+	revision, err := r.commitImportChunk(ctx, client, entries)
+	if err != nil {
+		t.Fatalf("Failed to commit due to %v", err)
+	}
+	// The revisions are stale now.
+	if _, err := r.commitImportChunk(ctx, client, entries); !xerrors.Is(err, tcErr.ErrConflict) {
+		t.Errorf("Invalid error. Expect: %v, Actual: %v", tcErr.ErrConflict, err)
+	}
+	for _, entry := range entries {
+		entry.committedRev = revision
+	}
+	if err := r.rollbackImport(ctx, client, entries); err != nil {
+		t.Fatalf("Failed to rollback due to %v", err)
+	}
+	actual, err := r.GetMachines(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get machines due to %v", err)
+	}
+	if !reflect.DeepEqual(actual, []*models.Machine{original}) {
+		t.Errorf("Machines are not restored. Actual: %#v", actual)
+	}
+}
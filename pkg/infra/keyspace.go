@@ -0,0 +1,157 @@
+package infra
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/backup"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// restoreChunkSize is the number of keys written in a transaction.
+// etcd accepts 128 operations in a transaction by default.
+const restoreChunkSize = 100
+
+type keyspaceRepoImpl struct {
+	*baseRepoImpl
+}
+
+// keyspacePrefix returns the prefix with a trailing slash,
+// which prevents matching with other prefixes which start with the same string.
+func keyspacePrefix(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/"
+}
+
+func (k *keyspaceRepoImpl) Snapshot(ctx context.Context, prefix string) (*backup.Archive, error) {
+	ctx, cancel := k.withTimeout(ctx)
+	defer cancel()
+	client, err := k.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	base := keyspacePrefix(prefix)
+	// All keys are obtained at a single revision.
+	resp, err := client.Get(ctx, base, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the values whose key starts with '%s': %w", base, etcdError(err))
+	}
+	archive := &backup.Archive{
+		Prefix:    prefix,
+		Revision:  resp.Header.Revision,
+		CreatedAt: time.Now().Unix(),
+		Entries:   make([]backup.Entry, 0, len(resp.Kvs)),
+	}
+	for _, kv := range resp.Kvs {
+		// The keys bound to the leases, such as the candidates of the election and the heartbeats,
+		// live only while their owners are alive. Restoring them without the leases makes them live forever.
+		if kv.Lease != 0 {
+			continue
+		}
+		archive.Entries = append(archive.Entries, backup.Entry{
+			Key:   strings.TrimPrefix(string(kv.Key), base),
+			Value: kv.Value,
+		})
+	}
+	return archive, nil
+}
+
+func (k *keyspaceRepoImpl) Restore(ctx context.Context, archive *backup.Archive, prefix string, policy backup.ConflictPolicy) (*backup.RestoreResult, error) {
+	ctx, cancel := k.withTimeout(ctx)
+	defer cancel()
+	client, err := k.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	base := keyspacePrefix(prefix)
+	if policy == backup.ConflictFail {
+		if err := checkNoConflicts(ctx, client, base, archive.Entries); err != nil {
+			return nil, err
+		}
+	}
+	result := &backup.RestoreResult{}
+	for start := 0; start < len(archive.Entries); start += restoreChunkSize {
+		end := start + restoreChunkSize
+		if end > len(archive.Entries) {
+			end = len(archive.Entries)
+		}
+		written, err := restoreChunk(ctx, client, base, archive.Entries[start:end], policy)
+		if err != nil {
+			return result, err
+		}
+		result.Written += written
+		result.Skipped += end - start - written
+	}
+	return result, nil
+}
+
+// checkNoConflicts returns ErrAlreadyExists if any of the entries exists under the prefix.
+func checkNoConflicts(ctx context.Context, client *clientv3.Client, base string, entries []backup.Entry) error {
+	resp, err := client.Get(ctx, base, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return xerrors.Errorf("Failed to get the keys which start with '%s': %w", base, etcdError(err))
+	}
+	exists := map[string]bool{}
+	for _, kv := range resp.Kvs {
+		exists[string(kv.Key)] = true
+	}
+	for _, entry := range entries {
+		if exists[base+entry.Key] {
+			return xerrors.Errorf("'%s' already exists %w", base+entry.Key, tcErr.ErrAlreadyExists)
+		}
+	}
+	return nil
+}
+
+// restoreChunk writes the entries in a transaction and returns the number of the written keys.
+func restoreChunk(ctx context.Context, client *clientv3.Client, base string, entries []backup.Entry, policy backup.ConflictPolicy) (int, error) {
+	var (
+		cmps []clientv3.Cmp
+		ops  []clientv3.Op
+	)
+	for _, entry := range entries {
+		key := base + entry.Key
+		put := clientv3.OpPut(key, string(entry.Value))
+		doesNotExist := clientv3.Compare(clientv3.Version(key), "=", 0)
+		switch policy {
+		case backup.ConflictOverwrite:
+			ops = append(ops, put)
+		case backup.ConflictSkip:
+			ops = append(ops, clientv3.OpTxn([]clientv3.Cmp{doesNotExist}, []clientv3.Op{put}, nil))
+		default:
+			cmps = append(cmps, doesNotExist)
+			ops = append(ops, put)
+		}
+	}
+	resp, err := client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return 0, xerrors.Errorf("etcd client operation error: %w", etcdError(err))
+	}
+	if !resp.Succeeded {
+		// Some keys were created by others after checkNoConflicts.
+		return 0, tcErr.ErrConflict
+	}
+	if policy != backup.ConflictSkip {
+		return len(entries), nil
+	}
+	written := 0
+	for _, r := range resp.Responses {
+		if r.GetResponseTxn().Succeeded {
+			written++
+		}
+	}
+	return written, nil
+}
+
+// NewKeyspaceRepository returns the repository to read and write the raw key-value pairs in etcd.
+func NewKeyspaceRepository(client *Client) repo.KeyspaceRepository {
+	return &keyspaceRepoImpl{
+		baseRepoImpl: &baseRepoImpl{
+			client: client,
+		},
+	}
+}
@@ -0,0 +1,181 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/backup"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+const (
+	keyspaceTestSource = BasePrefix + "-test/keyspace/source"
+	keyspaceTestTarget = BasePrefix + "-test/keyspace/target"
+)
+
+func cleanKeyspace(ctx context.Context, t *testing.T, client *clientv3.Client) {
+	if _, err := client.Delete(ctx, BasePrefix+"-test/keyspace/", clientv3.WithPrefix()); err != nil {
+		t.Fatalf("Failed to clean the keyspace due to %v", err)
+	}
+}
+
+func getKeyspace(ctx context.Context, t *testing.T, client *clientv3.Client, prefix string) map[string]string {
+	resp, err := client.Get(ctx, prefix+"/", clientv3.WithPrefix())
+	if err != nil {
+		t.Fatalf("Failed to get the keyspace due to %v", err)
+	}
+	values := map[string]string{}
+	for _, kv := range resp.Kvs {
+		values[string(kv.Key)] = string(kv.Value)
+	}
+	return values
+}
+
+func Test_keyspaceRepoImpl_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewKeyspaceRepository(etcdClient)
+	client := getTestClient(t)
+	defer cleanKeyspace(ctx, t, client)
+	for _, kv := range [][2]string{
+		{keyspaceTestSource + "/b", "2"},
+		{keyspaceTestSource + "/a/1", "1"},
+		// Must not be contained since only the prefix is matched.
+		{keyspaceTestSource + "-other/c", "3"},
+	} {
+		if _, err := client.Put(ctx, kv[0], kv[1]); err != nil {
+			t.Fatalf("Failed to put the value due to %v", err)
+		}
+	}
+
+	archive, err := r.Snapshot(ctx, keyspaceTestSource)
+	if err != nil {
+		t.Fatalf("Failed to take a snapshot due to %v", err)
+	}
+	expect := []backup.Entry{
+		{Key: "a/1", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+	}
+	if !reflect.DeepEqual(archive.Entries, expect) {
+		t.Errorf("Invalid entries. Expect: %v, Actual: %v", expect, archive.Entries)
+	}
+	if archive.Prefix != keyspaceTestSource || archive.Revision == 0 {
+		t.Errorf("Invalid archive. Actual: %#v", archive)
+	}
+}
+
+func Test_keyspaceRepoImpl_Restore(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewKeyspaceRepository(etcdClient)
+	client := getTestClient(t)
+	archive := &backup.Archive{Prefix: keyspaceTestSource}
+	// More entries than restoreChunkSize to be written in several transactions.
+	for i := 0; i < restoreChunkSize+10; i++ {
+		archive.Entries = append(archive.Entries, backup.Entry{
+			Key:   fmt.Sprintf("key%03d", i),
+			Value: []byte("restored"),
+		})
+	}
+	existing := keyspaceTestTarget + "/key001"
+	cases := map[string]struct {
+		policy   backup.ConflictPolicy
+		expect   *backup.RestoreResult
+		existing string
+		err      error
+	}{
+		"fail": {
+			policy:   backup.ConflictFail,
+			existing: "existing",
+			err:      tcErr.ErrAlreadyExists,
+		},
+		"skip": {
+			policy:   backup.ConflictSkip,
+			expect:   &backup.RestoreResult{Written: len(archive.Entries) - 1, Skipped: 1},
+			existing: "existing",
+		},
+		"overwrite": {
+			policy:   backup.ConflictOverwrite,
+			expect:   &backup.RestoreResult{Written: len(archive.Entries)},
+			existing: "restored",
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			cleanKeyspace(ctx, t, client)
+			defer cleanKeyspace(ctx, t, client)
+			if _, err := client.Put(ctx, existing, "existing"); err != nil {
+				t.Fatalf("Failed to put the value due to %v", err)
+			}
+			actual, err := r.Restore(ctx, archive, keyspaceTestTarget, tc.policy)
+			if tc.err != nil {
+				if !xerrors.Is(err, tc.err) {
+					t.Fatalf("Expect: %v, Actual: %v", tc.err, err)
+				}
+				if values := getKeyspace(ctx, t, client, keyspaceTestTarget); len(values) != 1 {
+					t.Errorf("Nothing must be written. Actual: %d keys", len(values))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to restore due to %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid result. Expect: %v, Actual: %v", tc.expect, actual)
+			}
+			values := getKeyspace(ctx, t, client, keyspaceTestTarget)
+			if len(values) != len(archive.Entries) {
+				t.Errorf("All keys must exist. Expect: %d, Actual: %d", len(archive.Entries), len(values))
+			}
+			if values[existing] != tc.existing {
+				t.Errorf("Invalid value of the existing key. Expect: %s, Actual: %s", tc.existing, values[existing])
+			}
+		})
+	}
+}
+
+func Test_keyspaceRepoImpl_SnapshotRestore_leases(t *testing.T) {
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewKeyspaceRepository(etcdClient)
+	client := getTestClient(t)
+	defer cleanKeyspace(ctx, t, client)
+	lease, err := client.Grant(ctx, 60)
+	if err != nil {
+		t.Fatalf("Failed to grant the lease due to %v", err)
+	}
+	defer client.Revoke(ctx, lease.ID)
+	for _, kv := range [][2]string{
+		{keyspaceTestSource + "/election/bootserver/1", "replica1"},
+		{keyspaceTestSource + "/alive/v1/00:00:5e:00:53:01", "alive"},
+	} {
+		if _, err := client.Put(ctx, kv[0], kv[1], clientv3.WithLease(lease.ID)); err != nil {
+			t.Fatalf("Failed to put the value due to %v", err)
+		}
+	}
+	if _, err := client.Put(ctx, keyspaceTestSource+"/machines/v1/00:00:5e:00:53:01", "machine"); err != nil {
+		t.Fatalf("Failed to put the value due to %v", err)
+	}
+
+	archive, err := r.Snapshot(ctx, keyspaceTestSource)
+	if err != nil {
+		t.Fatalf("Failed to take a snapshot due to %v", err)
+	}
+	if _, err := r.Restore(ctx, archive, keyspaceTestTarget, backup.ConflictFail); err != nil {
+		t.Fatalf("Failed to restore due to %v", err)
+	}
+	// The keys bound to the leases must not be restored, since nothing could ever expire them.
+	expect := map[string]string{keyspaceTestTarget + "/machines/v1/00:00:5e:00:53:01": "machine"}
+	if actual := getKeyspace(ctx, t, client, keyspaceTestTarget); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
@@ -4,32 +4,38 @@ import (
 	"context"
 	"encoding/json"
 	"path"
-	"time"
+	"reflect"
 
-	"go.etcd.io/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3"
+	"golang.org/x/xerrors"
 
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
 	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
 	repo "github.com/pddg/tiny-cluster/pkg/repositories"
 )
 
-var machinePrefix = path.Join(BasePrefix, "machines/v1")
+// machineKeyPath is the path under the prefix of the namespace where the machines are placed.
+const machineKeyPath = "machines/v1"
 
 type machineRepoImpl struct {
 	*baseRepoImpl
 }
 
-func (m *machineRepoImpl) getKey(machine *models.Machine) string {
-	return path.Join(machinePrefix, machine.MAC)
+// machinePrefix returns the prefix of the machine keys in the namespace of ctx.
+func (m *machineRepoImpl) machinePrefix(ctx context.Context) string {
+	return path.Join(m.keyPrefix(ctx), machineKeyPath)
 }
 
 func (m *machineRepoImpl) GetMachines(ctx context.Context) ([]*models.Machine, error) {
 	var machines []*models.Machine
-	client, err := m.newClient(ctx)
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
-	allValues, err := doGetAll(ctx, client, machinePrefix)
+	allValues, err := doGetAll(ctx, client, m.machinePrefix(ctx)+"/")
 	if err != nil {
 		return machines, err
 	}
@@ -43,67 +49,278 @@ func (m *machineRepoImpl) GetMachines(ctx context.Context) ([]*models.Machine, e
 	return machines, nil
 }
 
-func (m *machineRepoImpl) RegisterMachine(ctx context.Context, machine *models.Machine) error {
-	client, err := m.newClient(ctx)
+func (m *machineRepoImpl) GetMachine(ctx context.Context, mac string) (*models.Machine, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	valueByte, err := doGet(ctx, client, path.Join(m.machinePrefix(ctx), mac))
+	if xerrors.Is(err, tcErr.ErrNotFound) {
+		return nil, tcErr.Wrap("GetMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
 	}
-	defer client.Close()
-	key := m.getKey(machine)
-	valueByte, err := json.Marshal(machine)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	machine := new(models.Machine)
+	if err := json.Unmarshal(valueByte, machine); err != nil {
+		return nil, err
 	}
-	value := string(valueByte)
-	if err := doCreate(ctx, client, key, value); err != nil {
+	return machine, nil
+}
+
+// machineMutation receives the current machine and returns the new one.
+// current is nil if the machine does not exist, and returning nil deletes the machine.
+type machineMutation func(current *models.Machine) (*models.Machine, error)
+
+// machineState is the machine read with the state of its histories at a revision of the store.
+type machineState struct {
+	// machine is the stored machine, or nil if it does not exist.
+	machine *models.Machine
+	// rev is the ModRevision of the machine, or 0 if it does not exist.
+	rev int64
+	// readRev is the revision of the store when it was read.
+	readRev int64
+	// historyRev is the revision of the history appended by the next mutation of the machine.
+	historyRev int64
+}
+
+// readMachine reads the machine whose MAC is mac and the latest history of it in a transaction.
+func (m *machineRepoImpl) readMachine(ctx context.Context, client *clientv3.Client, mac string) (*machineState, error) {
+	key := path.Join(m.machinePrefix(ctx), mac)
+	resp, err := client.Txn(ctx).Then(clientv3.OpGet(key), m.lastMachineHistoryOp(ctx, mac)).Commit()
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the key ('%s') %w:", key, etcdError(err))
+	}
+	state := &machineState{readRev: resp.Header.Revision}
+	if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) != 0 {
+		state.machine = new(models.Machine)
+		if err := json.Unmarshal(kvs[0].Value, state.machine); err != nil {
+			return nil, err
+		}
+		state.rev = kvs[0].ModRevision
+	}
+	if state.historyRev, err = nextHistoryRevision(state.readRev, resp.Responses[1].GetResponseRange().Kvs); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// mutate applies the mutation to the machine whose MAC is mac and appends the history of it atomically.
+// If the machine is modified by others during the mutation, it is retried with the latest one.
+func (m *machineRepoImpl) mutate(ctx context.Context, client *clientv3.Client, mac string, operation string, mutation machineMutation) (*models.Machine, error) {
+	key := path.Join(m.machinePrefix(ctx), mac)
+	for {
+		state, err := m.readMachine(ctx, client, mac)
+		if err != nil {
+			return nil, err
+		}
+		current := state.machine
+		next, err := mutation(current)
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(current, next) {
+			return next, nil
+		}
+		historyOp, err := m.newMachineHistoryOp(ctx, mac, state.historyRev, operation, current, next)
+		if err != nil {
+			return nil, err
+		}
+		historyUnchanged := []clientv3.Cmp{m.machineHistoryUnchanged(ctx, mac, state.readRev)}
+		var succeeded bool
+		if next == nil {
+			// The check-ins of the deleted machine would be left forever otherwise.
+			ops := append([]clientv3.Op{historyOp}, deleteHeartbeatOps(m.keyPrefix(ctx), mac)...)
+			succeeded, err = doCompareAndDelete(ctx, client, state.rev, key, historyUnchanged, ops...)
+		} else {
+			valueByte, marshalErr := json.Marshal(next)
+			if marshalErr != nil {
+				return nil, marshalErr
+			}
+			succeeded, err = doCompareAndSwap(ctx, client, state.rev, key, string(valueByte), historyUnchanged, historyOp)
+		}
+		if err != nil && !xerrors.Is(err, tcErr.ErrNotFound) {
+			return nil, err
+		}
+		if succeeded {
+			return next, nil
+		}
+		// the item has been modified by others, apply the mutation to the latest one
+	}
+}
+
+func (m *machineRepoImpl) RegisterMachine(ctx context.Context, machine *models.Machine) error {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
+	if err != nil {
 		return err
 	}
-	return nil
+	_, err = m.mutate(ctx, client, machine.MAC, models.OperationRegister, func(current *models.Machine) (*models.Machine, error) {
+		if current != nil {
+			return nil, tcErr.Wrap("RegisterMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrAlreadyExists)
+		}
+		registered := *machine
+		return &registered, nil
+	})
+	return err
 }
 
 func (m *machineRepoImpl) DeleteMachine(ctx context.Context, machine *models.Machine) error {
-	client, err := m.newClient(ctx)
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
-	key := m.getKey(machine)
-	return doDelete(ctx, client, key)
+	_, err = m.mutate(ctx, client, machine.MAC, models.OperationDelete, func(current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("DeleteMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrNotFound)
+		}
+		return nil, nil
+	})
+	return err
 }
 
 func (m *machineRepoImpl) UpdateMachine(ctx context.Context, machine *models.Machine) error {
-	client, err := m.newClient(ctx)
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
-	key := m.getKey(machine)
-	value, rev, err := doGetWithRev(ctx, client, key)
+	_, err = m.mutate(ctx, client, machine.MAC, models.OperationUpdate, func(current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("UpdateMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrNotFound)
+		}
+		updated := *machine
+		return &updated, nil
+	})
+	return err
+}
+
+func (m *machineRepoImpl) PatchMachine(ctx context.Context, mac string, patch repo.MachinePatchFunc) (*models.Machine, error) {
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	existsMachine := new(models.Machine)
-	if err := json.Unmarshal(value, existsMachine); err != nil {
-		return err
+	return m.mutate(ctx, client, mac, models.OperationUpdate, func(current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("PatchMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+		}
+		machine := *current
+		if err := patch(&machine); err != nil {
+			return nil, err
+		}
+		if machine.MAC != current.MAC {
+			// MAC is a part of the key. It can not be changed by patch.
+			return nil, tcErr.Wrap("PatchMachine", tcErr.KindMachine, mac, tcErr.ErrInvalidArgument)
+		}
+		return &machine, nil
+	})
+}
+
+func (m *machineRepoImpl) RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error) {
+	histories, err := m.GetMachineHistory(ctx, mac)
+	if err != nil {
+		return nil, err
+	}
+	var target *models.MachineHistory
+	for _, h := range histories {
+		if h.Revision == revision {
+			target = h
+			break
+		}
 	}
-	if machine == existsMachine {
-		return nil
+	if target == nil {
+		return nil, tcErr.Wrap("RevertMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
 	}
-	valueByte, err := json.Marshal(machine)
+	ctx, cancel := m.withTimeout(ctx)
+	defer cancel()
+	client, err := m.getClient(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	valueStr := string(valueByte)
-	return doUpdate(ctx, client, rev, key, valueStr)
+	return m.mutate(ctx, client, mac, models.OperationRevert, func(current *models.Machine) (*models.Machine, error) {
+		if target.Machine == nil {
+			return nil, nil
+		}
+		reverted := *target.Machine
+		return &reverted, nil
+	})
 }
 
-func NewMachineRepository(endpoints []string, timeout int) repo.MachineRepository {
+func NewMachineRepository(client *Client) repo.MachineRepository {
 	return &machineRepoImpl{
 		baseRepoImpl: &baseRepoImpl{
-			config: &clientv3.Config{
-				Endpoints:   endpoints,
-				DialTimeout: time.Duration(timeout) * time.Second,
-			},
+			client: client,
 		},
 	}
 }
+
+func (m *machineRepoImpl) WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error) {
+	// The watch is not bounded by the request timeout since it lasts until ctx is done.
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := m.machinePrefix(ctx) + "/"
+	ns := namespace.FromContext(ctx)
+	// Start watching from the next revision explicitly,
+	// otherwise the changes made before the watch is registered on the server are missed.
+	resp, err := client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+	if err != nil {
+		return nil, xerrors.Errorf("Failed to get the current revision %w:", etcdError(err))
+	}
+	watchCh := client.Watch(clientv3.WithRequireLeader(ctx), prefix,
+		clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(resp.Header.Revision+1))
+	events := make(chan *models.MachineEvent)
+	go func() {
+		defer close(events)
+		for watchResp := range watchCh {
+			// The watch was canceled or compacted.
+			if watchResp.Err() != nil {
+				return
+			}
+			for _, ev := range watchResp.Events {
+				event, err := machineEventFromEtcd(ev)
+				if err != nil {
+					return
+				}
+				event.Namespace = ns
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func machineEventFromEtcd(ev *clientv3.Event) (*models.MachineEvent, error) {
+	event := &models.MachineEvent{
+		Type:     models.EventPut,
+		Revision: ev.Kv.ModRevision,
+		Machine:  new(models.Machine),
+	}
+	value := ev.Kv.Value
+	if ev.Type == clientv3.EventTypeDelete {
+		event.Type = models.EventDelete
+		if ev.PrevKv == nil {
+			// The previous value has been compacted. Only MAC is known from the key.
+			event.Machine.MAC = path.Base(string(ev.Kv.Key))
+			return event, nil
+		}
+		value = ev.PrevKv.Value
+	}
+	if err := json.Unmarshal(value, event.Machine); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
@@ -8,12 +8,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/coreos/etcd/clientv3"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
-	"go.etcd.io/etcd/clientv3"
 	"golang.org/x/xerrors"
 
 	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
 	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/repositories/repotest"
 )
 
 type machineFixtureImpl []*models.Machine
@@ -22,7 +24,7 @@ func (mf *machineFixtureImpl) prepare(ctx context.Context, t *testing.T, client
 	t.Helper()
 	for _, v := range *mf {
 		valueByte, _ := json.Marshal(v)
-		_, err := client.Put(ctx, path.Join(machinePrefix, v.MAC), string(valueByte))
+		_, err := client.Put(ctx, path.Join(testMachinePrefix, v.MAC), string(valueByte))
 		if err != nil {
 			t.Errorf("Failed to put value due to %v", err)
 		}
@@ -31,7 +33,7 @@ func (mf *machineFixtureImpl) prepare(ctx context.Context, t *testing.T, client
 
 func (mf *machineFixtureImpl) clean(ctx context.Context, t *testing.T, client *clientv3.Client) {
 	for _, v := range *mf {
-		_, err := client.Delete(ctx, path.Join(machinePrefix, v.MAC))
+		_, err := client.Delete(ctx, path.Join(testMachinePrefix, v.MAC))
 		if err != nil {
 			switch {
 			case xerrors.Is(err, rpctypes.ErrKeyNotFound):
@@ -92,13 +94,14 @@ func Test_machineRepoImpl_GetMachines(t *testing.T) {
 		},
 	}
 	ctx := context.Background()
-	endpoints := getTestEndpoints(t)
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
 	for tn, tc := range testCases {
 		t.Run(tn, func(t *testing.T) {
 			client := getTestClient(t)
 			setUpTest(ctx, t, client, tc.fixtures)
 			defer tearDownTest(ctx, t, client, tc.fixtures)
-			r := NewMachineRepository(endpoints, 10)
+			r := NewMachineRepository(etcdClient)
 			actual, actualErr := r.GetMachines(ctx)
 			if !xerrors.Is(actualErr, tc.expectErr) {
 				t.Errorf("Invalid error. Expect: %#v, Actual: %v", tc.expectErr, actualErr)
@@ -135,8 +138,9 @@ func Test_machineRepoImpl_RegisterMachine(t *testing.T) {
 		},
 	}
 	ctx := context.Background()
-	endpoints := getTestEndpoints(t)
-	r := NewMachineRepository(endpoints, 10)
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
 	for tn, tc := range testCases {
 		t.Run(tn, func(t *testing.T) {
 			client := getTestClient(t)
@@ -179,8 +183,9 @@ func Test_machineRepoImpl_UpdateMachine(t *testing.T) {
 		},
 	}
 	ctx := context.Background()
-	endpoints := getTestEndpoints(t)
-	r := NewMachineRepository(endpoints, 10)
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
 	for tn, tc := range testCases {
 		t.Run(tn, func(t *testing.T) {
 			client := getTestClient(t)
@@ -212,8 +217,9 @@ func Test_machineRepoImpl_DeleteMachine(t *testing.T) {
 		},
 	}
 	ctx := context.Background()
-	endpoints := getTestEndpoints(t)
-	r := NewMachineRepository(endpoints, 10)
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
 	for tn, tc := range testCases {
 		t.Run(tn, func(t *testing.T) {
 			client := getTestClient(t)
@@ -226,3 +232,104 @@ func Test_machineRepoImpl_DeleteMachine(t *testing.T) {
 		})
 	}
 }
+
+func Test_machineRepoImpl_PatchMachine(t *testing.T) {
+	patchErr := xerrors.New("patch error")
+	original := machineFixtures.toSlice()[1]
+	patched := *original
+	patched.IPv4Addr = "192.168.1.3"
+	testCases := map[string]struct {
+		fixtures  *machineFixtureImpl
+		mac       string
+		patch     repo.MachinePatchFunc
+		expect    *models.Machine
+		expectErr error
+	}{
+		"patch normally": {
+			fixtures: machineFixtures,
+			mac:      original.MAC,
+			patch: func(m *models.Machine) error {
+				m.IPv4Addr = patched.IPv4Addr
+				return nil
+			},
+			expect:    &patched,
+			expectErr: nil,
+		},
+		"no changes": {
+			fixtures: machineFixtures,
+			mac:      original.MAC,
+			patch: func(m *models.Machine) error {
+				return nil
+			},
+			expect:    original,
+			expectErr: nil,
+		},
+		"change MAC": {
+			fixtures: machineFixtures,
+			mac:      original.MAC,
+			patch: func(m *models.Machine) error {
+				m.MAC = "changed"
+				return nil
+			},
+			expect:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"patch returns error": {
+			fixtures: machineFixtures,
+			mac:      original.MAC,
+			patch: func(m *models.Machine) error {
+				return patchErr
+			},
+			expect:    nil,
+			expectErr: patchErr,
+		},
+		"patch non exist item": {
+			fixtures: &machineFixtureImpl{},
+			mac:      original.MAC,
+			patch: func(m *models.Machine) error {
+				return nil
+			},
+			expect:    nil,
+			expectErr: tcErr.ErrNotFound,
+		},
+	}
+	ctx := context.Background()
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	r := NewMachineRepository(etcdClient)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			client := getTestClient(t)
+			setUpTest(ctx, t, client, tc.fixtures)
+			defer tearDownTest(ctx, t, client, tc.fixtures)
+			actual, actualErr := r.PatchMachine(ctx, tc.mac, tc.patch)
+			if !xerrors.Is(actualErr, tc.expectErr) {
+				t.Errorf("Invalid error. Expect: %v, Actual: %v", tc.expectErr, actualErr)
+				return
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid response. Expect: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestMachineRepository_conformance(t *testing.T) {
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	client := getTestClient(t)
+	clean := func() {
+		ctx := context.Background()
+		for _, prefix := range []string{testMachinePrefix + "/", testMachineHistoryPrefix + "/", path.Join(BasePrefix, namespacesKeyPath) + "/"} {
+			if _, err := client.Delete(ctx, prefix, clientv3.WithPrefix()); err != nil {
+				t.Fatalf("Failed to clean the keys due to %v", err)
+			}
+		}
+	}
+	repotest.TestMachineRepository(t, func(t *testing.T) repo.MachineRepository {
+		clean()
+		t.Cleanup(clean)
+		return NewMachineRepository(etcdClient)
+	})
+}
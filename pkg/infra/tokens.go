@@ -0,0 +1,104 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/coreos/etcd/clientv3"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// tokenKeyPath is the path under the prefix where the tokens are placed.
+// The tokens are not in the namespaces, since they are shared among all of them.
+const tokenKeyPath = "tokens/v1"
+
+type tokenRepoImpl struct {
+	*baseRepoImpl
+}
+
+func (r *tokenRepoImpl) tokenPrefix() string {
+	return path.Join(r.client.config.Prefix, tokenKeyPath)
+}
+
+func (r *tokenRepoImpl) CreateToken(ctx context.Context, token *models.Token) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	valueByte, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := doCreate(ctx, client, path.Join(r.tokenPrefix(), token.ID), string(valueByte)); err != nil {
+		return tcErr.Wrap("CreateToken", tcErr.KindToken, token.ID, err)
+	}
+	return nil
+}
+
+func (r *tokenRepoImpl) GetToken(ctx context.Context, id string) (*models.Token, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	valueByte, err := doGet(ctx, client, path.Join(r.tokenPrefix(), id))
+	if err != nil {
+		return nil, tcErr.Wrap("GetToken", tcErr.KindToken, id, err)
+	}
+	token := new(models.Token)
+	if err := json.Unmarshal(valueByte, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *tokenRepoImpl) GetTokens(ctx context.Context) ([]*models.Token, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values, err := doGetAll(ctx, client, r.tokenPrefix()+"/", clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+	var tokens []*models.Token
+	for _, v := range values {
+		token := new(models.Token)
+		if err := json.Unmarshal(v, token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (r *tokenRepoImpl) DeleteToken(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	if err := doDelete(ctx, client, path.Join(r.tokenPrefix(), id)); err != nil {
+		return tcErr.Wrap("DeleteToken", tcErr.KindToken, id, err)
+	}
+	return nil
+}
+
+// NewTokenRepository returns the repository which stores the tokens in etcd.
+func NewTokenRepository(client *Client) repo.TokenRepository {
+	return &tokenRepoImpl{
+		baseRepoImpl: &baseRepoImpl{
+			client: client,
+		},
+	}
+}
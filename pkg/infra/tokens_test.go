@@ -0,0 +1,28 @@
+package infra
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/repositories/repotest"
+)
+
+func TestTokenRepository_conformance(t *testing.T) {
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	client := getTestClient(t)
+	clean := func() {
+		if _, err := client.Delete(context.Background(), path.Join(BasePrefix, tokenKeyPath)+"/", clientv3.WithPrefix()); err != nil {
+			t.Fatalf("Failed to clean the keys due to %v", err)
+		}
+	}
+	repotest.TestTokenRepository(t, func(t *testing.T) repo.TokenRepository {
+		clean()
+		t.Cleanup(clean)
+		return NewTokenRepository(etcdClient)
+	})
+}
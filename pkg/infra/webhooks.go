@@ -0,0 +1,170 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/coreos/etcd/clientv3"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+const (
+	// webhookKeyPath is the path under the prefix where the webhooks of all namespaces are placed.
+	webhookKeyPath = "webhooks/v1"
+	// webhookDeliveryKeyPath is the path under the prefix where the deliveries are placed for each webhook.
+	webhookDeliveryKeyPath = "history/webhooks/v1"
+)
+
+type webhookRepoImpl struct {
+	*baseRepoImpl
+}
+
+func (r *webhookRepoImpl) webhookKey(id string) string {
+	return path.Join(r.client.config.Prefix, webhookKeyPath, id)
+}
+
+// deliveryPrefix returns the prefix of the keys of the deliveries of the webhook, which ends with '/'.
+func (r *webhookRepoImpl) deliveryPrefix(id string) string {
+	return path.Join(r.client.config.Prefix, webhookDeliveryKeyPath, id) + "/"
+}
+
+func (r *webhookRepoImpl) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	valueByte, err := json.Marshal(webhook)
+	if err != nil {
+		return err
+	}
+	if err := doCreate(ctx, client, r.webhookKey(webhook.ID), string(valueByte)); err != nil {
+		return tcErr.Wrap("CreateWebhook", tcErr.KindWebhook, webhook.ID, err)
+	}
+	return nil
+}
+
+func (r *webhookRepoImpl) GetWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values, err := doGetAll(ctx, client, path.Join(r.client.config.Prefix, webhookKeyPath)+"/", clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+	var webhooks []*models.Webhook
+	for _, v := range values {
+		webhook := new(models.Webhook)
+		if err := json.Unmarshal(v, webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepoImpl) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	key := r.webhookKey(id)
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Version(key), ">", 0)).
+		Then(clientv3.OpDelete(key), clientv3.OpDelete(r.deliveryPrefix(id), clientv3.WithPrefix())).
+		Commit()
+	if err != nil {
+		return tcErr.Wrap("DeleteWebhook", tcErr.KindWebhook, id, etcdError(err))
+	}
+	if !resp.Succeeded {
+		return tcErr.Wrap("DeleteWebhook", tcErr.KindWebhook, id, tcErr.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *webhookRepoImpl) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	valueByte, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	webhookKey := r.webhookKey(delivery.WebhookID)
+	prefix := r.deliveryPrefix(delivery.WebhookID)
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Version(webhookKey), ">", 0)).
+		Then(clientv3.OpPut(prefix+delivery.ID, string(valueByte))).
+		Commit()
+	if err != nil {
+		return tcErr.Wrap("AddDelivery", tcErr.KindWebhook, delivery.WebhookID, etcdError(err))
+	}
+	if !resp.Succeeded {
+		return tcErr.Wrap("AddDelivery", tcErr.KindWebhook, delivery.WebhookID, tcErr.ErrNotFound)
+	}
+	// The oldest deliveries come first in the order of the keys.
+	keys, err := client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return tcErr.Wrap("AddDelivery", tcErr.KindWebhook, delivery.WebhookID, etcdError(err))
+	}
+	excess := len(keys.Kvs) - repo.MaxWebhookDeliveries
+	if excess <= 0 {
+		return nil
+	}
+	// The keys up to the excess one are deleted, which is the end of the range exclusive.
+	end := string(keys.Kvs[excess].Key)
+	if _, err := client.Delete(ctx, prefix, clientv3.WithRange(end)); err != nil {
+		return tcErr.Wrap("AddDelivery", tcErr.KindWebhook, delivery.WebhookID, etcdError(err))
+	}
+	return nil
+}
+
+func (r *webhookRepoImpl) GetDeliveries(ctx context.Context, id string) ([]*models.WebhookDelivery, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	client, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Txn(ctx).
+		If(clientv3.Compare(clientv3.Version(r.webhookKey(id)), ">", 0)).
+		Then(clientv3.OpGet(r.deliveryPrefix(id), clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))).
+		Commit()
+	if err != nil {
+		return nil, tcErr.Wrap("GetDeliveries", tcErr.KindWebhook, id, etcdError(err))
+	}
+	if !resp.Succeeded {
+		return nil, tcErr.Wrap("GetDeliveries", tcErr.KindWebhook, id, tcErr.ErrNotFound)
+	}
+	var deliveries []*models.WebhookDelivery
+	for _, kv := range resp.Responses[0].GetResponseRange().Kvs {
+		delivery := new(models.WebhookDelivery)
+		if err := json.Unmarshal(kv.Value, delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// NewWebhookRepository returns the repository which stores the webhooks in etcd.
+func NewWebhookRepository(client *Client) repo.WebhookRepository {
+	return &webhookRepoImpl{
+		baseRepoImpl: &baseRepoImpl{
+			client: client,
+		},
+	}
+}
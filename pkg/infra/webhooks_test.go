@@ -0,0 +1,30 @@
+package infra
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/coreos/etcd/clientv3"
+
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/repositories/repotest"
+)
+
+func TestWebhookRepository_conformance(t *testing.T) {
+	etcdClient := getTestEtcdClient(t)
+	defer etcdClient.Close()
+	client := getTestClient(t)
+	clean := func() {
+		for _, keyPath := range []string{webhookKeyPath, webhookDeliveryKeyPath} {
+			if _, err := client.Delete(context.Background(), path.Join(BasePrefix, keyPath)+"/", clientv3.WithPrefix()); err != nil {
+				t.Fatalf("Failed to clean the keys due to %v", err)
+			}
+		}
+	}
+	repotest.TestWebhookRepository(t, func(t *testing.T) repo.WebhookRepository {
+		clean()
+		t.Cleanup(clean)
+		return NewWebhookRepository(etcdClient)
+	})
+}
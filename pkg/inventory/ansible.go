@@ -0,0 +1,128 @@
+package inventory
+
+import (
+	"regexp"
+	"sort"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+const (
+	// GroupDeployed is the Ansible group of the machines which have been deployed.
+	GroupDeployed = "deployed"
+	// GroupUndeployed is the Ansible group of the machines which have not been deployed yet.
+	GroupUndeployed = "undeployed"
+)
+
+// invalidNameChars matches the characters which can not be used in the names of the Ansible groups and the Prometheus labels.
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// AnsibleGroup is a group of the hosts in the Ansible dynamic inventory.
+type AnsibleGroup struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+// AnsibleHostVars is the variables of a host in the Ansible dynamic inventory.
+type AnsibleHostVars struct {
+	// AnsibleHost is the address which Ansible connects to.
+	AnsibleHost string             `json:"ansible_host,omitempty"`
+	MAC         string             `json:"tc_mac"`
+	IPv4Addr    string             `json:"tc_ipv4_addr,omitempty"`
+	Spec        models.MachineSpec `json:"tc_spec"`
+	Labels      map[string]string  `json:"tc_labels,omitempty"`
+	Deployed    bool               `json:"tc_deployed"`
+	Liveness    string             `json:"tc_liveness,omitempty"`
+}
+
+// AnsibleMeta is the "_meta" section of the Ansible dynamic inventory, which avoids the query for each host.
+type AnsibleMeta struct {
+	HostVars map[string]*AnsibleHostVars `json:"hostvars"`
+}
+
+// AnsibleInventory is the Ansible dynamic inventory, which is the output of "--list" of the inventory script.
+// It is encoded as a JSON object of the groups with "_meta".
+type AnsibleInventory map[string]interface{}
+
+// Ansible returns the Ansible dynamic inventory of the machines, where each machine is named by its name.
+// The machines are grouped by each label as "<key>_<value>" such as "rack_a1", by the deployment as GroupDeployed or
+// GroupUndeployed, and by the liveness as models.LivenessReachable or models.LivenessUnreachable.
+// The machines have neither the rack nor the state, so that the groups by the rack and the state are approximated
+// by the labels such as "rack=a1" and by the deployment respectively.
+// The machines without the name are skipped. It fails with tcErr.ErrAlreadyExists if the different labels
+// are given the same group, such as "rack=a-1" and "rack=a_1" by AnsibleGroupName, instead of merging them.
+func Ansible(machines []*models.Machine) (AnsibleInventory, error) {
+	groups := map[string][]string{}
+	// sources is the label or the name which each group is made from.
+	sources := map[string]string{}
+	add := func(group, source, machineName string) error {
+		if other, ok := sources[group]; ok && other != source {
+			return xerrors.Errorf("group '%s' of '%s' collides with '%s' %w", group, source, other, tcErr.ErrAlreadyExists)
+		}
+		sources[group] = source
+		groups[group] = append(groups[group], machineName)
+		return nil
+	}
+	hostVars := map[string]*AnsibleHostVars{}
+	for _, machine := range machines {
+		if len(machine.Name) == 0 {
+			continue
+		}
+		vars := AnsibleHostVarsOf(machine)
+		hostVars[machine.Name] = vars
+		for key, value := range machine.Labels {
+			if err := add(AnsibleGroupName(key+"_"+value), key+"="+value, machine.Name); err != nil {
+				return nil, err
+			}
+		}
+		state := GroupUndeployed
+		if vars.Deployed {
+			state = GroupDeployed
+		}
+		if err := add(state, state, machine.Name); err != nil {
+			return nil, err
+		}
+		if len(machine.Liveness) != 0 {
+			if err := add(machine.Liveness, machine.Liveness, machine.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	inv := AnsibleInventory{
+		"_meta": &AnsibleMeta{HostVars: hostVars},
+	}
+	children := make([]string, 0, len(groups))
+	for name, hosts := range groups {
+		sort.Strings(hosts)
+		inv[name] = &AnsibleGroup{Hosts: hosts}
+		children = append(children, name)
+	}
+	sort.Strings(children)
+	inv["all"] = &AnsibleGroup{Children: children}
+	return inv, nil
+}
+
+// AnsibleHostVarsOf returns the variables of the machine, which is the output of "--host" of the inventory script.
+func AnsibleHostVarsOf(machine *models.Machine) *AnsibleHostVars {
+	return &AnsibleHostVars{
+		AnsibleHost: machine.IPv4Addr,
+		MAC:         machine.MAC,
+		IPv4Addr:    machine.IPv4Addr,
+		Spec:        machine.Spec,
+		Labels:      machine.Labels,
+		Deployed:    machine.DeployedDate > 0,
+		Liveness:    machine.Liveness,
+	}
+}
+
+// AnsibleGroupName returns the name which can be used as the Ansible group by replacing the invalid characters with '_'.
+func AnsibleGroupName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if len(name) != 0 && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
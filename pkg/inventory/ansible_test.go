@@ -0,0 +1,108 @@
+package inventory_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/inventory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func Test_Ansible(t *testing.T) {
+	machines := []*models.Machine{
+		machineFixtures[0],
+		machineFixtures[1],
+		{Name: "machine3", MAC: "mac3", DeployedDate: 1, Labels: map[string]string{"rack": "a-1"}, Liveness: models.LivenessReachable},
+		// The machine without the name is skipped.
+		{MAC: "mac4", Labels: map[string]string{"env": "staging"}},
+	}
+	inv, err := inventory.Ansible(machines)
+	if err != nil {
+		t.Fatalf("Failed to make the inventory due to %v", err)
+	}
+	valueByte, err := json.Marshal(inv)
+	if err != nil {
+		t.Fatalf("Failed to encode due to %v", err)
+	}
+	var actual map[string]struct {
+		Hosts    []string                   `json:"hosts"`
+		Children []string                   `json:"children"`
+		HostVars map[string]json.RawMessage `json:"hostvars"`
+	}
+	if err := json.Unmarshal(valueByte, &actual); err != nil {
+		t.Fatalf("Failed to decode due to %v", err)
+	}
+	expectedGroups := map[string][]string{
+		"env_staging":            {"machine2"},
+		"rack_a1":                {"machine2"},
+		"rack_a_1":               {"machine3"},
+		inventory.GroupDeployed:  {"machine1", "machine2", "machine3"},
+		models.LivenessReachable: {"machine3"},
+	}
+	children := []string{inventory.GroupDeployed, "env_staging", "rack_a1", "rack_a_1", models.LivenessReachable}
+	if !reflect.DeepEqual(actual["all"].Children, children) {
+		t.Errorf("Expect: %v, Actual: %v", children, actual["all"].Children)
+	}
+	for group, hosts := range expectedGroups {
+		if !reflect.DeepEqual(actual[group].Hosts, hosts) {
+			t.Errorf("%s: Expect: %v, Actual: %v", group, hosts, actual[group].Hosts)
+		}
+	}
+	if _, ok := actual[inventory.GroupUndeployed]; ok {
+		t.Errorf("The empty group must not exist")
+	}
+	if len(actual["_meta"].HostVars) != 3 {
+		t.Errorf("Expect the variables of 3 hosts, Actual: %v", actual["_meta"].HostVars)
+	}
+	var vars inventory.AnsibleHostVars
+	if err := json.Unmarshal(actual["_meta"].HostVars["machine1"], &vars); err != nil {
+		t.Fatalf("Failed to decode due to %v", err)
+	}
+	if !reflect.DeepEqual(&vars, inventory.AnsibleHostVarsOf(machineFixtures[1])) {
+		t.Errorf("Expect: %#v, Actual: %#v", inventory.AnsibleHostVarsOf(machineFixtures[1]), vars)
+	}
+	if vars.AnsibleHost != "19.168.0.2" || !vars.Deployed {
+		t.Errorf("Invalid variables: %#v", vars)
+	}
+}
+
+func Test_Ansible_collision(t *testing.T) {
+	testCases := map[string][]*models.Machine{
+		"substituted values": {
+			{Name: "machine1", MAC: "mac1", Labels: map[string]string{"rack": "a-1"}},
+			{Name: "machine2", MAC: "mac2", Labels: map[string]string{"rack": "a_1"}},
+		},
+		"separated differently": {
+			{Name: "machine1", MAC: "mac1", Labels: map[string]string{"rack_a": "1"}},
+			{Name: "machine2", MAC: "mac2", Labels: map[string]string{"rack": "a_1"}},
+		},
+	}
+	for tn, machines := range testCases {
+		machines := machines
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			if _, err := inventory.Ansible(machines); !xerrors.Is(err, tcErr.ErrAlreadyExists) {
+				t.Errorf("Expect: %v, Actual: %v", tcErr.ErrAlreadyExists, err)
+			}
+		})
+	}
+}
+
+func Test_AnsibleGroupName(t *testing.T) {
+	cases := map[string]string{
+		"env_prod":  "env_prod",
+		"rack_a-1":  "rack_a_1",
+		"zone_jp.1": "zone_jp_1",
+		"1u_true":   "_1u_true",
+		"site_東京":   "site___",
+	}
+	for name, expected := range cases {
+		if actual := inventory.AnsibleGroupName(name); actual != expected {
+			t.Errorf("%s: Expect: %s, Actual: %s", name, expected, actual)
+		}
+	}
+}
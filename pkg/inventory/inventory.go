@@ -0,0 +1,219 @@
+// Package inventory encodes and decodes the list of machines in several file formats.
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+	"sigs.k8s.io/yaml"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// Format is a file format of the inventory.
+type Format string
+
+const (
+	// FormatCSV is the CSV format whose first line is the header.
+	FormatCSV Format = "csv"
+	// FormatYAML is the YAML format which has the same structure as FormatJSON.
+	FormatYAML Format = "yaml"
+	// FormatJSON is the JSON format which is an array of models.Machine.
+	FormatJSON Format = "json"
+)
+
+// csvHeader is the list of the columns in the CSV format.
+// The files without the last column "labels", which were written before the labels were introduced, are also accepted.
+var csvHeader = []string{
+	"mac",
+	"name",
+	"ipv4_addr",
+	"deployed_date",
+	"spec.core",
+	"spec.memory",
+	"spec.disk",
+	"labels",
+}
+
+// ParseFormat returns the Format whose name is matched with the given one.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "csv":
+		return FormatCSV, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return "", xerrors.Errorf("unknown format '%s' %w", name, tcErr.ErrInvalidArgument)
+	}
+}
+
+// FormatFromPath returns the Format detected by the extension of the file.
+func FormatFromPath(path string) (Format, error) {
+	return ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatYAML:
+		return "application/yaml"
+	default:
+		return "application/json"
+	}
+}
+
+// Encode writes the machines into w in the format.
+// They are written in the order of MAC to make the output stable.
+func Encode(w io.Writer, format Format, machines []*models.Machine) error {
+	machines = append([]*models.Machine{}, machines...)
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].MAC < machines[j].MAC
+	})
+	switch format {
+	case FormatCSV:
+		return encodeCSV(w, machines)
+	case FormatYAML:
+		valueByte, err := yaml.Marshal(machines)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(valueByte)
+		return err
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(machines)
+	default:
+		return xerrors.Errorf("unknown format '%s' %w", format, tcErr.ErrInvalidArgument)
+	}
+}
+
+// Decode reads the machines from r in the format.
+func Decode(r io.Reader, format Format) ([]*models.Machine, error) {
+	var machines []*models.Machine
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatYAML:
+		valueByte, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.UnmarshalStrict(valueByte, &machines); err != nil {
+			return nil, xerrors.Errorf("malformed YAML: %v %w", err, tcErr.ErrInvalidArgument)
+		}
+	case FormatJSON:
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&machines); err != nil {
+			return nil, xerrors.Errorf("malformed JSON: %v %w", err, tcErr.ErrInvalidArgument)
+		}
+	default:
+		return nil, xerrors.Errorf("unknown format '%s' %w", format, tcErr.ErrInvalidArgument)
+	}
+	return machines, nil
+}
+
+func encodeCSV(w io.Writer, machines []*models.Machine) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, m := range machines {
+		record := []string{
+			m.MAC,
+			m.Name,
+			m.IPv4Addr,
+			strconv.FormatInt(m.DeployedDate, 10),
+			strconv.Itoa(m.Spec.Core),
+			strconv.Itoa(m.Spec.Memory),
+			strconv.Itoa(m.Spec.Disk),
+			models.FormatLabels(m.Labels),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func decodeCSV(r io.Reader) ([]*models.Machine, error) {
+	reader := csv.NewReader(r)
+	// The records must have as many fields as the header.
+	reader.FieldsPerRecord = 0
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read the header of CSV: %v %w", err, tcErr.ErrInvalidArgument)
+	}
+	if len(header) != len(csvHeader) && len(header) != len(csvHeader)-1 {
+		return nil, xerrors.Errorf("CSV must have %d columns but %d %w", len(csvHeader), len(header), tcErr.ErrInvalidArgument)
+	}
+	for i, column := range header {
+		if csvHeader[i] != column {
+			return nil, xerrors.Errorf("column %d of CSV must be '%s' but '%s' %w", i+1, csvHeader[i], column, tcErr.ErrInvalidArgument)
+		}
+	}
+	var machines []*models.Machine
+	// The first record is the header.
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("malformed CSV: %v %w", err, tcErr.ErrInvalidArgument)
+		}
+		machine, err := machineFromRecord(record)
+		if err != nil {
+			return nil, xerrors.Errorf("record %d: %w", row, err)
+		}
+		machines = append(machines, machine)
+	}
+	return machines, nil
+}
+
+func machineFromRecord(record []string) (*models.Machine, error) {
+	deployedDate, err := strconv.ParseInt(record[3], 10, 64)
+	if err != nil {
+		return nil, xerrors.Errorf("deployed_date must be an integer %w", tcErr.ErrInvalidArgument)
+	}
+	var spec [3]int
+	for i := range spec {
+		spec[i], err = strconv.Atoi(record[4+i])
+		if err != nil {
+			return nil, xerrors.Errorf("%s must be an integer %w", csvHeader[4+i], tcErr.ErrInvalidArgument)
+		}
+	}
+	var labels map[string]string
+	if len(record) > 7 {
+		if labels, err = models.ParseLabels(record[7]); err != nil {
+			return nil, err
+		}
+	}
+	return &models.Machine{
+		MAC:          record[0],
+		Name:         record[1],
+		IPv4Addr:     record[2],
+		DeployedDate: deployedDate,
+		Spec: models.MachineSpec{
+			Core:   spec[0],
+			Memory: spec[1],
+			Disk:   spec[2],
+		},
+		Labels: labels,
+	}, nil
+}
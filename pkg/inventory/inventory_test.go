@@ -0,0 +1,138 @@
+package inventory_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/inventory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+var machineFixtures = []*models.Machine{
+	{
+		Name:         "machine2",
+		MAC:          "mac2",
+		IPv4Addr:     "19.168.1.2",
+		DeployedDate: time.Date(2020, 10, 2, 0, 0, 0, 0, time.UTC).Unix(),
+		Spec: models.MachineSpec{
+			Core:   2,
+			Memory: 1024,
+			Disk:   64,
+		},
+		Labels: map[string]string{"env": "staging", "rack": "a1"},
+	},
+	{
+		Name:         "machine1",
+		MAC:          "mac1",
+		IPv4Addr:     "19.168.0.2",
+		DeployedDate: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC).Unix(),
+		Spec: models.MachineSpec{
+			Core:   4,
+			Memory: 2048,
+			Disk:   128,
+		},
+	},
+}
+
+func Test_EncodeDecode(t *testing.T) {
+	sorted := []*models.Machine{machineFixtures[1], machineFixtures[0]}
+	for _, format := range []inventory.Format{inventory.FormatCSV, inventory.FormatYAML, inventory.FormatJSON} {
+		format := format
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := inventory.Encode(&buf, format, machineFixtures); err != nil {
+				t.Fatalf("Failed to encode due to %v", err)
+			}
+			actual, err := inventory.Decode(&buf, format)
+			if err != nil {
+				t.Fatalf("Failed to decode due to %v", err)
+			}
+			if !reflect.DeepEqual(actual, sorted) {
+				t.Errorf("Invalid machines. Expected: %#v, Actual: %#v", sorted, actual)
+			}
+		})
+	}
+}
+
+func Test_Decode(t *testing.T) {
+	testCases := map[string]struct {
+		format    inventory.Format
+		input     string
+		expect    []*models.Machine
+		expectErr error
+	}{
+		"csv": {
+			format: inventory.FormatCSV,
+			input:  "mac,name,ipv4_addr,deployed_date,spec.core,spec.memory,spec.disk\nmac1,machine1,192.168.0.2,0,4,2048,128\n",
+			expect: []*models.Machine{
+				{MAC: "mac1", Name: "machine1", IPv4Addr: "192.168.0.2", Spec: models.MachineSpec{Core: 4, Memory: 2048, Disk: 128}},
+			},
+			expectErr: nil,
+		},
+		"csv with labels": {
+			format: inventory.FormatCSV,
+			input:  "mac,name,ipv4_addr,deployed_date,spec.core,spec.memory,spec.disk,labels\nmac1,machine1,192.168.0.2,0,4,2048,128,env=prod;rack=a1\n",
+			expect: []*models.Machine{
+				{MAC: "mac1", Name: "machine1", IPv4Addr: "192.168.0.2", Spec: models.MachineSpec{Core: 4, Memory: 2048, Disk: 128}, Labels: map[string]string{"env": "prod", "rack": "a1"}},
+			},
+			expectErr: nil,
+		},
+		"csv with invalid labels": {
+			format:    inventory.FormatCSV,
+			input:     "mac,name,ipv4_addr,deployed_date,spec.core,spec.memory,spec.disk,labels\nmac1,machine1,192.168.0.2,0,4,2048,128,prod\n",
+			expect:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"csv with invalid header": {
+			format:    inventory.FormatCSV,
+			input:     "name,mac,ipv4_addr,deployed_date,spec.core,spec.memory,spec.disk\n",
+			expect:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"csv with invalid number": {
+			format:    inventory.FormatCSV,
+			input:     "mac,name,ipv4_addr,deployed_date,spec.core,spec.memory,spec.disk\nmac1,machine1,192.168.0.2,0,four,2048,128\n",
+			expect:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"yaml": {
+			format: inventory.FormatYAML,
+			input:  "- mac: mac1\n  name: machine1\n  spec:\n    core: 4\n",
+			expect: []*models.Machine{
+				{MAC: "mac1", Name: "machine1", Spec: models.MachineSpec{Core: 4}},
+			},
+			expectErr: nil,
+		},
+		"yaml with unknown field": {
+			format:    inventory.FormatYAML,
+			input:     "- mac: mac1\n  unknown: value\n",
+			expect:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"json with unknown field": {
+			format:    inventory.FormatJSON,
+			input:     `[{"mac": "mac1", "unknown": "value"}]`,
+			expect:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			actual, err := inventory.Decode(strings.NewReader(tc.input), tc.format)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %v, Actual: %v", tc.expectErr, err)
+				return
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid machines. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
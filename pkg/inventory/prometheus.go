@@ -0,0 +1,92 @@
+package inventory
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// DefaultPrometheusPort is the port of node_exporter, which is scraped if no PortRule is given.
+const DefaultPrometheusPort = 9100
+
+// PortRule is the port to scrape on the machines which have the labels in Selector.
+type PortRule struct {
+	// Selector is the labels of the machines. An empty selector matches all machines.
+	Selector map[string]string
+	Port     int
+}
+
+// ParsePortRule parses the rule such as "9100" or "env=prod;role=db:9187",
+// where the selector is the text form of the labels returned by models.FormatLabels.
+func ParsePortRule(text string) (*PortRule, error) {
+	selector, portText := "", text
+	if i := strings.LastIndex(text, ":"); i >= 0 {
+		selector, portText = text[:i], text[i+1:]
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, xerrors.Errorf("port of '%s' must be from 1 to 65535 %w", text, tcErr.ErrInvalidArgument)
+	}
+	labels, err := models.ParseLabels(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &PortRule{Selector: labels, Port: port}, nil
+}
+
+// PrometheusTargetGroup is a group of the targets in the response of Prometheus HTTP service discovery.
+type PrometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Prometheus returns the targets of Prometheus HTTP service discovery on the deployed machines.
+// Each machine is scraped on the ports of all rules matched with its labels, and on DefaultPrometheusPort if rules is empty.
+// Its targets have the labels "tc_name", "tc_mac", "tc_spec_core", "tc_spec_memory", "tc_spec_disk"
+// and "tc_label_<key>" for each label such as "tc_label_rack".
+// The machines without the IPv4 address are skipped.
+func Prometheus(machines []*models.Machine, rules []*PortRule) []*PrometheusTargetGroup {
+	if len(rules) == 0 {
+		rules = []*PortRule{{Port: DefaultPrometheusPort}}
+	}
+	machines = append([]*models.Machine{}, machines...)
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].MAC < machines[j].MAC
+	})
+	groups := []*PrometheusTargetGroup{}
+	for _, machine := range machines {
+		if machine.DeployedDate <= 0 || net.ParseIP(machine.IPv4Addr).To4() == nil {
+			continue
+		}
+		var targets []string
+		seen := map[int]bool{}
+		for _, rule := range rules {
+			if seen[rule.Port] || !models.MatchLabels(rule.Selector, machine.Labels) {
+				continue
+			}
+			seen[rule.Port] = true
+			targets = append(targets, net.JoinHostPort(machine.IPv4Addr, strconv.Itoa(rule.Port)))
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		labels := map[string]string{
+			"tc_name":        machine.Name,
+			"tc_mac":         machine.MAC,
+			"tc_spec_core":   strconv.Itoa(machine.Spec.Core),
+			"tc_spec_memory": strconv.Itoa(machine.Spec.Memory),
+			"tc_spec_disk":   strconv.Itoa(machine.Spec.Disk),
+		}
+		for key, value := range machine.Labels {
+			labels["tc_label_"+invalidNameChars.ReplaceAllString(key, "_")] = value
+		}
+		groups = append(groups, &PrometheusTargetGroup{Targets: targets, Labels: labels})
+	}
+	return groups
+}
@@ -0,0 +1,120 @@
+package inventory_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/inventory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func Test_ParsePortRule(t *testing.T) {
+	testCases := map[string]struct {
+		input     string
+		expect    *inventory.PortRule
+		expectErr error
+	}{
+		"port only": {
+			input:  "9100",
+			expect: &inventory.PortRule{Port: 9100},
+		},
+		"with selector": {
+			input:  "env=prod;role=db:9187",
+			expect: &inventory.PortRule{Selector: map[string]string{"env": "prod", "role": "db"}, Port: 9187},
+		},
+		"invalid port": {
+			input:     "role=db:http",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"out of range": {
+			input:     "65536",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"invalid selector": {
+			input:     "role:9187",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			actual, err := inventory.ParsePortRule(tc.input)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Expect: %v, Actual: %v", tc.expectErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Expect: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_Prometheus(t *testing.T) {
+	db := &models.Machine{
+		Name:         "db1",
+		MAC:          "mac3",
+		IPv4Addr:     "192.168.0.3",
+		DeployedDate: 1,
+		Spec:         models.MachineSpec{Core: 8, Memory: 4096, Disk: 256},
+		Labels:       map[string]string{"role": "db", "rack-id": "a1"},
+	}
+	undeployed := &models.Machine{Name: "new", MAC: "mac4", IPv4Addr: "192.168.0.4"}
+	machines := []*models.Machine{db, machineFixtures[0], machineFixtures[1], undeployed}
+	rules := []*inventory.PortRule{
+		{Port: 9100},
+		{Selector: map[string]string{"role": "db"}, Port: 9187},
+		{Selector: map[string]string{"role": "db"}, Port: 9100},
+	}
+	actual := inventory.Prometheus(machines, rules)
+	expect := []*inventory.PrometheusTargetGroup{
+		{
+			Targets: []string{"19.168.0.2:9100"},
+			Labels: map[string]string{
+				"tc_name":        "machine1",
+				"tc_mac":         "mac1",
+				"tc_spec_core":   "4",
+				"tc_spec_memory": "2048",
+				"tc_spec_disk":   "128",
+			},
+		},
+		{
+			Targets: []string{"19.168.1.2:9100"},
+			Labels: map[string]string{
+				"tc_name":        "machine2",
+				"tc_mac":         "mac2",
+				"tc_spec_core":   "2",
+				"tc_spec_memory": "1024",
+				"tc_spec_disk":   "64",
+				"tc_label_env":   "staging",
+				"tc_label_rack":  "a1",
+			},
+		},
+		{
+			Targets: []string{"192.168.0.3:9100", "192.168.0.3:9187"},
+			Labels: map[string]string{
+				"tc_name":          "db1",
+				"tc_mac":           "mac3",
+				"tc_spec_core":     "8",
+				"tc_spec_memory":   "4096",
+				"tc_spec_disk":     "256",
+				"tc_label_role":    "db",
+				"tc_label_rack_id": "a1",
+			},
+		},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		for i := range actual {
+			t.Logf("%d: %#v", i, actual[i])
+		}
+		t.Errorf("Invalid targets")
+	}
+
+	defaults := inventory.Prometheus([]*models.Machine{db}, nil)
+	if len(defaults) != 1 || !reflect.DeepEqual(defaults[0].Targets, []string{"192.168.0.3:9100"}) {
+		t.Errorf("Expect the default port, Actual: %#v", defaults)
+	}
+}
@@ -0,0 +1,151 @@
+// Package leader runs the background tasks on only one of the replicas of the server.
+package leader
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+const (
+	// retryInterval is the wait before campaigning again after the failure.
+	retryInterval = 5 * time.Second
+	// resignTimeout is the time to wait for the resignation.
+	resignTimeout = 5 * time.Second
+)
+
+// Task is the work which runs only on the leader.
+// ctx is canceled when the leadership is lost, and the task must return soon after that.
+type Task func(ctx context.Context) error
+
+// Status is the state of the election seen from a replica.
+type Status struct {
+	// ID is the ID of this replica.
+	ID string `json:"id"`
+	// Leader is the ID of the current leader, or empty if there is no leader.
+	Leader string `json:"leader"`
+	// IsLeader is true if this replica runs the tasks as the leader.
+	IsLeader bool `json:"is_leader"`
+	// Tasks is the names of the leader-only tasks.
+	Tasks []string `json:"tasks"`
+}
+
+// Runner campaigns for the leader and runs the registered tasks while it leads.
+type Runner struct {
+	id       string
+	election repo.LeaderElection
+
+	mu       sync.Mutex
+	tasks    map[string]Task
+	isLeader bool
+}
+
+// NewRunner returns the runner of the replica whose ID is id.
+func NewRunner(id string, election repo.LeaderElection) *Runner {
+	return &Runner{
+		id:       id,
+		election: election,
+		tasks:    map[string]Task{},
+	}
+}
+
+// Register registers the task which runs only on the leader. It must be called before Run.
+func (r *Runner) Register(name string, task Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[name] = task
+}
+
+// Run campaigns for the leader repeatedly until ctx is done.
+// The tasks are started when elected and stopped when the leadership is lost.
+// The leadership is resigned before returning so that another replica takes over immediately.
+func (r *Runner) Run(ctx context.Context) error {
+	for {
+		lost, err := r.election.Campaign(ctx, r.id)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			log.Printf("Failed to campaign for the leader: %v", err)
+			select {
+			case <-time.After(retryInterval):
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		log.Printf("%s became the leader", r.id)
+		r.lead(ctx, lost)
+		resignCtx, cancel := context.WithTimeout(context.Background(), resignTimeout)
+		err = r.election.Resign(resignCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return err
+		}
+		if err != nil {
+			log.Printf("Failed to resign the leadership: %v", err)
+		}
+	}
+}
+
+// lead runs the tasks until the leadership is lost or ctx is done, and waits for them to stop.
+func (r *Runner) lead(ctx context.Context, lost <-chan struct{}) {
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.mu.Lock()
+	r.isLeader = true
+	tasks := make(map[string]Task, len(r.tasks))
+	for name, task := range r.tasks {
+		tasks[name] = task
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, task := range tasks {
+		wg.Add(1)
+		go func(name string, task Task) {
+			defer wg.Done()
+			if err := task(taskCtx); err != nil && taskCtx.Err() == nil {
+				log.Printf("Leader-only task '%s' failed: %v", name, err)
+			}
+		}(name, task)
+	}
+	select {
+	case <-lost:
+		log.Printf("%s lost the leadership", r.id)
+	case <-ctx.Done():
+	}
+	cancel()
+	wg.Wait()
+	r.mu.Lock()
+	r.isLeader = false
+	r.mu.Unlock()
+}
+
+// Status returns the state of the election.
+func (r *Runner) Status(ctx context.Context) (*Status, error) {
+	leader, err := r.election.Leader(ctx)
+	if err != nil && !xerrors.Is(err, tcErr.ErrNotFound) {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := &Status{
+		ID:       r.id,
+		Leader:   leader,
+		IsLeader: r.isLeader,
+		Tasks:    make([]string, 0, len(r.tasks)),
+	}
+	for name := range r.tasks {
+		status.Tasks = append(status.Tasks, name)
+	}
+	sort.Strings(status.Tasks)
+	return status, nil
+}
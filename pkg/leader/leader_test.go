@@ -0,0 +1,141 @@
+package leader_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/leader"
+)
+
+const waitTimeout = 5 * time.Second
+
+// fakeElection elects the candidate when the test sends the channel to tell the loss of the leadership.
+type fakeElection struct {
+	elect chan chan struct{}
+
+	mu       sync.Mutex
+	leader   string
+	resigned int
+}
+
+func (f *fakeElection) Campaign(ctx context.Context, id string) (<-chan struct{}, error) {
+	select {
+	case lost := <-f.elect:
+		f.mu.Lock()
+		f.leader = id
+		f.mu.Unlock()
+		return lost, nil
+	case <-ctx.Done():
+		return nil, tcErr.ErrTimedOut
+	}
+}
+
+func (f *fakeElection) Resign(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leader = ""
+	f.resigned++
+	return nil
+}
+
+func (f *fakeElection) Leader(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.leader) == 0 {
+		return "", tcErr.ErrNotFound
+	}
+	return f.leader, nil
+}
+
+func wait(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(waitTimeout):
+		t.Fatalf("Timed out to wait for %s", what)
+	}
+}
+
+func assertStatus(t *testing.T, runner *leader.Runner, expect *leader.Status) {
+	t.Helper()
+	actual, err := runner.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get the status due to %v", err)
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	election := &fakeElection{elect: make(chan chan struct{})}
+	runner := leader.NewRunner("replica1", election)
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	runner.Register("gc", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		stopped <- struct{}{}
+		return nil
+	})
+	assertStatus(t, runner, &leader.Status{ID: "replica1", Tasks: []string{"gc"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := runner.Run(ctx); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}()
+
+	lost := make(chan struct{})
+	election.elect <- lost
+	wait(t, started, "the task to start")
+	assertStatus(t, runner, &leader.Status{ID: "replica1", Leader: "replica1", IsLeader: true, Tasks: []string{"gc"}})
+
+	// The task stops when the leadership is lost, and starts again when elected again.
+	close(lost)
+	wait(t, stopped, "the task to stop after the leadership is lost")
+	election.elect <- make(chan struct{})
+	wait(t, started, "the task to start again")
+
+	cancel()
+	wait(t, stopped, "the task to stop after the context is done")
+	wait(t, done, "Run to return")
+	assertStatus(t, runner, &leader.Status{ID: "replica1", Tasks: []string{"gc"}})
+	if election.resigned != 2 {
+		t.Errorf("Expect: resigned 2 times, Actual: %d", election.resigned)
+	}
+}
+
+func TestLocalElection(t *testing.T) {
+	election := leader.NewLocalElection()
+	runner := leader.NewRunner("replica1", election)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	runner.Register("gc", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runner.Run(ctx)
+	}()
+	wait(t, started, "the task to start")
+	assertStatus(t, runner, &leader.Status{ID: "replica1", Leader: "replica1", IsLeader: true, Tasks: []string{"gc"}})
+	cancel()
+	wait(t, done, "Run to return")
+	if _, err := election.Leader(context.Background()); !xerrors.Is(err, tcErr.ErrNotFound) {
+		t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,45 @@
+package leader
+
+import (
+	"context"
+	"sync"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// localElection is the election of the single replica, which always wins.
+// It is used with the datastores which can not be shared among the replicas.
+type localElection struct {
+	mu     sync.Mutex
+	leader string
+}
+
+func (l *localElection) Campaign(ctx context.Context, id string) (<-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leader = id
+	// The leadership is never lost.
+	return make(chan struct{}), nil
+}
+
+func (l *localElection) Resign(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leader = ""
+	return nil
+}
+
+func (l *localElection) Leader(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.leader) == 0 {
+		return "", tcErr.ErrNotFound
+	}
+	return l.leader, nil
+}
+
+// NewLocalElection returns the election in which the only replica is always elected.
+func NewLocalElection() repo.LeaderElection {
+	return &localElection{}
+}
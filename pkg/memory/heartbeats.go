@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// beat is the last check-in of a machine.
+type beat struct {
+	lastSeen  time.Time
+	expiresAt time.Time
+}
+
+type heartbeatRepoImpl struct {
+	mu sync.Mutex
+	// beats maps the namespace to the check-ins of the machines by MAC.
+	beats map[string]map[string]*beat
+}
+
+func (r *heartbeatRepoImpl) Heartbeat(ctx context.Context, mac string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ns := namespace.FromContext(ctx)
+	if r.beats[ns] == nil {
+		r.beats[ns] = map[string]*beat{}
+	}
+	now := time.Now()
+	r.beats[ns][mac] = &beat{
+		lastSeen:  now,
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+func (r *heartbeatRepoImpl) GetHeartbeats(ctx context.Context) ([]*models.Heartbeat, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var heartbeats []*models.Heartbeat
+	for mac, b := range r.beats[namespace.FromContext(ctx)] {
+		heartbeats = append(heartbeats, &models.Heartbeat{
+			MAC:       mac,
+			LastSeen:  b.lastSeen.Unix(),
+			Reachable: now.Before(b.expiresAt),
+		})
+	}
+	sort.Slice(heartbeats, func(i, j int) bool {
+		return heartbeats[i].MAC < heartbeats[j].MAC
+	})
+	return heartbeats, nil
+}
+
+func (r *heartbeatRepoImpl) DeleteHeartbeats(ctx context.Context, macs []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	beats := r.beats[namespace.FromContext(ctx)]
+	for _, mac := range macs {
+		delete(beats, mac)
+	}
+	return nil
+}
+
+func (r *heartbeatRepoImpl) GetHeartbeatNamespaces(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var namespaces []string
+	for ns, beats := range r.beats {
+		if len(beats) != 0 {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// NewHeartbeatRepository returns the repository which keeps the heartbeats in memory.
+// The heartbeats are not persisted since the machines check in again soon after the restart.
+func NewHeartbeatRepository() repo.HeartbeatRepository {
+	return &heartbeatRepoImpl{
+		beats: map[string]map[string]*beat{},
+	}
+}
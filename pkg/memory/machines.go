@@ -0,0 +1,353 @@
+// Package memory implements the repositories which keep the data in memory.
+// It is intended for the local development and the tests which do not have etcd.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/actor"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+// state is the whole data of the repository. It is never modified after stored,
+// so that a failure of the persistence does not leave the partial change.
+type state struct {
+	// Revision is incremented on each change.
+	Revision int64 `json:"revision"`
+	// Namespaces is the machines in each namespace.
+	Namespaces map[string]*machineSet `json:"namespaces"`
+}
+
+func newState() *state {
+	return &state{
+		Namespaces: map[string]*machineSet{},
+	}
+}
+
+// namespace returns the machines in the namespace. It must not be modified.
+func (s *state) namespace(name string) *machineSet {
+	if set, ok := s.Namespaces[name]; ok {
+		return set
+	}
+	return newMachineSet()
+}
+
+// machineSet is the machines and their histories in a namespace.
+type machineSet struct {
+	Machines  map[string]*models.Machine          `json:"machines"`
+	Histories map[string][]*models.MachineHistory `json:"histories"`
+}
+
+func newMachineSet() *machineSet {
+	return &machineSet{
+		Machines:  map[string]*models.Machine{},
+		Histories: map[string][]*models.MachineHistory{},
+	}
+}
+
+func (s *machineSet) clone() *machineSet {
+	cloned := &machineSet{
+		Machines:  make(map[string]*models.Machine, len(s.Machines)),
+		Histories: make(map[string][]*models.MachineHistory, len(s.Histories)),
+	}
+	for mac, m := range s.Machines {
+		cloned.Machines[mac] = m
+	}
+	for mac, h := range s.Histories {
+		// Appending to the cloned slice must not modify the original one.
+		cloned.Histories[mac] = h[:len(h):len(h)]
+	}
+	return cloned
+}
+
+// change is a mutation of a machine. after is nil if the machine is deleted.
+type change struct {
+	mac       string
+	operation string
+	before    *models.Machine
+	after     *models.Machine
+}
+
+type machineRepoImpl struct {
+	// path is the JSON file to persist the state. Nothing is persisted if it is empty.
+	path string
+
+	mu     sync.Mutex
+	state  *state
+	events *watch.Hub
+}
+
+func copyMachine(m *models.Machine) *models.Machine {
+	if m == nil {
+		return nil
+	}
+	copied := *m
+	return &copied
+}
+
+func (r *machineRepoImpl) GetMachines(ctx context.Context) ([]*models.Machine, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var machines []*models.Machine
+	for _, m := range r.state.namespace(namespace.FromContext(ctx)).Machines {
+		machines = append(machines, copyMachine(m))
+	}
+	// Same order as etcd, which returns them in the order of the keys.
+	sort.Slice(machines, func(i, j int) bool {
+		return machines[i].MAC < machines[j].MAC
+	})
+	return machines, nil
+}
+
+func (r *machineRepoImpl) GetMachine(ctx context.Context, mac string) (*models.Machine, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	machine, ok := r.state.namespace(namespace.FromContext(ctx)).Machines[mac]
+	if !ok {
+		return nil, tcErr.Wrap("GetMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+	}
+	return copyMachine(machine), nil
+}
+
+// commit applies the changes in the namespace of ctx as a revision. r.mu must be held.
+func (r *machineRepoImpl) commit(ctx context.Context, changes []*change) error {
+	ns := namespace.FromContext(ctx)
+	next := &state{
+		Revision:   r.state.Revision + 1,
+		Namespaces: make(map[string]*machineSet, len(r.state.Namespaces)+1),
+	}
+	for name, set := range r.state.Namespaces {
+		next.Namespaces[name] = set
+	}
+	set := r.state.namespace(ns).clone()
+	next.Namespaces[ns] = set
+	now := time.Now().Unix()
+	for _, c := range changes {
+		if c.after == nil {
+			delete(set.Machines, c.mac)
+		} else {
+			set.Machines[c.mac] = copyMachine(c.after)
+		}
+		set.Histories[c.mac] = append(set.Histories[c.mac], &models.MachineHistory{
+			Revision:  next.Revision,
+			MAC:       c.mac,
+			Actor:     actor.FromContext(ctx),
+			Timestamp: now,
+			Operation: c.operation,
+			Machine:   copyMachine(c.after),
+			Changes:   models.DiffMachines(c.before, c.after),
+		})
+	}
+	if err := r.persist(next); err != nil {
+		return err
+	}
+	r.state = next
+	var events []*models.MachineEvent
+	for _, c := range changes {
+		event := &models.MachineEvent{
+			Type:      models.EventPut,
+			Namespace: ns,
+			Revision:  next.Revision,
+			Machine:   c.after,
+		}
+		if c.after == nil {
+			event.Type = models.EventDelete
+			event.Machine = c.before
+		}
+		events = append(events, event)
+	}
+	r.events.Publish(events...)
+	return nil
+}
+
+// persist writes the state into the file atomically.
+func (r *machineRepoImpl) persist(s *state) error {
+	if len(r.path) == 0 {
+		return nil
+	}
+	valueByte, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".tmp")
+	if err != nil {
+		return xerrors.Errorf("Failed to persist the machines: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(valueByte); err != nil {
+		f.Close()
+		return xerrors.Errorf("Failed to persist the machines: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return xerrors.Errorf("Failed to persist the machines: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return xerrors.Errorf("Failed to persist the machines: %w", err)
+	}
+	if err := os.Rename(f.Name(), r.path); err != nil {
+		return xerrors.Errorf("Failed to persist the machines: %w", err)
+	}
+	return nil
+}
+
+// mutate applies the mutation to the machine whose MAC is mac and appends the history of it atomically.
+// current is nil if the machine does not exist, and returning nil deletes the machine.
+func (r *machineRepoImpl) mutate(ctx context.Context, mac string, operation string, mutation func(current *models.Machine) (*models.Machine, error)) (*models.Machine, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := copyMachine(r.state.namespace(namespace.FromContext(ctx)).Machines[mac])
+	next, err := mutation(copyMachine(current))
+	if err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(current, next) {
+		return next, nil
+	}
+	if err := r.commit(ctx, []*change{{mac: mac, operation: operation, before: current, after: next}}); err != nil {
+		return nil, err
+	}
+	return copyMachine(next), nil
+}
+
+func (r *machineRepoImpl) RegisterMachine(ctx context.Context, machine *models.Machine) error {
+	_, err := r.mutate(ctx, machine.MAC, models.OperationRegister, func(current *models.Machine) (*models.Machine, error) {
+		if current != nil {
+			return nil, tcErr.Wrap("RegisterMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrAlreadyExists)
+		}
+		return copyMachine(machine), nil
+	})
+	return err
+}
+
+func (r *machineRepoImpl) UpdateMachine(ctx context.Context, machine *models.Machine) error {
+	_, err := r.mutate(ctx, machine.MAC, models.OperationUpdate, func(current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("UpdateMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrNotFound)
+		}
+		return copyMachine(machine), nil
+	})
+	return err
+}
+
+func (r *machineRepoImpl) PatchMachine(ctx context.Context, mac string, patch repo.MachinePatchFunc) (*models.Machine, error) {
+	return r.mutate(ctx, mac, models.OperationUpdate, func(current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("PatchMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+		}
+		machine := copyMachine(current)
+		if err := patch(machine); err != nil {
+			return nil, err
+		}
+		if machine.MAC != current.MAC {
+			return nil, tcErr.Wrap("PatchMachine", tcErr.KindMachine, mac, tcErr.ErrInvalidArgument)
+		}
+		return machine, nil
+	})
+}
+
+func (r *machineRepoImpl) DeleteMachine(ctx context.Context, machine *models.Machine) error {
+	_, err := r.mutate(ctx, machine.MAC, models.OperationDelete, func(current *models.Machine) (*models.Machine, error) {
+		if current == nil {
+			return nil, tcErr.Wrap("DeleteMachine", tcErr.KindMachine, machine.MAC, tcErr.ErrNotFound)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (r *machineRepoImpl) GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var histories []*models.MachineHistory
+	for _, h := range r.state.namespace(namespace.FromContext(ctx)).Histories[mac] {
+		copied := *h
+		copied.Machine = copyMachine(h.Machine)
+		histories = append(histories, &copied)
+	}
+	return histories, nil
+}
+
+func (r *machineRepoImpl) RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error) {
+	return r.mutate(ctx, mac, models.OperationRevert, func(current *models.Machine) (*models.Machine, error) {
+		// r.mu is held, so the histories can be read directly.
+		for _, h := range r.state.namespace(namespace.FromContext(ctx)).Histories[mac] {
+			if h.Revision == revision {
+				return copyMachine(h.Machine), nil
+			}
+		}
+		return nil, tcErr.Wrap("RevertMachine", tcErr.KindMachine, mac, tcErr.ErrNotFound)
+	})
+}
+
+func (r *machineRepoImpl) ImportMachines(ctx context.Context, machines []*models.Machine) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []*change
+	set := r.state.namespace(namespace.FromContext(ctx))
+	for _, machine := range machines {
+		current := set.Machines[machine.MAC]
+		if reflect.DeepEqual(current, machine) {
+			continue
+		}
+		operation := models.OperationUpdate
+		if current == nil {
+			operation = models.OperationRegister
+		}
+		changes = append(changes, &change{
+			mac:       machine.MAC,
+			operation: operation,
+			before:    copyMachine(current),
+			after:     copyMachine(machine),
+		})
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return r.commit(ctx, changes)
+}
+
+func (r *machineRepoImpl) WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error) {
+	return r.events.Subscribe(ctx), nil
+}
+
+// NewMachineRepository returns the repository which keeps the machines in memory.
+// If path is not empty, the machines are loaded from the JSON file and saved into it on each change.
+func NewMachineRepository(path string) (repo.MachineRepository, error) {
+	r := &machineRepoImpl{
+		path:   path,
+		state:  newState(),
+		events: watch.NewHub(),
+	}
+	if len(path) == 0 {
+		return r, nil
+	}
+	valueByte, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(valueByte, r.state); err != nil {
+		return nil, xerrors.Errorf("malformed file '%s': %v %w", path, err, tcErr.ErrInvalidArgument)
+	}
+	if r.state.Namespaces == nil {
+		r.state.Namespaces = map[string]*machineSet{}
+	}
+	return r, nil
+}
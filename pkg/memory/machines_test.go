@@ -0,0 +1,72 @@
+package memory_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func TestMachineRepository_persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tc-memory")
+	if err != nil {
+		t.Fatalf("Failed to create the directory due to %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "machines.json")
+	ctx := context.Background()
+	machine := &models.Machine{MAC: "mac1", Name: "machine1"}
+
+	r, err := memory.NewMachineRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to create the repository due to %v", err)
+	}
+	if err := r.RegisterMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+
+	reloaded, err := memory.NewMachineRepository(path)
+	if err != nil {
+		t.Fatalf("Failed to load the repository due to %v", err)
+	}
+	machines, err := reloaded.GetMachines(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the machines due to %v", err)
+	}
+	if !reflect.DeepEqual(machines, []*models.Machine{machine}) {
+		t.Errorf("Expect: %v, Actual: %v", []*models.Machine{machine}, machines)
+	}
+	histories, err := reloaded.GetMachineHistory(ctx, machine.MAC)
+	if err != nil {
+		t.Fatalf("Failed to get the histories due to %v", err)
+	}
+	if len(histories) != 1 {
+		t.Errorf("The history must be persisted. Actual: %v", histories)
+	}
+	// The revision continues after reloaded.
+	if err := reloaded.DeleteMachine(ctx, machine); err != nil {
+		t.Fatalf("Failed to delete the machine due to %v", err)
+	}
+	histories, _ = reloaded.GetMachineHistory(ctx, machine.MAC)
+	if len(histories) != 2 || histories[1].Revision <= histories[0].Revision {
+		t.Errorf("Revisions must increase. Actual: %v", histories)
+	}
+}
+
+func TestNewMachineRepository_malformed(t *testing.T) {
+	f, err := ioutil.TempFile("", "tc-memory")
+	if err != nil {
+		t.Fatalf("Failed to create the file due to %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not json")
+	f.Close()
+	if _, err := memory.NewMachineRepository(f.Name()); err == nil {
+		t.Error("Malformed file must be rejected")
+	}
+}
@@ -0,0 +1,42 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+	"github.com/pddg/tiny-cluster/pkg/repositories/repotest"
+)
+
+// TestRepositories runs the conformance suites of the repositories against the in-memory ones.
+func TestRepositories(t *testing.T) {
+	suites := map[string]func(t *testing.T){
+		"machines": func(t *testing.T) {
+			repotest.TestMachineRepository(t, func(t *testing.T) repo.MachineRepository {
+				r, err := memory.NewMachineRepository("")
+				if err != nil {
+					t.Fatalf("Failed to create the repository due to %v", err)
+				}
+				return r
+			})
+		},
+		"heartbeats": func(t *testing.T) {
+			repotest.TestHeartbeatRepository(t, func(t *testing.T) repo.HeartbeatRepository {
+				return memory.NewHeartbeatRepository()
+			})
+		},
+		"tokens": func(t *testing.T) {
+			repotest.TestTokenRepository(t, func(t *testing.T) repo.TokenRepository {
+				return memory.NewTokenRepository()
+			})
+		},
+		"webhooks": func(t *testing.T) {
+			repotest.TestWebhookRepository(t, func(t *testing.T) repo.WebhookRepository {
+				return memory.NewWebhookRepository()
+			})
+		},
+	}
+	for name, suite := range suites {
+		t.Run(name, suite)
+	}
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+type tokenRepoImpl struct {
+	mu     sync.Mutex
+	tokens map[string]models.Token
+}
+
+func (r *tokenRepoImpl) CreateToken(ctx context.Context, token *models.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tokens[token.ID]; ok {
+		return tcErr.Wrap("CreateToken", tcErr.KindToken, token.ID, tcErr.ErrAlreadyExists)
+	}
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *tokenRepoImpl) GetToken(ctx context.Context, id string) (*models.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil, tcErr.Wrap("GetToken", tcErr.KindToken, id, tcErr.ErrNotFound)
+	}
+	return &token, nil
+}
+
+func (r *tokenRepoImpl) GetTokens(ctx context.Context) ([]*models.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tokens := make([]*models.Token, 0, len(r.tokens))
+	for _, token := range r.tokens {
+		token := token
+		tokens = append(tokens, &token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *tokenRepoImpl) DeleteToken(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tokens[id]; !ok {
+		return tcErr.Wrap("DeleteToken", tcErr.KindToken, id, tcErr.ErrNotFound)
+	}
+	delete(r.tokens, id)
+	return nil
+}
+
+// NewTokenRepository returns the repository which keeps the tokens in memory.
+// The tokens are lost when the process exits, so that this is intended for tests.
+func NewTokenRepository() repo.TokenRepository {
+	return &tokenRepoImpl{
+		tokens: map[string]models.Token{},
+	}
+}
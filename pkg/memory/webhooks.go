@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+type webhookRepoImpl struct {
+	mu       sync.Mutex
+	webhooks map[string]models.Webhook
+	// deliveries is the deliveries of each webhook in the order of their IDs.
+	deliveries map[string][]models.WebhookDelivery
+}
+
+func (r *webhookRepoImpl) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[webhook.ID]; ok {
+		return tcErr.Wrap("CreateWebhook", tcErr.KindWebhook, webhook.ID, tcErr.ErrAlreadyExists)
+	}
+	copied := *webhook
+	copied.Events = append([]string(nil), webhook.Events...)
+	r.webhooks[webhook.ID] = copied
+	return nil
+}
+
+func (r *webhookRepoImpl) GetWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhooks := make([]*models.Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhook := webhook
+		webhook.Events = append([]string(nil), webhook.Events...)
+		webhooks = append(webhooks, &webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+func (r *webhookRepoImpl) DeleteWebhook(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[id]; !ok {
+		return tcErr.Wrap("DeleteWebhook", tcErr.KindWebhook, id, tcErr.ErrNotFound)
+	}
+	delete(r.webhooks, id)
+	delete(r.deliveries, id)
+	return nil
+}
+
+func (r *webhookRepoImpl) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[delivery.WebhookID]; !ok {
+		return tcErr.Wrap("AddDelivery", tcErr.KindWebhook, delivery.WebhookID, tcErr.ErrNotFound)
+	}
+	deliveries := append(r.deliveries[delivery.WebhookID], *delivery)
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	if len(deliveries) > repo.MaxWebhookDeliveries {
+		deliveries = append([]models.WebhookDelivery(nil), deliveries[len(deliveries)-repo.MaxWebhookDeliveries:]...)
+	}
+	r.deliveries[delivery.WebhookID] = deliveries
+	return nil
+}
+
+func (r *webhookRepoImpl) GetDeliveries(ctx context.Context, id string) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[id]; !ok {
+		return nil, tcErr.Wrap("GetDeliveries", tcErr.KindWebhook, id, tcErr.ErrNotFound)
+	}
+	deliveries := make([]*models.WebhookDelivery, 0, len(r.deliveries[id]))
+	for _, delivery := range r.deliveries[id] {
+		delivery := delivery
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, nil
+}
+
+// NewWebhookRepository returns the repository which keeps the webhooks in memory.
+// The webhooks are lost when the process exits, so that this is intended for tests.
+func NewWebhookRepository() repo.WebhookRepository {
+	return &webhookRepoImpl{
+		webhooks:   map[string]models.Webhook{},
+		deliveries: map[string][]models.WebhookDelivery{},
+	}
+}
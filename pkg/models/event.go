@@ -0,0 +1,20 @@
+package models
+
+const (
+	// EventPut indicates that the machine was registered or updated.
+	EventPut = "put"
+	// EventDelete indicates that the machine was deleted.
+	EventDelete = "delete"
+)
+
+// MachineEvent is a notification of a change of the machine.
+type MachineEvent struct {
+	// Type is EventPut or EventDelete.
+	Type string `json:"type"`
+	// Namespace is the namespace of the machine.
+	Namespace string `json:"namespace"`
+	// Revision is the revision of the datastore when the change was done.
+	Revision int64 `json:"revision"`
+	// Machine is the machine after the change, or the one just before deleted.
+	Machine *Machine `json:"machine"`
+}
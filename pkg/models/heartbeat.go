@@ -0,0 +1,29 @@
+package models
+
+const (
+	// LivenessReachable indicates that the heartbeat of the machine is alive.
+	LivenessReachable = "reachable"
+	// LivenessUnreachable indicates that the heartbeat of the machine has expired.
+	LivenessUnreachable = "unreachable"
+	// LivenessUnknown indicates that the machine has never checked in.
+	// Such machines have no liveness, and this is used only to query them.
+	LivenessUnknown = "unknown"
+)
+
+// Heartbeat is the last check-in of a machine.
+type Heartbeat struct {
+	// MAC is the MAC address of the machine.
+	MAC string `json:"mac"`
+	// LastSeen is a UNIX time of the last check-in.
+	LastSeen int64 `json:"last_seen"`
+	// Reachable is true until the TTL of the last check-in passes.
+	Reachable bool `json:"reachable"`
+}
+
+// Liveness returns LivenessReachable or LivenessUnreachable according to the heartbeat.
+func (h *Heartbeat) Liveness() string {
+	if h.Reachable {
+		return LivenessReachable
+	}
+	return LivenessUnreachable
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+const (
+	// OperationRegister indicates that the machine was registered.
+	OperationRegister = "register"
+	// OperationUpdate indicates that the machine was updated.
+	OperationUpdate = "update"
+	// OperationDelete indicates that the machine was deleted.
+	OperationDelete = "delete"
+	// OperationRevert indicates that the machine was reverted to the past state.
+	OperationRevert = "revert"
+)
+
+// FieldChange is a change of a field of the machine.
+type FieldChange struct {
+	// Field is a path of the changed field. Nested fields are joined by '.' such as "spec.core".
+	Field string `json:"field"`
+	// Old is the value before the change. This is nil if the field did not exist.
+	Old interface{} `json:"old"`
+	// New is the value after the change. This is nil if the field was removed.
+	New interface{} `json:"new"`
+}
+
+// MachineHistory is a record of a mutation of the machine.
+type MachineHistory struct {
+	// Revision is the revision of the datastore when the mutation was done.
+	Revision int64 `json:"revision"`
+	// MAC is Media Access Control address of the mutated machine.
+	MAC string `json:"mac"`
+	// Actor is the name of the one who did the mutation.
+	Actor string `json:"actor"`
+	// Timestamp is a UNIX time when the mutation was done.
+	Timestamp int64 `json:"timestamp"`
+	// Operation is the kind of the mutation such as OperationUpdate.
+	Operation string `json:"operation"`
+	// Machine is the state of the machine just after the mutation.
+	// This is nil if the machine was deleted.
+	Machine *Machine `json:"machine"`
+	// Changes is the list of the changed fields.
+	Changes []FieldChange `json:"changes"`
+}
+
+// flattenMachine returns the JSON representation of the machine as a flat map.
+func flattenMachine(machine *Machine) map[string]interface{} {
+	flatten := map[string]interface{}{}
+	if machine == nil {
+		return flatten
+	}
+	var nested map[string]interface{}
+	valueByte, _ := json.Marshal(machine)
+	_ = json.Unmarshal(valueByte, &nested)
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if child, ok := v.(map[string]interface{}); ok {
+				walk(prefix+k+".", child)
+				continue
+			}
+			flatten[prefix+k] = v
+		}
+	}
+	walk("", nested)
+	return flatten
+}
+
+// DiffMachines returns the changes of the fields from before to after.
+// Either of them can be nil, which means the machine does not exist.
+func DiffMachines(before *Machine, after *Machine) []FieldChange {
+	oldFields := flattenMachine(before)
+	newFields := flattenMachine(after)
+	var changes []FieldChange
+	for field, oldValue := range oldFields {
+		newValue, ok := newFields[field]
+		if ok && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+	}
+	for field, newValue := range newFields {
+		if _, ok := oldFields[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Old: nil, New: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
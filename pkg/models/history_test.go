@@ -0,0 +1,72 @@
+package models_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func Test_DiffMachines(t *testing.T) {
+	machine := &models.Machine{
+		MAC:      "mac1",
+		Name:     "machine1",
+		IPv4Addr: "192.168.0.2",
+		Spec: models.MachineSpec{
+			Core:   4,
+			Memory: 2048,
+			Disk:   128,
+		},
+	}
+	updated := *machine
+	updated.Name = "updated"
+	updated.Spec.Memory = 4096
+	testCases := map[string]struct {
+		before *models.Machine
+		after  *models.Machine
+		expect []models.FieldChange
+	}{
+		"update": {
+			before: machine,
+			after:  &updated,
+			expect: []models.FieldChange{
+				{Field: "name", Old: "machine1", New: "updated"},
+				{Field: "spec.memory", Old: float64(2048), New: float64(4096)},
+			},
+		},
+		"relabel": {
+			before: &models.Machine{MAC: "mac1", Labels: map[string]string{"env": "staging", "rack": "a1"}},
+			after:  &models.Machine{MAC: "mac1", Labels: map[string]string{"env": "prod", "rack": "a1"}},
+			expect: []models.FieldChange{
+				{Field: "labels.env", Old: "staging", New: "prod"},
+			},
+		},
+		"no changes": {
+			before: machine,
+			after:  machine,
+			expect: nil,
+		},
+		"create": {
+			before: nil,
+			after:  &models.Machine{MAC: "mac1"},
+			expect: []models.FieldChange{
+				{Field: "deployed_date", Old: nil, New: float64(0)},
+				{Field: "ipv4_addr", Old: nil, New: ""},
+				{Field: "mac", Old: nil, New: "mac1"},
+				{Field: "name", Old: nil, New: ""},
+				{Field: "spec.core", Old: nil, New: float64(0)},
+				{Field: "spec.disk", Old: nil, New: float64(0)},
+				{Field: "spec.memory", Old: nil, New: float64(0)},
+			},
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			actual := models.DiffMachines(tc.before, tc.after)
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid changes. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+// MachineDiff is the difference between the stored machine and the given one.
+type MachineDiff struct {
+	// MAC is Media Access Control address of the machine.
+	MAC string `json:"mac"`
+	// Changes is the list of the changed fields.
+	Changes []FieldChange `json:"changes"`
+}
+
+// ImportResult is the result of importing the inventory.
+type ImportResult struct {
+	// DryRun indicates that nothing was written actually.
+	DryRun bool `json:"dry_run"`
+	// Created is the list of the machines which were (or would be) registered newly.
+	Created []*Machine `json:"created"`
+	// Updated is the list of the differences of the machines which were (or would be) updated.
+	Updated []*MachineDiff `json:"updated"`
+	// Unchanged is the list of MAC addresses of the machines which were not changed.
+	Unchanged []string `json:"unchanged"`
+}
+
+// MACMigration is a machine whose MAC is re-keyed into the normalized form.
+type MACMigration struct {
+	// From is the MAC as it was stored.
+	From string `json:"from"`
+	// To is the normalized MAC.
+	To string `json:"to"`
+}
+
+// MACCollision is the stored MACs which can not be re-keyed, since they are the same MAC after normalized.
+type MACCollision struct {
+	// MAC is the normalized MAC.
+	MAC string `json:"mac"`
+	// Stored is the list of the MACs as they are stored, including the normalized one if it exists.
+	Stored []string `json:"stored"`
+}
+
+// MACMigrationResult is the result of normalizing the MACs of the stored machines.
+type MACMigrationResult struct {
+	// DryRun indicates that nothing was written actually.
+	DryRun bool `json:"dry_run"`
+	// Migrated is the list of the machines which were (or would be) re-keyed by the normalized MAC.
+	Migrated []*MACMigration `json:"migrated"`
+	// Merged is the list of the machines which were (or would be) deleted, since they are the same as
+	// the machine stored by the normalized MAC.
+	Merged []*MACMigration `json:"merged"`
+	// Collisions is the list of the machines which were left as they are, since they differ from each other.
+	Collisions []*MACCollision `json:"collisions"`
+	// Invalid is the list of the stored MACs which can not be normalized.
+	Invalid []string `json:"invalid"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+const (
+	// labelSeparator separates the labels in the text form.
+	labelSeparator = ";"
+	// labelAssign separates the key and the value of a label in the text form.
+	labelAssign = "="
+)
+
+// MatchLabels returns true if the labels have all key-value pairs in the selector.
+// An empty selector matches any labels.
+func MatchLabels(selector map[string]string, labels map[string]string) bool {
+	for k, v := range selector {
+		if actual, ok := labels[k]; !ok || actual != v {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatLabels returns the text form of the labels such as "env=prod;rack=a1" in the order of the keys.
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+labelAssign+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, labelSeparator)
+}
+
+// ParseLabels parses the text form of the labels returned by FormatLabels.
+// An empty text is parsed as no labels.
+func ParseLabels(text string) (map[string]string, error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil, nil
+	}
+	labels := map[string]string{}
+	for _, pair := range strings.Split(text, labelSeparator) {
+		elems := strings.SplitN(pair, labelAssign, 2)
+		key := strings.TrimSpace(elems[0])
+		if len(elems) != 2 || len(key) == 0 {
+			return nil, xerrors.Errorf("label must be 'key=value' but '%s' %w", pair, tcErr.ErrInvalidArgument)
+		}
+		labels[key] = strings.TrimSpace(elems[1])
+	}
+	return labels, nil
+}
@@ -0,0 +1,81 @@
+package models_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+func Test_MatchLabels(t *testing.T) {
+	labels := map[string]string{"env": "staging", "rack": "a1"}
+	testCases := map[string]struct {
+		selector map[string]string
+		expect   bool
+	}{
+		"empty selector":   {selector: nil, expect: true},
+		"match":            {selector: map[string]string{"env": "staging"}, expect: true},
+		"match all":        {selector: map[string]string{"env": "staging", "rack": "a1"}, expect: true},
+		"different value":  {selector: map[string]string{"env": "prod"}, expect: false},
+		"missing label":    {selector: map[string]string{"team": "infra"}, expect: false},
+		"partially differ": {selector: map[string]string{"env": "staging", "rack": "b1"}, expect: false},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			if actual := models.MatchLabels(tc.selector, labels); actual != tc.expect {
+				t.Errorf("Expect: %v, Actual: %v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_ParseLabels(t *testing.T) {
+	testCases := map[string]struct {
+		text      string
+		expect    map[string]string
+		expectErr error
+	}{
+		"labels": {
+			text:   "rack=a1;env=prod",
+			expect: map[string]string{"env": "prod", "rack": "a1"},
+		},
+		"empty value": {
+			text:   "env=",
+			expect: map[string]string{"env": ""},
+		},
+		"empty": {
+			text:   "",
+			expect: nil,
+		},
+		"no value": {
+			text:      "env",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"no key": {
+			text:      "=prod",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			actual, err := models.ParseLabels(tc.text)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Expect: %v, Actual: %v", tc.expect, actual)
+			}
+			if tc.expectErr == nil && models.FormatLabels(actual) != models.FormatLabels(tc.expect) {
+				t.Errorf("The labels must be formatted back. Actual: %s", models.FormatLabels(actual))
+			}
+		})
+	}
+	if actual := models.FormatLabels(map[string]string{"rack": "a1", "env": "prod"}); actual != "env=prod;rack=a1" {
+		t.Errorf("Expect: env=prod;rack=a1, Actual: %s", actual)
+	}
+}
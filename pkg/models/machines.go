@@ -22,4 +22,22 @@ type Machine struct {
 	DeployedDate int64 `json:"deployed_date"`
 	// Spec indicates the machine spec of the host.
 	Spec MachineSpec `json:"spec"`
+	// Labels is the key-value pairs to group the hosts, such as "env=prod".
+	// They are used by the scopes of the roles.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// LastSeen is a UNIX time of the last heartbeat from this host, or 0 if it has never checked in.
+	// It is filled from the heartbeats on read, and never stored with the machine.
+	LastSeen int64 `json:"last_seen,omitempty"`
+	// Liveness is LivenessReachable or LivenessUnreachable, or empty if it has never checked in.
+	// It is filled as well as LastSeen.
+	Liveness string `json:"liveness,omitempty"`
+}
+
+// WithoutLiveness returns the copy of the machine without the fields filled from the heartbeats.
+func (m *Machine) WithoutLiveness() *Machine {
+	copied := *m
+	copied.LastSeen = 0
+	copied.Liveness = ""
+	return &copied
 }
@@ -0,0 +1,29 @@
+package models
+
+// Token is the API token to authenticate the clients.
+// Only the hash of the secret is stored, so that the secret can not be recovered from the datastore.
+type Token struct {
+	// ID identifies the token. It is the public part of the bearer token.
+	ID string `json:"id"`
+	// Name describes who uses the token, which is recorded as the actor of the operations.
+	Name string `json:"name"`
+	// Hash is the SHA-256 hash of the secret in hex.
+	Hash string `json:"hash"`
+	// CreatedAt is a UNIX time when the token was created.
+	CreatedAt int64 `json:"created_at"`
+	// ExpiresAt is a UNIX time when the token expires, or 0 if it never expires.
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+	// Role decides which operations are permitted. See package auth for the roles.
+	Role string `json:"role,omitempty"`
+	// Scope is the label selector of the machines which the token can access.
+	// The token can access all machines if it is empty.
+	Scope map[string]string `json:"scope,omitempty"`
+	// MAC is the MAC address of the machine which the token is bound to, such as the one of tcagent.
+	// Such a token can only read the machine, patch its spec and send its heartbeats, whatever Role is.
+	MAC string `json:"mac,omitempty"`
+}
+
+// Expired returns true if the token has expired at now in UNIX time.
+func (t *Token) Expired(now int64) bool {
+	return t.ExpiresAt != 0 && t.ExpiresAt <= now
+}
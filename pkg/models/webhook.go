@@ -0,0 +1,101 @@
+package models
+
+// Events notified to the webhooks.
+const (
+	// WebhookEventRegistered is notified when the machine is registered.
+	WebhookEventRegistered = "machine.registered"
+	// WebhookEventUpdated is notified when the machine is updated.
+	WebhookEventUpdated = "machine.updated"
+	// WebhookEventDeleted is notified when the machine is deleted.
+	WebhookEventDeleted = "machine.deleted"
+	// WebhookEventStateChanged is notified when the machine is deployed or its deployed date is cleared.
+	WebhookEventStateChanged = "machine.state_changed"
+	// WebhookEventHeartbeatLost is notified when the heartbeat of the machine expires.
+	WebhookEventHeartbeatLost = "machine.heartbeat_lost"
+	// WebhookEventProvisioningStarted is notified when the deployed date of the machine is cleared to provision it again.
+	WebhookEventProvisioningStarted = "provisioning.started"
+	// WebhookEventProvisioningFinished is notified when the machine is deployed.
+	WebhookEventProvisioningFinished = "provisioning.finished"
+	// WebhookEventProvisioningFailed is notified when the machine is neither deployed nor checks in
+	// within the deadline after the provisioning started.
+	WebhookEventProvisioningFailed = "provisioning.failed"
+)
+
+// WebhookEvents is the list of all events notified to the webhooks.
+var WebhookEvents = []string{
+	WebhookEventRegistered,
+	WebhookEventUpdated,
+	WebhookEventDeleted,
+	WebhookEventStateChanged,
+	WebhookEventHeartbeatLost,
+	WebhookEventProvisioningStarted,
+	WebhookEventProvisioningFinished,
+	WebhookEventProvisioningFailed,
+}
+
+// Webhook is the URL which the events of the machines in the namespace are posted to.
+type Webhook struct {
+	// ID identifies the webhook.
+	ID string `json:"id"`
+	// Namespace is the namespace of the machines whose events are notified.
+	Namespace string `json:"namespace"`
+	// URL is the HTTP or HTTPS URL which the events are posted to.
+	URL string `json:"url"`
+	// Events is the events to be notified. All events are notified if it is empty.
+	Events []string `json:"events,omitempty"`
+	// Secret is the key of HMAC-SHA256 signature of the payloads.
+	Secret string `json:"secret"`
+	// CreatedAt is a UNIX time when the webhook was created.
+	CreatedAt int64 `json:"created_at"`
+}
+
+// Subscribes returns true if the event is notified to the webhook.
+func (w *Webhook) Subscribes(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload is the body posted to the webhooks in JSON.
+type WebhookPayload struct {
+	// ID identifies the event. The retries of the same event have the same ID.
+	ID string `json:"id"`
+	// Event is one of WebhookEvents.
+	Event string `json:"event"`
+	// Namespace is the namespace of the machine.
+	Namespace string `json:"namespace"`
+	// Timestamp is a UNIX time when the event was detected.
+	Timestamp int64 `json:"timestamp"`
+	// Machine is the machine after the change, or the one just before deleted.
+	Machine *Machine `json:"machine"`
+	// Previous is the machine before the change if it is known.
+	Previous *Machine `json:"previous,omitempty"`
+}
+
+// WebhookDelivery is the result of an attempt to post the event to the webhook.
+type WebhookDelivery struct {
+	// ID identifies the attempt. The IDs are ordered by the time of the attempts.
+	ID string `json:"id"`
+	// WebhookID is the ID of the webhook.
+	WebhookID string `json:"webhook_id"`
+	// EventID is the ID of the payload.
+	EventID string `json:"event_id"`
+	// Event is the event of the payload.
+	Event string `json:"event"`
+	// Attempt is the number of the attempt starting from 1.
+	Attempt int `json:"attempt"`
+	// StatusCode is the HTTP status code of the response, or 0 if no response was received.
+	StatusCode int `json:"status_code,omitempty"`
+	// Error describes why the attempt failed.
+	Error string `json:"error,omitempty"`
+	// Success is true if the webhook responded with 2xx.
+	Success bool `json:"success"`
+	// Timestamp is a UNIX time of the attempt.
+	Timestamp int64 `json:"timestamp"`
+}
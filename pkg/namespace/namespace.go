@@ -0,0 +1,44 @@
+// Package namespace carries the cluster namespace of the operation through context.
+// Each namespace has its own machines and histories isolated from the others.
+package namespace
+
+import (
+	"context"
+	"regexp"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+)
+
+// Default is the namespace used when it is not given.
+const Default = "default"
+
+// namePattern is the pattern of DNS label, which is safe to be a part of keys and URLs.
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+type contextKey struct{}
+
+// Validate returns ErrInvalidArgument if the name can not be used as a namespace.
+func Validate(name string) error {
+	if !namePattern.MatchString(name) {
+		return xerrors.Errorf("namespace '%s' must consist of at most 63 lower case alphanumeric characters or '-', and start and end with an alphanumeric character %w", name, tcErr.ErrInvalidArgument)
+	}
+	return nil
+}
+
+// NewContext returns a new context which carries the namespace.
+// The name must have been validated by Validate.
+func NewContext(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// FromContext returns the namespace stored in ctx.
+// This returns Default if the namespace has not been stored.
+func FromContext(ctx context.Context) string {
+	name, ok := ctx.Value(contextKey{}).(string)
+	if !ok || len(name) == 0 {
+		return Default
+	}
+	return name
+}
@@ -0,0 +1,48 @@
+package namespace_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		name  string
+		valid bool
+	}{
+		"default":         {name: namespace.Default, valid: true},
+		"with hyphen":     {name: "lab-1", valid: true},
+		"63 characters":   {name: strings.Repeat("a", 63), valid: true},
+		"empty":           {name: ""},
+		"upper case":      {name: "Lab"},
+		"leading hyphen":  {name: "-lab"},
+		"trailing hyphen": {name: "lab-"},
+		"slash":           {name: "lab/prod"},
+		"64 characters":   {name: strings.Repeat("a", 64)},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			err := namespace.Validate(tc.name)
+			if tc.valid && err != nil {
+				t.Errorf("'%s' must be valid but %v", tc.name, err)
+			}
+			if !tc.valid && err == nil {
+				t.Errorf("'%s' must be invalid", tc.name)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if actual := namespace.FromContext(context.Background()); actual != namespace.Default {
+		t.Errorf("Expect: %s, Actual: %s", namespace.Default, actual)
+	}
+	ctx := namespace.NewContext(context.Background(), "lab")
+	if actual := namespace.FromContext(ctx); actual != "lab" {
+		t.Errorf("Expect: lab, Actual: %s", actual)
+	}
+}
@@ -0,0 +1,175 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+// WriteFile replaces the file at path with data atomically, so that the readers never see it partially written.
+func WriteFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// File is the configuration file which is kept up to date with the machines.
+type File struct {
+	// Path is the path to the file.
+	Path string
+	// Format is the format of the file.
+	Format Format
+	// Options is the settings of the file. The serial is managed by the file.
+	Options Options
+	// ReloadCommand is the shell command run after the file is rewritten, such as "systemctl reload dnsmasq".
+	ReloadCommand string
+	// Delay is the time to wait for the following changes before the file is rewritten,
+	// which prevents the reload on each change of a bulk import.
+	Delay time.Duration
+
+	mu       sync.Mutex
+	machines map[string]*models.Machine
+	changed  chan struct{}
+	// last is the content written last time.
+	last []byte
+}
+
+// Replace implements watch.Handler.
+func (f *File) Replace(machines []*models.Machine) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.machines = make(map[string]*models.Machine, len(machines))
+	for _, machine := range machines {
+		f.machines[machine.MAC] = machine
+	}
+	f.notify()
+}
+
+// Apply implements watch.Handler.
+func (f *File) Apply(event *models.MachineEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch event.Type {
+	case models.EventPut:
+		f.machines[event.Machine.MAC] = event.Machine
+	case models.EventDelete:
+		delete(f.machines, event.Machine.MAC)
+	}
+	f.notify()
+}
+
+// notify wakes up Watch without blocking. f.mu must be held.
+func (f *File) notify() {
+	select {
+	case f.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Watch rewrites the file and runs the reload command whenever its content is changed by the machines of source,
+// until ctx is done.
+func (f *File) Watch(ctx context.Context, source watch.Source) error {
+	f.mu.Lock()
+	f.machines = map[string]*models.Machine{}
+	f.changed = make(chan struct{}, 1)
+	f.mu.Unlock()
+	if last, err := ioutil.ReadFile(f.Path); err == nil {
+		f.last = last
+	}
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		watch.Sync(ctx, source, f)
+	}()
+	defer func() { <-synced }()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-f.changed:
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(f.Delay):
+		}
+		if err := f.update(ctx); err != nil {
+			log.Printf("Failed to update %s: %v", f.Path, err)
+		}
+	}
+}
+
+// update rewrites the file if its content is changed, and runs the reload command.
+func (f *File) update(ctx context.Context) error {
+	f.mu.Lock()
+	machines := make([]*models.Machine, 0, len(f.machines))
+	for _, machine := range f.machines {
+		machines = append(machines, machine)
+	}
+	f.mu.Unlock()
+	var buf bytes.Buffer
+	if err := Render(&buf, f.Format, machines, f.Options); err != nil {
+		return err
+	}
+	if bytes.Equal(buf.Bytes(), f.last) {
+		return nil
+	}
+	if f.Format == FormatBIND {
+		// The serial must be increased so that the secondaries notice the change.
+		serial := uint32(time.Now().Unix())
+		if serial <= f.Options.Serial {
+			serial = f.Options.Serial + 1
+		}
+		f.Options.Serial = serial
+		buf.Reset()
+		if err := Render(&buf, f.Format, machines, f.Options); err != nil {
+			return err
+		}
+	}
+	if err := WriteFile(f.Path, buf.Bytes()); err != nil {
+		return err
+	}
+	f.last = buf.Bytes()
+	log.Printf("Updated %s", f.Path)
+	if len(f.ReloadCommand) == 0 {
+		return nil
+	}
+	out, err := exec.CommandContext(ctx, "sh", "-c", f.ReloadCommand).CombinedOutput()
+	if err != nil {
+		return xerrors.Errorf("failed to run '%s': %v: %s", f.ReloadCommand, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package render_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/render"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := render.WriteFile(path, []byte("new")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("The mode must be kept. Expect: %v, Actual: %v", os.FileMode(0600), info.Mode().Perm())
+	}
+	content, _ := ioutil.ReadFile(path)
+	if string(content) != "new" {
+		t.Errorf("Expect: new, Actual: %s", content)
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("The temporary file must be removed, Actual: %d files", len(files))
+	}
+}
+
+func TestFile_Watch(t *testing.T) {
+	repo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	machineUsecase := usecase.NewMachineUseCase(repo, memory.NewHeartbeatRepository())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := machineUsecase.RegisterOrUpdateMachine(ctx, machineFixtures[0]); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	reloaded := filepath.Join(dir, "reloaded")
+	file := &render.File{
+		Path:          filepath.Join(dir, "hosts"),
+		Format:        render.FormatHosts,
+		ReloadCommand: "echo reloaded >> " + reloaded,
+		Delay:         10 * time.Millisecond,
+	}
+	done := make(chan error)
+	go func() {
+		done <- file.Watch(ctx, machineUsecase)
+	}()
+
+	waitFor := func(line string, reloads int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			content, _ := ioutil.ReadFile(file.Path)
+			log, _ := ioutil.ReadFile(reloaded)
+			if strings.Contains(string(content), line) && strings.Count(string(log), "reloaded") == reloads {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("'%s' was not written with %d reloads", line, reloads)
+	}
+	waitFor("192.168.1.2\tmachine2\n", 1)
+
+	if err := machineUsecase.RegisterOrUpdateMachine(ctx, machineFixtures[1]); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("192.168.1.1\tmachine1\n", 2)
+
+	// The change which does not affect the file does not reload.
+	changed := *machineFixtures[1]
+	changed.Spec.Core = 8
+	if err := machineUsecase.RegisterOrUpdateMachine(ctx, &changed); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	waitFor("192.168.1.1\tmachine1\n", 2)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Watch must return when the context is done")
+	}
+}
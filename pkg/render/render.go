@@ -0,0 +1,183 @@
+// Package render generates the configuration files of the existing DNS and DHCP servers from the machines.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// Format is a kind of the configuration file.
+type Format string
+
+const (
+	// FormatHosts is the hosts file such as /etc/hosts.
+	FormatHosts Format = "hosts"
+	// FormatBIND is the zone file of the cluster domain for BIND.
+	FormatBIND Format = "bind"
+	// FormatDnsmasq is the dhcp-host options of dnsmasq.
+	FormatDnsmasq Format = "dnsmasq"
+	// FormatDhcpd is the host declarations of ISC dhcpd.
+	FormatDhcpd Format = "dhcpd"
+)
+
+// header is the comment at the top of the generated files.
+const header = "Generated by tiny-cluster. Do not edit manually."
+
+// ParseFormat returns the Format whose name is matched with the given one.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatHosts:
+		return FormatHosts, nil
+	case FormatBIND:
+		return FormatBIND, nil
+	case FormatDnsmasq:
+		return FormatDnsmasq, nil
+	case FormatDhcpd:
+		return FormatDhcpd, nil
+	default:
+		return "", xerrors.Errorf("unknown format '%s' %w", name, tcErr.ErrInvalidArgument)
+	}
+}
+
+// Options is the settings of the generated files.
+type Options struct {
+	// Domain is the domain of the machines. Each machine is named as <name>.<domain>.
+	Domain string
+	// TTL is the TTL of the records in the zone file.
+	TTL time.Duration
+	// NameServer is the name server of the zone. "ns.<domain>" is used if empty.
+	NameServer string
+	// Serial is the serial number of the zone, which must be increased when the zone is changed.
+	Serial uint32
+}
+
+// fqdn returns the fully qualified domain name which ends with '.'.
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// addressed returns the machines which have both the name and the IPv4 address in the order of the name.
+func addressed(machines []*models.Machine) []*models.Machine {
+	var filtered []*models.Machine
+	for _, machine := range sorted(machines) {
+		if len(machine.Name) != 0 && net.ParseIP(machine.IPv4Addr).To4() != nil {
+			filtered = append(filtered, machine)
+		}
+	}
+	return filtered
+}
+
+// sorted returns the copy of the machines sorted by the name to make the output stable.
+func sorted(machines []*models.Machine) []*models.Machine {
+	machines = append([]*models.Machine{}, machines...)
+	sort.Slice(machines, func(i, j int) bool {
+		if machines[i].Name != machines[j].Name {
+			return machines[i].Name < machines[j].Name
+		}
+		return machines[i].MAC < machines[j].MAC
+	})
+	return machines
+}
+
+// Render writes the configuration file of the machines into w in the format.
+// The machines without the name are skipped, and so are the ones without the IPv4 address except for the DHCP formats.
+func Render(w io.Writer, format Format, machines []*models.Machine, opts Options) error {
+	var buf bytes.Buffer
+	switch format {
+	case FormatHosts:
+		renderHosts(&buf, machines, opts)
+	case FormatBIND:
+		if len(opts.Domain) == 0 {
+			return xerrors.Errorf("the domain is required for the zone file %w", tcErr.ErrInvalidArgument)
+		}
+		renderBIND(&buf, machines, opts)
+	case FormatDnsmasq:
+		renderDnsmasq(&buf, machines)
+	case FormatDhcpd:
+		renderDhcpd(&buf, machines, opts)
+	default:
+		return xerrors.Errorf("unknown format '%s' %w", format, tcErr.ErrInvalidArgument)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func renderHosts(buf *bytes.Buffer, machines []*models.Machine, opts Options) {
+	tw := tabwriter.NewWriter(buf, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(tw, "# %s\n", header)
+	for _, machine := range addressed(machines) {
+		if len(opts.Domain) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\n", machine.IPv4Addr, machine.Name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s.%s\t%s\n", machine.IPv4Addr, machine.Name, strings.TrimSuffix(opts.Domain, "."), machine.Name)
+	}
+	tw.Flush()
+}
+
+func renderBIND(buf *bytes.Buffer, machines []*models.Machine, opts Options) {
+	origin := fqdn(opts.Domain)
+	nameServer := opts.NameServer
+	if len(nameServer) == 0 {
+		nameServer = "ns." + origin
+	}
+	ttl := int64(opts.TTL / time.Second)
+	tw := tabwriter.NewWriter(buf, 0, 8, 1, '\t', 0)
+	fmt.Fprintf(tw, "; %s\n", header)
+	fmt.Fprintf(tw, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(tw, "$TTL %d\n", ttl)
+	fmt.Fprintf(tw, "@\tIN\tSOA\t%s hostmaster.%s %d 3600 600 86400 %d\n", fqdn(nameServer), origin, opts.Serial, ttl)
+	fmt.Fprintf(tw, "@\tIN\tNS\t%s\n", fqdn(nameServer))
+	for _, machine := range addressed(machines) {
+		fmt.Fprintf(tw, "%s\tIN\tA\t%s\n", machine.Name, machine.IPv4Addr)
+	}
+	tw.Flush()
+}
+
+func renderDnsmasq(buf *bytes.Buffer, machines []*models.Machine) {
+	fmt.Fprintf(buf, "# %s\n", header)
+	for _, machine := range sorted(machines) {
+		if len(machine.Name) == 0 {
+			continue
+		}
+		fields := []string{machine.MAC}
+		if net.ParseIP(machine.IPv4Addr).To4() != nil {
+			fields = append(fields, machine.IPv4Addr)
+		}
+		fields = append(fields, machine.Name)
+		fmt.Fprintf(buf, "dhcp-host=%s\n", strings.Join(fields, ","))
+	}
+}
+
+func renderDhcpd(buf *bytes.Buffer, machines []*models.Machine, opts Options) {
+	fmt.Fprintf(buf, "# %s\n", header)
+	for _, machine := range sorted(machines) {
+		if len(machine.Name) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "host %s {\n", machine.Name)
+		fmt.Fprintf(buf, "  hardware ethernet %s;\n", machine.MAC)
+		if net.ParseIP(machine.IPv4Addr).To4() != nil {
+			fmt.Fprintf(buf, "  fixed-address %s;\n", machine.IPv4Addr)
+		}
+		fmt.Fprintf(buf, "  option host-name \"%s\";\n", machine.Name)
+		if len(opts.Domain) != 0 {
+			fmt.Fprintf(buf, "  option domain-name \"%s\";\n", strings.TrimSuffix(opts.Domain, "."))
+		}
+		buf.WriteString("}\n")
+	}
+}
@@ -0,0 +1,110 @@
+package render_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/render"
+)
+
+var spec = models.MachineSpec{Core: 2, Memory: 1024, Disk: 64}
+
+var machineFixtures = []*models.Machine{
+	{Name: "machine2", MAC: "52:54:00:00:00:02", IPv4Addr: "192.168.1.2", Spec: spec},
+	{Name: "machine1", MAC: "52:54:00:00:00:01", IPv4Addr: "192.168.1.1", Spec: spec},
+	// This has not been assigned the address.
+	{Name: "machine3", MAC: "52:54:00:00:00:03", Spec: spec},
+}
+
+func TestRender(t *testing.T) {
+	opts := render.Options{Domain: "cluster.internal", TTL: time.Minute, Serial: 42}
+	cases := map[string]struct {
+		format   render.Format
+		opts     render.Options
+		expected string
+	}{
+		"hosts": {
+			format: render.FormatHosts,
+			opts:   opts,
+			expected: `# Generated by tiny-cluster. Do not edit manually.
+192.168.1.1	machine1.cluster.internal	machine1
+192.168.1.2	machine2.cluster.internal	machine2
+`,
+		},
+		"hosts without domain": {
+			format: render.FormatHosts,
+			expected: `# Generated by tiny-cluster. Do not edit manually.
+192.168.1.1	machine1
+192.168.1.2	machine2
+`,
+		},
+		"bind": {
+			format: render.FormatBIND,
+			opts:   opts,
+			expected: `; Generated by tiny-cluster. Do not edit manually.
+$ORIGIN cluster.internal.
+$TTL 60
+@		IN	SOA	ns.cluster.internal. hostmaster.cluster.internal. 42 3600 600 86400 60
+@		IN	NS	ns.cluster.internal.
+machine1	IN	A	192.168.1.1
+machine2	IN	A	192.168.1.2
+`,
+		},
+		"dnsmasq": {
+			format: render.FormatDnsmasq,
+			opts:   opts,
+			expected: `# Generated by tiny-cluster. Do not edit manually.
+dhcp-host=52:54:00:00:00:01,192.168.1.1,machine1
+dhcp-host=52:54:00:00:00:02,192.168.1.2,machine2
+dhcp-host=52:54:00:00:00:03,machine3
+`,
+		},
+		"dhcpd": {
+			format: render.FormatDhcpd,
+			expected: `# Generated by tiny-cluster. Do not edit manually.
+host machine1 {
+  hardware ethernet 52:54:00:00:00:01;
+  fixed-address 192.168.1.1;
+  option host-name "machine1";
+}
+host machine2 {
+  hardware ethernet 52:54:00:00:00:02;
+  fixed-address 192.168.1.2;
+  option host-name "machine2";
+}
+host machine3 {
+  hardware ethernet 52:54:00:00:00:03;
+  option host-name "machine3";
+}
+`,
+		},
+	}
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := render.Render(&buf, tc.format, machineFixtures, tc.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tc.expected {
+				t.Errorf("Expect:\n%s\nActual:\n%s", tc.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestRender_invalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := render.Render(&buf, render.FormatBIND, machineFixtures, render.Options{}); !xerrors.Is(err, tcErr.ErrInvalidArgument) {
+		t.Errorf("Expect: %v, Actual: %v", tcErr.ErrInvalidArgument, err)
+	}
+	if _, err := render.ParseFormat("unbound"); !xerrors.Is(err, tcErr.ErrInvalidArgument) {
+		t.Errorf("Expect: %v, Actual: %v", tcErr.ErrInvalidArgument, err)
+	}
+}
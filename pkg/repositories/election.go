@@ -0,0 +1,18 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package repositories
+
+import (
+	"context"
+)
+
+// LeaderElection is a repository to elect one of the replicas of the server as the leader.
+type LeaderElection interface {
+	// Campaign blocks until the replica whose ID is id is elected as the leader or ctx is done.
+	// The returned channel is closed when the leadership is lost.
+	Campaign(ctx context.Context, id string) (<-chan struct{}, error)
+	// Resign gives up the leadership if this replica has it.
+	Resign(ctx context.Context) error
+	// Leader returns the ID of the current leader.
+	// This returns ErrNotFound if there is no leader.
+	Leader(ctx context.Context) (string, error)
+}
@@ -0,0 +1,23 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// HeartbeatRepository is a repository to record the check-ins of the machines.
+type HeartbeatRepository interface {
+	// Heartbeat records the check-in of the machine whose MAC is mac.
+	// The machine is reachable until ttl passes without the next check-in.
+	Heartbeat(ctx context.Context, mac string, ttl time.Duration) error
+	// GetHeartbeats returns the last check-ins of all machines which have ever checked in.
+	GetHeartbeats(ctx context.Context) ([]*models.Heartbeat, error)
+	// DeleteHeartbeats deletes the check-ins of the machines whose MACs are macs.
+	// The MACs which have never checked in are ignored.
+	DeleteHeartbeats(ctx context.Context, macs []string) error
+	// GetHeartbeatNamespaces returns the namespaces which have any check-ins regardless of the namespace of ctx.
+	GetHeartbeatNamespaces(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,22 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package repositories
+
+import (
+	"context"
+
+	"github.com/pddg/tiny-cluster/pkg/backup"
+)
+
+// KeyspaceRepository is a repository to read and write the raw key-value pairs of the datastore.
+type KeyspaceRepository interface {
+	// Snapshot returns all key-value pairs under the prefix at a single revision.
+	// The keys which live only while their owners are alive, such as the heartbeats and
+	// the candidates of the election, are not contained.
+	Snapshot(ctx context.Context, prefix string) (*backup.Archive, error)
+	// Restore writes the entries of the archive under the prefix.
+	// The existing keys are handled according to the policy.
+	// This returns ErrAlreadyExists if the policy is ConflictFail and any of the keys exists.
+	// The entries are written in several transactions. If it fails halfway, the entries written
+	// before the error are kept and counted in the result returned with the error.
+	Restore(ctx context.Context, archive *backup.Archive, prefix string, policy backup.ConflictPolicy) (*backup.RestoreResult, error)
+}
@@ -7,18 +7,45 @@ import (
 	"github.com/pddg/tiny-cluster/pkg/models"
 )
 
+// MachinePatchFunc modifies the given machine in place.
+// Returning an error aborts the patch without writing anything.
+type MachinePatchFunc func(machine *models.Machine) error
+
 // MachineRepository is a repository about Machine.
 type MachineRepository interface {
 	// GetMachines returns all machines.
 	// This returns empty list and no error if no machines were found.
 	GetMachines(ctx context.Context) ([]*models.Machine, error)
+	// GetMachine returns the machine whose MAC is mac.
+	// This returns ErrNotFound if it does not exist.
+	GetMachine(ctx context.Context, mac string) (*models.Machine, error)
 	// RegisterMachine creates a record of the machine.
 	// This returns error when the item has been created.
 	RegisterMachine(ctx context.Context, machine *models.Machine) error
 	// UpdateMachine updates the record of the machine.
 	// This returns error when the item does not exist.
 	UpdateMachine(ctx context.Context, machine *models.Machine) error
+	// PatchMachine applies the patch to the stored machine whose MAC is mac and returns the result.
+	// The read and the write are done atomically, so concurrent changes are never overwritten.
+	// This returns error when the item does not exist.
+	PatchMachine(ctx context.Context, mac string, patch MachinePatchFunc) (*models.Machine, error)
 	// DeleteMachine deletes the record of the machine.
 	// This returns error when the item does not exist.
 	DeleteMachine(ctx context.Context, machine *models.Machine) error
+	// GetMachineHistory returns the histories of the machine whose MAC is mac in chronological order.
+	// The history of each mutation is recorded atomically with the mutation itself.
+	// This returns empty list and no error if no histories were found.
+	GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error)
+	// RevertMachine restores the machine whose MAC is mac to the state just after the given revision.
+	// This returns nil machine if the machine had been deleted at the revision.
+	// This returns error when the history of the revision does not exist.
+	RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error)
+	// ImportMachines registers or updates all the given machines by MAC.
+	// Either all of them are written or nothing is written.
+	// This returns ErrConflict when any of them is modified by others during the import.
+	ImportMachines(ctx context.Context, machines []*models.Machine) error
+	// WatchMachines returns the channel to receive the changes of the machines made after the call.
+	// The channel is closed when ctx is done, or when the changes can not be followed anymore.
+	// The receiver should call this again to continue watching in the latter case.
+	WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error)
 }
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: election.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	reflect "reflect"
+)
+
+// MockLeaderElection is a mock of LeaderElection interface
+type MockLeaderElection struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaderElectionMockRecorder
+}
+
+// MockLeaderElectionMockRecorder is the mock recorder for MockLeaderElection
+type MockLeaderElectionMockRecorder struct {
+	mock *MockLeaderElection
+}
+
+// NewMockLeaderElection creates a new mock instance
+func NewMockLeaderElection(ctrl *gomock.Controller) *MockLeaderElection {
+	mock := &MockLeaderElection{ctrl: ctrl}
+	mock.recorder = &MockLeaderElectionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockLeaderElection) EXPECT() *MockLeaderElectionMockRecorder {
+	return m.recorder
+}
+
+// Campaign mocks base method
+func (m *MockLeaderElection) Campaign(ctx context.Context, id string) (<-chan struct{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Campaign", ctx, id)
+	ret0, _ := ret[0].(<-chan struct{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Campaign indicates an expected call of Campaign
+func (mr *MockLeaderElectionMockRecorder) Campaign(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Campaign", reflect.TypeOf((*MockLeaderElection)(nil).Campaign), ctx, id)
+}
+
+// Resign mocks base method
+func (m *MockLeaderElection) Resign(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resign", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Resign indicates an expected call of Resign
+func (mr *MockLeaderElectionMockRecorder) Resign(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resign", reflect.TypeOf((*MockLeaderElection)(nil).Resign), ctx)
+}
+
+// Leader mocks base method
+func (m *MockLeaderElection) Leader(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Leader", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Leader indicates an expected call of Leader
+func (mr *MockLeaderElectionMockRecorder) Leader(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Leader", reflect.TypeOf((*MockLeaderElection)(nil).Leader), ctx)
+}
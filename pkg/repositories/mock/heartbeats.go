@@ -0,0 +1,94 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: heartbeats.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/pddg/tiny-cluster/pkg/models"
+	reflect "reflect"
+	time "time"
+)
+
+// MockHeartbeatRepository is a mock of HeartbeatRepository interface
+type MockHeartbeatRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockHeartbeatRepositoryMockRecorder
+}
+
+// MockHeartbeatRepositoryMockRecorder is the mock recorder for MockHeartbeatRepository
+type MockHeartbeatRepositoryMockRecorder struct {
+	mock *MockHeartbeatRepository
+}
+
+// NewMockHeartbeatRepository creates a new mock instance
+func NewMockHeartbeatRepository(ctrl *gomock.Controller) *MockHeartbeatRepository {
+	mock := &MockHeartbeatRepository{ctrl: ctrl}
+	mock.recorder = &MockHeartbeatRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockHeartbeatRepository) EXPECT() *MockHeartbeatRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Heartbeat mocks base method
+func (m *MockHeartbeatRepository) Heartbeat(ctx context.Context, mac string, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", ctx, mac, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Heartbeat indicates an expected call of Heartbeat
+func (mr *MockHeartbeatRepositoryMockRecorder) Heartbeat(ctx, mac, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockHeartbeatRepository)(nil).Heartbeat), ctx, mac, ttl)
+}
+
+// GetHeartbeats mocks base method
+func (m *MockHeartbeatRepository) GetHeartbeats(ctx context.Context) ([]*models.Heartbeat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeartbeats", ctx)
+	ret0, _ := ret[0].([]*models.Heartbeat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeartbeats indicates an expected call of GetHeartbeats
+func (mr *MockHeartbeatRepositoryMockRecorder) GetHeartbeats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeartbeats", reflect.TypeOf((*MockHeartbeatRepository)(nil).GetHeartbeats), ctx)
+}
+
+// DeleteHeartbeats mocks base method
+func (m *MockHeartbeatRepository) DeleteHeartbeats(ctx context.Context, macs []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteHeartbeats", ctx, macs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteHeartbeats indicates an expected call of DeleteHeartbeats
+func (mr *MockHeartbeatRepositoryMockRecorder) DeleteHeartbeats(ctx, macs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHeartbeats", reflect.TypeOf((*MockHeartbeatRepository)(nil).DeleteHeartbeats), ctx, macs)
+}
+
+// GetHeartbeatNamespaces mocks base method
+func (m *MockHeartbeatRepository) GetHeartbeatNamespaces(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeartbeatNamespaces", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeartbeatNamespaces indicates an expected call of GetHeartbeatNamespaces
+func (mr *MockHeartbeatRepositoryMockRecorder) GetHeartbeatNamespaces(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeartbeatNamespaces", reflect.TypeOf((*MockHeartbeatRepository)(nil).GetHeartbeatNamespaces), ctx)
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: keyspace.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	backup "github.com/pddg/tiny-cluster/pkg/backup"
+	reflect "reflect"
+)
+
+// MockKeyspaceRepository is a mock of KeyspaceRepository interface
+type MockKeyspaceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyspaceRepositoryMockRecorder
+}
+
+// MockKeyspaceRepositoryMockRecorder is the mock recorder for MockKeyspaceRepository
+type MockKeyspaceRepositoryMockRecorder struct {
+	mock *MockKeyspaceRepository
+}
+
+// NewMockKeyspaceRepository creates a new mock instance
+func NewMockKeyspaceRepository(ctrl *gomock.Controller) *MockKeyspaceRepository {
+	mock := &MockKeyspaceRepository{ctrl: ctrl}
+	mock.recorder = &MockKeyspaceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockKeyspaceRepository) EXPECT() *MockKeyspaceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Snapshot mocks base method
+func (m *MockKeyspaceRepository) Snapshot(ctx context.Context, prefix string) (*backup.Archive, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx, prefix)
+	ret0, _ := ret[0].(*backup.Archive)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot
+func (mr *MockKeyspaceRepositoryMockRecorder) Snapshot(ctx, prefix interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockKeyspaceRepository)(nil).Snapshot), ctx, prefix)
+}
+
+// Restore mocks base method
+func (m *MockKeyspaceRepository) Restore(ctx context.Context, archive *backup.Archive, prefix string, policy backup.ConflictPolicy) (*backup.RestoreResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, archive, prefix, policy)
+	ret0, _ := ret[0].(*backup.RestoreResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore
+func (mr *MockKeyspaceRepositoryMockRecorder) Restore(ctx, archive, prefix, policy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockKeyspaceRepository)(nil).Restore), ctx, archive, prefix, policy)
+}
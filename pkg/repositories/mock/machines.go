@@ -8,6 +8,7 @@ import (
 	context "context"
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/pddg/tiny-cluster/pkg/models"
+	repositories "github.com/pddg/tiny-cluster/pkg/repositories"
 	reflect "reflect"
 )
 
@@ -49,6 +50,21 @@ func (mr *MockMachineRepositoryMockRecorder) GetMachines(ctx interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachines", reflect.TypeOf((*MockMachineRepository)(nil).GetMachines), ctx)
 }
 
+// GetMachine mocks base method
+func (m *MockMachineRepository) GetMachine(ctx context.Context, mac string) (*models.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachine", ctx, mac)
+	ret0, _ := ret[0].(*models.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachine indicates an expected call of GetMachine
+func (mr *MockMachineRepositoryMockRecorder) GetMachine(ctx, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachine", reflect.TypeOf((*MockMachineRepository)(nil).GetMachine), ctx, mac)
+}
+
 // RegisterMachine mocks base method
 func (m *MockMachineRepository) RegisterMachine(ctx context.Context, machine *models.Machine) error {
 	m.ctrl.T.Helper()
@@ -77,6 +93,21 @@ func (mr *MockMachineRepositoryMockRecorder) UpdateMachine(ctx, machine interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMachine", reflect.TypeOf((*MockMachineRepository)(nil).UpdateMachine), ctx, machine)
 }
 
+// PatchMachine mocks base method
+func (m *MockMachineRepository) PatchMachine(ctx context.Context, mac string, patch repositories.MachinePatchFunc) (*models.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchMachine", ctx, mac, patch)
+	ret0, _ := ret[0].(*models.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchMachine indicates an expected call of PatchMachine
+func (mr *MockMachineRepositoryMockRecorder) PatchMachine(ctx, mac, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchMachine", reflect.TypeOf((*MockMachineRepository)(nil).PatchMachine), ctx, mac, patch)
+}
+
 // DeleteMachine mocks base method
 func (m *MockMachineRepository) DeleteMachine(ctx context.Context, machine *models.Machine) error {
 	m.ctrl.T.Helper()
@@ -90,3 +121,62 @@ func (mr *MockMachineRepositoryMockRecorder) DeleteMachine(ctx, machine interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMachine", reflect.TypeOf((*MockMachineRepository)(nil).DeleteMachine), ctx, machine)
 }
+
+// GetMachineHistory mocks base method
+func (m *MockMachineRepository) GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachineHistory", ctx, mac)
+	ret0, _ := ret[0].([]*models.MachineHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachineHistory indicates an expected call of GetMachineHistory
+func (mr *MockMachineRepositoryMockRecorder) GetMachineHistory(ctx, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineHistory", reflect.TypeOf((*MockMachineRepository)(nil).GetMachineHistory), ctx, mac)
+}
+
+// RevertMachine mocks base method
+func (m *MockMachineRepository) RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertMachine", ctx, mac, revision)
+	ret0, _ := ret[0].(*models.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertMachine indicates an expected call of RevertMachine
+func (mr *MockMachineRepositoryMockRecorder) RevertMachine(ctx, mac, revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertMachine", reflect.TypeOf((*MockMachineRepository)(nil).RevertMachine), ctx, mac, revision)
+}
+
+// ImportMachines mocks base method
+func (m *MockMachineRepository) ImportMachines(ctx context.Context, machines []*models.Machine) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportMachines", ctx, machines)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ImportMachines indicates an expected call of ImportMachines
+func (mr *MockMachineRepositoryMockRecorder) ImportMachines(ctx, machines interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportMachines", reflect.TypeOf((*MockMachineRepository)(nil).ImportMachines), ctx, machines)
+}
+
+// WatchMachines mocks base method
+func (m *MockMachineRepository) WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchMachines", ctx)
+	ret0, _ := ret[0].(<-chan *models.MachineEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchMachines indicates an expected call of WatchMachines
+func (mr *MockMachineRepositoryMockRecorder) WatchMachines(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchMachines", reflect.TypeOf((*MockMachineRepository)(nil).WatchMachines), ctx)
+}
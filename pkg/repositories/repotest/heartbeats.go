@@ -0,0 +1,152 @@
+package repotest
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// HeartbeatRepositoryFactory returns the repository which has no heartbeats.
+// It is called for each test case.
+type HeartbeatRepositoryFactory func(t *testing.T) repo.HeartbeatRepository
+
+// expireTimeout is the time to wait for the heartbeat to expire.
+// etcd extends the TTL of a lease shorter than its minimum.
+const expireTimeout = 10 * time.Second
+
+func getHeartbeats(t *testing.T, ctx context.Context, r repo.HeartbeatRepository) map[string]*models.Heartbeat {
+	t.Helper()
+	heartbeats, err := r.GetHeartbeats(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the heartbeats due to %v", err)
+	}
+	byMAC := map[string]*models.Heartbeat{}
+	for _, h := range heartbeats {
+		byMAC[h.MAC] = h
+	}
+	return byMAC
+}
+
+func heartbeat(t *testing.T, ctx context.Context, r repo.HeartbeatRepository, mac string, ttl time.Duration) {
+	t.Helper()
+	if err := r.Heartbeat(ctx, mac, ttl); err != nil {
+		t.Fatalf("Failed to record the heartbeat due to %v", err)
+	}
+}
+
+// TestHeartbeatRepository runs the test suite of HeartbeatRepository.
+func TestHeartbeatRepository(t *testing.T, newRepo HeartbeatRepositoryFactory) {
+	t.Run("Heartbeat", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		if heartbeats := getHeartbeats(t, ctx, r); len(heartbeats) != 0 {
+			t.Fatalf("Expect: no heartbeats, Actual: %v", heartbeats)
+		}
+		before := time.Now().Unix()
+		heartbeat(t, ctx, r, "conformance-mac1", time.Minute)
+		heartbeat(t, ctx, r, "conformance-mac1", time.Minute)
+		after := time.Now().Unix()
+		actual, ok := getHeartbeats(t, ctx, r)["conformance-mac1"]
+		if !ok {
+			t.Fatal("The heartbeat must be recorded")
+		}
+		if !actual.Reachable {
+			t.Error("The machine must be reachable until the TTL passes")
+		}
+		if actual.LastSeen < before || actual.LastSeen > after {
+			t.Errorf("Expect: last seen between %d and %d, Actual: %d", before, after, actual.LastSeen)
+		}
+	})
+	t.Run("Expire", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		heartbeat(t, ctx, r, "conformance-mac1", time.Second)
+		lastSeen := getHeartbeats(t, ctx, r)["conformance-mac1"].LastSeen
+		deadline := time.Now().Add(expireTimeout)
+		for {
+			actual := getHeartbeats(t, ctx, r)["conformance-mac1"]
+			if actual == nil {
+				t.Fatal("The heartbeat must be kept after expired")
+			}
+			if !actual.Reachable {
+				if actual.LastSeen != lastSeen {
+					t.Errorf("Expect: %d, Actual: %d", lastSeen, actual.LastSeen)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("The machine must be unreachable after the TTL passes")
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		heartbeat(t, ctx, r, "conformance-mac1", time.Minute)
+		if actual := getHeartbeats(t, ctx, r)["conformance-mac1"]; !actual.Reachable {
+			t.Error("The machine must be reachable again after the check-in")
+		}
+	})
+	t.Run("DeleteHeartbeats", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		heartbeat(t, ctx, r, "conformance-mac1", time.Minute)
+		heartbeat(t, ctx, r, "conformance-mac2", time.Minute)
+		if err := r.DeleteHeartbeats(ctx, []string{"conformance-mac1", "conformance-unknown"}); err != nil {
+			t.Fatalf("Failed to delete the heartbeats due to %v", err)
+		}
+		heartbeats := getHeartbeats(t, ctx, r)
+		if _, ok := heartbeats["conformance-mac1"]; ok || len(heartbeats) != 1 {
+			t.Errorf("Expect: only conformance-mac2, Actual: %v", heartbeats)
+		}
+		// Deleting in the namespace without the check-ins is not an error.
+		labCtx := namespace.NewContext(ctx, "conformance-lab")
+		if err := r.DeleteHeartbeats(labCtx, []string{"conformance-mac2"}); err != nil {
+			t.Fatalf("Failed to delete the heartbeats due to %v", err)
+		}
+		if _, ok := getHeartbeats(t, ctx, r)["conformance-mac2"]; !ok {
+			t.Error("The heartbeat in the other namespace must be kept")
+		}
+	})
+	t.Run("GetHeartbeatNamespaces", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		assertNamespaces := func(expect ...string) {
+			t.Helper()
+			actual, err := r.GetHeartbeatNamespaces(ctx)
+			if err != nil {
+				t.Fatalf("Failed to get the namespaces due to %v", err)
+			}
+			sort.Strings(actual)
+			sort.Strings(expect)
+			if len(actual) != 0 || len(expect) != 0 {
+				if !reflect.DeepEqual(actual, expect) {
+					t.Errorf("Expect: %v, Actual: %v", expect, actual)
+				}
+			}
+		}
+		assertNamespaces()
+		labCtx := namespace.NewContext(ctx, "conformance-lab")
+		heartbeat(t, ctx, r, "conformance-mac1", time.Minute)
+		heartbeat(t, labCtx, r, "conformance-mac1", time.Minute)
+		assertNamespaces(namespace.Default, "conformance-lab")
+		if err := r.DeleteHeartbeats(labCtx, []string{"conformance-mac1"}); err != nil {
+			t.Fatalf("Failed to delete the heartbeats due to %v", err)
+		}
+		assertNamespaces(namespace.Default)
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		r := newRepo(t)
+		labCtx := namespace.NewContext(context.Background(), "conformance-lab")
+		heartbeat(t, labCtx, r, "conformance-mac1", time.Minute)
+		if _, ok := getHeartbeats(t, labCtx, r)["conformance-mac1"]; !ok {
+			t.Error("The heartbeat must be recorded in the namespace")
+		}
+		if heartbeats := getHeartbeats(t, context.Background(), r); len(heartbeats) != 0 {
+			t.Errorf("Expect: no heartbeats in the default namespace, Actual: %v", heartbeats)
+		}
+	})
+}
@@ -0,0 +1,348 @@
+// Package repotest provides the test suites which every implementation of the repositories must pass.
+package repotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// MachineRepositoryFactory returns the repository which has no machines.
+// It is called for each test case.
+type MachineRepositoryFactory func(t *testing.T) repo.MachineRepository
+
+// watchTimeout is the time to wait for the event of the watch.
+const watchTimeout = 5 * time.Second
+
+func newFixture() *models.Machine {
+	return &models.Machine{
+		MAC:          "conformance-mac1",
+		Name:         "conformance1",
+		IPv4Addr:     "192.168.0.1",
+		DeployedDate: 1600000000,
+		Spec: models.MachineSpec{
+			Core:   4,
+			Memory: 8,
+			Disk:   100,
+		},
+	}
+}
+
+func assertError(t *testing.T, actual error, expect error) {
+	t.Helper()
+	if !xerrors.Is(actual, expect) {
+		t.Fatalf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
+
+func assertMachines(t *testing.T, r repo.MachineRepository, expect ...*models.Machine) {
+	t.Helper()
+	assertMachinesIn(t, context.Background(), r, expect...)
+}
+
+// assertMachinesIn asserts the machines in the namespace of ctx.
+func assertMachinesIn(t *testing.T, ctx context.Context, r repo.MachineRepository, expect ...*models.Machine) {
+	t.Helper()
+	actual, err := r.GetMachines(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get machines due to %v", err)
+	}
+	if len(actual) == 0 && len(expect) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Fatalf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
+
+func register(t *testing.T, r repo.MachineRepository, machine *models.Machine) {
+	t.Helper()
+	if err := r.RegisterMachine(context.Background(), machine); err != nil {
+		t.Fatalf("Failed to register the machine due to %v", err)
+	}
+}
+
+// TestMachineRepository runs the test suite of MachineRepository.
+func TestMachineRepository(t *testing.T, newRepo MachineRepositoryFactory) {
+	t.Run("RegisterMachine", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		register(t, r, machine)
+		assertMachines(t, r, machine)
+		assertError(t, r.RegisterMachine(context.Background(), machine), tcErr.ErrAlreadyExists)
+	})
+	t.Run("GetMachine", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		_, err := r.GetMachine(context.Background(), machine.MAC)
+		assertError(t, err, tcErr.ErrNotFound)
+		register(t, r, machine)
+		actual, err := r.GetMachine(context.Background(), machine.MAC)
+		if err != nil {
+			t.Fatalf("Failed to get the machine due to %v", err)
+		}
+		if !reflect.DeepEqual(actual, machine) {
+			t.Errorf("Expect: %v, Actual: %v", machine, actual)
+		}
+		if err := r.DeleteMachine(context.Background(), machine); err != nil {
+			t.Fatalf("Failed to delete the machine due to %v", err)
+		}
+		_, err = r.GetMachine(context.Background(), machine.MAC)
+		assertError(t, err, tcErr.ErrNotFound)
+	})
+	t.Run("UpdateMachine", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		assertError(t, r.UpdateMachine(context.Background(), machine), tcErr.ErrNotFound)
+		register(t, r, machine)
+		updated := newFixture()
+		updated.Name = "updated"
+		if err := r.UpdateMachine(context.Background(), updated); err != nil {
+			t.Fatalf("Failed to update the machine due to %v", err)
+		}
+		assertMachines(t, r, updated)
+	})
+	t.Run("PatchMachine", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		rename := func(m *models.Machine) error {
+			m.Name = "patched"
+			return nil
+		}
+		_, err := r.PatchMachine(context.Background(), machine.MAC, rename)
+		assertError(t, err, tcErr.ErrNotFound)
+		register(t, r, machine)
+		patched, err := r.PatchMachine(context.Background(), machine.MAC, rename)
+		if err != nil {
+			t.Fatalf("Failed to patch the machine due to %v", err)
+		}
+		expect := newFixture()
+		expect.Name = "patched"
+		if !reflect.DeepEqual(patched, expect) {
+			t.Errorf("Expect: %v, Actual: %v", expect, patched)
+		}
+		_, err = r.PatchMachine(context.Background(), machine.MAC, func(m *models.Machine) error {
+			m.MAC = "changed"
+			return nil
+		})
+		assertError(t, err, tcErr.ErrInvalidArgument)
+		_, err = r.PatchMachine(context.Background(), machine.MAC, func(m *models.Machine) error {
+			m.Name = "aborted"
+			return tcErr.ErrInvalidArgument
+		})
+		assertError(t, err, tcErr.ErrInvalidArgument)
+		assertMachines(t, r, expect)
+	})
+	t.Run("DeleteMachine", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		assertError(t, r.DeleteMachine(context.Background(), machine), tcErr.ErrNotFound)
+		register(t, r, machine)
+		if err := r.DeleteMachine(context.Background(), machine); err != nil {
+			t.Fatalf("Failed to delete the machine due to %v", err)
+		}
+		assertMachines(t, r)
+	})
+	t.Run("GetMachineHistory", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		register(t, r, machine)
+		updated := newFixture()
+		updated.IPv4Addr = "192.168.0.2"
+		if err := r.UpdateMachine(context.Background(), updated); err != nil {
+			t.Fatalf("Failed to update the machine due to %v", err)
+		}
+		if err := r.DeleteMachine(context.Background(), updated); err != nil {
+			t.Fatalf("Failed to delete the machine due to %v", err)
+		}
+		histories, err := r.GetMachineHistory(context.Background(), machine.MAC)
+		if err != nil {
+			t.Fatalf("Failed to get the histories due to %v", err)
+		}
+		operations := []string{models.OperationRegister, models.OperationUpdate, models.OperationDelete}
+		if len(histories) != len(operations) {
+			t.Fatalf("Expect: %d histories, Actual: %d histories", len(operations), len(histories))
+		}
+		for i, h := range histories {
+			if h.Operation != operations[i] {
+				t.Errorf("Expect: %s, Actual: %s", operations[i], h.Operation)
+			}
+			if i > 0 && h.Revision <= histories[i-1].Revision {
+				t.Errorf("Revisions must increase. Actual: %d after %d", h.Revision, histories[i-1].Revision)
+			}
+		}
+		expectChanges := []models.FieldChange{{Field: "ipv4_addr", Old: "192.168.0.1", New: "192.168.0.2"}}
+		if !reflect.DeepEqual(histories[1].Changes, expectChanges) {
+			t.Errorf("Expect: %v, Actual: %v", expectChanges, histories[1].Changes)
+		}
+		if histories[2].Machine != nil {
+			t.Errorf("The machine must be nil after deleted. Actual: %v", histories[2].Machine)
+		}
+	})
+	t.Run("RevertMachine", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		register(t, r, machine)
+		if err := r.DeleteMachine(context.Background(), machine); err != nil {
+			t.Fatalf("Failed to delete the machine due to %v", err)
+		}
+		histories, err := r.GetMachineHistory(context.Background(), machine.MAC)
+		if err != nil {
+			t.Fatalf("Failed to get the histories due to %v", err)
+		}
+		_, err = r.RevertMachine(context.Background(), machine.MAC, histories[1].Revision+1000)
+		assertError(t, err, tcErr.ErrNotFound)
+		reverted, err := r.RevertMachine(context.Background(), machine.MAC, histories[0].Revision)
+		if err != nil {
+			t.Fatalf("Failed to revert the machine due to %v", err)
+		}
+		if !reflect.DeepEqual(reverted, machine) {
+			t.Errorf("Expect: %v, Actual: %v", machine, reverted)
+		}
+		assertMachines(t, r, machine)
+	})
+	t.Run("ImportMachines", func(t *testing.T) {
+		r := newRepo(t)
+		machine := newFixture()
+		register(t, r, machine)
+		updated := newFixture()
+		updated.Name = "updated"
+		created := newFixture()
+		created.MAC = "conformance-mac2"
+		if err := r.ImportMachines(context.Background(), []*models.Machine{updated, created}); err != nil {
+			t.Fatalf("Failed to import the machines due to %v", err)
+		}
+		assertMachines(t, r, updated, created)
+		histories, err := r.GetMachineHistory(context.Background(), machine.MAC)
+		if err != nil {
+			t.Fatalf("Failed to get the histories due to %v", err)
+		}
+		if len(histories) != 2 || histories[1].Operation != models.OperationUpdate {
+			t.Errorf("The update must be recorded. Actual: %v", histories)
+		}
+	})
+	t.Run("WatchMachines", func(t *testing.T) {
+		r := newRepo(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events, err := r.WatchMachines(ctx)
+		if err != nil {
+			t.Fatalf("Failed to watch the machines due to %v", err)
+		}
+		machine := newFixture()
+		register(t, r, machine)
+		updated := newFixture()
+		updated.Name = "updated"
+		if err := r.UpdateMachine(context.Background(), updated); err != nil {
+			t.Fatalf("Failed to update the machine due to %v", err)
+		}
+		if err := r.DeleteMachine(context.Background(), updated); err != nil {
+			t.Fatalf("Failed to delete the machine due to %v", err)
+		}
+		expect := []*models.MachineEvent{
+			{Type: models.EventPut, Namespace: namespace.Default, Machine: machine},
+			{Type: models.EventPut, Namespace: namespace.Default, Machine: updated},
+			{Type: models.EventDelete, Namespace: namespace.Default, Machine: updated},
+		}
+		var lastRevision int64
+		for _, e := range expect {
+			select {
+			case actual, ok := <-events:
+				if !ok {
+					t.Fatal("The channel was closed unexpectedly")
+				}
+				if actual.Revision <= lastRevision {
+					t.Errorf("Revisions must increase. Actual: %d after %d", actual.Revision, lastRevision)
+				}
+				lastRevision = actual.Revision
+				e.Revision = actual.Revision
+				if !reflect.DeepEqual(actual, e) {
+					t.Errorf("Expect: %v, Actual: %v", e, actual)
+				}
+			case <-time.After(watchTimeout):
+				t.Fatal("Timed out to wait for the event")
+			}
+		}
+		cancel()
+		select {
+		case _, ok := <-events:
+			if ok {
+				t.Error("No more events are expected")
+			}
+		case <-time.After(watchTimeout):
+			t.Error("The channel must be closed after the context is done")
+		}
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		r := newRepo(t)
+		labCtx := namespace.NewContext(context.Background(), "conformance-lab")
+		prodCtx := namespace.NewContext(context.Background(), "conformance-prod")
+		watchCtx, cancel := context.WithCancel(prodCtx)
+		defer cancel()
+		events, err := r.WatchMachines(watchCtx)
+		if err != nil {
+			t.Fatalf("Failed to watch the machines due to %v", err)
+		}
+		lab := newFixture()
+		if err := r.RegisterMachine(labCtx, lab); err != nil {
+			t.Fatalf("Failed to register the machine due to %v", err)
+		}
+		assertMachinesIn(t, labCtx, r, lab)
+		assertMachinesIn(t, prodCtx, r)
+		assertMachines(t, r)
+
+		// The same MAC can be registered in another namespace.
+		prod := newFixture()
+		prod.Name = "prod"
+		if err := r.RegisterMachine(prodCtx, prod); err != nil {
+			t.Fatalf("Failed to register the machine due to %v", err)
+		}
+		assertMachinesIn(t, labCtx, r, lab)
+		assertMachinesIn(t, prodCtx, r, prod)
+
+		if err := r.DeleteMachine(labCtx, lab); err != nil {
+			t.Fatalf("Failed to delete the machine due to %v", err)
+		}
+		assertMachinesIn(t, labCtx, r)
+		assertMachinesIn(t, prodCtx, r, prod)
+		histories, err := r.GetMachineHistory(prodCtx, prod.MAC)
+		if err != nil {
+			t.Fatalf("Failed to get the histories due to %v", err)
+		}
+		if len(histories) != 1 || histories[0].Operation != models.OperationRegister {
+			t.Errorf("Expect: only the registration in the namespace, Actual: %v", histories)
+		}
+
+		// The watch receives only the events in its namespace.
+		select {
+		case actual, ok := <-events:
+			if !ok {
+				t.Fatal("The channel was closed unexpectedly")
+			}
+			expect := &models.MachineEvent{
+				Type:      models.EventPut,
+				Namespace: "conformance-prod",
+				Revision:  actual.Revision,
+				Machine:   prod,
+			}
+			if !reflect.DeepEqual(actual, expect) {
+				t.Errorf("Expect: %v, Actual: %v", expect, actual)
+			}
+		case <-time.After(watchTimeout):
+			t.Fatal("Timed out to wait for the event")
+		}
+		select {
+		case actual := <-events:
+			t.Errorf("No more events are expected. Actual: %v", actual)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package repotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// TokenRepositoryFactory returns the repository which has no tokens.
+// It is called for each test case.
+type TokenRepositoryFactory func(t *testing.T) repo.TokenRepository
+
+var tokenFixtures = []*models.Token{
+	{
+		ID:        "conformance-token1",
+		Name:      "admin",
+		Hash:      "0123456789abcdef",
+		CreatedAt: 1601510400,
+	},
+	{
+		ID:        "conformance-token2",
+		Name:      "machine1",
+		Hash:      "fedcba9876543210",
+		CreatedAt: 1601510400,
+		ExpiresAt: 1604188800,
+	},
+}
+
+func createTokens(t *testing.T, ctx context.Context, r repo.TokenRepository, tokens ...*models.Token) {
+	t.Helper()
+	for _, token := range tokens {
+		if err := r.CreateToken(ctx, token); err != nil {
+			t.Fatalf("Failed to create the token due to %v", err)
+		}
+	}
+}
+
+func assertTokens(t *testing.T, ctx context.Context, r repo.TokenRepository, expect []*models.Token) {
+	t.Helper()
+	actual, err := r.GetTokens(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the tokens due to %v", err)
+	}
+	if len(actual) == 0 && len(expect) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
+
+// TestTokenRepository runs the test suite of TokenRepository.
+func TestTokenRepository(t *testing.T, newRepo TokenRepositoryFactory) {
+	t.Run("CreateToken", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		assertTokens(t, ctx, r, nil)
+		createTokens(t, ctx, r, tokenFixtures[1], tokenFixtures[0])
+		assertTokens(t, ctx, r, tokenFixtures)
+		duplicated := *tokenFixtures[0]
+		duplicated.Name = "duplicated"
+		if err := r.CreateToken(ctx, &duplicated); !xerrors.Is(err, tcErr.ErrAlreadyExists) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrAlreadyExists, err)
+		}
+	})
+	t.Run("GetToken", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		createTokens(t, ctx, r, tokenFixtures...)
+		actual, err := r.GetToken(ctx, tokenFixtures[1].ID)
+		if err != nil {
+			t.Fatalf("Failed to get the token due to %v", err)
+		}
+		if !reflect.DeepEqual(actual, tokenFixtures[1]) {
+			t.Errorf("Expect: %v, Actual: %v", tokenFixtures[1], actual)
+		}
+		if _, err := r.GetToken(ctx, "conformance-unknown"); !xerrors.Is(err, tcErr.ErrNotFound) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+		}
+	})
+	t.Run("DeleteToken", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		createTokens(t, ctx, r, tokenFixtures...)
+		if err := r.DeleteToken(ctx, tokenFixtures[0].ID); err != nil {
+			t.Fatalf("Failed to delete the token due to %v", err)
+		}
+		assertTokens(t, ctx, r, tokenFixtures[1:])
+		if err := r.DeleteToken(ctx, tokenFixtures[0].ID); !xerrors.Is(err, tcErr.ErrNotFound) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+		}
+	})
+	t.Run("Namespace", func(t *testing.T) {
+		r := newRepo(t)
+		createTokens(t, namespace.NewContext(context.Background(), "conformance-lab"), r, tokenFixtures[0])
+		assertTokens(t, context.Background(), r, tokenFixtures[:1])
+	})
+}
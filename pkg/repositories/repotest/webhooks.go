@@ -0,0 +1,146 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	repo "github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+// WebhookRepositoryFactory returns the repository which has no webhooks.
+// It is called for each test case.
+type WebhookRepositoryFactory func(t *testing.T) repo.WebhookRepository
+
+var webhookFixtures = []*models.Webhook{
+	{
+		ID:        "conformance-webhook1",
+		Namespace: "default",
+		URL:       "https://example.com/hooks/1",
+		Secret:    "0123456789abcdef",
+		CreatedAt: 1601510400,
+	},
+	{
+		ID:        "conformance-webhook2",
+		Namespace: "conformance-lab",
+		URL:       "https://example.com/hooks/2",
+		Events:    []string{models.WebhookEventDeleted, models.WebhookEventHeartbeatLost},
+		Secret:    "fedcba9876543210",
+		CreatedAt: 1601510400,
+	},
+}
+
+func createWebhooks(t *testing.T, ctx context.Context, r repo.WebhookRepository, webhooks ...*models.Webhook) {
+	t.Helper()
+	for _, webhook := range webhooks {
+		if err := r.CreateWebhook(ctx, webhook); err != nil {
+			t.Fatalf("Failed to create the webhook due to %v", err)
+		}
+	}
+}
+
+func assertWebhooks(t *testing.T, ctx context.Context, r repo.WebhookRepository, expect []*models.Webhook) {
+	t.Helper()
+	actual, err := r.GetWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get the webhooks due to %v", err)
+	}
+	if len(actual) == 0 && len(expect) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, actual)
+	}
+}
+
+func newDelivery(webhookID string, i int) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:         fmt.Sprintf("%08d", i),
+		WebhookID:  webhookID,
+		EventID:    fmt.Sprintf("event%d", i),
+		Event:      models.WebhookEventDeleted,
+		Attempt:    1,
+		StatusCode: 200,
+		Success:    true,
+		Timestamp:  1601510400 + int64(i),
+	}
+}
+
+// TestWebhookRepository runs the test suite of WebhookRepository.
+func TestWebhookRepository(t *testing.T, newRepo WebhookRepositoryFactory) {
+	t.Run("CreateWebhook", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		assertWebhooks(t, ctx, r, nil)
+		createWebhooks(t, ctx, r, webhookFixtures[1], webhookFixtures[0])
+		assertWebhooks(t, ctx, r, webhookFixtures)
+		duplicated := *webhookFixtures[0]
+		duplicated.URL = "https://example.com/duplicated"
+		if err := r.CreateWebhook(ctx, &duplicated); !xerrors.Is(err, tcErr.ErrAlreadyExists) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrAlreadyExists, err)
+		}
+	})
+	t.Run("DeleteWebhook", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		createWebhooks(t, ctx, r, webhookFixtures...)
+		if err := r.AddDelivery(ctx, newDelivery(webhookFixtures[0].ID, 1)); err != nil {
+			t.Fatalf("Failed to add the delivery due to %v", err)
+		}
+		if err := r.DeleteWebhook(ctx, webhookFixtures[0].ID); err != nil {
+			t.Fatalf("Failed to delete the webhook due to %v", err)
+		}
+		assertWebhooks(t, ctx, r, webhookFixtures[1:])
+		if err := r.DeleteWebhook(ctx, webhookFixtures[0].ID); !xerrors.Is(err, tcErr.ErrNotFound) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+		}
+		// The deliveries are deleted with the webhook.
+		createWebhooks(t, ctx, r, webhookFixtures[0])
+		deliveries, err := r.GetDeliveries(ctx, webhookFixtures[0].ID)
+		if err != nil {
+			t.Fatalf("Failed to get the deliveries due to %v", err)
+		}
+		if len(deliveries) != 0 {
+			t.Errorf("Expect no deliveries, Actual: %v", deliveries)
+		}
+	})
+	t.Run("Deliveries", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+		createWebhooks(t, ctx, r, webhookFixtures...)
+		total := repo.MaxWebhookDeliveries + 5
+		for i := total; i > 0; i-- {
+			if err := r.AddDelivery(ctx, newDelivery(webhookFixtures[0].ID, i)); err != nil {
+				t.Fatalf("Failed to add the delivery due to %v", err)
+			}
+		}
+		if err := r.AddDelivery(ctx, newDelivery(webhookFixtures[1].ID, 1)); err != nil {
+			t.Fatalf("Failed to add the delivery due to %v", err)
+		}
+		deliveries, err := r.GetDeliveries(ctx, webhookFixtures[0].ID)
+		if err != nil {
+			t.Fatalf("Failed to get the deliveries due to %v", err)
+		}
+		if len(deliveries) != repo.MaxWebhookDeliveries {
+			t.Fatalf("Expect: %d deliveries, Actual: %d", repo.MaxWebhookDeliveries, len(deliveries))
+		}
+		// The oldest ones are dropped.
+		if !reflect.DeepEqual(deliveries[0], newDelivery(webhookFixtures[0].ID, 6)) {
+			t.Errorf("Expect: %v, Actual: %v", newDelivery(webhookFixtures[0].ID, 6), deliveries[0])
+		}
+		if !reflect.DeepEqual(deliveries[len(deliveries)-1], newDelivery(webhookFixtures[0].ID, total)) {
+			t.Errorf("Expect: %v, Actual: %v", newDelivery(webhookFixtures[0].ID, total), deliveries[len(deliveries)-1])
+		}
+		if err := r.AddDelivery(ctx, newDelivery("conformance-unknown", 1)); !xerrors.Is(err, tcErr.ErrNotFound) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+		}
+		if _, err := r.GetDeliveries(ctx, "conformance-unknown"); !xerrors.Is(err, tcErr.ErrNotFound) {
+			t.Errorf("Expect: %v, Actual: %v", tcErr.ErrNotFound, err)
+		}
+	})
+}
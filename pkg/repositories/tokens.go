@@ -0,0 +1,21 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package repositories
+
+import (
+	"context"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// TokenRepository is a repository to store the API tokens.
+// The tokens are shared among all namespaces.
+type TokenRepository interface {
+	// CreateToken stores the token. This returns ErrAlreadyExists if the ID has been used.
+	CreateToken(ctx context.Context, token *models.Token) error
+	// GetToken returns the token whose ID is id. This returns ErrNotFound if it does not exist.
+	GetToken(ctx context.Context, id string) (*models.Token, error)
+	// GetTokens returns all tokens in the order of their IDs.
+	GetTokens(ctx context.Context) ([]*models.Token, error)
+	// DeleteToken deletes the token whose ID is id. This returns ErrNotFound if it does not exist.
+	DeleteToken(ctx context.Context, id string) error
+}
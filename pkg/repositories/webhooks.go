@@ -0,0 +1,30 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package repositories
+
+import (
+	"context"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// MaxWebhookDeliveries is the number of the latest deliveries kept for each webhook.
+const MaxWebhookDeliveries = 100
+
+// WebhookRepository is a repository to store the webhooks and the log of their deliveries.
+// The webhooks of all namespaces are stored together, and each of them has its namespace.
+type WebhookRepository interface {
+	// CreateWebhook stores the webhook. This returns ErrAlreadyExists if the ID has been used.
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	// GetWebhooks returns all webhooks in the order of their IDs.
+	GetWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	// DeleteWebhook deletes the webhook whose ID is id and its deliveries.
+	// This returns ErrNotFound if it does not exist.
+	DeleteWebhook(ctx context.Context, id string) error
+	// AddDelivery appends the delivery to the log of its webhook.
+	// Only the latest MaxWebhookDeliveries deliveries are kept for each webhook.
+	// This returns ErrNotFound if the webhook does not exist.
+	AddDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// GetDeliveries returns the deliveries of the webhook whose ID is id in the order of their IDs.
+	// This returns ErrNotFound if the webhook does not exist.
+	GetDeliveries(ctx context.Context, id string) ([]*models.WebhookDelivery, error)
+}
@@ -0,0 +1,19 @@
+// Code generated by gen.go. DO NOT EDIT.
+
+package ui
+
+// assets is the files of the dashboard by their names.
+var assets = map[string]asset{
+	"app.js": {
+		contentType: "application/javascript; charset=utf-8",
+		content:     "// The dashboard uses only the REST API under /api/v1, so that it is permitted exactly what its token is.\n\"use strict\";\n\nconst state = {\n  namespace: localStorage.getItem(\"tc.namespace\") || \"default\",\n  token: sessionStorage.getItem(\"tc.token\") || \"\",\n  // selected is the MAC of the machine shown in the detail, or null for a new machine.\n  selected: null,\n  machine: null,\n};\n\nconst $ = (id) => document.getElementById(id);\n\nfunction el(tag, text, className) {\n  const e = document.createElement(tag);\n  if (text !== undefined && text !== null) {\n    e.textContent = String(text);\n  }\n  if (className) {\n    e.className = className;\n  }\n  return e;\n}\n\nfunction showError(message) {\n  const box = $(\"error\");\n  box.textContent = message;\n  box.hidden = !message;\n}\n\n// api calls the API in the current namespace and returns the decoded JSON, or null for no content.\n// The problem+json errors are thrown with their details and invalid parameters.\nasync function api(method, path, body) {\n  const headers = {};\n  if (state.token) {\n    headers[\"Authorization\"] = \"Bearer \" + state.token;\n  }\n  if (body !== undefined) {\n    headers[\"Content-Type\"] = \"application/json\";\n  }\n  const res = await fetch(\"../api/v1/namespaces/\" + encodeURIComponent(state.namespace) + path, {\n    method: method,\n    headers: headers,\n    body: body === undefined ? undefined : JSON.stringify(body),\n  });\n  if (res.status === 204) {\n    return null;\n  }\n  const data = await res.json().catch(() => null);\n  if (!res.ok) {\n    let message = res.status + \" \" + res.statusText;\n    if (data && data.detail) {\n      message += \": \" + data.detail;\n    }\n    if (data && data[\"invalid-params\"]) {\n      for (const p of data[\"invalid-params\"]) {\n        message += \"\\n  \" + p.field + \": \" + p.description;\n      }\n    }\n    throw new Error(message);\n  }\n  return data;\n}\n\nfunction formatTime(unix) {\n  return unix ? new Date(unix * 1000).toLocaleString() : \"\";\n}\n\nfunction formatLabels(labels) {\n  return Object.keys(labels || {}).sort().map((k) => k + \"=\" + labels[k]);\n}\n\nfunction parseLabels(text, separator) {\n  const labels = {};\n  for (const item of text.split(separator)) {\n    const pair = item.trim();\n    if (!pair) {\n      continue;\n    }\n    const i = pair.indexOf(\"=\");\n    if (i <= 0) {\n      throw new Error(\"Invalid label '\" + pair + \"'. It must be key=value\");\n    }\n    labels[pair.slice(0, i).trim()] = pair.slice(i + 1).trim();\n  }\n  return labels;\n}\n\nfunction machinePath(mac) {\n  return \"/machines/\" + encodeURIComponent(mac);\n}\n\nasync function loadMachines() {\n  const form = $(\"filters\");\n  const params = new URLSearchParams();\n  for (const key of [\"name\", \"ipv4\", \"liveness\"]) {\n    if (form.elements[key].value) {\n      params.set(key, form.elements[key].value);\n    }\n  }\n  const labels = parseLabels(form.elements.labels.value, \",\");\n  for (const key of Object.keys(labels)) {\n    params.set(\"label.\" + key, labels[key]);\n  }\n  const query = params.toString();\n  let machines = await api(\"GET\", \"/machines\" + (query ? \"?\" + query : \"\"));\n  const deployed = form.elements.state.value;\n  if (deployed) {\n    machines = machines.filter((m) => (m.deployed_date > 0) === (deployed === \"deployed\"));\n  }\n  machines.sort((a, b) => a.name.localeCompare(b.name));\n\n  const tbody = $(\"machines\");\n  tbody.textContent = \"\";\n  for (const m of machines) {\n    const tr = el(\"tr\");\n    if (m.mac === state.selected) {\n      tr.className = \"selected\";\n    }\n    tr.appendChild(el(\"td\", m.name));\n    tr.appendChild(el(\"td\", m.mac));\n    tr.appendChild(el(\"td\", m.ipv4_addr));\n    tr.appendChild(el(\"td\", m.spec.core + \" cores, \" + m.spec.memory + \" MB, \" + m.spec.disk + \" GB\"));\n    tr.appendChild(el(\"td\", formatLabels(m.labels).join(\", \")));\n    tr.appendChild(el(\"td\", m.deployed_date ? \"deployed \" + formatTime(m.deployed_date) : \"undeployed\"));\n    tr.appendChild(el(\"td\", (m.liveness || \"unknown\") + (m.last_seen ? \" (\" + formatTime(m.last_seen) + \")\" : \"\"), m.liveness));\n    tr.addEventListener(\"click\", () => run(() => openMachine(m.mac)));\n    tbody.appendChild(tr);\n  }\n  $(\"count\").textContent = machines.length + \" machines\";\n}\n\nfunction fillForm(m) {\n  const form = $(\"machine-form\");\n  form.elements.mac.value = m ? m.mac : \"\";\n  form.elements.mac.readOnly = !!m;\n  form.elements.name.value = m ? m.name : \"\";\n  form.elements.ipv4_addr.value = m ? m.ipv4_addr : \"\";\n  form.elements[\"spec.core\"].value = m ? m.spec.core : \"\";\n  form.elements[\"spec.memory\"].value = m ? m.spec.memory : \"\";\n  form.elements[\"spec.disk\"].value = m ? m.spec.disk : \"\";\n  form.elements.labels.value = m ? formatLabels(m.labels).join(\"\\n\") : \"\";\n  $(\"reprovision\").hidden = !m;\n  $(\"delete\").hidden = !m;\n\n  const status = $(\"status\");\n  status.textContent = \"\";\n  if (m) {\n    const rows = [\n      [\"State\", m.deployed_date ? \"deployed at \" + formatTime(m.deployed_date) : \"undeployed\"],\n      [\"Liveness\", m.liveness || \"unknown\"],\n      [\"Last seen\", formatTime(m.last_seen) || \"never\"],\n    ];\n    for (const [k, v] of rows) {\n      status.appendChild(el(\"dt\", k));\n      status.appendChild(el(\"dd\", v));\n    }\n  }\n}\n\nfunction renderHistory(histories) {\n  const tbody = $(\"history\");\n  tbody.textContent = \"\";\n  for (const h of histories.slice().reverse()) {\n    const tr = el(\"tr\");\n    tr.appendChild(el(\"td\", h.revision));\n    tr.appendChild(el(\"td\", formatTime(h.timestamp)));\n    tr.appendChild(el(\"td\", h.actor));\n    tr.appendChild(el(\"td\", h.operation));\n    const changes = el(\"td\");\n    for (const c of h.changes || []) {\n      changes.appendChild(el(\"div\", c.field + \": \" + JSON.stringify(c.old) + \" → \" + JSON.stringify(c.new)));\n    }\n    tr.appendChild(changes);\n    const action = el(\"td\");\n    const revert = el(\"button\", \"Revert\");\n    revert.type = \"button\";\n    revert.addEventListener(\"click\", () => run(async () => {\n      if (!confirm(\"Revert \" + state.selected + \" to revision \" + h.revision + \"?\")) {\n        return;\n      }\n      const reverted = await api(\"POST\", machinePath(state.selected) + \"/revert?revision=\" + h.revision);\n      await refresh(reverted ? reverted.mac : null);\n    }));\n    action.appendChild(revert);\n    tr.appendChild(action);\n    tbody.appendChild(tr);\n  }\n}\n\nasync function openMachine(mac) {\n  state.selected = mac;\n  $(\"detail\").hidden = false;\n  if (mac === null) {\n    state.machine = null;\n    $(\"detail-title\").textContent = \"New machine\";\n    fillForm(null);\n    renderHistory([]);\n    return;\n  }\n  const [machine, histories] = await Promise.all([\n    api(\"GET\", machinePath(mac)),\n    api(\"GET\", machinePath(mac) + \"/history\"),\n  ]);\n  state.machine = machine;\n  $(\"detail-title\").textContent = machine.name;\n  fillForm(machine);\n  renderHistory(histories);\n}\n\nfunction closeMachine() {\n  state.selected = null;\n  state.machine = null;\n  $(\"detail\").hidden = true;\n}\n\n// refresh reloads the list and the machine in the detail, which is closed if mac is null.\nasync function refresh(mac) {\n  if (mac) {\n    await openMachine(mac);\n  } else {\n    closeMachine();\n  }\n  await loadMachines();\n}\n\nfunction formMachine() {\n  const form = $(\"machine-form\");\n  return {\n    mac: form.elements.mac.value.trim(),\n    name: form.elements.name.value.trim(),\n    ipv4_addr: form.elements.ipv4_addr.value.trim(),\n    spec: {\n      core: Number(form.elements[\"spec.core\"].value),\n      memory: Number(form.elements[\"spec.memory\"].value),\n      disk: Number(form.elements[\"spec.disk\"].value),\n    },\n    labels: parseLabels(form.elements.labels.value, \"\\n\"),\n  };\n}\n\nasync function saveMachine() {\n  const edited = formMachine();\n  const current = state.machine;\n  if (!current) {\n    const created = await api(\"PUT\", machinePath(edited.mac), edited);\n    await refresh(created.mac);\n    return;\n  }\n  // Only the changed fields are patched so that the concurrent changes of the others are kept.\n  const patch = {};\n  for (const key of [\"name\", \"ipv4_addr\"]) {\n    if (edited[key] !== current[key]) {\n      patch[key] = edited[key];\n    }\n  }\n  const spec = {};\n  for (const key of [\"core\", \"memory\", \"disk\"]) {\n    if (edited.spec[key] !== current.spec[key]) {\n      spec[key] = edited.spec[key];\n    }\n  }\n  if (Object.keys(spec).length) {\n    patch.spec = spec;\n  }\n  if (formatLabels(edited.labels).join(\"\\n\") !== formatLabels(current.labels).join(\"\\n\")) {\n    patch.labels = edited.labels;\n  }\n  if (!Object.keys(patch).length) {\n    return;\n  }\n  await api(\"PATCH\", machinePath(current.mac), patch);\n  await refresh(current.mac);\n}\n\n// run runs the action and shows its error.\nasync function run(action) {\n  showError(\"\");\n  try {\n    await action();\n  } catch (e) {\n    showError(e.message);\n  }\n}\n\nfunction init() {\n  const settings = $(\"settings\");\n  settings.elements.namespace.value = state.namespace;\n  settings.elements.token.value = state.token;\n  settings.addEventListener(\"submit\", (e) => {\n    e.preventDefault();\n    state.namespace = settings.elements.namespace.value.trim();\n    state.token = settings.elements.token.value.trim();\n    localStorage.setItem(\"tc.namespace\", state.namespace);\n    // The token is kept only while the tab is open.\n    sessionStorage.setItem(\"tc.token\", state.token);\n    run(() => refresh(null));\n  });\n  $(\"filters\").addEventListener(\"submit\", (e) => {\n    e.preventDefault();\n    run(loadMachines);\n  });\n  $(\"new-machine\").addEventListener(\"click\", () => run(() => openMachine(null)));\n  $(\"machine-form\").addEventListener(\"submit\", (e) => {\n    e.preventDefault();\n    run(saveMachine);\n  });\n  $(\"reprovision\").addEventListener(\"click\", () => run(async () => {\n    if (!confirm(\"Mark \" + state.machine.name + \" as undeployed to provision it again?\")) {\n      return;\n    }\n    await api(\"POST\", machinePath(state.machine.mac) + \"/reprovision\");\n    await refresh(state.machine.mac);\n  }));\n  $(\"delete\").addEventListener(\"click\", () => run(async () => {\n    if (!confirm(\"Delete \" + state.machine.name + \"?\")) {\n      return;\n    }\n    await api(\"DELETE\", machinePath(state.machine.mac));\n    await refresh(null);\n  }));\n  $(\"close\").addEventListener(\"click\", closeMachine);\n  run(loadMachines);\n}\n\ninit();\n",
+	},
+	"index.html": {
+		contentType: "text/html; charset=utf-8",
+		content:     "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n  <meta charset=\"utf-8\">\n  <meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n  <title>tiny-cluster</title>\n  <link rel=\"stylesheet\" href=\"style.css\">\n</head>\n<body>\n  <header>\n    <h1>tiny-cluster</h1>\n    <form id=\"settings\">\n      <label>Namespace <input id=\"namespace\" name=\"namespace\" value=\"default\" required></label>\n      <label>Token <input id=\"token\" name=\"token\" type=\"password\" placeholder=\"not required without auth\" autocomplete=\"off\"></label>\n      <button type=\"submit\">Apply</button>\n    </form>\n  </header>\n\n  <div id=\"error\" class=\"error\" hidden></div>\n\n  <main>\n    <section id=\"list\">\n      <form id=\"filters\">\n        <input name=\"name\" placeholder=\"Name\">\n        <input name=\"ipv4\" placeholder=\"IPv4 address\">\n        <input name=\"labels\" placeholder=\"Labels: env=prod,rack=a1\">\n        <select name=\"liveness\">\n          <option value=\"\">Any liveness</option>\n          <option value=\"reachable\">Reachable</option>\n          <option value=\"unreachable\">Unreachable</option>\n          <option value=\"unknown\">Unknown</option>\n        </select>\n        <select name=\"state\">\n          <option value=\"\">Any state</option>\n          <option value=\"deployed\">Deployed</option>\n          <option value=\"undeployed\">Undeployed</option>\n        </select>\n        <button type=\"submit\">Search</button>\n        <button type=\"button\" id=\"new-machine\">New machine</button>\n      </form>\n      <table>\n        <thead>\n          <tr>\n            <th>Name</th><th>MAC</th><th>IPv4</th><th>Spec</th><th>Labels</th><th>State</th><th>Liveness</th>\n          </tr>\n        </thead>\n        <tbody id=\"machines\"></tbody>\n      </table>\n      <p id=\"count\"></p>\n    </section>\n\n    <section id=\"detail\" hidden>\n      <h2 id=\"detail-title\"></h2>\n      <form id=\"machine-form\">\n        <label>MAC <input name=\"mac\" required></label>\n        <label>Name <input name=\"name\" required></label>\n        <label>IPv4 address <input name=\"ipv4_addr\" required></label>\n        <label>Cores <input name=\"spec.core\" type=\"number\" min=\"1\" required></label>\n        <label>Memory (MB) <input name=\"spec.memory\" type=\"number\" min=\"1\" required></label>\n        <label>Disk (GB) <input name=\"spec.disk\" type=\"number\" min=\"1\" required></label>\n        <label>Labels <textarea name=\"labels\" rows=\"3\" placeholder=\"one key=value per line\"></textarea></label>\n        <dl id=\"status\"></dl>\n        <div class=\"actions\">\n          <button type=\"submit\">Save</button>\n          <button type=\"button\" id=\"reprovision\">Reprovision</button>\n          <button type=\"button\" id=\"delete\" class=\"danger\">Delete</button>\n          <button type=\"button\" id=\"close\">Close</button>\n        </div>\n      </form>\n      <h3>History</h3>\n      <table>\n        <thead>\n          <tr><th>Revision</th><th>Time</th><th>Actor</th><th>Operation</th><th>Changes</th><th></th></tr>\n        </thead>\n        <tbody id=\"history\"></tbody>\n      </table>\n    </section>\n  </main>\n\n  <script src=\"app.js\"></script>\n</body>\n</html>\n",
+	},
+	"style.css": {
+		contentType: "text/css; charset=utf-8",
+		content:     "body {\n  margin: 0;\n  font-family: -apple-system, \"Segoe UI\", Helvetica, Arial, sans-serif;\n  font-size: 14px;\n  color: #222;\n}\n\nheader {\n  display: flex;\n  align-items: center;\n  justify-content: space-between;\n  padding: 8px 16px;\n  background: #2d3e50;\n  color: #fff;\n}\n\nheader h1 {\n  margin: 0;\n  font-size: 18px;\n}\n\nheader form label {\n  margin-left: 12px;\n}\n\nmain {\n  display: flex;\n  gap: 16px;\n  padding: 16px;\n  align-items: flex-start;\n}\n\n#list {\n  flex: 3;\n  overflow-x: auto;\n}\n\n#detail {\n  flex: 2;\n  border-left: 1px solid #ddd;\n  padding-left: 16px;\n}\n\n#filters {\n  display: flex;\n  flex-wrap: wrap;\n  gap: 6px;\n  margin-bottom: 8px;\n}\n\ntable {\n  width: 100%;\n  border-collapse: collapse;\n}\n\nth, td {\n  padding: 4px 8px;\n  border-bottom: 1px solid #eee;\n  text-align: left;\n  vertical-align: top;\n}\n\n#machines tr {\n  cursor: pointer;\n}\n\n#machines tr:hover, #machines tr.selected {\n  background: #eef4fb;\n}\n\n#machine-form label {\n  display: block;\n  margin-bottom: 6px;\n}\n\n#machine-form input, #machine-form textarea {\n  display: block;\n  width: 100%;\n  box-sizing: border-box;\n}\n\n#status dt {\n  font-weight: bold;\n}\n\n#status dd {\n  margin: 0 0 4px 0;\n}\n\n.actions button {\n  margin-right: 6px;\n}\n\n.reachable {\n  color: #1a7f37;\n}\n\n.unreachable {\n  color: #cf222e;\n}\n\n.error {\n  margin: 8px 16px;\n  padding: 8px;\n  border: 1px solid #cf222e;\n  background: #ffebe9;\n  white-space: pre-wrap;\n}\n\nbutton.danger {\n  color: #cf222e;\n}\n",
+	},
+}
@@ -0,0 +1,326 @@
+// The dashboard uses only the REST API under /api/v1, so that it is permitted exactly what its token is.
+"use strict";
+
+const state = {
+  namespace: localStorage.getItem("tc.namespace") || "default",
+  token: sessionStorage.getItem("tc.token") || "",
+  // selected is the MAC of the machine shown in the detail, or null for a new machine.
+  selected: null,
+  machine: null,
+};
+
+const $ = (id) => document.getElementById(id);
+
+function el(tag, text, className) {
+  const e = document.createElement(tag);
+  if (text !== undefined && text !== null) {
+    e.textContent = String(text);
+  }
+  if (className) {
+    e.className = className;
+  }
+  return e;
+}
+
+function showError(message) {
+  const box = $("error");
+  box.textContent = message;
+  box.hidden = !message;
+}
+
+// api calls the API in the current namespace and returns the decoded JSON, or null for no content.
+// The problem+json errors are thrown with their details and invalid parameters.
+async function api(method, path, body) {
+  const headers = {};
+  if (state.token) {
+    headers["Authorization"] = "Bearer " + state.token;
+  }
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+  const res = await fetch("../api/v1/namespaces/" + encodeURIComponent(state.namespace) + path, {
+    method: method,
+    headers: headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  if (res.status === 204) {
+    return null;
+  }
+  const data = await res.json().catch(() => null);
+  if (!res.ok) {
+    let message = res.status + " " + res.statusText;
+    if (data && data.detail) {
+      message += ": " + data.detail;
+    }
+    if (data && data["invalid-params"]) {
+      for (const p of data["invalid-params"]) {
+        message += "\n  " + p.field + ": " + p.description;
+      }
+    }
+    throw new Error(message);
+  }
+  return data;
+}
+
+function formatTime(unix) {
+  return unix ? new Date(unix * 1000).toLocaleString() : "";
+}
+
+function formatLabels(labels) {
+  return Object.keys(labels || {}).sort().map((k) => k + "=" + labels[k]);
+}
+
+function parseLabels(text, separator) {
+  const labels = {};
+  for (const item of text.split(separator)) {
+    const pair = item.trim();
+    if (!pair) {
+      continue;
+    }
+    const i = pair.indexOf("=");
+    if (i <= 0) {
+      throw new Error("Invalid label '" + pair + "'. It must be key=value");
+    }
+    labels[pair.slice(0, i).trim()] = pair.slice(i + 1).trim();
+  }
+  return labels;
+}
+
+function machinePath(mac) {
+  return "/machines/" + encodeURIComponent(mac);
+}
+
+async function loadMachines() {
+  const form = $("filters");
+  const params = new URLSearchParams();
+  for (const key of ["name", "ipv4", "liveness"]) {
+    if (form.elements[key].value) {
+      params.set(key, form.elements[key].value);
+    }
+  }
+  const labels = parseLabels(form.elements.labels.value, ",");
+  for (const key of Object.keys(labels)) {
+    params.set("label." + key, labels[key]);
+  }
+  const query = params.toString();
+  let machines = await api("GET", "/machines" + (query ? "?" + query : ""));
+  const deployed = form.elements.state.value;
+  if (deployed) {
+    machines = machines.filter((m) => (m.deployed_date > 0) === (deployed === "deployed"));
+  }
+  machines.sort((a, b) => a.name.localeCompare(b.name));
+
+  const tbody = $("machines");
+  tbody.textContent = "";
+  for (const m of machines) {
+    const tr = el("tr");
+    if (m.mac === state.selected) {
+      tr.className = "selected";
+    }
+    tr.appendChild(el("td", m.name));
+    tr.appendChild(el("td", m.mac));
+    tr.appendChild(el("td", m.ipv4_addr));
+    tr.appendChild(el("td", m.spec.core + " cores, " + m.spec.memory + " MB, " + m.spec.disk + " GB"));
+    tr.appendChild(el("td", formatLabels(m.labels).join(", ")));
+    tr.appendChild(el("td", m.deployed_date ? "deployed " + formatTime(m.deployed_date) : "undeployed"));
+    tr.appendChild(el("td", (m.liveness || "unknown") + (m.last_seen ? " (" + formatTime(m.last_seen) + ")" : ""), m.liveness));
+    tr.addEventListener("click", () => run(() => openMachine(m.mac)));
+    tbody.appendChild(tr);
+  }
+  $("count").textContent = machines.length + " machines";
+}
+
+function fillForm(m) {
+  const form = $("machine-form");
+  form.elements.mac.value = m ? m.mac : "";
+  form.elements.mac.readOnly = !!m;
+  form.elements.name.value = m ? m.name : "";
+  form.elements.ipv4_addr.value = m ? m.ipv4_addr : "";
+  form.elements["spec.core"].value = m ? m.spec.core : "";
+  form.elements["spec.memory"].value = m ? m.spec.memory : "";
+  form.elements["spec.disk"].value = m ? m.spec.disk : "";
+  form.elements.labels.value = m ? formatLabels(m.labels).join("\n") : "";
+  $("reprovision").hidden = !m;
+  $("delete").hidden = !m;
+
+  const status = $("status");
+  status.textContent = "";
+  if (m) {
+    const rows = [
+      ["State", m.deployed_date ? "deployed at " + formatTime(m.deployed_date) : "undeployed"],
+      ["Liveness", m.liveness || "unknown"],
+      ["Last seen", formatTime(m.last_seen) || "never"],
+    ];
+    for (const [k, v] of rows) {
+      status.appendChild(el("dt", k));
+      status.appendChild(el("dd", v));
+    }
+  }
+}
+
+function renderHistory(histories) {
+  const tbody = $("history");
+  tbody.textContent = "";
+  for (const h of histories.slice().reverse()) {
+    const tr = el("tr");
+    tr.appendChild(el("td", h.revision));
+    tr.appendChild(el("td", formatTime(h.timestamp)));
+    tr.appendChild(el("td", h.actor));
+    tr.appendChild(el("td", h.operation));
+    const changes = el("td");
+    for (const c of h.changes || []) {
+      changes.appendChild(el("div", c.field + ": " + JSON.stringify(c.old) + " → " + JSON.stringify(c.new)));
+    }
+    tr.appendChild(changes);
+    const action = el("td");
+    const revert = el("button", "Revert");
+    revert.type = "button";
+    revert.addEventListener("click", () => run(async () => {
+      if (!confirm("Revert " + state.selected + " to revision " + h.revision + "?")) {
+        return;
+      }
+      const reverted = await api("POST", machinePath(state.selected) + "/revert?revision=" + h.revision);
+      await refresh(reverted ? reverted.mac : null);
+    }));
+    action.appendChild(revert);
+    tr.appendChild(action);
+    tbody.appendChild(tr);
+  }
+}
+
+async function openMachine(mac) {
+  state.selected = mac;
+  $("detail").hidden = false;
+  if (mac === null) {
+    state.machine = null;
+    $("detail-title").textContent = "New machine";
+    fillForm(null);
+    renderHistory([]);
+    return;
+  }
+  const [machine, histories] = await Promise.all([
+    api("GET", machinePath(mac)),
+    api("GET", machinePath(mac) + "/history"),
+  ]);
+  state.machine = machine;
+  $("detail-title").textContent = machine.name;
+  fillForm(machine);
+  renderHistory(histories);
+}
+
+function closeMachine() {
+  state.selected = null;
+  state.machine = null;
+  $("detail").hidden = true;
+}
+
+// refresh reloads the list and the machine in the detail, which is closed if mac is null.
+async function refresh(mac) {
+  if (mac) {
+    await openMachine(mac);
+  } else {
+    closeMachine();
+  }
+  await loadMachines();
+}
+
+function formMachine() {
+  const form = $("machine-form");
+  return {
+    mac: form.elements.mac.value.trim(),
+    name: form.elements.name.value.trim(),
+    ipv4_addr: form.elements.ipv4_addr.value.trim(),
+    spec: {
+      core: Number(form.elements["spec.core"].value),
+      memory: Number(form.elements["spec.memory"].value),
+      disk: Number(form.elements["spec.disk"].value),
+    },
+    labels: parseLabels(form.elements.labels.value, "\n"),
+  };
+}
+
+async function saveMachine() {
+  const edited = formMachine();
+  const current = state.machine;
+  if (!current) {
+    const created = await api("PUT", machinePath(edited.mac), edited);
+    await refresh(created.mac);
+    return;
+  }
+  // Only the changed fields are patched so that the concurrent changes of the others are kept.
+  const patch = {};
+  for (const key of ["name", "ipv4_addr"]) {
+    if (edited[key] !== current[key]) {
+      patch[key] = edited[key];
+    }
+  }
+  const spec = {};
+  for (const key of ["core", "memory", "disk"]) {
+    if (edited.spec[key] !== current.spec[key]) {
+      spec[key] = edited.spec[key];
+    }
+  }
+  if (Object.keys(spec).length) {
+    patch.spec = spec;
+  }
+  if (formatLabels(edited.labels).join("\n") !== formatLabels(current.labels).join("\n")) {
+    patch.labels = edited.labels;
+  }
+  if (!Object.keys(patch).length) {
+    return;
+  }
+  await api("PATCH", machinePath(current.mac), patch);
+  await refresh(current.mac);
+}
+
+// run runs the action and shows its error.
+async function run(action) {
+  showError("");
+  try {
+    await action();
+  } catch (e) {
+    showError(e.message);
+  }
+}
+
+function init() {
+  const settings = $("settings");
+  settings.elements.namespace.value = state.namespace;
+  settings.elements.token.value = state.token;
+  settings.addEventListener("submit", (e) => {
+    e.preventDefault();
+    state.namespace = settings.elements.namespace.value.trim();
+    state.token = settings.elements.token.value.trim();
+    localStorage.setItem("tc.namespace", state.namespace);
+    // The token is kept only while the tab is open.
+    sessionStorage.setItem("tc.token", state.token);
+    run(() => refresh(null));
+  });
+  $("filters").addEventListener("submit", (e) => {
+    e.preventDefault();
+    run(loadMachines);
+  });
+  $("new-machine").addEventListener("click", () => run(() => openMachine(null)));
+  $("machine-form").addEventListener("submit", (e) => {
+    e.preventDefault();
+    run(saveMachine);
+  });
+  $("reprovision").addEventListener("click", () => run(async () => {
+    if (!confirm("Mark " + state.machine.name + " as undeployed to provision it again?")) {
+      return;
+    }
+    await api("POST", machinePath(state.machine.mac) + "/reprovision");
+    await refresh(state.machine.mac);
+  }));
+  $("delete").addEventListener("click", () => run(async () => {
+    if (!confirm("Delete " + state.machine.name + "?")) {
+      return;
+    }
+    await api("DELETE", machinePath(state.machine.mac));
+    await refresh(null);
+  }));
+  $("close").addEventListener("click", closeMachine);
+  run(loadMachines);
+}
+
+init();
@@ -0,0 +1,57 @@
+//go:build ignore
+// +build ignore
+
+// gen.go embeds the files in assets/ into assets.go. Run it by 'go generate ./pkg/ui' after editing them.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// contentTypes is the Content-Type of the assets by their extensions.
+var contentTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".css":  "text/css; charset=utf-8",
+	".js":   "application/javascript; charset=utf-8",
+	".svg":  "image/svg+xml",
+	".png":  "image/png",
+}
+
+func main() {
+	paths, err := filepath.Glob(filepath.Join("assets", "*"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(paths)
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go. DO NOT EDIT.\n\npackage ui\n\n")
+	buf.WriteString("// assets is the files of the dashboard by their names.\n")
+	buf.WriteString("var assets = map[string]asset{\n")
+	for _, path := range paths {
+		contentType, ok := contentTypes[filepath.Ext(path)]
+		if !ok {
+			log.Fatalf("unknown content type of %s", path)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintf(&buf, "\t%s: {\n\t\tcontentType: %s,\n\t\tcontent: %s,\n\t},\n",
+			strconv.Quote(filepath.Base(path)), strconv.Quote(contentType), strconv.Quote(string(content)))
+	}
+	buf.WriteString("}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("assets.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
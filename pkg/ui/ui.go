@@ -0,0 +1,49 @@
+//go:generate go run gen.go
+
+// Package ui serves the web dashboard of the machines.
+// The dashboard calls only the REST API with the token given by the user, so that it is permitted
+// exactly what the token is, and its files are embedded into the binary by gen.go.
+package ui
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// indexFile is the file served at the root of the dashboard.
+const indexFile = "index.html"
+
+// asset is a file of the dashboard.
+type asset struct {
+	contentType string
+	content     string
+}
+
+// Register registers the routes of the dashboard into the group, which is expected to be '/ui'.
+// The files contain no secret, so that they are served without the tokens.
+func Register(g *echo.Group) {
+	g.GET("", redirectToIndex)
+	g.GET("/", serveAsset)
+	g.GET("/:file", serveAsset)
+}
+
+// redirectToIndex redirects to the path with the trailing slash, which the relative paths in the files are based on.
+func redirectToIndex(c echo.Context) error {
+	return c.Redirect(http.StatusMovedPermanently, c.Request().URL.Path+"/")
+}
+
+// serveAsset writes the file given by the path parameter, or the index if it is empty.
+func serveAsset(c echo.Context) error {
+	name := c.Param("file")
+	if len(name) == 0 {
+		name = indexFile
+	}
+	a, ok := assets[name]
+	if !ok {
+		return echo.ErrNotFound
+	}
+	// The files change with the binary, so that they are always revalidated.
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	return c.Blob(http.StatusOK, a.contentType, []byte(a.content))
+}
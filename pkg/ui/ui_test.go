@@ -0,0 +1,82 @@
+package ui_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/pddg/tiny-cluster/pkg/ui"
+)
+
+func TestRegister(t *testing.T) {
+	index, err := ioutil.ReadFile(filepath.Join("assets", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		path        string
+		expect      int
+		contentType string
+		body        string
+	}{
+		"redirect": {
+			path:   "/ui",
+			expect: http.StatusMovedPermanently,
+		},
+		"index": {
+			path:        "/ui/",
+			expect:      http.StatusOK,
+			contentType: "text/html",
+			body:        string(index),
+		},
+		"unknown file": {
+			path:   "/ui/secret.txt",
+			expect: http.StatusNotFound,
+		},
+	}
+	e := echo.New()
+	ui.Register(e.Group("/ui"))
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.expect {
+				t.Fatalf("Expect: %d, Actual: %d", tc.expect, rec.Code)
+			}
+			if !strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), tc.contentType) {
+				t.Errorf("Expect: %s, Actual: %s", tc.contentType, rec.Header().Get(echo.HeaderContentType))
+			}
+			if rec.Body.String() != tc.body && len(tc.body) != 0 {
+				t.Errorf("Unexpected body: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestAssets fails if assets.go is not generated again after the files are edited.
+func TestAssets(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("assets", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	ui.Register(e.Group("/ui"))
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui/"+filepath.Base(path), nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != string(content) {
+			t.Errorf("%s is out of date. Run 'go generate ./pkg/ui'", path)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package usecase_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+// newScopedMachine returns the machine whose name and IPv4 address are unique to its MAC,
+// so that RegisterOrUpdateMachine matches only the machine of the same MAC.
+func newScopedMachine(mac, env string) *models.Machine {
+	suffix := mac[len(mac)-2:]
+	return &models.Machine{
+		Name:     "machine" + suffix,
+		MAC:      mac,
+		IPv4Addr: "192.168.0." + strings.TrimLeft(suffix, "0"),
+		Spec:     models.MachineSpec{Core: 2, Memory: 2048, Disk: 64},
+		Labels:   map[string]string{"env": env},
+	}
+}
+
+const (
+	stagingMAC = "52:54:00:00:00:01"
+	prodMAC    = "52:54:00:00:00:02"
+)
+
+// newScopedUseCase returns the usecase which has a staging machine and a prod machine.
+func newScopedUseCase(t *testing.T) usecase.MachineUsecase {
+	t.Helper()
+	machineRepo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatalf("Failed to create the repository due to %v", err)
+	}
+	machineUseCase := usecase.NewMachineUseCase(machineRepo, memory.NewHeartbeatRepository())
+	fixtures := []*models.Machine{newScopedMachine(stagingMAC, "staging"), newScopedMachine(prodMAC, "prod")}
+	if _, err := machineUseCase.ImportMachines(context.Background(), fixtures, false); err != nil {
+		t.Fatalf("Failed to register the machines due to %v", err)
+	}
+	return machineUseCase
+}
+
+func Test_machineUseCaseImpl_permissions(t *testing.T) {
+	stagingOperator := &auth.Principal{Name: "staging", Role: auth.RoleOperator, Scope: map[string]string{"env": "staging"}}
+	viewer := &auth.Principal{Name: "viewer", Role: auth.RoleViewer}
+	admin := &auth.Principal{Name: "admin", Role: auth.RoleAdmin}
+	stagingMachine := auth.PrincipalFromToken(&models.Token{Name: "machine-staging", Role: auth.RoleAdmin, MAC: stagingMAC})
+	testCases := map[string]struct {
+		principal *auth.Principal
+		operation func(ctx context.Context, m usecase.MachineUsecase) error
+		expectErr error
+	}{
+		"operator updates staging": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				machine := newScopedMachine(stagingMAC, "staging")
+				machine.Spec.Core = 4
+				return m.RegisterOrUpdateMachine(ctx, machine)
+			},
+			expectErr: nil,
+		},
+		"operator updates prod": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.RegisterOrUpdateMachine(ctx, newScopedMachine(prodMAC, "staging"))
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator moves staging to prod": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.PatchMachine(ctx, stagingMAC, &models.Machine{Labels: map[string]string{"env": "prod"}}, []string{"labels"})
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator patches prod": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.PatchMachine(ctx, prodMAC, &models.Machine{Name: "renamed"}, []string{"name"})
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator imports prod": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.ImportMachines(ctx, []*models.Machine{newScopedMachine("52:54:00:00:00:03", "prod")}, true)
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator reverts prod": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.RevertMachine(ctx, prodMAC, 1)
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"viewer prunes heartbeats": {
+			principal: viewer,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.PruneHeartbeats(ctx)
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator reverts staging to its deletion": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				// The staging machine is deleted and registered again by the administrator.
+				adminCtx := auth.NewContext(context.Background(), admin)
+				if err := m.DeleteMachine(adminCtx, &models.Machine{MAC: stagingMAC}); err != nil {
+					return err
+				}
+				if err := m.RegisterOrUpdateMachine(adminCtx, newScopedMachine(stagingMAC, "staging")); err != nil {
+					return err
+				}
+				histories, err := m.GetMachineHistory(ctx, stagingMAC)
+				if err != nil {
+					return err
+				}
+				for _, h := range histories {
+					if h.Operation == models.OperationDelete {
+						_, err := m.RevertMachine(ctx, stagingMAC, h.Revision)
+						return err
+					}
+				}
+				return xerrors.New("the deletion is not recorded")
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator reverts staging to an unknown revision": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.RevertMachine(ctx, stagingMAC, 100)
+				return err
+			},
+			expectErr: tcErr.ErrNotFound,
+		},
+		"viewer reverts staging to an unknown revision": {
+			principal: viewer,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.RevertMachine(ctx, stagingMAC, 100)
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator sends heartbeat of staging": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.Heartbeat(ctx, stagingMAC)
+			},
+			expectErr: nil,
+		},
+		"operator reads prod history": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.GetMachineHistory(ctx, prodMAC)
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"operator deletes staging": {
+			principal: stagingOperator,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.DeleteMachine(ctx, &models.Machine{MAC: stagingMAC})
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"machine patches its spec": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.PatchMachine(ctx, stagingMAC, &models.Machine{Spec: models.MachineSpec{Core: 8}}, []string{"spec.core"})
+				return err
+			},
+			expectErr: nil,
+		},
+		"machine sends its heartbeat": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.Heartbeat(ctx, stagingMAC)
+			},
+			expectErr: nil,
+		},
+		"machine patches the spec of another machine": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.PatchMachine(ctx, prodMAC, &models.Machine{Spec: models.MachineSpec{Core: 8}}, []string{"spec.core"})
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"machine sends the heartbeat of another machine": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.Heartbeat(ctx, prodMAC)
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"machine patches its labels": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.PatchMachine(ctx, stagingMAC, &models.Machine{Spec: models.MachineSpec{Core: 8}, Labels: map[string]string{"env": "prod"}}, []string{"spec.core", "labels"})
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"machine updates itself": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.RegisterOrUpdateMachine(ctx, newScopedMachine(stagingMAC, "staging"))
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"machine reads the history of another machine": {
+			principal: stagingMachine,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				_, err := m.GetMachineHistory(ctx, prodMAC)
+				return err
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"viewer updates": {
+			principal: viewer,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.RegisterOrUpdateMachine(ctx, newScopedMachine(stagingMAC, "staging"))
+			},
+			expectErr: tcErr.ErrPermissionDenied,
+		},
+		"admin deletes prod": {
+			principal: admin,
+			operation: func(ctx context.Context, m usecase.MachineUsecase) error {
+				return m.DeleteMachine(ctx, &models.Machine{MAC: prodMAC})
+			},
+			expectErr: nil,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			machineUseCase := newScopedUseCase(t)
+			ctx := auth.NewContext(context.Background(), tc.principal)
+			err := tc.operation(ctx, machineUseCase)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_scopedRead(t *testing.T) {
+	machineUseCase := newScopedUseCase(t)
+	for _, p := range []*auth.Principal{
+		{Name: "staging", Role: auth.RoleViewer, Scope: map[string]string{"env": "staging"}},
+		auth.PrincipalFromToken(&models.Token{Name: "machine-staging", MAC: stagingMAC}),
+	} {
+		ctx := auth.NewContext(context.Background(), p)
+		all, err := machineUseCase.GetAllMachines(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get the machines due to %v", err)
+		}
+		if len(all) != 1 || all[0].MAC != stagingMAC {
+			t.Errorf("Only the machines in the scope of %s must be shown. Actual: %v", p.Name, all)
+		}
+		queried, err := machineUseCase.GetMachineByQuery(ctx, &usecase.MachineQuery{"mac": prodMAC})
+		if err != nil {
+			t.Fatalf("Failed to get the machines due to %v", err)
+		}
+		if len(queried) != 0 {
+			t.Errorf("The machines out of the scope of %s must not be shown. Actual: %v", p.Name, queried)
+		}
+	}
+}
+
+func Test_machineUseCaseImpl_scopedWatch(t *testing.T) {
+	machineUseCase := newScopedUseCase(t)
+	staging := &auth.Principal{Name: "staging", Role: auth.RoleViewer, Scope: map[string]string{"env": "staging"}}
+	ctx, cancel := context.WithCancel(auth.NewContext(context.Background(), staging))
+	defer cancel()
+	events, err := machineUseCase.WatchMachines(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The changes are made by the administrator.
+	prod := newScopedMachine(prodMAC, "prod")
+	prod.Spec.Core = 4
+	for _, machine := range []*models.Machine{
+		// The machine which has never been in the scope is not notified at all.
+		prod,
+		// The machine moved out of the scope is notified as deleted.
+		newScopedMachine(stagingMAC, "prod"),
+		// The changes after it left the scope are not notified.
+		newScopedMachine(stagingMAC, "prod2"),
+		newScopedMachine("52:54:00:00:00:03", "staging"),
+	} {
+		if err := machineUseCase.RegisterOrUpdateMachine(context.Background(), machine); err != nil {
+			t.Fatal(err)
+		}
+	}
+	left := <-events
+	expect := &models.Machine{MAC: stagingMAC}
+	if left.Type != models.EventDelete || !reflect.DeepEqual(left.Machine, expect) {
+		t.Errorf("Only the MAC of the machine out of the scope must be notified. Actual: %s %v", left.Type, left.Machine)
+	}
+	registered := <-events
+	if registered.Type != models.EventPut || registered.Machine.MAC != "52:54:00:00:00:03" {
+		t.Errorf("Expect: put 52:54:00:00:00:03, Actual: %s %v", registered.Type, registered.Machine)
+	}
+}
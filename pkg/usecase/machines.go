@@ -3,29 +3,99 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
 	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
 	"github.com/pddg/tiny-cluster/pkg/repositories"
 )
 
+// HeartbeatTTL is the time a machine stays reachable after its check-in.
+// The machines should check in at the shorter interval than this.
+const HeartbeatTTL = 90 * time.Second
+
 // MachineUsecase is the interface to manipulate the machine data.
+// If the context carries the authenticated principal, each operation returns ErrPermissionDenied
+// when the principal is not permitted it, and only the machines in the scope of the principal are shown.
 type MachineUsecase interface {
-	// GetAllMachines returns all machines.
+	// GetAllMachines returns all machines as stored, without the liveness.
 	GetAllMachines(ctx context.Context) ([]*models.Machine, error)
+	// GetAllMachinesWithLiveness returns all machines. The machines which have ever checked in have their
+	// last-seen time and liveness.
+	GetAllMachinesWithLiveness(ctx context.Context) ([]*models.Machine, error)
 	// GetMachineByName returns the machine whose name is matched with the given name.
 	GetMachineByName(ctx context.Context, name string) (*models.Machine, error)
 	// GetMachineByQuery returns the machine which is filtered by given query.
+	// The machines which have ever checked in have their last-seen time and liveness.
 	GetMachineByQuery(ctx context.Context, query *MachineQuery) ([]*models.Machine, error)
-	// RegisterMachine register the machine if it has not been registered.
+	// RegisterOrUpdateMachine registers the machine if none of its name, IPv4 address and MAC has been registered,
+	// otherwise updates it.
+	// The machine is normalized by NormalizeMachine before it is written.
 	RegisterOrUpdateMachine(ctx context.Context, machine *models.Machine) error
+	// PatchMachine updates only the fields specified by paths of the machine whose MAC is matched with the given mac.
+	// The values are taken from the given machine. See MachineFieldPaths for the available paths.
+	PatchMachine(ctx context.Context, mac string, machine *models.Machine, paths []string) (*models.Machine, error)
+	// DeleteMachine deletes the machine.
+	DeleteMachine(ctx context.Context, machine *models.Machine) error
+	// GetMachineHistory returns the histories of the machine whose MAC is matched with the given mac.
+	GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error)
+	// RevertMachine restores the machine to the state just after the given revision.
+	// This returns nil machine if the machine had been deleted at the revision.
+	RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error)
+	// ImportMachines registers the machines whose MACs have not been registered, otherwise updates them.
+	// Either all of them are written or nothing is written.
+	// If dryRun is true, this only returns what would be changed.
+	ImportMachines(ctx context.Context, machines []*models.Machine, dryRun bool) (*models.ImportResult, error)
+	// MigrateMACs re-keys the machines stored by the MACs which are not normalized by NormalizeMAC,
+	// such as the ones registered before the MACs were normalized. The machines which are the same
+	// as the one stored by the normalized MAC are merged into it, and the others sharing the normalized MAC
+	// are reported as the collisions and left as they are.
+	// If dryRun is true, this only returns what would be changed.
+	MigrateMACs(ctx context.Context, dryRun bool) (*models.MACMigrationResult, error)
+	// WatchMachines returns the channel to receive the changes of the machines made after the call.
+	// The channel is closed when ctx is done or the watch is interrupted, and the caller should get
+	// the machines again and restart watching. The changes of the machines out of the scope are not notified,
+	// except that the machine moved out of the scope is notified as deleted with only its MAC.
+	WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error)
+	// Heartbeat records the check-in of the machine whose MAC is mac, which keeps it reachable for HeartbeatTTL.
+	// This returns ErrNotFound if the machine has not been registered.
+	Heartbeat(ctx context.Context, mac string) error
+	// PruneHeartbeats deletes the check-ins of the machines which do not exist in any namespace,
+	// and returns the number of them. They are usually deleted together with the machines,
+	// but left by the check-ins during the deletions and by the datastores which can not do so.
+	PruneHeartbeats(ctx context.Context) (int, error)
 }
 
 type machineUseCaseImpl struct {
-	repo repositories.MachineRepository
+	repo          repositories.MachineRepository
+	heartbeatRepo repositories.HeartbeatRepository
 }
 
 func (m *machineUseCaseImpl) GetAllMachines(ctx context.Context) ([]*models.Machine, error) {
-	return m.repo.GetMachines(ctx)
+	if err := auth.Authorize(ctx, auth.OperationRead); err != nil {
+		return nil, err
+	}
+	machines, err := m.repo.GetMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return auth.Filter(ctx, machines), nil
+}
+
+func (m *machineUseCaseImpl) GetAllMachinesWithLiveness(ctx context.Context) ([]*models.Machine, error) {
+	machines, err := m.GetAllMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.fillLiveness(ctx, machines)
 }
 
 func (m *machineUseCaseImpl) GetMachineByName(ctx context.Context, name string) (*models.Machine, error) {
@@ -41,10 +111,28 @@ func (m *machineUseCaseImpl) GetMachineByName(ctx context.Context, name string)
 }
 
 func (m *machineUseCaseImpl) GetMachineByQuery(ctx context.Context, query *MachineQuery) ([]*models.Machine, error) {
+	if err := auth.Authorize(ctx, auth.OperationRead); err != nil {
+		return nil, err
+	}
 	machines, err := m.repo.GetMachines(ctx)
 	if err != nil {
 		return nil, err
 	}
+	machines = auth.Filter(ctx, machines)
+	if machines, err = m.fillLiveness(ctx, machines); err != nil {
+		return nil, err
+	}
+	if value, ok := (*query)["mac"]; ok {
+		// The MAC in any form matches the stored one. The invalid MAC matches nothing as it is.
+		if mac, err := NormalizeMAC(value); err == nil {
+			normalized := MachineQuery{}
+			for k, v := range *query {
+				normalized[k] = v
+			}
+			normalized["mac"] = mac
+			query = &normalized
+		}
+	}
 	var matchedMachines []*models.Machine
 	for _, machine := range machines {
 		if query.Match(machine) {
@@ -54,25 +142,502 @@ func (m *machineUseCaseImpl) GetMachineByQuery(ctx context.Context, query *Machi
 	return matchedMachines, nil
 }
 
+// fillLiveness returns the machines with the last-seen time and the liveness of their heartbeats.
+// The machines which have never checked in are returned as they are.
+func (m *machineUseCaseImpl) fillLiveness(ctx context.Context, machines []*models.Machine) ([]*models.Machine, error) {
+	heartbeats, err := m.heartbeatRepo.GetHeartbeats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(heartbeats) == 0 {
+		return machines, nil
+	}
+	byMAC := make(map[string]*models.Heartbeat, len(heartbeats))
+	for _, h := range heartbeats {
+		byMAC[h.MAC] = h
+	}
+	filled := make([]*models.Machine, 0, len(machines))
+	for _, machine := range machines {
+		if h, ok := byMAC[machine.MAC]; ok {
+			copied := *machine
+			copied.LastSeen = h.LastSeen
+			copied.Liveness = h.Liveness()
+			machine = &copied
+		}
+		filled = append(filled, machine)
+	}
+	return filled, nil
+}
+
 func (m *machineUseCaseImpl) RegisterOrUpdateMachine(ctx context.Context, machine *models.Machine) error {
+	// The liveness is not a part of the stored machine.
+	machine, err := NormalizeMachine(machine.WithoutLiveness())
+	if err != nil {
+		return err
+	}
 	query := &MachineQuery{
 		"name": machine.Name,
 		"ipv4": machine.IPv4Addr,
 		"mac":  machine.MAC,
 		"and":  "false",
 	}
-	existsMachines, err := m.GetMachineByQuery(ctx, query)
+	// All machines are matched regardless of the scope, so that the ones out of it are rejected below.
+	machines, err := m.repo.GetMachines(ctx)
 	if err != nil {
 		return err
 	}
+	var existsMachines []*models.Machine
+	for _, exists := range machines {
+		if query.Match(exists) {
+			existsMachines = append(existsMachines, exists)
+		}
+	}
+	// Both of the current and the new labels must be in the scope,
+	// so that the machine can not be moved into or out of the scope.
+	if err := auth.Authorize(ctx, auth.OperationWrite, append(existsMachines, machine)...); err != nil {
+		return err
+	}
 	if len(existsMachines) != 0 {
 		return m.repo.UpdateMachine(ctx, machine)
 	}
 	return m.repo.RegisterMachine(ctx, machine)
 }
 
-func NewMachineUseCase(repo repositories.MachineRepository) MachineUsecase {
+func (m *machineUseCaseImpl) PatchMachine(ctx context.Context, mac string, machine *models.Machine, paths []string) (*models.Machine, error) {
+	if len(paths) == 0 {
+		return nil, xerrors.Errorf("no fields to patch are specified %w", tcErr.ErrInvalidArgument)
+	}
+	// Validate paths before accessing the repository.
+	if err := applyMachineFields(&models.Machine{}, machine, paths); err != nil {
+		return nil, err
+	}
+	// The spec can also be patched by the machine itself, which detects it.
+	op := auth.OperationPatchSpec
+	for _, path := range paths {
+		if path != "spec" && !strings.HasPrefix(path, "spec.") {
+			op = auth.OperationWrite
+		}
+	}
+	if err := auth.Authorize(ctx, op); err != nil {
+		return nil, err
+	}
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	return m.repo.PatchMachine(ctx, mac, func(target *models.Machine) error {
+		if err := auth.Authorize(ctx, op, target); err != nil {
+			return err
+		}
+		if err := applyMachineFields(target, machine, paths); err != nil {
+			return err
+		}
+		// The patched machine must be as valid as the registered one.
+		normalized, err := NormalizeMachine(target)
+		if err != nil {
+			return err
+		}
+		*target = *normalized
+		return auth.Authorize(ctx, op, target)
+	})
+}
+
+func (m *machineUseCaseImpl) DeleteMachine(ctx context.Context, machine *models.Machine) error {
+	if err := auth.Authorize(ctx, auth.OperationDelete); err != nil {
+		return err
+	}
+	mac, err := NormalizeMAC(machine.MAC)
+	if err != nil {
+		return err
+	}
+	if mac != machine.MAC {
+		copied := *machine
+		copied.MAC = mac
+		machine = &copied
+	}
+	if _, ok := auth.FromContext(ctx); ok {
+		// The given machine may have only MAC, so that the labels are taken from the stored one.
+		exists, err := m.findMachine(ctx, machine.MAC)
+		if err != nil {
+			return err
+		}
+		if err := auth.Authorize(ctx, auth.OperationDelete, exists); err != nil {
+			return err
+		}
+	}
+	return m.repo.DeleteMachine(ctx, machine)
+}
+
+func (m *machineUseCaseImpl) GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error) {
+	if err := auth.Authorize(ctx, auth.OperationRead); err != nil {
+		return nil, err
+	}
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	histories, err := m.repo.GetMachineHistory(ctx, mac)
+	if err != nil {
+		return nil, err
+	}
+	// The histories of the deleted machines are also scoped by their last labels.
+	if err := auth.Authorize(ctx, auth.OperationRead, lastState(histories)); err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func (m *machineUseCaseImpl) RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error) {
+	if revision <= 0 {
+		return nil, xerrors.Errorf("revision must be positive %w", tcErr.ErrInvalidArgument)
+	}
+	if err := auth.Authorize(ctx, auth.OperationWrite); err != nil {
+		return nil, err
+	}
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := auth.FromContext(ctx); ok {
+		histories, err := m.repo.GetMachineHistory(ctx, mac)
+		if err != nil {
+			return nil, err
+		}
+		current := lastState(histories)
+		var target *models.MachineHistory
+		for _, h := range histories {
+			if h.Revision == revision {
+				target = h
+			}
+		}
+		switch {
+		case target == nil:
+			// The repository reports that the revision does not exist.
+			if err := auth.Authorize(ctx, auth.OperationWrite, current); err != nil {
+				return nil, err
+			}
+		case target.Machine == nil:
+			// Reverting to the revision where the machine had been deleted deletes it.
+			if err := auth.Authorize(ctx, auth.OperationDelete, current); err != nil {
+				return nil, err
+			}
+		default:
+			// Both of the current state and the restored state must be in the scope.
+			if err := auth.Authorize(ctx, auth.OperationWrite, current, target.Machine); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m.repo.RevertMachine(ctx, mac, revision)
+}
+
+func (m *machineUseCaseImpl) ImportMachines(ctx context.Context, machines []*models.Machine, dryRun bool) (*models.ImportResult, error) {
+	normalized := make([]*models.Machine, 0, len(machines))
+	seen := map[string]bool{}
+	v := &tcErr.ValidationError{}
+	for i, machine := range machines {
+		field := fmt.Sprintf("machines[%d]", i)
+		machine, err := NormalizeMachine(machine.WithoutLiveness())
+		if err != nil {
+			var invalid *tcErr.ValidationError
+			if !xerrors.As(err, &invalid) {
+				return nil, err
+			}
+			v.Violations = append(v.Violations, invalid.Prefix(field).Violations...)
+			continue
+		}
+		// The MACs in the different forms are duplicated after normalized.
+		if seen[machine.MAC] {
+			v.Add(field+".mac", fmt.Sprintf("'%s' is duplicated", machine.MAC))
+		}
+		seen[machine.MAC] = true
+		normalized = append(normalized, machine)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	machines = normalized
+	existsMachines, err := m.repo.GetMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existsByMAC := map[string]*models.Machine{}
+	for _, machine := range existsMachines {
+		existsByMAC[machine.MAC] = machine
+	}
+	for _, machine := range machines {
+		if err := auth.Authorize(ctx, auth.OperationWrite, existsByMAC[machine.MAC], machine); err != nil {
+			return nil, err
+		}
+	}
+	result := &models.ImportResult{
+		DryRun: dryRun,
+	}
+	var changed []*models.Machine
+	for _, machine := range machines {
+		exists, ok := existsByMAC[machine.MAC]
+		if !ok {
+			result.Created = append(result.Created, machine)
+			changed = append(changed, machine)
+			continue
+		}
+		changes := models.DiffMachines(exists, machine)
+		if len(changes) == 0 {
+			result.Unchanged = append(result.Unchanged, machine.MAC)
+			continue
+		}
+		result.Updated = append(result.Updated, &models.MachineDiff{
+			MAC:     machine.MAC,
+			Changes: changes,
+		})
+		changed = append(changed, machine)
+	}
+	if dryRun || len(changed) == 0 {
+		return result, nil
+	}
+	if err := m.repo.ImportMachines(ctx, changed); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *machineUseCaseImpl) MigrateMACs(ctx context.Context, dryRun bool) (*models.MACMigrationResult, error) {
+	if err := auth.Authorize(ctx, auth.OperationDelete); err != nil {
+		return nil, err
+	}
+	machines, err := m.repo.GetMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.MACMigrationResult{
+		DryRun: dryRun,
+	}
+	var normalizedMACs []string
+	byMAC := map[string][]*models.Machine{}
+	for _, machine := range machines {
+		mac, err := NormalizeMAC(machine.MAC)
+		if err != nil {
+			result.Invalid = append(result.Invalid, machine.MAC)
+			continue
+		}
+		if _, ok := byMAC[mac]; !ok {
+			normalizedMACs = append(normalizedMACs, mac)
+		}
+		byMAC[mac] = append(byMAC[mac], machine)
+	}
+	sort.Strings(normalizedMACs)
+	var migrated, merged []*models.Machine
+	for _, mac := range normalizedMACs {
+		group := byMAC[mac]
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].MAC < group[j].MAC
+		})
+		var target *models.Machine
+		var raws []*models.Machine
+		for _, machine := range group {
+			if machine.MAC == mac {
+				target = machine
+			} else {
+				raws = append(raws, machine)
+			}
+		}
+		if len(raws) == 0 {
+			continue
+		}
+		if err := auth.Authorize(ctx, auth.OperationDelete, group...); err != nil {
+			return nil, err
+		}
+		var migrating *models.Machine
+		if target == nil {
+			migrating, raws = raws[0], raws[1:]
+			target = withMAC(migrating, mac)
+		}
+		collided := false
+		for _, raw := range raws {
+			if !reflect.DeepEqual(withMAC(raw, mac), target) {
+				collided = true
+			}
+		}
+		if collided {
+			collision := &models.MACCollision{MAC: mac}
+			for _, machine := range group {
+				collision.Stored = append(collision.Stored, machine.MAC)
+			}
+			result.Collisions = append(result.Collisions, collision)
+			continue
+		}
+		if migrating != nil {
+			result.Migrated = append(result.Migrated, &models.MACMigration{From: migrating.MAC, To: mac})
+			migrated = append(migrated, migrating)
+		}
+		for _, raw := range raws {
+			result.Merged = append(result.Merged, &models.MACMigration{From: raw.MAC, To: mac})
+			merged = append(merged, raw)
+		}
+	}
+	if dryRun {
+		return result, nil
+	}
+	// The machine is registered by the normalized MAC before the old one is deleted,
+	// so that it is merged by running this again if the deletion fails.
+	for _, machine := range migrated {
+		mac, _ := NormalizeMAC(machine.MAC)
+		if err := m.repo.RegisterMachine(ctx, withMAC(machine, mac)); err != nil {
+			return nil, err
+		}
+		if err := m.repo.DeleteMachine(ctx, machine); err != nil {
+			return nil, err
+		}
+	}
+	for _, machine := range merged {
+		if err := m.repo.DeleteMachine(ctx, machine); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (m *machineUseCaseImpl) WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error) {
+	if err := auth.Authorize(ctx, auth.OperationRead); err != nil {
+		return nil, err
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok || !p.Scoped() {
+		return m.repo.WatchMachines(ctx)
+	}
+	// tracked is the MACs of the machines in the scope, which the watcher can have seen.
+	// It is loaded before watching, so that the machines moved out of the scope after that are notified.
+	machines, err := m.repo.GetMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tracked := map[string]bool{}
+	for _, machine := range machines {
+		if p.InScope(machine) {
+			tracked[machine.MAC] = true
+		}
+	}
+	events, err := m.repo.WatchMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make(chan *models.MachineEvent)
+	go func() {
+		defer close(scoped)
+		for event := range events {
+			mac := event.Machine.MAC
+			switch {
+			case p.InScope(event.Machine):
+				tracked[mac] = event.Type == models.EventPut
+			case tracked[mac]:
+				// Nothing but the MAC is notified, since the machine is no longer visible to the watcher.
+				delete(tracked, mac)
+				event = &models.MachineEvent{
+					Type:      models.EventDelete,
+					Namespace: event.Namespace,
+					Revision:  event.Revision,
+					Machine:   &models.Machine{MAC: mac},
+				}
+			default:
+				continue
+			}
+			select {
+			case scoped <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return scoped, nil
+}
+
+func (m *machineUseCaseImpl) Heartbeat(ctx context.Context, mac string) error {
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return err
+	}
+	// The machines check in frequently, so that only the machine itself is read.
+	machine, err := m.repo.GetMachine(ctx, mac)
+	if err != nil {
+		return err
+	}
+	if err := auth.Authorize(ctx, auth.OperationHeartbeat, machine); err != nil {
+		return err
+	}
+	return m.heartbeatRepo.Heartbeat(ctx, mac, HeartbeatTTL)
+}
+
+func (m *machineUseCaseImpl) PruneHeartbeats(ctx context.Context) (int, error) {
+	if err := auth.Authorize(ctx, auth.OperationDelete); err != nil {
+		return 0, err
+	}
+	namespaces, err := m.heartbeatRepo.GetHeartbeatNamespaces(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var pruned int
+	for _, ns := range namespaces {
+		nsCtx := namespace.NewContext(ctx, ns)
+		// The check-ins are read before the machines, since a machine is registered before it checks in.
+		heartbeats, err := m.heartbeatRepo.GetHeartbeats(nsCtx)
+		if err != nil {
+			return pruned, err
+		}
+		machines, err := m.repo.GetMachines(nsCtx)
+		if err != nil {
+			return pruned, err
+		}
+		exists := make(map[string]bool, len(machines))
+		for _, machine := range machines {
+			exists[machine.MAC] = true
+		}
+		var orphans []string
+		for _, h := range heartbeats {
+			if !exists[h.MAC] {
+				orphans = append(orphans, h.MAC)
+			}
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		if err := m.heartbeatRepo.DeleteHeartbeats(nsCtx, orphans); err != nil {
+			return pruned, err
+		}
+		pruned += len(orphans)
+	}
+	return pruned, nil
+}
+
+// findMachine returns the stored machine whose MAC is mac, or nil if it does not exist.
+func (m *machineUseCaseImpl) findMachine(ctx context.Context, mac string) (*models.Machine, error) {
+	machine, err := m.repo.GetMachine(ctx, mac)
+	if xerrors.Is(err, tcErr.ErrNotFound) {
+		return nil, nil
+	}
+	return machine, err
+}
+
+// withMAC returns the copy of the machine whose MAC is mac.
+func withMAC(machine *models.Machine, mac string) *models.Machine {
+	copied := *machine
+	copied.MAC = mac
+	return &copied
+}
+
+// lastState returns the last state of the machine which is recorded in the histories.
+// This returns nil if there are no histories.
+func lastState(histories []*models.MachineHistory) *models.Machine {
+	for i := len(histories) - 1; i >= 0; i-- {
+		if histories[i].Machine != nil {
+			return histories[i].Machine
+		}
+	}
+	return nil
+}
+
+func NewMachineUseCase(repo repositories.MachineRepository, heartbeatRepo repositories.HeartbeatRepository) MachineUsecase {
 	return &machineUseCaseImpl{
-		repo: repo,
+		repo:          repo,
+		heartbeatRepo: heartbeatRepo,
 	}
 }
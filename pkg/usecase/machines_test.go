@@ -8,11 +8,37 @@ import (
 	"github.com/golang/mock/gomock"
 	"golang.org/x/xerrors"
 
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/memory"
 	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/repositories"
 	"github.com/pddg/tiny-cluster/pkg/repositories/mock"
 	"github.com/pddg/tiny-cluster/pkg/usecase"
 )
 
+// noHeartbeats returns the repository where no machines have checked in.
+func noHeartbeats(ctrl *gomock.Controller) repositories.HeartbeatRepository {
+	heartbeatMock := mock.NewMockHeartbeatRepository(ctrl)
+	heartbeatMock.EXPECT().GetHeartbeats(gomock.Any()).Return(nil, nil).AnyTimes()
+	return heartbeatMock
+}
+
+// getMachineFrom returns the function which finds the machine by MAC from the fixtures as the repository does.
+func getMachineFrom(fixtures []*models.Machine, errFixture error) func(context.Context, string) (*models.Machine, error) {
+	return func(_ context.Context, mac string) (*models.Machine, error) {
+		if errFixture != nil {
+			return nil, errFixture
+		}
+		for _, machine := range fixtures {
+			if machine.MAC == mac {
+				return machine, nil
+			}
+		}
+		return nil, tcErr.ErrNotFound
+	}
+}
+
 func Test_machineUseCaseImpl_GetMachineByName(t *testing.T) {
 	sampleErr := xerrors.Errorf("Sample error")
 	testCases := map[string]struct {
@@ -52,13 +78,14 @@ func Test_machineUseCaseImpl_GetMachineByName(t *testing.T) {
 		},
 	}
 	for tn, tc := range testCases {
+		tc := tc
 		ctx := context.TODO()
 		ctrl := gomock.NewController(t)
 		t.Run(tn, func(t *testing.T) {
 			t.Parallel()
 			repoMock := mock.NewMockMachineRepository(ctrl)
 			repoMock.EXPECT().GetMachines(ctx).Return(tc.fixtures, tc.errFixture)
-			machineUseCase := usecase.NewMachineUseCase(repoMock)
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
 			actual, err := machineUseCase.GetMachineByName(ctx, tc.name)
 			if err != tc.expectErr {
 				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
@@ -101,11 +128,12 @@ func Test_machineUseCaseImpl_GetAllMachines(t *testing.T) {
 	ctx := context.TODO()
 	ctrl := gomock.NewController(t)
 	for tn, tc := range testCases {
+		tc := tc
 		t.Run(tn, func(t *testing.T) {
 			t.Parallel()
 			repoMock := mock.NewMockMachineRepository(ctrl)
 			repoMock.EXPECT().GetMachines(ctx).Return(tc.fixtures, tc.errFixture)
-			machineUseCase := usecase.NewMachineUseCase(repoMock)
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
 			actual, err := machineUseCase.GetAllMachines(ctx)
 			if err != tc.expectErr {
 				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", err, tc.expectErr)
@@ -175,7 +203,7 @@ func Test_machineUseCaseImpl_GetMachineByQuery(t *testing.T) {
 				"name": "not found",
 				"ipv4": "not found",
 			},
-			expect:    machineFixtures[0:1],
+			expect:    emptyMachines,
 			expectErr: nil,
 		},
 		"query empty machines": {
@@ -195,11 +223,12 @@ func Test_machineUseCaseImpl_GetMachineByQuery(t *testing.T) {
 	ctx := context.TODO()
 	ctrl := gomock.NewController(t)
 	for tn, tc := range testCases {
+		tc := tc
 		t.Run(tn, func(t *testing.T) {
 			t.Parallel()
 			repoMock := mock.NewMockMachineRepository(ctrl)
 			repoMock.EXPECT().GetMachines(ctx).Return(tc.fixtures, tc.errFixture)
-			machineUseCase := usecase.NewMachineUseCase(repoMock)
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
 			actual, err := machineUseCase.GetMachineByQuery(ctx, tc.query)
 			if err != tc.expectErr {
 				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", err, tc.expectErr)
@@ -212,6 +241,53 @@ func Test_machineUseCaseImpl_GetMachineByQuery(t *testing.T) {
 	}
 }
 
+// Test_machineUseCaseImpl_GetMachineByQuery_and tests that all factors of the query must match unless "and" is false.
+func Test_machineUseCaseImpl_GetMachineByQuery_and(t *testing.T) {
+	var emptyMachines []*models.Machine
+	testCases := map[string]struct {
+		and    string
+		expect []*models.Machine
+	}{
+		"all factors by default": {
+			and:    "",
+			expect: emptyMachines,
+		},
+		"all factors": {
+			and:    "true",
+			expect: emptyMachines,
+		},
+		"any factor": {
+			and:    "false",
+			expect: machineFixtures[0:1],
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockMachineRepository(ctrl)
+			repoMock.EXPECT().GetMachines(ctx).Return(machineFixtures, nil)
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
+			query := usecase.MachineQuery{
+				"mac":  machineFixtures[0].MAC,
+				"name": "not found",
+			}
+			if len(tc.and) != 0 {
+				query["and"] = tc.and
+			}
+			actual, err := machineUseCase.GetMachineByQuery(ctx, &query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
 func Test_machineUseCaseImpl_RegisterOrUpdateMachine(t *testing.T) {
 	sampleErr := xerrors.Errorf("Sample error")
 	testCases := map[string]struct {
@@ -235,6 +311,13 @@ func Test_machineUseCaseImpl_RegisterOrUpdateMachine(t *testing.T) {
 			machine:    machineFixtures[0],
 			expect:     nil,
 		},
+		"update by name": {
+			fixtures:   machineFixtures,
+			errFixture: nil,
+			isUpdate: true,
+			machine:    &models.Machine{Name: machineFixtures[0].Name, MAC: "52:54:00:00:00:ff", IPv4Addr: "192.168.0.255", Spec: machineFixtures[0].Spec},
+			expect:     nil,
+		},
 		"error": {
 			fixtures:   machineFixtures,
 			errFixture: sampleErr,
@@ -246,6 +329,7 @@ func Test_machineUseCaseImpl_RegisterOrUpdateMachine(t *testing.T) {
 	ctx := context.TODO()
 	ctrl := gomock.NewController(t)
 	for tn, tc := range testCases {
+		tc := tc
 		t.Run(tn, func(t *testing.T) {
 			t.Parallel()
 			repoMock := mock.NewMockMachineRepository(ctrl)
@@ -255,7 +339,7 @@ func Test_machineUseCaseImpl_RegisterOrUpdateMachine(t *testing.T) {
 			} else {
 				repoMock.EXPECT().RegisterMachine(ctx, tc.machine).Return(tc.errFixture)
 			}
-			machineUseCase := usecase.NewMachineUseCase(repoMock)
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
 			actual := machineUseCase.RegisterOrUpdateMachine(ctx, tc.machine)
 			if actual != tc.expect {
 				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", actual, tc.expect)
@@ -263,3 +347,407 @@ func Test_machineUseCaseImpl_RegisterOrUpdateMachine(t *testing.T) {
 		})
 	}
 }
+
+func Test_machineUseCaseImpl_PatchMachine(t *testing.T) {
+	sampleErr := xerrors.Errorf("Sample error")
+	original := machineFixtures[1]
+	patchedIP := *original
+	patchedIP.IPv4Addr = "192.168.1.3"
+	patchedSpec := *original
+	patchedSpec.Spec.Memory = 4096
+	testCases := map[string]struct {
+		errFixture error
+		callRepo   bool
+		machine    *models.Machine
+		paths      []string
+		expect     *models.Machine
+		expectErr  error
+	}{
+		"patch ipv4 address": {
+			errFixture: nil,
+			callRepo:   true,
+			machine:    &models.Machine{IPv4Addr: patchedIP.IPv4Addr, Name: "ignored"},
+			paths:      []string{"ipv4_addr"},
+			expect:     &patchedIP,
+			expectErr:  nil,
+		},
+		"patch nested field": {
+			errFixture: nil,
+			callRepo:   true,
+			machine:    &models.Machine{Spec: models.MachineSpec{Memory: 4096}},
+			paths:      []string{"spec.memory"},
+			expect:     &patchedSpec,
+			expectErr:  nil,
+		},
+		"no paths": {
+			errFixture: nil,
+			callRepo:   false,
+			machine:    &models.Machine{},
+			paths:      []string{},
+			expect:     nil,
+			expectErr:  tcErr.ErrInvalidArgument,
+		},
+		"unknown path": {
+			errFixture: nil,
+			callRepo:   false,
+			machine:    &models.Machine{},
+			paths:      []string{"mac"},
+			expect:     nil,
+			expectErr:  tcErr.ErrInvalidArgument,
+		},
+		"error": {
+			errFixture: sampleErr,
+			callRepo:   true,
+			machine:    &models.Machine{IPv4Addr: patchedIP.IPv4Addr},
+			paths:      []string{"ipv4_addr"},
+			expect:     nil,
+			expectErr:  sampleErr,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockMachineRepository(ctrl)
+			if tc.callRepo {
+				repoMock.EXPECT().PatchMachine(ctx, original.MAC, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, mac string, patch repositories.MachinePatchFunc) (*models.Machine, error) {
+						if tc.errFixture != nil {
+							return nil, tc.errFixture
+						}
+						machine := *original
+						if err := patch(&machine); err != nil {
+							return nil, err
+						}
+						return &machine, nil
+					})
+			}
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
+			actual, err := machineUseCase.PatchMachine(ctx, original.MAC, tc.machine, tc.paths)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+				return
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_DeleteMachine(t *testing.T) {
+	sampleErr := xerrors.Errorf("Sample error")
+	testCases := map[string]struct {
+		errFixture error
+		machine    *models.Machine
+		expect     error
+	}{
+		"delete normally": {
+			errFixture: nil,
+			machine:    machineFixtures[0],
+			expect:     nil,
+		},
+		"error": {
+			errFixture: sampleErr,
+			machine:    machineFixtures[0],
+			expect:     sampleErr,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockMachineRepository(ctrl)
+			repoMock.EXPECT().DeleteMachine(ctx, tc.machine).Return(tc.errFixture)
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
+			actual := machineUseCase.DeleteMachine(ctx, tc.machine)
+			if actual != tc.expect {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_RevertMachine(t *testing.T) {
+	sampleErr := xerrors.Errorf("Sample error")
+	testCases := map[string]struct {
+		revision   int64
+		callRepo   bool
+		errFixture error
+		expect     *models.Machine
+		expectErr  error
+	}{
+		"revert normally": {
+			revision:   10,
+			callRepo:   true,
+			errFixture: nil,
+			expect:     machineFixtures[0],
+			expectErr:  nil,
+		},
+		"invalid revision": {
+			revision:   0,
+			callRepo:   false,
+			errFixture: nil,
+			expect:     nil,
+			expectErr:  tcErr.ErrInvalidArgument,
+		},
+		"error": {
+			revision:   10,
+			callRepo:   true,
+			errFixture: sampleErr,
+			expect:     nil,
+			expectErr:  sampleErr,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockMachineRepository(ctrl)
+			if tc.callRepo {
+				repoMock.EXPECT().RevertMachine(ctx, machineFixtures[0].MAC, tc.revision).Return(tc.expect, tc.errFixture)
+			}
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
+			actual, err := machineUseCase.RevertMachine(ctx, machineFixtures[0].MAC, tc.revision)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+				return
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_ImportMachines(t *testing.T) {
+	sampleErr := xerrors.Errorf("Sample error")
+	updated := *machineFixtures[1]
+	updated.Name = "updated"
+	created := &models.Machine{MAC: "52:54:00:00:00:03", Name: "machine3", IPv4Addr: "192.168.0.4", Spec: models.MachineSpec{Core: 1, Memory: 1024, Disk: 32}}
+	testCases := map[string]struct {
+		machines    []*models.Machine
+		dryRun      bool
+		errFixture  error
+		expectWrite []*models.Machine
+		expect      *models.ImportResult
+		expectErr   error
+	}{
+		"import normally": {
+			machines:    []*models.Machine{machineFixtures[0], &updated, created},
+			dryRun:      false,
+			errFixture:  nil,
+			expectWrite: []*models.Machine{&updated, created},
+			expect: &models.ImportResult{
+				DryRun:  false,
+				Created: []*models.Machine{created},
+				Updated: []*models.MachineDiff{
+					{MAC: updated.MAC, Changes: []models.FieldChange{{Field: "name", Old: "machine2", New: "updated"}}},
+				},
+				Unchanged: []string{machineFixtures[0].MAC},
+			},
+			expectErr: nil,
+		},
+		"dry run": {
+			machines:    []*models.Machine{created},
+			dryRun:      true,
+			errFixture:  nil,
+			expectWrite: nil,
+			expect: &models.ImportResult{
+				DryRun:  true,
+				Created: []*models.Machine{created},
+			},
+			expectErr: nil,
+		},
+		"duplicated MAC": {
+			machines:    []*models.Machine{created, created},
+			dryRun:      false,
+			errFixture:  nil,
+			expectWrite: nil,
+			expect:      nil,
+			expectErr:   tcErr.ErrInvalidArgument,
+		},
+		"error": {
+			machines:    []*models.Machine{created},
+			dryRun:      false,
+			errFixture:  sampleErr,
+			expectWrite: []*models.Machine{created},
+			expect:      nil,
+			expectErr:   sampleErr,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockMachineRepository(ctrl)
+			repoMock.EXPECT().GetMachines(ctx).Return(machineFixtures, nil).AnyTimes()
+			if tc.expectWrite != nil {
+				repoMock.EXPECT().ImportMachines(ctx, tc.expectWrite).Return(tc.errFixture)
+			}
+			machineUseCase := usecase.NewMachineUseCase(repoMock, noHeartbeats(ctrl))
+			actual, err := machineUseCase.ImportMachines(ctx, tc.machines, tc.dryRun)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+				return
+			}
+			if !reflect.DeepEqual(actual, tc.expect) {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_GetMachineByQuery_liveness(t *testing.T) {
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewMockMachineRepository(ctrl)
+	repoMock.EXPECT().GetMachines(ctx).Return(machineFixtures, nil).AnyTimes()
+	heartbeatMock := mock.NewMockHeartbeatRepository(ctrl)
+	heartbeatMock.EXPECT().GetHeartbeats(ctx).Return([]*models.Heartbeat{
+		{MAC: machineFixtures[0].MAC, LastSeen: 1601510400, Reachable: true},
+		{MAC: machineFixtures[1].MAC, LastSeen: 1601510000, Reachable: false},
+	}, nil).AnyTimes()
+	machineUseCase := usecase.NewMachineUseCase(repoMock, heartbeatMock)
+
+	actual, err := machineUseCase.GetMachineByQuery(ctx, &usecase.MachineQuery{"liveness": models.LivenessUnreachable})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expect := *machineFixtures[1]
+	expect.LastSeen = 1601510000
+	expect.Liveness = models.LivenessUnreachable
+	if !reflect.DeepEqual(actual, []*models.Machine{&expect}) {
+		t.Errorf("Invalid response. Expected: %#v, Actual: %#v", []*models.Machine{&expect}, actual)
+	}
+	if machineFixtures[1].Liveness != "" {
+		t.Error("The machines returned by the repository must not be modified")
+	}
+}
+
+func Test_machineUseCaseImpl_GetAllMachinesWithLiveness(t *testing.T) {
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewMockMachineRepository(ctrl)
+	repoMock.EXPECT().GetMachines(ctx).Return(machineFixtures, nil).AnyTimes()
+	heartbeatMock := mock.NewMockHeartbeatRepository(ctrl)
+	heartbeatMock.EXPECT().GetHeartbeats(ctx).Return([]*models.Heartbeat{
+		{MAC: machineFixtures[0].MAC, LastSeen: 1601510400, Reachable: true},
+	}, nil).AnyTimes()
+	machineUseCase := usecase.NewMachineUseCase(repoMock, heartbeatMock)
+
+	actual, err := machineUseCase.GetAllMachinesWithLiveness(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	reachable := *machineFixtures[0]
+	reachable.LastSeen = 1601510400
+	reachable.Liveness = models.LivenessReachable
+	expect := []*models.Machine{&reachable, machineFixtures[1]}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Invalid response. Expected: %#v, Actual: %#v", expect, actual)
+	}
+}
+
+func Test_machineUseCaseImpl_Heartbeat(t *testing.T) {
+	sampleErr := xerrors.Errorf("Sample error")
+	testCases := map[string]struct {
+		mac        string
+		errFixture error
+		expectErr  error
+	}{
+		"registered machine": {
+			mac:        machineFixtures[0].MAC,
+			errFixture: nil,
+			expectErr:  nil,
+		},
+		"unknown machine": {
+			mac:        "52:54:00:00:00:ff",
+			errFixture: nil,
+			expectErr:  tcErr.ErrNotFound,
+		},
+		"error": {
+			mac:        machineFixtures[0].MAC,
+			errFixture: sampleErr,
+			expectErr:  sampleErr,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockMachineRepository(ctrl)
+			repoMock.EXPECT().GetMachine(ctx, tc.mac).DoAndReturn(getMachineFrom(machineFixtures, nil))
+			heartbeatMock := mock.NewMockHeartbeatRepository(ctrl)
+			if tc.mac != "52:54:00:00:00:ff" {
+				heartbeatMock.EXPECT().Heartbeat(ctx, tc.mac, usecase.HeartbeatTTL).Return(tc.errFixture)
+			}
+			machineUseCase := usecase.NewMachineUseCase(repoMock, heartbeatMock)
+			err := machineUseCase.Heartbeat(ctx, tc.mac)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_PruneHeartbeats(t *testing.T) {
+	machineRepo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatalf("Failed to create the repository due to %v", err)
+	}
+	heartbeatRepo := memory.NewHeartbeatRepository()
+	machineUseCase := usecase.NewMachineUseCase(machineRepo, heartbeatRepo)
+	defaultCtx := context.Background()
+	labCtx := namespace.NewContext(defaultCtx, "lab")
+	if _, err := machineUseCase.ImportMachines(defaultCtx, machineFixtures[:1], false); err != nil {
+		t.Fatalf("Failed to register the machines due to %v", err)
+	}
+	// The check-in of the registered machine remains, while the others are left by the deleted machines.
+	checkIns := map[context.Context][]string{
+		defaultCtx: {machineFixtures[0].MAC, "52:54:00:00:00:ff"},
+		labCtx:     {machineFixtures[0].MAC},
+	}
+	for ctx, macs := range checkIns {
+		for _, mac := range macs {
+			if err := heartbeatRepo.Heartbeat(ctx, mac, usecase.HeartbeatTTL); err != nil {
+				t.Fatalf("Failed to check in due to %v", err)
+			}
+		}
+	}
+
+	pruned, err := machineUseCase.PruneHeartbeats(defaultCtx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("Invalid number of the pruned check-ins. Expected: 2, Actual: %d", pruned)
+	}
+	expected := map[context.Context][]string{
+		defaultCtx: {machineFixtures[0].MAC},
+		labCtx:     nil,
+	}
+	for ctx, macs := range expected {
+		heartbeats, err := heartbeatRepo.GetHeartbeats(ctx)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var actual []string
+		for _, h := range heartbeats {
+			actual = append(actual, h.MAC)
+		}
+		if !reflect.DeepEqual(actual, macs) {
+			t.Errorf("Invalid check-ins in %s. Expected: %v, Actual: %v", namespace.FromContext(ctx), macs, actual)
+		}
+	}
+}
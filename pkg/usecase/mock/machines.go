@@ -50,6 +50,21 @@ func (mr *MockMachineUsecaseMockRecorder) GetAllMachines(ctx interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllMachines", reflect.TypeOf((*MockMachineUsecase)(nil).GetAllMachines), ctx)
 }
 
+// GetAllMachinesWithLiveness mocks base method
+func (m *MockMachineUsecase) GetAllMachinesWithLiveness(ctx context.Context) ([]*models.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllMachinesWithLiveness", ctx)
+	ret0, _ := ret[0].([]*models.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllMachinesWithLiveness indicates an expected call of GetAllMachinesWithLiveness
+func (mr *MockMachineUsecaseMockRecorder) GetAllMachinesWithLiveness(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllMachinesWithLiveness", reflect.TypeOf((*MockMachineUsecase)(nil).GetAllMachinesWithLiveness), ctx)
+}
+
 // GetMachineByName mocks base method
 func (m *MockMachineUsecase) GetMachineByName(ctx context.Context, name string) (*models.Machine, error) {
 	m.ctrl.T.Helper()
@@ -93,3 +108,136 @@ func (mr *MockMachineUsecaseMockRecorder) RegisterOrUpdateMachine(ctx, machine i
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterOrUpdateMachine", reflect.TypeOf((*MockMachineUsecase)(nil).RegisterOrUpdateMachine), ctx, machine)
 }
+
+// PatchMachine mocks base method
+func (m *MockMachineUsecase) PatchMachine(ctx context.Context, mac string, machine *models.Machine, paths []string) (*models.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchMachine", ctx, mac, machine, paths)
+	ret0, _ := ret[0].(*models.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchMachine indicates an expected call of PatchMachine
+func (mr *MockMachineUsecaseMockRecorder) PatchMachine(ctx, mac, machine, paths interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchMachine", reflect.TypeOf((*MockMachineUsecase)(nil).PatchMachine), ctx, mac, machine, paths)
+}
+
+// DeleteMachine mocks base method
+func (m *MockMachineUsecase) DeleteMachine(ctx context.Context, machine *models.Machine) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMachine", ctx, machine)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMachine indicates an expected call of DeleteMachine
+func (mr *MockMachineUsecaseMockRecorder) DeleteMachine(ctx, machine interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMachine", reflect.TypeOf((*MockMachineUsecase)(nil).DeleteMachine), ctx, machine)
+}
+
+// GetMachineHistory mocks base method
+func (m *MockMachineUsecase) GetMachineHistory(ctx context.Context, mac string) ([]*models.MachineHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMachineHistory", ctx, mac)
+	ret0, _ := ret[0].([]*models.MachineHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMachineHistory indicates an expected call of GetMachineHistory
+func (mr *MockMachineUsecaseMockRecorder) GetMachineHistory(ctx, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMachineHistory", reflect.TypeOf((*MockMachineUsecase)(nil).GetMachineHistory), ctx, mac)
+}
+
+// RevertMachine mocks base method
+func (m *MockMachineUsecase) RevertMachine(ctx context.Context, mac string, revision int64) (*models.Machine, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertMachine", ctx, mac, revision)
+	ret0, _ := ret[0].(*models.Machine)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertMachine indicates an expected call of RevertMachine
+func (mr *MockMachineUsecaseMockRecorder) RevertMachine(ctx, mac, revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertMachine", reflect.TypeOf((*MockMachineUsecase)(nil).RevertMachine), ctx, mac, revision)
+}
+
+// ImportMachines mocks base method
+func (m *MockMachineUsecase) ImportMachines(ctx context.Context, machines []*models.Machine, dryRun bool) (*models.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportMachines", ctx, machines, dryRun)
+	ret0, _ := ret[0].(*models.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportMachines indicates an expected call of ImportMachines
+func (mr *MockMachineUsecaseMockRecorder) ImportMachines(ctx, machines, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportMachines", reflect.TypeOf((*MockMachineUsecase)(nil).ImportMachines), ctx, machines, dryRun)
+}
+
+// MigrateMACs mocks base method
+func (m *MockMachineUsecase) MigrateMACs(ctx context.Context, dryRun bool) (*models.MACMigrationResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrateMACs", ctx, dryRun)
+	ret0, _ := ret[0].(*models.MACMigrationResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrateMACs indicates an expected call of MigrateMACs
+func (mr *MockMachineUsecaseMockRecorder) MigrateMACs(ctx, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateMACs", reflect.TypeOf((*MockMachineUsecase)(nil).MigrateMACs), ctx, dryRun)
+}
+
+// WatchMachines mocks base method
+func (m *MockMachineUsecase) WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchMachines", ctx)
+	ret0, _ := ret[0].(<-chan *models.MachineEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WatchMachines indicates an expected call of WatchMachines
+func (mr *MockMachineUsecaseMockRecorder) WatchMachines(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchMachines", reflect.TypeOf((*MockMachineUsecase)(nil).WatchMachines), ctx)
+}
+
+// Heartbeat mocks base method
+func (m *MockMachineUsecase) Heartbeat(ctx context.Context, mac string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", ctx, mac)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Heartbeat indicates an expected call of Heartbeat
+func (mr *MockMachineUsecaseMockRecorder) Heartbeat(ctx, mac interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockMachineUsecase)(nil).Heartbeat), ctx, mac)
+}
+
+// PruneHeartbeats mocks base method
+func (m *MockMachineUsecase) PruneHeartbeats(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PruneHeartbeats", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PruneHeartbeats indicates an expected call of PruneHeartbeats
+func (mr *MockMachineUsecaseMockRecorder) PruneHeartbeats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PruneHeartbeats", reflect.TypeOf((*MockMachineUsecase)(nil).PruneHeartbeats), ctx)
+}
@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: tokens.go
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	gomock "github.com/golang/mock/gomock"
+	models "github.com/pddg/tiny-cluster/pkg/models"
+	reflect "reflect"
+	time "time"
+)
+
+// MockTokenUsecase is a mock of TokenUsecase interface
+type MockTokenUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenUsecaseMockRecorder
+}
+
+// MockTokenUsecaseMockRecorder is the mock recorder for MockTokenUsecase
+type MockTokenUsecaseMockRecorder struct {
+	mock *MockTokenUsecase
+}
+
+// NewMockTokenUsecase creates a new mock instance
+func NewMockTokenUsecase(ctrl *gomock.Controller) *MockTokenUsecase {
+	mock := &MockTokenUsecase{ctrl: ctrl}
+	mock.recorder = &MockTokenUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTokenUsecase) EXPECT() *MockTokenUsecaseMockRecorder {
+	return m.recorder
+}
+
+// CreateToken mocks base method
+func (m *MockTokenUsecase) CreateToken(ctx context.Context, name, role string, scope map[string]string, ttl time.Duration) (*models.Token, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, name, role, scope, ttl)
+	ret0, _ := ret[0].(*models.Token)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateToken indicates an expected call of CreateToken
+func (mr *MockTokenUsecaseMockRecorder) CreateToken(ctx, name, role, scope, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockTokenUsecase)(nil).CreateToken), ctx, name, role, scope, ttl)
+}
+
+// CreateMachineToken mocks base method
+func (m *MockTokenUsecase) CreateMachineToken(ctx context.Context, name, mac string, ttl time.Duration) (*models.Token, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMachineToken", ctx, name, mac, ttl)
+	ret0, _ := ret[0].(*models.Token)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateMachineToken indicates an expected call of CreateMachineToken
+func (mr *MockTokenUsecaseMockRecorder) CreateMachineToken(ctx, name, mac, ttl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMachineToken", reflect.TypeOf((*MockTokenUsecase)(nil).CreateMachineToken), ctx, name, mac, ttl)
+}
+
+// GetTokens mocks base method
+func (m *MockTokenUsecase) GetTokens(ctx context.Context) ([]*models.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokens", ctx)
+	ret0, _ := ret[0].([]*models.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokens indicates an expected call of GetTokens
+func (mr *MockTokenUsecaseMockRecorder) GetTokens(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokens", reflect.TypeOf((*MockTokenUsecase)(nil).GetTokens), ctx)
+}
+
+// RevokeToken mocks base method
+func (m *MockTokenUsecase) RevokeToken(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken
+func (mr *MockTokenUsecaseMockRecorder) RevokeToken(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockTokenUsecase)(nil).RevokeToken), ctx, id)
+}
+
+// Authenticate mocks base method
+func (m *MockTokenUsecase) Authenticate(ctx context.Context, bearer string) (*models.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", ctx, bearer)
+	ret0, _ := ret[0].(*models.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate
+func (mr *MockTokenUsecaseMockRecorder) Authenticate(ctx, bearer interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockTokenUsecase)(nil).Authenticate), ctx, bearer)
+}
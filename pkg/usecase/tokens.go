@@ -0,0 +1,165 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+const (
+	// tokenIDBytes is the length of the random ID of the token.
+	tokenIDBytes = 8
+	// tokenSecretBytes is the length of the random secret of the token.
+	tokenSecretBytes = 32
+	// tokenSeparator separates the ID and the secret in the bearer token.
+	tokenSeparator = "."
+)
+
+// TokenUsecase is the interface to manage the API tokens and to authenticate the clients by them.
+type TokenUsecase interface {
+	// CreateToken issues the token for name, which expires after ttl. The token never expires if ttl is 0.
+	// The token is permitted the operations of role on the machines matching scope.
+	// The bearer token is returned only here, since only the hash of it is stored.
+	CreateToken(ctx context.Context, name, role string, scope map[string]string, ttl time.Duration) (*models.Token, string, error)
+	// CreateMachineToken issues the token bound to the machine whose MAC is mac, which expires after ttl.
+	// The token can only read the machine, patch its spec and send its heartbeats, as auth.RoleMachine.
+	// The name is the MAC if it is empty. The bearer token is returned only here as well as CreateToken.
+	CreateMachineToken(ctx context.Context, name, mac string, ttl time.Duration) (*models.Token, string, error)
+	// GetTokens returns all tokens.
+	GetTokens(ctx context.Context) ([]*models.Token, error)
+	// RevokeToken deletes the token whose ID is id. This returns ErrNotFound if it does not exist,
+	// and ErrInvalidArgument if id is not in the form of the generated IDs.
+	RevokeToken(ctx context.Context, id string) error
+	// Authenticate returns the token which the bearer token belongs to.
+	// This returns ErrAuthFailed if the token is malformed, unknown, revoked or expired.
+	Authenticate(ctx context.Context, bearer string) (*models.Token, error)
+}
+
+type tokenUseCaseImpl struct {
+	repo repositories.TokenRepository
+}
+
+// hashSecret returns the hash of the secret to be stored.
+// The secrets have enough entropy, so that they do not need to be salted or stretched.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// validTokenID reports whether id is in the form of the generated IDs, which is safe to be a part of the key.
+func validTokenID(id string) bool {
+	if len(id) != hex.EncodedLen(tokenIDBytes) {
+		return false
+	}
+	b, err := hex.DecodeString(id)
+	return err == nil && hex.EncodeToString(b) == id
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, xerrors.Errorf("Failed to generate the token %w", err)
+	}
+	return b, nil
+}
+
+func (t *tokenUseCaseImpl) CreateToken(ctx context.Context, name, role string, scope map[string]string, ttl time.Duration) (*models.Token, string, error) {
+	if len(name) == 0 {
+		return nil, "", xerrors.Errorf("name of the token is required %w", tcErr.ErrInvalidArgument)
+	}
+	if err := auth.ValidateRole(role); err != nil {
+		return nil, "", err
+	}
+	return t.issue(ctx, &models.Token{Name: name, Role: role, Scope: scope}, ttl)
+}
+
+func (t *tokenUseCaseImpl) CreateMachineToken(ctx context.Context, name, mac string, ttl time.Duration) (*models.Token, string, error) {
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(name) == 0 {
+		name = mac
+	}
+	return t.issue(ctx, &models.Token{Name: name, Role: auth.RoleMachine, MAC: mac}, ttl)
+}
+
+// issue generates the ID and the secret of the token, and stores it.
+func (t *tokenUseCaseImpl) issue(ctx context.Context, token *models.Token, ttl time.Duration) (*models.Token, string, error) {
+	if ttl < 0 {
+		return nil, "", xerrors.Errorf("ttl must not be negative %w", tcErr.ErrInvalidArgument)
+	}
+	id, err := randomBytes(tokenIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomBytes(tokenSecretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	token.ID = hex.EncodeToString(id)
+	token.CreatedAt = now.Unix()
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl).Unix()
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	token.Hash = hashSecret(encodedSecret)
+	if err := t.repo.CreateToken(ctx, token); err != nil {
+		return nil, "", err
+	}
+	return token, token.ID + tokenSeparator + encodedSecret, nil
+}
+
+func (t *tokenUseCaseImpl) GetTokens(ctx context.Context) ([]*models.Token, error) {
+	return t.repo.GetTokens(ctx)
+}
+
+func (t *tokenUseCaseImpl) RevokeToken(ctx context.Context, id string) error {
+	if !validTokenID(id) {
+		return xerrors.Errorf("malformed token ID '%s' %w", id, tcErr.ErrInvalidArgument)
+	}
+	return t.repo.DeleteToken(ctx, id)
+}
+
+func (t *tokenUseCaseImpl) Authenticate(ctx context.Context, bearer string) (*models.Token, error) {
+	elems := strings.SplitN(bearer, tokenSeparator, 2)
+	// The ID is checked before it is looked up, since it is given by the untrusted client.
+	if len(elems) != 2 || !validTokenID(elems[0]) || len(elems[1]) == 0 {
+		return nil, xerrors.Errorf("malformed token %w", tcErr.ErrAuthFailed)
+	}
+	token, err := t.repo.GetToken(ctx, elems[0])
+	if xerrors.Is(err, tcErr.ErrNotFound) {
+		return nil, xerrors.Errorf("unknown token '%s' %w", elems[0], tcErr.ErrAuthFailed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(elems[1])), []byte(token.Hash)) != 1 {
+		return nil, xerrors.Errorf("invalid secret of the token '%s' %w", token.ID, tcErr.ErrAuthFailed)
+	}
+	if token.Expired(time.Now().Unix()) {
+		return nil, xerrors.Errorf("token '%s' has expired %w", token.ID, tcErr.ErrAuthFailed)
+	}
+	return token, nil
+}
+
+// NewTokenUseCase returns the usecase of the tokens stored in repo.
+func NewTokenUseCase(repo repositories.TokenRepository) TokenUsecase {
+	return &tokenUseCaseImpl{
+		repo: repo,
+	}
+}
@@ -0,0 +1,213 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"golang.org/x/xerrors"
+
+	"github.com/pddg/tiny-cluster/pkg/auth"
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/repositories/mock"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+func Test_tokenUseCaseImpl_CreateToken(t *testing.T) {
+	testCases := map[string]struct {
+		name      string
+		role      string
+		ttl       time.Duration
+		expectTTL bool
+		expectErr error
+	}{
+		"never expires": {
+			name:      "admin",
+			role:      auth.RoleAdmin,
+			ttl:       0,
+			expectTTL: false,
+			expectErr: nil,
+		},
+		"expires": {
+			name:      "machine1",
+			role:      auth.RoleOperator,
+			ttl:       time.Hour,
+			expectTTL: true,
+			expectErr: nil,
+		},
+		"no name": {
+			name:      "",
+			role:      auth.RoleViewer,
+			ttl:       0,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"negative ttl": {
+			name:      "admin",
+			role:      auth.RoleAdmin,
+			ttl:       -time.Hour,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"unknown role": {
+			name:      "admin",
+			role:      "root",
+			ttl:       0,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockTokenRepository(ctrl)
+			var stored *models.Token
+			if tc.expectErr == nil {
+				repoMock.EXPECT().CreateToken(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, token *models.Token) error {
+					stored = token
+					return nil
+				})
+			}
+			tokenUseCase := usecase.NewTokenUseCase(repoMock)
+			token, bearer, err := tokenUseCase.CreateToken(ctx, tc.name, tc.role, nil, tc.ttl)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if tc.expectErr != nil {
+				return
+			}
+			if token != stored || token.Name != tc.name || token.Role != tc.role {
+				t.Errorf("The created token must be stored. Actual: %v", stored)
+			}
+			if (token.ExpiresAt != 0) != tc.expectTTL {
+				t.Errorf("Invalid expiry. Actual: %d", token.ExpiresAt)
+			}
+			if !strings.HasPrefix(bearer, token.ID+".") {
+				t.Errorf("The bearer token must start with the ID. Actual: %s", bearer)
+			}
+			if strings.Contains(token.Hash, strings.TrimPrefix(bearer, token.ID+".")) {
+				t.Error("The secret must not be stored")
+			}
+		})
+	}
+}
+
+func Test_tokenUseCaseImpl_CreateMachineToken(t *testing.T) {
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewMockTokenRepository(ctrl)
+	repoMock.EXPECT().CreateToken(ctx, gomock.Any()).Return(nil)
+	tokenUseCase := usecase.NewTokenUseCase(repoMock)
+	token, bearer, err := tokenUseCase.CreateMachineToken(ctx, "", "52-54-00-AA-BB-CC", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.MAC != "52:54:00:aa:bb:cc" || token.Name != token.MAC || token.Role != auth.RoleMachine || token.ExpiresAt == 0 {
+		t.Errorf("The token must be bound to the normalized MAC. Actual: %v", token)
+	}
+	if !strings.HasPrefix(bearer, token.ID+".") {
+		t.Errorf("The bearer token must start with the ID. Actual: %s", bearer)
+	}
+	if _, _, err := tokenUseCase.CreateMachineToken(ctx, "", "invalid", 0); !xerrors.Is(err, tcErr.ErrInvalidArgument) {
+		t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tcErr.ErrInvalidArgument, err)
+	}
+}
+
+func Test_tokenUseCaseImpl_Authenticate(t *testing.T) {
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	repoMock := mock.NewMockTokenRepository(ctrl)
+	tokens := map[string]*models.Token{}
+	repoMock.EXPECT().CreateToken(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, token *models.Token) error {
+		tokens[token.ID] = token
+		return nil
+	}).AnyTimes()
+	repoMock.EXPECT().GetToken(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, id string) (*models.Token, error) {
+		if token, ok := tokens[id]; ok {
+			return token, nil
+		}
+		return nil, tcErr.ErrNotFound
+	}).AnyTimes()
+	tokenUseCase := usecase.NewTokenUseCase(repoMock)
+	valid, validBearer, err := tokenUseCase.CreateToken(ctx, "admin", auth.RoleAdmin, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create the token due to %v", err)
+	}
+	expired, expiredBearer, err := tokenUseCase.CreateToken(ctx, "expired", auth.RoleViewer, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create the token due to %v", err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+	testCases := map[string]struct {
+		bearer    string
+		expect    *models.Token
+		expectErr error
+	}{
+		"valid": {
+			bearer:    validBearer,
+			expect:    valid,
+			expectErr: nil,
+		},
+		"expired": {
+			bearer:    expiredBearer,
+			expect:    nil,
+			expectErr: tcErr.ErrAuthFailed,
+		},
+		"wrong secret": {
+			bearer:    valid.ID + ".wrong",
+			expect:    nil,
+			expectErr: tcErr.ErrAuthFailed,
+		},
+		"unknown": {
+			bearer:    "0123456789abcdef.secret",
+			expect:    nil,
+			expectErr: tcErr.ErrAuthFailed,
+		},
+		"malformed": {
+			bearer:    valid.ID,
+			expect:    nil,
+			expectErr: tcErr.ErrAuthFailed,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			actual, err := tokenUseCase.Authenticate(ctx, tc.bearer)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if actual != tc.expect {
+				t.Errorf("Invalid response. Expected: %#v, Actual: %#v", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func Test_tokenUseCaseImpl_malformedID(t *testing.T) {
+	ctx := context.TODO()
+	ctrl := gomock.NewController(t)
+	// The repository must not be accessed by the IDs which are not generated, such as the paths to other keys.
+	repoMock := mock.NewMockTokenRepository(ctrl)
+	tokenUseCase := usecase.NewTokenUseCase(repoMock)
+	testCases := map[string]string{
+		"path":       "../machines/v1/52:54:00:00:00:01",
+		"short":      "0123456789abcde",
+		"upper case": "0123456789ABCDEF",
+		"not hex":    "0123456789abcdeg",
+	}
+	for tn, id := range testCases {
+		id := id
+		t.Run(tn, func(t *testing.T) {
+			if _, err := tokenUseCase.Authenticate(ctx, id+".secret"); !xerrors.Is(err, tcErr.ErrAuthFailed) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tcErr.ErrAuthFailed, err)
+			}
+			if err := tokenUseCase.RevokeToken(ctx, id); !xerrors.Is(err, tcErr.ErrInvalidArgument) {
+				t.Errorf("Invalid error. Expected: %#v, Actual: %#v", tcErr.ErrInvalidArgument, err)
+			}
+		})
+	}
+}
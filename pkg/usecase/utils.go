@@ -1,11 +1,18 @@
 package usecase
 
 import (
+	"strconv"
 	"strings"
 
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
 	"github.com/pddg/tiny-cluster/pkg/models"
 )
 
+// labelQueryPrefix is the prefix of the query keys to filter the machines by the label such as "label.env".
+const labelQueryPrefix = "label."
+
 // MachineQuery indicate that the query to filter the machine instance.
 type MachineQuery map[string]string
 
@@ -19,6 +26,17 @@ func (q MachineQuery) _match(machine *models.Machine, and bool) bool {
 			match = machine.Name == v
 		case k == "ipv4":
 			match = machine.IPv4Addr == v
+		case strings.HasPrefix(k, labelQueryPrefix):
+			value, ok := machine.Labels[strings.TrimPrefix(k, labelQueryPrefix)]
+			match = ok && value == v
+		case k == "liveness":
+			match = machine.Liveness == v || (v == models.LivenessUnknown && len(machine.Liveness) == 0)
+		case k == "last_seen_before":
+			before, err := strconv.ParseInt(v, 10, 64)
+			match = err == nil && machine.LastSeen != 0 && machine.LastSeen < before
+		case k == "last_seen_after":
+			after, err := strconv.ParseInt(v, 10, 64)
+			match = err == nil && machine.LastSeen > after
 		default:
 			continue
 		}
@@ -44,3 +62,43 @@ func (q MachineQuery) Match(machine *models.Machine) bool {
 	}
 	return q._match(machine, strings.ToLower(and) == "true")
 }
+
+// MachineFieldPaths is the list of the field paths which can be given to PatchMachine.
+// Each path is the JSON field name of models.Machine, and nested fields are joined by '.'.
+var MachineFieldPaths = []string{
+	"name",
+	"ipv4_addr",
+	"deployed_date",
+	"spec",
+	"spec.core",
+	"spec.memory",
+	"spec.disk",
+	"labels",
+}
+
+// applyMachineFields copies the fields specified by paths from src to dst.
+func applyMachineFields(dst *models.Machine, src *models.Machine, paths []string) error {
+	for _, p := range paths {
+		switch p {
+		case "name":
+			dst.Name = src.Name
+		case "ipv4_addr":
+			dst.IPv4Addr = src.IPv4Addr
+		case "deployed_date":
+			dst.DeployedDate = src.DeployedDate
+		case "spec":
+			dst.Spec = src.Spec
+		case "spec.core":
+			dst.Spec.Core = src.Spec.Core
+		case "spec.memory":
+			dst.Spec.Memory = src.Spec.Memory
+		case "spec.disk":
+			dst.Spec.Disk = src.Spec.Disk
+		case "labels":
+			dst.Labels = src.Labels
+		default:
+			return xerrors.Errorf("'%s' is not a patchable field %w", p, tcErr.ErrInvalidArgument)
+		}
+	}
+	return nil
+}
@@ -12,7 +12,7 @@ var (
 	machineFixtures = []*models.Machine{
 		{
 			Name:         "machine1",
-			MAC:          "mac1",
+			MAC:          "52:54:00:00:00:01",
 			IPv4Addr:     "19.168.0.2",
 			DeployedDate: time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC).Unix(),
 			Spec: models.MachineSpec{
@@ -23,7 +23,7 @@ var (
 		},
 		{
 			Name:         "machine2",
-			MAC:          "mac2",
+			MAC:          "52:54:00:00:00:02",
 			IPv4Addr:     "19.168.1.2",
 			DeployedDate: time.Date(2020, 10, 2, 0, 0, 0, 0, time.UTC).Unix(),
 			Spec: models.MachineSpec{
@@ -36,6 +36,10 @@ var (
 )
 
 func Test_MachineQueryMatch(t *testing.T) {
+	seenMachine := *machineFixtures[0]
+	seenMachine.LastSeen = time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC).Unix()
+	seenMachine.Liveness = models.LivenessReachable
+	seenMachine.Labels = map[string]string{"env": "staging"}
 	testCases := map[string]struct {
 		target *models.Machine
 		query  *usecase.MachineQuery
@@ -84,6 +88,46 @@ func Test_MachineQueryMatch(t *testing.T) {
 			},
 			expect: true,
 		},
+		"match by label": {
+			target: &seenMachine,
+			query:  &usecase.MachineQuery{"label.env": "staging"},
+			expect: true,
+		},
+		"do not match by missing label": {
+			target: machineFixtures[0],
+			query:  &usecase.MachineQuery{"label.env": ""},
+			expect: false,
+		},
+		"match by liveness": {
+			target: &seenMachine,
+			query:  &usecase.MachineQuery{"liveness": models.LivenessReachable},
+			expect: true,
+		},
+		"match never seen machine as unknown": {
+			target: machineFixtures[0],
+			query:  &usecase.MachineQuery{"liveness": models.LivenessUnknown},
+			expect: true,
+		},
+		"match by last seen before": {
+			target: &seenMachine,
+			query:  &usecase.MachineQuery{"last_seen_before": "1601510401"},
+			expect: true,
+		},
+		"never seen machine is not seen before": {
+			target: machineFixtures[0],
+			query:  &usecase.MachineQuery{"last_seen_before": "1601510401"},
+			expect: false,
+		},
+		"do not match by last seen after": {
+			target: &seenMachine,
+			query:  &usecase.MachineQuery{"last_seen_after": "1601510400"},
+			expect: false,
+		},
+		"invalid last seen": {
+			target: &seenMachine,
+			query:  &usecase.MachineQuery{"last_seen_after": "yesterday"},
+			expect: false,
+		},
 		"do not match": {
 			target: machineFixtures[0],
 			query: &usecase.MachineQuery{
@@ -95,6 +139,7 @@ func Test_MachineQueryMatch(t *testing.T) {
 		},
 	}
 	for tn, tc := range testCases {
+		tc := tc
 		t.Run(tn, func(t *testing.T) {
 			t.Parallel()
 			actual := tc.query.Match(tc.target)
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// dns1123LabelPattern is the pattern of DNS-1123 label, which the names of the machines must match.
+var dns1123LabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// NormalizeMAC returns the MAC address in the lower case colon-separated form such as "52:54:00:ab:cd:ef".
+// The forms accepted by net.ParseMAC, such as "52-54-00-AB-CD-EF" or "5254.00ab.cdef", are normalized.
+func NormalizeMAC(mac string) (string, error) {
+	hw, err := net.ParseMAC(strings.TrimSpace(mac))
+	if err != nil || len(hw) != 6 {
+		v := &tcErr.ValidationError{}
+		v.Add("mac", fmt.Sprintf("'%s' is not a 48-bit MAC address", mac))
+		return "", v
+	}
+	return hw.String(), nil
+}
+
+// NormalizeMachine returns the copy of the machine whose MAC and IPv4 address are normalized.
+// This returns ValidationError which has all invalid fields of the machine.
+func NormalizeMachine(machine *models.Machine) (*models.Machine, error) {
+	normalized := *machine
+	v := &tcErr.ValidationError{}
+	if mac, err := NormalizeMAC(machine.MAC); err != nil {
+		v.Add("mac", fmt.Sprintf("'%s' is not a 48-bit MAC address", machine.MAC))
+	} else {
+		normalized.MAC = mac
+	}
+	if !dns1123LabelPattern.MatchString(machine.Name) {
+		v.Add("name", fmt.Sprintf("'%s' must consist of at most 63 lower case alphanumeric characters or '-', and start and end with an alphanumeric character", machine.Name))
+	}
+	// The IPv4-mapped IPv6 addresses such as "::ffff:192.168.0.2" are rejected.
+	if ip := net.ParseIP(strings.TrimSpace(machine.IPv4Addr)); ip == nil || ip.To4() == nil || strings.Contains(machine.IPv4Addr, ":") {
+		v.Add("ipv4_addr", fmt.Sprintf("'%s' is not an IPv4 address", machine.IPv4Addr))
+	} else {
+		normalized.IPv4Addr = ip.To4().String()
+	}
+	if machine.DeployedDate < 0 {
+		v.Add("deployed_date", "must not be negative")
+	}
+	specs := []struct {
+		field string
+		value int
+	}{
+		{"spec.core", machine.Spec.Core},
+		{"spec.memory", machine.Spec.Memory},
+		{"spec.disk", machine.Spec.Disk},
+	}
+	for _, spec := range specs {
+		if spec.value <= 0 {
+			v.Add(spec.field, fmt.Sprintf("must be positive but %d", spec.value))
+		}
+	}
+	keys := make([]string, 0, len(machine.Labels))
+	for key := range machine.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// The labels must be able to be written in the text form of models.FormatLabels.
+		if len(key) == 0 || strings.ContainsAny(key, "=;") || strings.Contains(machine.Labels[key], ";") {
+			v.Add("labels."+key, "the key must be neither empty nor contain '=' or ';', and the value must not contain ';'")
+		}
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
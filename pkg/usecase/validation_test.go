@@ -0,0 +1,251 @@
+package usecase_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+func validMachine() *models.Machine {
+	return &models.Machine{
+		Name:     "machine1",
+		MAC:      "52:54:00:ab:cd:01",
+		IPv4Addr: "192.168.0.2",
+		Spec:     models.MachineSpec{Core: 2, Memory: 2048, Disk: 64},
+		Labels:   map[string]string{"env": "prod"},
+	}
+}
+
+func TestNormalizeMAC(t *testing.T) {
+	testCases := map[string]struct {
+		mac       string
+		expect    string
+		expectErr error
+	}{
+		"lower case colon": {
+			mac:    "52:54:00:ab:cd:01",
+			expect: "52:54:00:ab:cd:01",
+		},
+		"upper case": {
+			mac:    "52:54:00:AB:CD:01",
+			expect: "52:54:00:ab:cd:01",
+		},
+		"hyphen": {
+			mac:    "52-54-00-AB-CD-01",
+			expect: "52:54:00:ab:cd:01",
+		},
+		"dot": {
+			mac:    "5254.00ab.cd01",
+			expect: "52:54:00:ab:cd:01",
+		},
+		"EUI-64": {
+			mac:       "52:54:00:ab:cd:01:02:03",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"malformed": {
+			mac:       "mac1",
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			actual, err := usecase.NormalizeMAC(tc.mac)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if actual != tc.expect {
+				t.Errorf("Expect: %s, Actual: %s", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestNormalizeMachine(t *testing.T) {
+	testCases := map[string]struct {
+		modify       func(m *models.Machine)
+		expect       func(m *models.Machine)
+		expectFields []string
+	}{
+		"valid": {
+			modify: func(m *models.Machine) {},
+			expect: func(m *models.Machine) {},
+		},
+		"normalized": {
+			modify: func(m *models.Machine) {
+				m.MAC = "52-54-00-AB-CD-01"
+				m.IPv4Addr = " 192.168.0.2 "
+			},
+			expect: func(m *models.Machine) {},
+		},
+		"invalid name": {
+			modify:       func(m *models.Machine) { m.Name = "Machine_1" },
+			expectFields: []string{"name"},
+		},
+		"IPv6": {
+			modify:       func(m *models.Machine) { m.IPv4Addr = "::ffff:192.168.0.2" },
+			expectFields: []string{"ipv4_addr"},
+		},
+		"no IP": {
+			modify:       func(m *models.Machine) { m.IPv4Addr = "" },
+			expectFields: []string{"ipv4_addr"},
+		},
+		"non-positive specs": {
+			modify:       func(m *models.Machine) { m.Spec = models.MachineSpec{Core: 0, Memory: -1, Disk: 1} },
+			expectFields: []string{"spec.core", "spec.memory"},
+		},
+		"invalid label": {
+			modify:       func(m *models.Machine) { m.Labels = map[string]string{"a=b": "c"} },
+			expectFields: []string{"labels.a=b"},
+		},
+		"all fields": {
+			modify: func(m *models.Machine) {
+				*m = models.Machine{DeployedDate: -1, Spec: m.Spec}
+			},
+			expectFields: []string{"mac", "name", "ipv4_addr", "deployed_date"},
+		},
+	}
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			machine := validMachine()
+			tc.modify(machine)
+			actual, err := usecase.NormalizeMachine(machine)
+			if tc.expectFields == nil {
+				if err != nil {
+					t.Fatalf("Unexpected error %v", err)
+				}
+				expect := validMachine()
+				tc.expect(expect)
+				if !reflect.DeepEqual(actual, expect) {
+					t.Errorf("Expect: %#v, Actual: %#v", expect, actual)
+				}
+				return
+			}
+			var invalid *tcErr.ValidationError
+			if !xerrors.As(err, &invalid) || !xerrors.Is(err, tcErr.ErrInvalidArgument) {
+				t.Fatalf("Expect: validation error, Actual: %#v", err)
+			}
+			var fields []string
+			for _, v := range invalid.Violations {
+				fields = append(fields, v.Field)
+			}
+			if !reflect.DeepEqual(fields, tc.expectFields) {
+				t.Errorf("Expect: %v, Actual: %v", tc.expectFields, fields)
+			}
+		})
+	}
+}
+
+func Test_machineUseCaseImpl_ImportMachines_validation(t *testing.T) {
+	machineRepo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatalf("Failed to create the repository due to %v", err)
+	}
+	machineUseCase := usecase.NewMachineUseCase(machineRepo, memory.NewHeartbeatRepository())
+	upper := validMachine()
+	upper.MAC = "52:54:00:AB:CD:01"
+	invalid := validMachine()
+	invalid.Spec.Disk = 0
+	_, err = machineUseCase.ImportMachines(context.Background(), []*models.Machine{validMachine(), upper, invalid}, false)
+	var v *tcErr.ValidationError
+	if !xerrors.As(err, &v) {
+		t.Fatalf("Expect: validation error, Actual: %#v", err)
+	}
+	expect := []tcErr.FieldViolation{
+		{Field: "machines[1].mac", Description: "'52:54:00:ab:cd:01' is duplicated"},
+		{Field: "machines[2].spec.disk", Description: "must be positive but 0"},
+	}
+	if !reflect.DeepEqual(v.Violations, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, v.Violations)
+	}
+	if machines, _ := machineUseCase.GetAllMachines(context.Background()); len(machines) != 0 {
+		t.Errorf("Nothing must be imported. Actual: %v", machines)
+	}
+}
+
+func Test_machineUseCaseImpl_MigrateMACs(t *testing.T) {
+	ctx := context.Background()
+	machineRepo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatalf("Failed to create the repository due to %v", err)
+	}
+	machineUseCase := usecase.NewMachineUseCase(machineRepo, memory.NewHeartbeatRepository())
+	// The machines registered before the MACs were normalized are written into the repository directly.
+	stored := func(mac string, name string) *models.Machine {
+		machine := validMachine()
+		machine.MAC = mac
+		machine.Name = name
+		return machine
+	}
+	for _, machine := range []*models.Machine{
+		stored("52-54-00-AB-CD-01", "migrated"),
+		stored("52:54:00:AB:CD:02", "merged"),
+		stored("52:54:00:ab:cd:02", "merged"),
+		stored("52-54-00-ab-cd-03", "collided"),
+		stored("52:54:00:ab:cd:03", "normalized"),
+		stored("not-a-mac", "invalid"),
+	} {
+		if err := machineRepo.RegisterMachine(ctx, machine); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The pre-seeded machine can not be found by its normalized MAC.
+	if err := machineUseCase.DeleteMachine(ctx, &models.Machine{MAC: "52-54-00-AB-CD-01"}); !xerrors.Is(err, tcErr.ErrNotFound) {
+		t.Fatalf("Expect: not found, Actual: %#v", err)
+	}
+	expect := &models.MACMigrationResult{
+		Migrated:   []*models.MACMigration{{From: "52-54-00-AB-CD-01", To: "52:54:00:ab:cd:01"}},
+		Merged:     []*models.MACMigration{{From: "52:54:00:AB:CD:02", To: "52:54:00:ab:cd:02"}},
+		Collisions: []*models.MACCollision{{MAC: "52:54:00:ab:cd:03", Stored: []string{"52-54-00-ab-cd-03", "52:54:00:ab:cd:03"}}},
+		Invalid:    []string{"not-a-mac"},
+	}
+	before, err := machineUseCase.GetAllMachines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect.DryRun = true
+	result, err := machineUseCase.MigrateMACs(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, result)
+	}
+	if after, _ := machineUseCase.GetAllMachines(ctx); !reflect.DeepEqual(after, before) {
+		t.Errorf("Nothing must be changed by the dry run. Expect: %v, Actual: %v", before, after)
+	}
+	expect.DryRun = false
+	result, err = machineUseCase.MigrateMACs(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expect) {
+		t.Errorf("Expect: %v, Actual: %v", expect, result)
+	}
+	var macs []string
+	machines, err := machineUseCase.GetAllMachines(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, machine := range machines {
+		macs = append(macs, machine.MAC)
+	}
+	expectMACs := []string{"52-54-00-ab-cd-03", "52:54:00:ab:cd:01", "52:54:00:ab:cd:02", "52:54:00:ab:cd:03", "not-a-mac"}
+	if !reflect.DeepEqual(macs, expectMACs) {
+		t.Errorf("Expect: %v, Actual: %v", expectMACs, macs)
+	}
+	// The migrated machine is found by any form of its MAC.
+	if err := machineUseCase.DeleteMachine(ctx, &models.Machine{MAC: "52-54-00-AB-CD-01"}); err != nil {
+		t.Errorf("Failed to delete the migrated machine due to %v", err)
+	}
+}
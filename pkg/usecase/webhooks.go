@@ -0,0 +1,114 @@
+//go:generate gex mockgen -source=$GOFILE -destination=mock/$GOFILE -package=mock
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/repositories"
+)
+
+const (
+	// webhookIDBytes is the length of the random ID of the webhook.
+	webhookIDBytes = 8
+	// webhookSecretBytes is the length of the random secret to sign the payloads.
+	webhookSecretBytes = 32
+)
+
+// WebhookUsecase is the interface to manage the webhooks and the log of their deliveries.
+type WebhookUsecase interface {
+	// CreateWebhook registers url to be notified of the events of the machines in the namespace of ctx.
+	// All events are notified if events is empty. The secret to verify the signatures is generated.
+	CreateWebhook(ctx context.Context, url string, events []string) (*models.Webhook, error)
+	// GetWebhooks returns the webhooks of all namespaces.
+	GetWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	// DeleteWebhook deletes the webhook whose ID is id. This returns ErrNotFound if it does not exist.
+	DeleteWebhook(ctx context.Context, id string) error
+	// RecordDelivery appends the result of the attempt to the log of the webhook.
+	RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// GetDeliveries returns the latest deliveries of the webhook whose ID is id from the oldest one.
+	GetDeliveries(ctx context.Context, id string) ([]*models.WebhookDelivery, error)
+}
+
+type webhookUseCaseImpl struct {
+	repo repositories.WebhookRepository
+}
+
+// validateWebhook returns ErrInvalidArgument with the violations of the URL and the events.
+func validateWebhook(rawURL string, events []string) error {
+	v := &tcErr.ValidationError{}
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		v.Add("url", "must be an absolute HTTP or HTTPS URL")
+	}
+	for i, event := range events {
+		known := false
+		for _, e := range models.WebhookEvents {
+			known = known || e == event
+		}
+		if !known {
+			v.Add(fmt.Sprintf("events[%d]", i), fmt.Sprintf("unknown event '%s'", event))
+		}
+	}
+	return v.Err()
+}
+
+func (w *webhookUseCaseImpl) CreateWebhook(ctx context.Context, rawURL string, events []string) (*models.Webhook, error) {
+	if err := validateWebhook(rawURL, events); err != nil {
+		return nil, err
+	}
+	id, err := randomBytes(webhookIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomBytes(webhookSecretBytes)
+	if err != nil {
+		return nil, err
+	}
+	webhook := &models.Webhook{
+		ID:        hex.EncodeToString(id),
+		Namespace: namespace.FromContext(ctx),
+		URL:       rawURL,
+		Events:    events,
+		Secret:    base64.RawURLEncoding.EncodeToString(secret),
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := w.repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (w *webhookUseCaseImpl) GetWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	return w.repo.GetWebhooks(ctx)
+}
+
+func (w *webhookUseCaseImpl) DeleteWebhook(ctx context.Context, id string) error {
+	return w.repo.DeleteWebhook(ctx, id)
+}
+
+func (w *webhookUseCaseImpl) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if len(delivery.WebhookID) == 0 || len(delivery.ID) == 0 {
+		return xerrors.Errorf("the delivery must have its ID and the ID of the webhook %w", tcErr.ErrInvalidArgument)
+	}
+	return w.repo.AddDelivery(ctx, delivery)
+}
+
+func (w *webhookUseCaseImpl) GetDeliveries(ctx context.Context, id string) ([]*models.WebhookDelivery, error) {
+	return w.repo.GetDeliveries(ctx, id)
+}
+
+// NewWebhookUseCase returns the usecase of the webhooks stored in repo.
+func NewWebhookUseCase(repo repositories.WebhookRepository) WebhookUsecase {
+	return &webhookUseCaseImpl{
+		repo: repo,
+	}
+}
@@ -0,0 +1,82 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/repositories/mock"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+)
+
+func Test_webhookUseCaseImpl_CreateWebhook(t *testing.T) {
+	testCases := map[string]struct {
+		url       string
+		events    []string
+		expectErr error
+	}{
+		"all events": {
+			url:       "https://hooks.example.com/tc",
+			events:    nil,
+			expectErr: nil,
+		},
+		"some events": {
+			url:       "http://127.0.0.1:8000/",
+			events:    []string{models.WebhookEventDeleted, models.WebhookEventHeartbeatLost},
+			expectErr: nil,
+		},
+		"relative url": {
+			url:       "/hooks",
+			events:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"unsupported scheme": {
+			url:       "ftp://hooks.example.com/",
+			events:    nil,
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+		"unknown event": {
+			url:       "https://hooks.example.com/tc",
+			events:    []string{models.WebhookEventRegistered, "machine.exploded"},
+			expectErr: tcErr.ErrInvalidArgument,
+		},
+	}
+	ctx := namespace.NewContext(context.TODO(), "staging")
+	ctrl := gomock.NewController(t)
+	for tn, tc := range testCases {
+		tc := tc
+		t.Run(tn, func(t *testing.T) {
+			t.Parallel()
+			repoMock := mock.NewMockWebhookRepository(ctrl)
+			var stored *models.Webhook
+			if tc.expectErr == nil {
+				repoMock.EXPECT().CreateWebhook(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, webhook *models.Webhook) error {
+					stored = webhook
+					return nil
+				})
+			}
+			webhookUseCase := usecase.NewWebhookUseCase(repoMock)
+			webhook, err := webhookUseCase.CreateWebhook(ctx, tc.url, tc.events)
+			if !xerrors.Is(err, tc.expectErr) {
+				t.Fatalf("Invalid error. Expected: %#v, Actual: %#v", tc.expectErr, err)
+			}
+			if tc.expectErr != nil {
+				return
+			}
+			if webhook != stored || webhook.URL != tc.url || len(webhook.Events) != len(tc.events) {
+				t.Errorf("The created webhook must be stored. Actual: %v", stored)
+			}
+			if webhook.Namespace != "staging" {
+				t.Errorf("The webhook must belong to the namespace of the context. Actual: %s", webhook.Namespace)
+			}
+			if len(webhook.ID) == 0 || len(webhook.Secret) == 0 {
+				t.Errorf("The ID and the secret must be generated. Actual: %v", webhook)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package watch distributes the changes of the machines to the watchers in the process.
+package watch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+)
+
+// BufferSize is the number of the events buffered for each watcher.
+// The watcher which does not receive them in time is closed.
+const BufferSize = 64
+
+// Hub delivers the published events to all subscribers.
+type Hub struct {
+	mu sync.Mutex
+	// watchers maps the channel of each subscriber to its namespace.
+	watchers map[chan *models.MachineEvent]string
+}
+
+// NewHub returns the hub which has no subscribers.
+func NewHub() *Hub {
+	return &Hub{
+		watchers: map[chan *models.MachineEvent]string{},
+	}
+}
+
+// Subscribe returns the channel to receive the events in the namespace of ctx published after the call.
+// The channel is closed when ctx is done, or when the subscriber is too slow to receive the events.
+func (h *Hub) Subscribe(ctx context.Context) <-chan *models.MachineEvent {
+	ch := make(chan *models.MachineEvent, BufferSize)
+	h.mu.Lock()
+	h.watchers[ch] = namespace.FromContext(ctx)
+	h.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		// It may have been closed by Publish.
+		if _, ok := h.watchers[ch]; ok {
+			delete(h.watchers, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+// Publish sends the events to all subscribers in order.
+// Each subscriber receives its own copy of the events.
+func (h *Hub) Publish(events ...*models.MachineEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, ns := range h.watchers {
+		for _, event := range events {
+			if event.Namespace != ns {
+				continue
+			}
+			if !send(ch, event) {
+				delete(h.watchers, ch)
+				close(ch)
+				break
+			}
+		}
+	}
+}
+
+func send(ch chan *models.MachineEvent, event *models.MachineEvent) bool {
+	copied := *event
+	if event.Machine != nil {
+		machine := *event.Machine
+		copied.Machine = &machine
+	}
+	select {
+	case ch <- &copied:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,69 @@
+package watch_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+func TestHub(t *testing.T) {
+	hub := watch.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fast := hub.Subscribe(ctx)
+	slow := hub.Subscribe(ctx)
+	machine := &models.Machine{MAC: "mac1"}
+	for i := 0; i < watch.BufferSize; i++ {
+		hub.Publish(&models.MachineEvent{Type: models.EventPut, Namespace: namespace.Default, Revision: int64(i + 1), Machine: machine})
+		event := <-fast
+		if event.Revision != int64(i+1) {
+			t.Fatalf("Expect: %d, Actual: %d", i+1, event.Revision)
+		}
+		if event.Machine == machine {
+			t.Fatal("The machine must be copied")
+		}
+	}
+	// The buffer of slow is full.
+	hub.Publish(&models.MachineEvent{Type: models.EventDelete, Namespace: namespace.Default, Revision: watch.BufferSize + 1, Machine: machine})
+	for i := 0; i < watch.BufferSize; i++ {
+		<-slow
+	}
+	if _, ok := <-slow; ok {
+		t.Error("The slow watcher must be closed")
+	}
+	if event := <-fast; event.Type != models.EventDelete {
+		t.Errorf("Expect: %s, Actual: %s", models.EventDelete, event.Type)
+	}
+	cancel()
+	select {
+	case _, ok := <-fast:
+		if ok {
+			t.Error("No more events are expected")
+		}
+	case <-time.After(time.Second):
+		t.Error("The channel must be closed after the context is done")
+	}
+}
+
+func TestHub_namespace(t *testing.T) {
+	hub := watch.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lab := hub.Subscribe(namespace.NewContext(ctx, "lab"))
+	hub.Publish(
+		&models.MachineEvent{Type: models.EventPut, Namespace: namespace.Default, Revision: 1, Machine: &models.Machine{MAC: "mac1"}},
+		&models.MachineEvent{Type: models.EventPut, Namespace: "lab", Revision: 2, Machine: &models.Machine{MAC: "mac1"}},
+	)
+	if event := <-lab; event.Revision != 2 {
+		t.Errorf("Expect: the event in lab, Actual: %v", event)
+	}
+	select {
+	case event := <-lab:
+		t.Errorf("No more events are expected. Actual: %v", event)
+	default:
+	}
+}
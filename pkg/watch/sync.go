@@ -0,0 +1,73 @@
+package watch
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+const (
+	// minResyncBackoff is the wait after the first failure to load the machines.
+	minResyncBackoff = 500 * time.Millisecond
+	// maxResyncBackoff is the upper limit of the wait between the attempts to load the machines.
+	maxResyncBackoff = 30 * time.Second
+)
+
+// Source is where the machines and their changes are loaded from, such as usecase.MachineUsecase.
+type Source interface {
+	GetAllMachines(ctx context.Context) ([]*models.Machine, error)
+	WatchMachines(ctx context.Context) (<-chan *models.MachineEvent, error)
+}
+
+// Handler keeps the copy of the machines.
+type Handler interface {
+	// Replace replaces all machines by the given ones.
+	Replace(machines []*models.Machine)
+	// Apply applies the change of the machine.
+	Apply(event *models.MachineEvent)
+}
+
+// Sync keeps handler up to date with the machines of source until ctx is done.
+// The watch is started before the machines are loaded so that no change is missed between them,
+// and it is restarted with all machines loaded again whenever it is interrupted.
+func Sync(ctx context.Context, source Source, handler Handler) {
+	failures := 0
+	for {
+		if err := syncOnce(ctx, source, handler); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to sync the machines: %v", err)
+			failures++
+		} else {
+			failures = 0
+		}
+		backoff := minResyncBackoff << uint(failures)
+		if backoff > maxResyncBackoff || backoff <= 0 {
+			backoff = maxResyncBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// syncOnce loads all machines and applies the changes until the watch is interrupted.
+func syncOnce(ctx context.Context, source Source, handler Handler) error {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := source.WatchMachines(watchCtx)
+	if err != nil {
+		return err
+	}
+	machines, err := source.GetAllMachines(watchCtx)
+	if err != nil {
+		return err
+	}
+	handler.Replace(machines)
+	for event := range events {
+		handler.Apply(event)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package watch_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+// addrs keeps the IPv4 address of each machine.
+type addrs struct {
+	mu    sync.Mutex
+	addrs map[string]string
+}
+
+func (a *addrs) Replace(machines []*models.Machine) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addrs = map[string]string{}
+	for _, machine := range machines {
+		a.addrs[machine.MAC] = machine.IPv4Addr
+	}
+}
+
+func (a *addrs) Apply(event *models.MachineEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if event.Type == models.EventDelete {
+		delete(a.addrs, event.Machine.MAC)
+		return
+	}
+	a.addrs[event.Machine.MAC] = event.Machine.IPv4Addr
+}
+
+func (a *addrs) get(mac string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.addrs[mac]
+}
+
+func TestSync(t *testing.T) {
+	repo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	machineUsecase := usecase.NewMachineUseCase(repo, memory.NewHeartbeatRepository())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	machine := &models.Machine{
+		Name:     "machine1",
+		MAC:      "52:54:00:00:00:01",
+		IPv4Addr: "192.168.1.1",
+		Spec:     models.MachineSpec{Core: 2, Memory: 1024, Disk: 64},
+	}
+	if err := machineUsecase.RegisterOrUpdateMachine(ctx, machine); err != nil {
+		t.Fatal(err)
+	}
+	handler := &addrs{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watch.Sync(ctx, machineUsecase, handler)
+	}()
+
+	waitFor := func(expected string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if handler.get(machine.MAC) == expected {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Expect: '%s', Actual: '%s'", expected, handler.get(machine.MAC))
+	}
+	waitFor("192.168.1.1")
+
+	changed := *machine
+	changed.IPv4Addr = "192.168.1.10"
+	if err := machineUsecase.RegisterOrUpdateMachine(ctx, &changed); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("192.168.1.10")
+
+	if err := machineUsecase.DeleteMachine(ctx, &changed); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Sync must return when the context is done")
+	}
+}
@@ -0,0 +1,131 @@
+// Package webhook posts the events of the machines to the registered webhooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	tcErr "github.com/pddg/tiny-cluster/pkg/errors"
+	"github.com/pddg/tiny-cluster/pkg/models"
+)
+
+// Headers of the requests posted to the webhooks.
+const (
+	// HeaderEvent is the header of the event of the payload.
+	HeaderEvent = "X-TC-Event"
+	// HeaderDelivery is the header of the ID of the payload, which is the same among the retries.
+	HeaderDelivery = "X-TC-Delivery"
+	// HeaderSignature is the header of the signature of the body in the form of "sha256=<hex>".
+	HeaderSignature = "X-TC-Signature"
+)
+
+// signaturePrefix is the prefix of the signature naming the hash function.
+const signaturePrefix = "sha256="
+
+// Sign returns the HMAC-SHA256 signature of body with the secret of the webhook as HeaderSignature.
+// The receivers verify the payloads by computing it again with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomID returns a random hex string of n bytes.
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// The IDs only tell the events apart, so fall back to the time.
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// deliveryID returns the ID of the attempt, which is ordered by the time.
+func deliveryID(now time.Time) string {
+	return fmt.Sprintf("%020d-%s", now.UnixNano(), randomID(4))
+}
+
+// post posts body to the webhook once and returns the result of the attempt.
+func (d *Dispatcher) post(ctx context.Context, webhook *models.Webhook, payload *models.WebhookPayload, body []byte, attempt int) *models.WebhookDelivery {
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:        deliveryID(now),
+		WebhookID: webhook.ID,
+		EventID:   payload.ID,
+		Event:     payload.Event,
+		Attempt:   attempt,
+		Timestamp: now.Unix(),
+	}
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tiny-cluster-webhook")
+	req.Header.Set(HeaderEvent, payload.Event)
+	req.Header.Set(HeaderDelivery, payload.ID)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, body))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection is reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		delivery.Error = "unexpected status " + resp.Status
+		return delivery
+	}
+	delivery.Success = true
+	return delivery
+}
+
+// deliver posts body to the webhook until it succeeds or MaxAttempts attempts fail.
+// The wait between the attempts starts from MinBackoff and is doubled on each failure.
+// Every attempt is recorded in the log of the webhook.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *models.Webhook, payload *models.WebhookPayload, body []byte) {
+	backoff := d.MinBackoff
+	for attempt := 1; ; attempt++ {
+		delivery := d.post(ctx, webhook, payload, body, attempt)
+		if ctx.Err() != nil {
+			// The attempt was interrupted by the shutdown rather than by the webhook.
+			return
+		}
+		if err := d.webhooks.RecordDelivery(ctx, delivery); err != nil {
+			if xerrors.Is(err, tcErr.ErrNotFound) {
+				// The webhook has been deleted.
+				return
+			}
+			log.Printf("Failed to record the delivery of %s to webhook %s: %v", payload.Event, webhook.ID, err)
+		}
+		if delivery.Success {
+			return
+		}
+		if attempt >= d.MaxAttempts {
+			log.Printf("Gave up delivering %s %s to webhook %s: %s", payload.Event, payload.ID, webhook.ID, delivery.Error)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
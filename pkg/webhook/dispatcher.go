@@ -0,0 +1,305 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+	"github.com/pddg/tiny-cluster/pkg/watch"
+)
+
+const (
+	// DefaultMaxAttempts is the default number of the attempts to deliver an event.
+	DefaultMaxAttempts = 5
+	// DefaultMinBackoff is the default wait after the first failed attempt.
+	DefaultMinBackoff = time.Second
+	// DefaultRefreshInterval is the default interval to load the webhooks again.
+	DefaultRefreshInterval = 10 * time.Second
+	// DefaultHeartbeatInterval is the default interval to check the heartbeats of the machines.
+	DefaultHeartbeatInterval = 15 * time.Second
+	// DefaultTimeout is the default timeout of a request to the webhook.
+	DefaultTimeout = 10 * time.Second
+	// DefaultProvisioningTimeout is the default deadline of the provisioning.
+	DefaultProvisioningTimeout = time.Hour
+)
+
+// Dispatcher watches the machines of the namespaces which have webhooks and posts their events.
+// It must run on only one replica, such as a task of leader.Runner, or the events are notified twice.
+//
+// The events are derived from the changes of the machines:
+// machine.registered, machine.updated and machine.deleted from the changes themselves,
+// machine.state_changed when the machine is deployed or its deployed date is cleared,
+// and machine.heartbeat_lost when the liveness turns from reachable to unreachable.
+//
+// provisioning.started is notified when the deployed date is cleared, such as by the reprovision API,
+// and provisioning.finished when the deployed date is set. provisioning.failed is notified if the machine
+// is neither deployed nor checks in within ProvisioningTimeout after the provisioning started.
+// The deadlines are kept by the dispatcher, so the provisioning started before it runs,
+// e.g. before the leadership moved to this replica, is not reported as failed.
+//
+// The events are delivered concurrently, so the receivers should not rely on their order.
+type Dispatcher struct {
+	// Client is the HTTP client to post the events.
+	Client *http.Client
+	// MaxAttempts is the number of the attempts to deliver an event before giving up.
+	MaxAttempts int
+	// MinBackoff is the wait after the first failed attempt. It is doubled on each failure.
+	MinBackoff time.Duration
+	// RefreshInterval is the interval to load the webhooks to find the namespaces to watch.
+	RefreshInterval time.Duration
+	// HeartbeatInterval is the interval to check the heartbeats of the machines and the deadlines of the provisioning.
+	HeartbeatInterval time.Duration
+	// ProvisioningTimeout is the deadline for the machine being provisioned to be deployed or check in.
+	ProvisioningTimeout time.Duration
+
+	webhooks usecase.WebhookUsecase
+	machines usecase.MachineUsecase
+}
+
+// NewDispatcher returns the dispatcher of the webhooks with the default settings.
+func NewDispatcher(webhooks usecase.WebhookUsecase, machines usecase.MachineUsecase) *Dispatcher {
+	return &Dispatcher{
+		Client:              &http.Client{Timeout: DefaultTimeout},
+		MaxAttempts:         DefaultMaxAttempts,
+		MinBackoff:          DefaultMinBackoff,
+		RefreshInterval:     DefaultRefreshInterval,
+		HeartbeatInterval:   DefaultHeartbeatInterval,
+		ProvisioningTimeout: DefaultProvisioningTimeout,
+		webhooks:            webhooks,
+		machines:            machines,
+	}
+}
+
+// Run dispatches the events until ctx is done. It implements leader.Task.
+// The namespaces are watched while they have webhooks, and the deliveries in flight are canceled on return.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watching := map[string]context.CancelFunc{}
+	ticker := time.NewTicker(d.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		webhooks, err := d.webhooks.GetWebhooks(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to load the webhooks: %v", err)
+		}
+		if err == nil {
+			namespaces := map[string]struct{}{}
+			for _, webhook := range webhooks {
+				namespaces[webhook.Namespace] = struct{}{}
+			}
+			for name := range namespaces {
+				if _, ok := watching[name]; ok {
+					continue
+				}
+				nsCtx, nsCancel := context.WithCancel(namespace.NewContext(ctx, name))
+				watching[name] = nsCancel
+				d.watchNamespace(nsCtx, name, &wg)
+			}
+			for name, nsCancel := range watching {
+				if _, ok := namespaces[name]; !ok {
+					nsCancel()
+					delete(watching, name)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchNamespace starts to watch the machines and their heartbeats in the namespace until ctx is done.
+func (d *Dispatcher) watchNamespace(ctx context.Context, name string, wg *sync.WaitGroup) {
+	t := &tracker{
+		dispatcher:   d,
+		ctx:          ctx,
+		namespace:    name,
+		wg:           wg,
+		provisioning: map[string]time.Time{},
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		watch.Sync(ctx, d.machines, t)
+	}()
+	go func() {
+		defer wg.Done()
+		t.checkHeartbeats()
+	}()
+}
+
+// notify posts the event of the machine to the webhooks of the namespace which subscribe it.
+func (d *Dispatcher) notify(ctx context.Context, wg *sync.WaitGroup, event string, machine, previous *models.Machine) {
+	webhooks, err := d.webhooks.GetWebhooks(ctx)
+	if err != nil {
+		log.Printf("Failed to load the webhooks to notify %s of machine %s: %v", event, machine.MAC, err)
+		return
+	}
+	payload := &models.WebhookPayload{
+		ID:        randomID(16),
+		Event:     event,
+		Namespace: namespace.FromContext(ctx),
+		Timestamp: time.Now().Unix(),
+		Machine:   machine,
+		Previous:  previous,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode %s of machine %s: %v", event, machine.MAC, err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if webhook.Namespace != payload.Namespace || !webhook.Subscribes(event) {
+			continue
+		}
+		wg.Add(1)
+		go func(webhook *models.Webhook) {
+			defer wg.Done()
+			d.deliver(ctx, webhook, payload, body)
+		}(webhook)
+	}
+}
+
+// tracker keeps the machines of a namespace to derive the events from their changes.
+// It implements watch.Handler.
+type tracker struct {
+	dispatcher *Dispatcher
+	ctx        context.Context
+	namespace  string
+	wg         *sync.WaitGroup
+
+	// machines is nil until the machines are loaded first, whose changes are not notified.
+	machines map[string]*models.Machine
+
+	// provisioning is the time when the provisioning of the machines started by their MACs.
+	// It is shared with checkHeartbeats.
+	mu           sync.Mutex
+	provisioning map[string]time.Time
+}
+
+// Replace implements watch.Handler.
+// The differences from the known machines are notified since they were changed while the watch was interrupted.
+func (t *tracker) Replace(machines []*models.Machine) {
+	current := make(map[string]*models.Machine, len(machines))
+	for _, machine := range machines {
+		current[machine.MAC] = machine
+	}
+	if t.machines != nil {
+		for mac, machine := range current {
+			t.changed(t.machines[mac], machine)
+		}
+		for mac, machine := range t.machines {
+			if _, ok := current[mac]; !ok {
+				t.finishProvisioning(mac)
+				t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventDeleted, machine, nil)
+			}
+		}
+	}
+	t.machines = current
+}
+
+// Apply implements watch.Handler.
+func (t *tracker) Apply(event *models.MachineEvent) {
+	if t.machines == nil {
+		t.machines = map[string]*models.Machine{}
+	}
+	previous := t.machines[event.Machine.MAC]
+	if event.Type == models.EventDelete {
+		delete(t.machines, event.Machine.MAC)
+		t.finishProvisioning(event.Machine.MAC)
+		t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventDeleted, event.Machine, nil)
+		return
+	}
+	t.machines[event.Machine.MAC] = event.Machine
+	t.changed(previous, event.Machine)
+}
+
+// changed notifies the events of the change from previous to machine. previous is nil if it is new.
+func (t *tracker) changed(previous, machine *models.Machine) {
+	if previous == nil {
+		t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventRegistered, machine, nil)
+		return
+	}
+	if reflect.DeepEqual(previous, machine) {
+		return
+	}
+	t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventUpdated, machine, previous)
+	if (previous.DeployedDate > 0) == (machine.DeployedDate > 0) {
+		return
+	}
+	t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventStateChanged, machine, previous)
+	if machine.DeployedDate > 0 {
+		t.finishProvisioning(machine.MAC)
+		t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventProvisioningFinished, machine, previous)
+		return
+	}
+	t.mu.Lock()
+	t.provisioning[machine.MAC] = time.Now()
+	t.mu.Unlock()
+	t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventProvisioningStarted, machine, previous)
+}
+
+// finishProvisioning stops waiting for the machine whose MAC is mac to be provisioned.
+func (t *tracker) finishProvisioning(mac string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.provisioning, mac)
+}
+
+// checkProvisioning notifies the machine if it has been provisioned for longer than the deadline without any check-in.
+func (t *tracker) checkProvisioning(machine *models.Machine) {
+	t.mu.Lock()
+	started, ok := t.provisioning[machine.MAC]
+	if !ok || (machine.LastSeen < started.Unix() && time.Since(started) < t.dispatcher.ProvisioningTimeout) {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.provisioning, machine.MAC)
+	t.mu.Unlock()
+	// The machine which checked in after the start is running, though it has not been marked as deployed.
+	if machine.LastSeen < started.Unix() {
+		t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventProvisioningFailed, machine, nil)
+	}
+}
+
+// checkHeartbeats notifies the machines whose heartbeats have expired since the last check,
+// and the ones whose provisioning has not finished within the deadline until ctx is done.
+func (t *tracker) checkHeartbeats() {
+	var reachable map[string]bool
+	ticker := time.NewTicker(t.dispatcher.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		machines, err := t.dispatcher.machines.GetAllMachinesWithLiveness(t.ctx)
+		if err != nil && t.ctx.Err() == nil {
+			log.Printf("Failed to check the heartbeats in namespace %s: %v", t.namespace, err)
+		}
+		if err == nil {
+			current := make(map[string]bool, len(machines))
+			for _, machine := range machines {
+				current[machine.MAC] = machine.Liveness == models.LivenessReachable
+				if reachable[machine.MAC] && machine.Liveness == models.LivenessUnreachable {
+					t.dispatcher.notify(t.ctx, t.wg, models.WebhookEventHeartbeatLost, machine, nil)
+				}
+				t.checkProvisioning(machine)
+			}
+			reachable = current
+		}
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
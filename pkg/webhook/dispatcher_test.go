@@ -0,0 +1,208 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pddg/tiny-cluster/pkg/memory"
+	"github.com/pddg/tiny-cluster/pkg/models"
+	"github.com/pddg/tiny-cluster/pkg/namespace"
+	"github.com/pddg/tiny-cluster/pkg/usecase"
+	"github.com/pddg/tiny-cluster/pkg/webhook"
+)
+
+// receiver is the webhook which fails the first request.
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	requests int
+	payloads chan *models.WebhookPayload
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("Failed to read the body: %v", err)
+		return
+	}
+	if !hmac.Equal([]byte(req.Header.Get(webhook.HeaderSignature)), []byte(webhook.Sign(r.secret, body))) {
+		r.t.Errorf("Invalid signature: %s", req.Header.Get(webhook.HeaderSignature))
+	}
+	payload := &models.WebhookPayload{}
+	if err := json.Unmarshal(body, payload); err != nil {
+		r.t.Errorf("Failed to decode the payload: %v", err)
+	}
+	if req.Header.Get(webhook.HeaderEvent) != payload.Event || req.Header.Get(webhook.HeaderDelivery) != payload.ID {
+		r.t.Errorf("The headers do not match the payload: %v", req.Header)
+	}
+	r.mu.Lock()
+	r.requests++
+	first := r.requests == 1
+	r.mu.Unlock()
+	if first {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	r.payloads <- payload
+}
+
+// expect waits for the payloads of the events in any order.
+func (r *receiver) expect(events ...string) []*models.WebhookPayload {
+	r.t.Helper()
+	var received []*models.WebhookPayload
+	remaining := map[string]int{}
+	for _, event := range events {
+		remaining[event]++
+	}
+	for range events {
+		select {
+		case payload := <-r.payloads:
+			if remaining[payload.Event] == 0 {
+				r.t.Fatalf("Unexpected event %s. Expected: %v", payload.Event, events)
+			}
+			remaining[payload.Event]--
+			received = append(received, payload)
+		case <-time.After(5 * time.Second):
+			r.t.Fatalf("Timed out waiting for %v. Received: %d", events, len(received))
+		}
+	}
+	return received
+}
+
+func TestDispatcher(t *testing.T) {
+	machineRepo, err := memory.NewMachineRepository("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	heartbeatRepo := memory.NewHeartbeatRepository()
+	machineUsecase := usecase.NewMachineUseCase(machineRepo, heartbeatRepo)
+	webhookUsecase := usecase.NewWebhookUseCase(memory.NewWebhookRepository())
+	ctx, cancel := context.WithCancel(namespace.NewContext(context.Background(), "staging"))
+	defer cancel()
+
+	recv := &receiver{t: t, payloads: make(chan *models.WebhookPayload, 10)}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+	hook, err := webhookUsecase.CreateWebhook(ctx, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv.secret = hook.Secret
+	// The machines of the other namespaces must not be notified.
+	if _, err := webhookUsecase.CreateWebhook(namespace.NewContext(ctx, "prod"), server.URL+"/prod", []string{models.WebhookEventDeleted}); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := webhook.NewDispatcher(webhookUsecase, machineUsecase)
+	dispatcher.MinBackoff = 10 * time.Millisecond
+	dispatcher.RefreshInterval = 20 * time.Millisecond
+	dispatcher.HeartbeatInterval = 20 * time.Millisecond
+	dispatcher.ProvisioningTimeout = 100 * time.Millisecond
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dispatcher.Run(ctx)
+	}()
+	// Wait for the machines to be loaded, whose changes are notified after that.
+	time.Sleep(200 * time.Millisecond)
+
+	machine := &models.Machine{
+		Name:     "machine1",
+		MAC:      "52:54:00:00:00:01",
+		IPv4Addr: "192.168.1.1",
+		Spec:     models.MachineSpec{Core: 2, Memory: 1024, Disk: 64},
+	}
+	if err := machineUsecase.RegisterOrUpdateMachine(ctx, machine); err != nil {
+		t.Fatal(err)
+	}
+	registered := recv.expect(models.WebhookEventRegistered)[0]
+	if registered.Namespace != "staging" || registered.Machine.MAC != machine.MAC {
+		t.Errorf("Invalid payload: %v", registered)
+	}
+	// The success is recorded after the response.
+	var deliveries []*models.WebhookDelivery
+	for deadline := time.Now().Add(5 * time.Second); len(deliveries) < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		if deliveries, err = webhookUsecase.GetDeliveries(ctx, hook.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(deliveries) != 2 || deliveries[0].StatusCode != http.StatusInternalServerError || !deliveries[1].Success {
+		t.Fatalf("The failed attempt must be retried. Actual: %v", deliveries)
+	}
+	if deliveries[0].EventID != registered.ID || deliveries[1].EventID != registered.ID || deliveries[1].Attempt != 2 {
+		t.Errorf("The retry must post the same event. Actual: %v, %v", deliveries[0], deliveries[1])
+	}
+
+	if _, err := machineUsecase.PatchMachine(ctx, machine.MAC, &models.Machine{DeployedDate: time.Now().Unix()}, []string{"deployed_date"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range recv.expect(models.WebhookEventUpdated, models.WebhookEventStateChanged, models.WebhookEventProvisioningFinished) {
+		if payload.Previous == nil || payload.Previous.DeployedDate != 0 || payload.Machine.DeployedDate == 0 {
+			t.Errorf("Invalid payload of %s: %v", payload.Event, payload)
+		}
+	}
+
+	// The machine being provisioned again neither is deployed nor checks in.
+	if _, err := machineUsecase.PatchMachine(ctx, machine.MAC, &models.Machine{}, []string{"deployed_date"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range recv.expect(models.WebhookEventUpdated, models.WebhookEventStateChanged, models.WebhookEventProvisioningStarted) {
+		if payload.Previous == nil || payload.Previous.DeployedDate == 0 || payload.Machine.DeployedDate != 0 {
+			t.Errorf("Invalid payload of %s: %v", payload.Event, payload)
+		}
+	}
+	failed := recv.expect(models.WebhookEventProvisioningFailed)[0]
+	if failed.Machine.MAC != machine.MAC || failed.Machine.DeployedDate != 0 {
+		t.Errorf("Invalid payload: %v", failed)
+	}
+
+	if err := heartbeatRepo.Heartbeat(ctx, machine.MAC, 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	lost := recv.expect(models.WebhookEventHeartbeatLost)[0]
+	if lost.Machine.Liveness != models.LivenessUnreachable {
+		t.Errorf("Invalid liveness: %s", lost.Machine.Liveness)
+	}
+
+	// The machine which checks in within the deadline is not reported as failed.
+	if _, err := machineUsecase.PatchMachine(ctx, machine.MAC, &models.Machine{DeployedDate: time.Now().Unix()}, []string{"deployed_date"}); err != nil {
+		t.Fatal(err)
+	}
+	recv.expect(models.WebhookEventUpdated, models.WebhookEventStateChanged, models.WebhookEventProvisioningFinished)
+	if _, err := machineUsecase.PatchMachine(ctx, machine.MAC, &models.Machine{}, []string{"deployed_date"}); err != nil {
+		t.Fatal(err)
+	}
+	recv.expect(models.WebhookEventUpdated, models.WebhookEventStateChanged, models.WebhookEventProvisioningStarted)
+	if err := heartbeatRepo.Heartbeat(ctx, machine.MAC, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(3 * dispatcher.ProvisioningTimeout)
+	if _, err := machineUsecase.PatchMachine(ctx, machine.MAC, &models.Machine{DeployedDate: time.Now().Unix()}, []string{"deployed_date"}); err != nil {
+		t.Fatal(err)
+	}
+	recv.expect(models.WebhookEventUpdated, models.WebhookEventStateChanged, models.WebhookEventProvisioningFinished)
+
+	if err := machineUsecase.DeleteMachine(ctx, machine); err != nil {
+		t.Fatal(err)
+	}
+	recv.expect(models.WebhookEventDeleted)
+
+	cancel()
+	<-done
+	select {
+	case payload := <-recv.payloads:
+		t.Errorf("Unexpected event: %v", payload)
+	default:
+	}
+}